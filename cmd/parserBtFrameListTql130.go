@@ -1,17 +1,18 @@
 // 从抓包中过滤出写入操作
-// 1. 过滤上报的连接状态信息
-// 2. 过滤出对应的写入信息
+// 1. 跟踪连接状态信息
+// 2. 过滤出对应的写入信息，并解析到对端地址
 
 package main
 
 import (
-	"cmd/btsnooper.go/pkg/hci"
 	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
 
+	"wangdalian/btsnooper/pkg/analyzer"
 	"wangdalian/btsnooper/pkg/btsnoop"
+	"wangdalian/btsnooper/pkg/hci"
 )
 
 func main() {
@@ -45,43 +46,46 @@ func main() {
 	}
 
 	// 解析所有的包
-	// 1. 处理PKT_TYPE_HCI_EVT LE_ENHANCED_CONNECTION_COMPLETE_EVENT，获取连接信息(主要是对端地址，Connection Handle)
-	// 2. 处理PKT_TYPE_HCI_ACL ATT_WRITE_REQUEST，获取写Handle操作(主要通过Connection Handle匹配)
-	var leEnhancedConnectionCompleteEventList []hci.LeEnhancedConnectionCompleteEvent
-	var attWriteRequestList []hci.AttWriteRequest
-	var attWriteRequestConnHandleList []uint16
-	for _, hciPktBuf := range btsnooper.PacketRecordList {
-		hciPktType := hciPktBuf.Payload[0]
-		hciParserResult := hci.HciPktParse(hciPktType, hciPktBuf.Payload[1:])
-		if hciParserResult.Code == hci.HCI_PKT_RET_CODE_OK { // 解析成功后
-			if hciParserResult.HciPktType == hci.PKT_TYPE_HCI_EVT {
-				parsed, _ := hciParserResult.Ret.(hci.HciEvt)
-				hciEvtPktParseResult, _ := parsed.PayloadParsedResult.(hci.HciEvtPktParseResult)
-				if hciEvtPktParseResult.Code == hci.HCI_PKT_RET_CODE_OK {
-					if hciEvtPktParseResult.EventCode == hci.HCI_EVT_LE_META_EVENT && hciEvtPktParseResult.SubEventCode == hci.LE_ENHANCED_CONNECTION_COMPLETE_EVENT {
-						parsed, _ := hciEvtPktParseResult.Ret.(hci.LeEnhancedConnectionCompleteEvent)
-						leEnhancedConnectionCompleteEventList = append(leEnhancedConnectionCompleteEventList, parsed)
-					}
-				}
-			} else if hciParserResult.HciPktType == hci.PKT_TYPE_HCI_ACL {
-				parsed, _ := hciParserResult.Ret.(hci.HciAcl)
-				hciAclPktParseResult, _ := parsed.PayloadParsedResult.(hci.HciAclPktParseResult)
-				if hciAclPktParseResult.Code == hci.HCI_PKT_RET_CODE_OK {
-					if hciAclPktParseResult.OpCode == hci.ATT_WRITE_REQUEST {
-						attWriteRequestConnHandleList = append(attWriteRequestConnHandleList, parsed.Handle)
-						parsed, _ := hciAclPktParseResult.Ret.(hci.AttWriteRequest)
-						attWriteRequestList = append(attWriteRequestList, parsed)
-					}
-				}
-			}
-		}
+	// 1. 连接跟踪处理连接建立/断开事件，获取连接信息(主要是对端地址，Connection Handle)
+	// 2. 处理PKT_TYPE_HCI_ACL ATT_WRITE_REQ/ATT_WRITE_CMD/ATT_SIGNED_WRITE_CMD，按记录时间戳将Connection Handle解析到当时的连接
+	recordList, _ := analyzer.RecordListParse(btsnooper)
+	connTracker := analyzer.NewConnTracker()
+	for _, record := range recordList {
+		connTracker.Feed(record)
 	}
 
-	for _, item := range leEnhancedConnectionCompleteEventList {
-		fmt.Printf("LE_ENHANCED_CONNECTION_COMPLETE_EVENT: %d %s\n", item.ConnectionHandle, hex.EncodeToString(item.PeerAddress[:]))
+	for _, conn := range connTracker.ConnList {
+		if conn.Implicit {
+			continue
+		}
+		fmt.Printf("CONNECTION: %d %s\n", conn.ConnectionHandle, hci.BdAddrString(conn.PeerAddress))
 	}
 
-	for index, item := range attWriteRequestList {
-		fmt.Printf("ATT_WRITE_REQUEST: %d %d %s\n", attWriteRequestConnHandleList[index], item.Handle, hex.EncodeToString(item.Value))
+	for _, record := range recordList {
+		acl, ok := record.Acl()
+		if !ok {
+			continue
+		}
+		hciAclPktParseResult, _ := acl.PayloadParsedResult.(hci.HciAclPktParseResult)
+		if hciAclPktParseResult.Code != hci.HCI_PKT_RET_CODE_OK || hciAclPktParseResult.ChannelId != hci.L2CAP_CID_ATT {
+			continue
+		}
+		var handle uint16
+		var value []byte
+		switch parsed := hciAclPktParseResult.Ret.(type) {
+		case hci.AttWriteRequest:
+			handle, value = parsed.Handle, parsed.Value
+		case hci.AttWriteCommand:
+			handle, value = parsed.Handle, parsed.Value
+		default:
+			continue
+		}
+		// 按完整OpCode取名称，ATT_SIGNED_WRITE_CMD与ATT_WRITE_CMD区分
+		name := hci.AttOpCodeStrMap[int(hciAclPktParseResult.OpCode)]
+		peer := "unknown"
+		if conn, ok := connTracker.Resolve(acl.Handle, record.TimestampUs); ok && !conn.Implicit {
+			peer = hci.BdAddrString(conn.PeerAddress)
+		}
+		fmt.Printf("%s: %d %s %d %s\n", name, acl.Handle, peer, handle, hex.EncodeToString(value))
 	}
 }
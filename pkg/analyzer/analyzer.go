@@ -0,0 +1,129 @@
+// 抓包分析，基于btsnoop记录和HCI解析结果做跨包关联
+
+package analyzer
+
+import (
+	"fmt"
+	"time"
+
+	"wangdalian/btsnooper/pkg/btsnoop"
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 一条解析后的btsnoop记录
+type Record struct {
+	Index       int    // BTSnoop Packet Record Index
+	TimestampUs uint64 // btsnoop时间戳，单位微秒
+	PacketFlags uint32
+	Parsed      hci.HciPktParseResult
+
+	// ACL分片重组异常(无首包的后续分片、未完成的PDU被新首包/连接断开/抓包结束打断、长度不符)，记录在发现异常的记录上
+	L2capReassemblyErr error
+}
+
+// 解析btsnoop文件中的所有HCI包，ACL分片按handle和方向重组为完整的L2CAP PDU后解析，动态信道按PSM解析
+// 返回路由动态信道时使用的L2capChannelTracker，其中的信道、ERTM事件和credit stall不需要再遍历一次记录
+func RecordListParse(btsnooper *btsnoop.FileParser) ([]Record, *L2capChannelTracker) {
+	recordList := make([]Record, 0, len(btsnooper.PacketRecordList))
+	for index, pkt := range btsnooper.PacketRecordList {
+		if len(pkt.Payload) <= 0 {
+			continue
+		}
+		record := Record{Index: index, TimestampUs: pkt.TimestampMs, PacketFlags: pkt.PacketFlags}
+		record.Parsed = hci.HciPktParse(pkt.Payload[0], pkt.Payload[1:])
+		recordList = append(recordList, record)
+	}
+	L2capReassemble(recordList)
+	return recordList, L2capChannelRoute(recordList)
+}
+
+type aclFragmentKey struct {
+	handle   uint16
+	received bool
+}
+
+// ACL分片重组，重组完成的PDU解析结果填到最后一个分片的PayloadParsedResult，其他分片保持HCI_PKT_RET_CODE_FRAGMENT
+func L2capReassemble(recordList []Record) {
+	reassembler := hci.NewL2capReassembler()
+	lastFragmentMap := map[aclFragmentKey]int{} // 未完成PDU的最后一个分片在recordList中的位置
+	for pos := range recordList {
+		record := &recordList[pos]
+		if _, evt, ok := record.EvtParseResult(); ok {
+			if pkt, ok := evt.Ret.(hci.DisconnectionCompleteEvent); ok && pkt.Status == hci.HCI_STATUS_SUCCESS {
+				record.L2capReassemblyErr = reassembler.Flush(pkt.ConnectionHandle)
+				delete(lastFragmentMap, aclFragmentKey{handle: pkt.ConnectionHandle, received: false})
+				delete(lastFragmentMap, aclFragmentKey{handle: pkt.ConnectionHandle, received: true})
+			}
+			continue
+		}
+		acl, ok := record.Acl()
+		if !ok {
+			continue
+		}
+		key := aclFragmentKey{handle: acl.Handle, received: record.IsReceived()}
+		pdu, complete, err := reassembler.Push(key.received, acl)
+		record.L2capReassemblyErr = err
+		if !complete {
+			lastFragmentMap[key] = pos
+			continue
+		}
+		delete(lastFragmentMap, key)
+		if pdu.FragmentCount > 1 {
+			acl.PayloadParsedResult = hci.HciAclPktParse(pdu.Data)
+			record.Parsed.Ret = acl
+		}
+	}
+	for key, pos := range lastFragmentMap {
+		if pending, ok := reassembler.Pending(key.handle, key.received); ok && recordList[pos].L2capReassemblyErr == nil {
+			recordList[pos].L2capReassemblyErr = fmt.Errorf("acl handle %#x: l2cap pdu incomplete at end of capture, %d/%d bytes received", key.handle, len(pending.Data), int(pending.Length)+4)
+		}
+	}
+}
+
+// 是否是controller上报的数据
+func (record Record) IsReceived() bool {
+	return btsnoop.PacketFlagsIsReceived(record.PacketFlags)
+}
+
+// 记录时间
+func (record Record) Time() time.Time {
+	return btsnoop.TimestampTime(record.TimestampUs)
+}
+
+// 获取HCI CMD解析结果，解析失败返回false
+func (record Record) CmdParseResult() (hci.HciCmd, hci.HciCmdPktParseResult, bool) {
+	if record.Parsed.Code != hci.HCI_PKT_RET_CODE_OK || record.Parsed.HciPktType != hci.PKT_TYPE_HCI_CMD {
+		return hci.HciCmd{}, hci.HciCmdPktParseResult{}, false
+	}
+	pkt, _ := record.Parsed.Ret.(hci.HciCmd)
+	parsed, ok := pkt.PayloadParsedResult.(hci.HciCmdPktParseResult)
+	return pkt, parsed, ok && parsed.Code == hci.HCI_PKT_RET_CODE_OK
+}
+
+// 获取HCI EVT解析结果，解析失败返回false
+func (record Record) EvtParseResult() (hci.HciEvt, hci.HciEvtPktParseResult, bool) {
+	if record.Parsed.Code != hci.HCI_PKT_RET_CODE_OK || record.Parsed.HciPktType != hci.PKT_TYPE_HCI_EVT {
+		return hci.HciEvt{}, hci.HciEvtPktParseResult{}, false
+	}
+	pkt, _ := record.Parsed.Ret.(hci.HciEvt)
+	parsed, ok := pkt.PayloadParsedResult.(hci.HciEvtPktParseResult)
+	return pkt, parsed, ok && parsed.Code == hci.HCI_PKT_RET_CODE_OK
+}
+
+// 获取HCI ACL包，解析失败返回false
+func (record Record) Acl() (hci.HciAcl, bool) {
+	if record.Parsed.Code != hci.HCI_PKT_RET_CODE_OK || record.Parsed.HciPktType != hci.PKT_TYPE_HCI_ACL {
+		return hci.HciAcl{}, false
+	}
+	pkt, ok := record.Parsed.Ret.(hci.HciAcl)
+	return pkt, ok
+}
+
+// 获取HCI SYNC包，解析失败返回false
+func (record Record) Sync() (hci.HciSync, bool) {
+	if record.Parsed.Code != hci.HCI_PKT_RET_CODE_OK || record.Parsed.HciPktType != hci.PKT_TYPE_HCI_SYNC {
+		return hci.HciSync{}, false
+	}
+	pkt, ok := record.Parsed.Ret.(hci.HciSync)
+	return pkt, ok
+}
@@ -0,0 +1,405 @@
+// 广播/扫描状态时间线
+// 1. 跟踪广播/扫描参数、广播数据和随机地址，命令收到成功的Command Complete后才生效
+// 2. 根据Enable命令、LE Advertising Set Terminated、LE Scan Timeout、连接建立和HCI Reset划分广播/扫描时间段
+// 3. 每个时间段记录间隔、扫描窗口、占空比和使用的本端地址
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 广播结束原因
+const (
+	ADV_STOP_REASON_HOST_DISABLE = 0 // host关闭广播
+	ADV_STOP_REASON_CONNECTED    = 1 // 可连接广播建立连接后controller自动停止
+	ADV_STOP_REASON_TERMINATED   = 2 // LE Advertising Set Terminated，Duration超时或达到最大广播事件数
+	ADV_STOP_REASON_RESET        = 3 // HCI Reset
+)
+
+// 扫描结束原因
+const (
+	SCAN_STOP_REASON_HOST_DISABLE = 0
+	SCAN_STOP_REASON_TIMEOUT      = 1 // LE Scan Timeout，扩展扫描Duration到期
+	SCAN_STOP_REASON_RESET        = 2
+)
+
+// legacy广播在activeAdvMap中的key，扩展广播使用Advertising_Handle
+const advLegacyKey = 0x100
+
+// 一段广播
+type AdvertisingPeriod struct {
+	Legacy            bool  // LE Set Advertising Enable控制的广播
+	AdvertisingHandle uint8 // 仅扩展广播有效
+
+	StartRecordIndex int
+	StartUs          uint64
+	Stopped          bool // false表示抓包结束时仍在广播
+	StopRecordIndex  int
+	StopUs           uint64
+	StopReason       int           // ADV_STOP_REASON_XXX
+	StopStatus       hci.HciStatus // ADV_STOP_REASON_TERMINATED时的Status
+	ConnectionHandle uint16        // ADV_STOP_REASON_CONNECTED时建立的连接
+
+	AdvertisingType            uint8  // legacy广播，hci.ADV_TYPE_XXX
+	AdvertisingEventProperties uint16 // 扩展广播，hci.EXT_ADV_PROP_XXX
+	IntervalMin                uint32 // 单位0.625ms
+	IntervalMax                uint32
+	Duration                   uint16 // 扩展广播，单位10ms，0表示一直广播
+	OwnAddressType             uint8
+	Address                    [6]byte
+	AddressKnown               bool // 本端地址为controller生成的RPA或抓包中没有对应地址时为false
+
+	AdStructList        []hci.AdStruct
+	ScanRspAdStructList []hci.AdStruct
+}
+
+// 一段扫描
+type ScanPeriod struct {
+	Extended bool // LE Set Extended Scan Enable控制的扫描
+
+	StartRecordIndex int
+	StartUs          uint64
+	Stopped          bool
+	StopRecordIndex  int
+	StopUs           uint64
+	StopReason       int // SCAN_STOP_REASON_XXX
+
+	ScanType         uint8  // hci.LE_SCAN_TYPE_XXX，扩展扫描取第一个PHY
+	ScanInterval     uint16 // 单位0.625ms
+	ScanWindow       uint16
+	DutyCycle        float64 // ScanWindow/ScanInterval
+	ScanningPhys     uint8   // 扩展扫描
+	FilterDuplicates uint8
+	Duration         uint16 // 扩展扫描，单位10ms
+	Period           uint16 // 扩展扫描，单位1.28s
+	OwnAddressType   uint8
+	Address          [6]byte
+	AddressKnown     bool
+}
+
+// 广播配置，legacy广播和每个扩展广播集各一份
+type advConfig struct {
+	advertisingType            uint8
+	advertisingEventProperties uint16
+	intervalMin                uint32
+	intervalMax                uint32
+	ownAddressType             uint8
+	randomAddress              [6]byte
+	randomAddressKnown         bool
+	data                       []byte
+	scanRspData                []byte
+}
+
+type scanConfig struct {
+	scanType       uint8
+	scanInterval   uint16
+	scanWindow     uint16
+	scanningPhys   uint8
+	ownAddressType uint8
+}
+
+// 广播/扫描时间线
+type AdvScanTimeline struct {
+	AdvertisingPeriodList []*AdvertisingPeriod
+	ScanPeriodList        []*ScanPeriod
+
+	publicAddress      [6]byte
+	publicAddressKnown bool
+	randomAddress      [6]byte
+	randomAddressKnown bool
+
+	advConfigMap  map[int]*advConfig
+	scanConfig    scanConfig
+	extScanConfig scanConfig
+	activeAdvMap  map[int]*AdvertisingPeriod
+	activeScan    *ScanPeriod
+	pendingCmdMap map[uint16]interface{} // OpCode -> 等待Command Complete的命令参数
+}
+
+func NewAdvScanTimeline() *AdvScanTimeline {
+	return &AdvScanTimeline{
+		advConfigMap:  map[int]*advConfig{},
+		activeAdvMap:  map[int]*AdvertisingPeriod{},
+		pendingCmdMap: map[uint16]interface{}{},
+	}
+}
+
+func (timeline *AdvScanTimeline) advConfig(key int) *advConfig {
+	config, ok := timeline.advConfigMap[key]
+	if !ok {
+		config = &advConfig{}
+		timeline.advConfigMap[key] = config
+	}
+	return config
+}
+
+func (timeline *AdvScanTimeline) Feed(record Record) {
+	if cmd, parsed, ok := record.CmdParseResult(); ok {
+		timeline.pendingCmdMap[cmd.OpCode] = parsed.Ret
+		return
+	}
+	_, evt, ok := record.EvtParseResult()
+	if !ok {
+		return
+	}
+	switch pkt := evt.Ret.(type) {
+	case hci.CommandCompleteEvent:
+		if pkt.OpCodeOgf == hci.HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD && pkt.OpCodeOcf == hci.HCI_RESET && pkt.Status == hci.HCI_STATUS_SUCCESS {
+			timeline.reset(record)
+			return
+		}
+		if ret, ok := pkt.ReturnParsedResult.Ret.(hci.HciReadBdAddrRetParam); ok && ret.Status == hci.HCI_STATUS_SUCCESS {
+			timeline.publicAddress, timeline.publicAddressKnown = ret.BdAddr, true
+			return
+		}
+		cmd, ok := timeline.pendingCmdMap[pkt.CommandOpCode]
+		if !ok {
+			return
+		}
+		delete(timeline.pendingCmdMap, pkt.CommandOpCode)
+		if pkt.Status == hci.HCI_STATUS_SUCCESS {
+			timeline.cmdApply(record, cmd)
+		}
+	case hci.LeAdvertisingSetTerminatedEvent:
+		if period, ok := timeline.activeAdvMap[int(pkt.AdvertisingHandle)]; ok {
+			period.ConnectionHandle = pkt.ConnectionHandle
+			if pkt.Status == hci.HCI_STATUS_SUCCESS {
+				timeline.advStop(record, int(pkt.AdvertisingHandle), ADV_STOP_REASON_CONNECTED)
+			} else {
+				period.StopStatus = pkt.Status
+				timeline.advStop(record, int(pkt.AdvertisingHandle), ADV_STOP_REASON_TERMINATED)
+			}
+		}
+	case hci.LeScanTimeoutEvent:
+		timeline.scanStop(record, SCAN_STOP_REASON_TIMEOUT)
+	case hci.LeConnectionCompleteEvent:
+		if pkt.Status == hci.HCI_STATUS_SUCCESS && pkt.Role == hci.LE_ROLE_SLAVE {
+			timeline.legacyAdvConnected(record, pkt.ConnectionHandle)
+		}
+	case hci.LeEnhancedConnectionCompleteEvent:
+		if pkt.Status == hci.HCI_STATUS_SUCCESS && pkt.Role == hci.LE_ROLE_SLAVE {
+			timeline.legacyAdvConnected(record, pkt.ConnectionHandle)
+		}
+	}
+}
+
+// 命令执行成功后更新配置和时间段
+func (timeline *AdvScanTimeline) cmdApply(record Record, cmd interface{}) {
+	switch pkt := cmd.(type) {
+	case hci.HciLeSetRandomAddress:
+		timeline.randomAddress, timeline.randomAddressKnown = pkt.RandomAddress, true
+	case hci.HciLeSetAdvertisingParameters:
+		config := timeline.advConfig(advLegacyKey)
+		config.advertisingType = pkt.AdvertisingType
+		config.intervalMin, config.intervalMax = uint32(pkt.AdvertisingIntervalMin), uint32(pkt.AdvertisingIntervalMax)
+		config.ownAddressType = pkt.OwnAddressType
+	case hci.HciLeSetAdvertisingData:
+		config := timeline.advConfig(advLegacyKey)
+		if pkt.ScanResponse {
+			config.scanRspData = pkt.Data
+		} else {
+			config.data = pkt.Data
+		}
+		timeline.advDataUpdate(advLegacyKey)
+	case hci.HciLeSetAdvertisingEnable:
+		if pkt.AdvertisingEnable != 0 {
+			timeline.advStart(record, advLegacyKey, 0)
+		} else {
+			timeline.advStop(record, advLegacyKey, ADV_STOP_REASON_HOST_DISABLE)
+		}
+	case hci.HciLeSetAdvertisingSetRandomAddress:
+		config := timeline.advConfig(int(pkt.AdvertisingHandle))
+		config.randomAddress, config.randomAddressKnown = pkt.RandomAddress, true
+	case hci.HciLeSetExtendedAdvertisingParameters:
+		config := timeline.advConfig(int(pkt.AdvertisingHandle))
+		config.advertisingEventProperties = pkt.AdvertisingEventProperties
+		config.intervalMin, config.intervalMax = pkt.PrimaryAdvertisingIntervalMin, pkt.PrimaryAdvertisingIntervalMax
+		config.ownAddressType = pkt.OwnAddressType
+	case hci.HciLeSetExtendedAdvertisingData:
+		config := timeline.advConfig(int(pkt.AdvertisingHandle))
+		data := &config.data
+		if pkt.ScanResponse {
+			data = &config.scanRspData
+		}
+		switch pkt.Operation {
+		case hci.EXT_ADV_DATA_OPERATION_FIRST_FRAGMENT, hci.EXT_ADV_DATA_OPERATION_COMPLETE:
+			*data = append([]byte{}, pkt.Data...)
+		case hci.EXT_ADV_DATA_OPERATION_INTERMEDIATE_FRAGMENT, hci.EXT_ADV_DATA_OPERATION_LAST_FRAGMENT:
+			*data = append(*data, pkt.Data...)
+		}
+		if pkt.Operation == hci.EXT_ADV_DATA_OPERATION_COMPLETE || pkt.Operation == hci.EXT_ADV_DATA_OPERATION_LAST_FRAGMENT {
+			timeline.advDataUpdate(int(pkt.AdvertisingHandle))
+		}
+	case hci.HciLeSetExtendedAdvertisingEnable:
+		if pkt.Enable == 0 && pkt.NumSets == 0 {
+			for key := range timeline.activeAdvMap {
+				if key != advLegacyKey {
+					timeline.advStop(record, key, ADV_STOP_REASON_HOST_DISABLE)
+				}
+			}
+			return
+		}
+		for _, set := range pkt.SetList {
+			if pkt.Enable != 0 {
+				timeline.advStart(record, int(set.AdvertisingHandle), set.Duration)
+			} else {
+				timeline.advStop(record, int(set.AdvertisingHandle), ADV_STOP_REASON_HOST_DISABLE)
+			}
+		}
+	case hci.HciLeSetScanParameters:
+		timeline.scanConfig = scanConfig{
+			scanType:       pkt.LeScanType,
+			scanInterval:   pkt.LeScanInterval,
+			scanWindow:     pkt.LeScanWindow,
+			ownAddressType: pkt.OwnAddressType,
+		}
+	case hci.HciLeSetScanEnable:
+		if pkt.LeScanEnable != 0 {
+			timeline.scanStart(record, false, timeline.scanConfig, pkt.FilterDuplicates, 0, 0)
+		} else {
+			timeline.scanStop(record, SCAN_STOP_REASON_HOST_DISABLE)
+		}
+	case hci.HciLeSetExtendedScanParameters:
+		timeline.extScanConfig = scanConfig{scanningPhys: pkt.ScanningPhys, ownAddressType: pkt.OwnAddressType}
+		for index, phy := range pkt.PhyParametersList {
+			if pkt.ScanningPhys&(0x01<<uint8(index)) != 0 {
+				timeline.extScanConfig.scanType = phy.ScanType
+				timeline.extScanConfig.scanInterval = phy.ScanInterval
+				timeline.extScanConfig.scanWindow = phy.ScanWindow
+				break
+			}
+		}
+	case hci.HciLeSetExtendedScanEnable:
+		if pkt.Enable != 0 {
+			timeline.scanStart(record, true, timeline.extScanConfig, pkt.FilterDuplicates, pkt.Duration, pkt.Period)
+		} else {
+			timeline.scanStop(record, SCAN_STOP_REASON_HOST_DISABLE)
+		}
+	}
+}
+
+// 本端使用的地址，controller生成的RPA无法从HCI获取
+func (timeline *AdvScanTimeline) ownAddress(ownAddressType uint8, config *advConfig) ([6]byte, bool) {
+	switch ownAddressType {
+	case hci.OWN_ADDRESS_TYPE_PUBLIC:
+		return timeline.publicAddress, timeline.publicAddressKnown
+	case hci.OWN_ADDRESS_TYPE_RANDOM:
+		if config != nil && config.randomAddressKnown {
+			return config.randomAddress, true
+		}
+		return timeline.randomAddress, timeline.randomAddressKnown
+	}
+	return [6]byte{}, false
+}
+
+func (timeline *AdvScanTimeline) advStart(record Record, key int, duration uint16) {
+	if _, ok := timeline.activeAdvMap[key]; ok {
+		// 广播过程中再次使能，只更新Duration
+		timeline.activeAdvMap[key].Duration = duration
+		return
+	}
+	config := timeline.advConfig(key)
+	period := &AdvertisingPeriod{
+		Legacy:                     key == advLegacyKey,
+		StartRecordIndex:           record.Index,
+		StartUs:                    record.TimestampUs,
+		AdvertisingType:            config.advertisingType,
+		AdvertisingEventProperties: config.advertisingEventProperties,
+		IntervalMin:                config.intervalMin,
+		IntervalMax:                config.intervalMax,
+		Duration:                   duration,
+		OwnAddressType:             config.ownAddressType,
+	}
+	if key != advLegacyKey {
+		period.AdvertisingHandle = uint8(key)
+		period.Address, period.AddressKnown = timeline.ownAddress(config.ownAddressType, config)
+	} else {
+		period.Address, period.AddressKnown = timeline.ownAddress(config.ownAddressType, nil)
+	}
+	period.AdStructList, _ = hci.AdStructListParse(config.data)
+	period.ScanRspAdStructList, _ = hci.AdStructListParse(config.scanRspData)
+	timeline.activeAdvMap[key] = period
+	timeline.AdvertisingPeriodList = append(timeline.AdvertisingPeriodList, period)
+}
+
+// 广播过程中更新数据
+func (timeline *AdvScanTimeline) advDataUpdate(key int) {
+	period, ok := timeline.activeAdvMap[key]
+	if !ok {
+		return
+	}
+	config := timeline.advConfig(key)
+	period.AdStructList, _ = hci.AdStructListParse(config.data)
+	period.ScanRspAdStructList, _ = hci.AdStructListParse(config.scanRspData)
+}
+
+func (timeline *AdvScanTimeline) advStop(record Record, key int, reason int) {
+	period, ok := timeline.activeAdvMap[key]
+	if !ok {
+		return
+	}
+	period.Stopped = true
+	period.StopRecordIndex = record.Index
+	period.StopUs = record.TimestampUs
+	period.StopReason = reason
+	delete(timeline.activeAdvMap, key)
+}
+
+// legacy可连接广播在建立连接后停止，扩展广播通过LE Advertising Set Terminated上报
+func (timeline *AdvScanTimeline) legacyAdvConnected(record Record, connectionHandle uint16) {
+	if period, ok := timeline.activeAdvMap[advLegacyKey]; ok {
+		period.ConnectionHandle = connectionHandle
+		timeline.advStop(record, advLegacyKey, ADV_STOP_REASON_CONNECTED)
+	}
+}
+
+func (timeline *AdvScanTimeline) scanStart(record Record, extended bool, config scanConfig, filterDuplicates uint8, duration uint16, period uint16) {
+	if timeline.activeScan != nil {
+		// 扫描过程中再次使能，controller只更新Filter_Duplicates等参数
+		timeline.activeScan.FilterDuplicates = filterDuplicates
+		return
+	}
+	scan := &ScanPeriod{
+		Extended:         extended,
+		StartRecordIndex: record.Index,
+		StartUs:          record.TimestampUs,
+		ScanType:         config.scanType,
+		ScanInterval:     config.scanInterval,
+		ScanWindow:       config.scanWindow,
+		ScanningPhys:     config.scanningPhys,
+		FilterDuplicates: filterDuplicates,
+		Duration:         duration,
+		Period:           period,
+		OwnAddressType:   config.ownAddressType,
+	}
+	if config.scanInterval > 0 {
+		scan.DutyCycle = float64(config.scanWindow) / float64(config.scanInterval)
+	}
+	scan.Address, scan.AddressKnown = timeline.ownAddress(config.ownAddressType, nil)
+	timeline.activeScan = scan
+	timeline.ScanPeriodList = append(timeline.ScanPeriodList, scan)
+}
+
+func (timeline *AdvScanTimeline) scanStop(record Record, reason int) {
+	if timeline.activeScan == nil {
+		return
+	}
+	timeline.activeScan.Stopped = true
+	timeline.activeScan.StopRecordIndex = record.Index
+	timeline.activeScan.StopUs = record.TimestampUs
+	timeline.activeScan.StopReason = reason
+	timeline.activeScan = nil
+}
+
+// HCI Reset后广播/扫描停止，配置恢复默认值
+func (timeline *AdvScanTimeline) reset(record Record) {
+	for key := range timeline.activeAdvMap {
+		timeline.advStop(record, key, ADV_STOP_REASON_RESET)
+	}
+	timeline.scanStop(record, SCAN_STOP_REASON_RESET)
+	timeline.advConfigMap = map[int]*advConfig{}
+	timeline.scanConfig = scanConfig{}
+	timeline.extScanConfig = scanConfig{}
+	timeline.randomAddressKnown = false
+}
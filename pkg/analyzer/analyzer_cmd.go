@@ -0,0 +1,149 @@
+// HCI命令与Command Complete/Command Status关联
+// 1. 按OpCode和发送顺序，将每个命令关联到第一个未被使用的Command Complete/Command Status
+// 2. 计算命令往返时延(响应时间戳早于命令时时延未知)，标记未收到响应的命令
+// 3. 根据Num_HCI_Command_Packets跟踪命令credit，标记超过credit发送的命令；抓包可能从中途开始，收到第一个Num_HCI_Command_Packets或HCI_Reset之前credit未知，不做标记
+
+package analyzer
+
+import (
+	"time"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 上电/复位后host可以发送一个命令
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 4.4 Command Flow Control
+const CMD_CREDIT_INITIAL = 1
+
+// 不会产生Command Complete/Command Status的命令
+var CmdNoResponseOpCodeMap = map[uint16]bool{
+	hci.HciOpCode(hci.HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD, hci.HCI_HOST_NUMBER_OF_COMPLETED_PACKETS): true,
+}
+
+// 一条命令和它的响应
+type CmdLink struct {
+	OpCode         uint16
+	OpCodeOgf      uint8
+	OpCodeOcf      uint16
+	CmdRecordIndex int
+	CmdTimestampUs uint64
+	Cmd            hci.HciCmd
+
+	Responded      bool
+	RspRecordIndex int
+	RspTimestampUs uint64
+	RspEventCode   uint8       // hci.HCI_EVT_COMMAND_COMPLETE/hci.HCI_EVT_COMMAND_STATUS
+	Rsp            interface{} // hci.CommandCompleteEvent/hci.CommandStatusEvent
+	Status         hci.HciStatus
+	Latency        time.Duration
+	LatencyKnown   bool // 响应时间戳早于命令(抓包时钟回退)时时延未知，Latency为0
+
+	CreditExceeded bool // 发送时Num_HCI_Command_Packets已经用完
+}
+
+// 命令关联
+type CmdCorrelator struct {
+	LinkList    []*CmdLink
+	pendingList []*CmdLink
+	credit      int
+	creditKnown bool
+	recordMap   map[int]*CmdLink // 命令/响应的btsnoop记录index -> 关联结果
+}
+
+func NewCmdCorrelator() *CmdCorrelator {
+	return &CmdCorrelator{recordMap: map[int]*CmdLink{}}
+}
+
+func (correlator *CmdCorrelator) Feed(record Record) {
+	if record.Parsed.Code == hci.HCI_PKT_RET_CODE_OK && record.Parsed.HciPktType == hci.PKT_TYPE_HCI_CMD {
+		cmd, ok := record.Parsed.Ret.(hci.HciCmd)
+		if !ok || CmdNoResponseOpCodeMap[cmd.OpCode] {
+			return
+		}
+		if cmd.OpCode == hci.HciOpCode(hci.HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD, hci.HCI_RESET) {
+			correlator.credit, correlator.creditKnown = CMD_CREDIT_INITIAL, true
+		}
+		link := &CmdLink{
+			OpCode:         cmd.OpCode,
+			OpCodeOgf:      cmd.OpCodeOgf,
+			OpCodeOcf:      cmd.OpCodeOcf,
+			CmdRecordIndex: record.Index,
+			CmdTimestampUs: record.TimestampUs,
+			Cmd:            cmd,
+			CreditExceeded: correlator.creditKnown && correlator.credit <= 0,
+		}
+		correlator.credit--
+		correlator.LinkList = append(correlator.LinkList, link)
+		correlator.pendingList = append(correlator.pendingList, link)
+		correlator.recordMap[record.Index] = link
+		return
+	}
+
+	_, evt, ok := record.EvtParseResult()
+	if !ok {
+		return
+	}
+	var opCode uint16
+	var status hci.HciStatus
+	switch pkt := evt.Ret.(type) {
+	case hci.CommandCompleteEvent:
+		correlator.credit, correlator.creditKnown = int(pkt.NumHciCommandPackets), true
+		opCode = pkt.CommandOpCode
+		status = pkt.Status
+	case hci.CommandStatusEvent:
+		correlator.credit, correlator.creditKnown = int(pkt.NumHciCommandPackets), true
+		opCode = pkt.CommandOpCode
+		status = pkt.Status
+	default:
+		return
+	}
+	if opCode == 0x0000 {
+		return
+	}
+	for index, link := range correlator.pendingList {
+		if link.OpCode != opCode {
+			continue
+		}
+		link.Responded = true
+		link.RspRecordIndex = record.Index
+		link.RspTimestampUs = record.TimestampUs
+		link.RspEventCode = evt.EventCode
+		link.Rsp = evt.Ret
+		link.Status = status
+		if record.TimestampUs >= link.CmdTimestampUs {
+			link.Latency = time.Duration(record.TimestampUs-link.CmdTimestampUs) * time.Microsecond
+			link.LatencyKnown = true
+		}
+		correlator.pendingList = append(correlator.pendingList[:index], correlator.pendingList[index+1:]...)
+		correlator.recordMap[record.Index] = link
+		return
+	}
+}
+
+// 根据命令或响应的btsnoop记录index查找关联结果
+func (correlator *CmdCorrelator) LinkByRecordIndex(recordIndex int) (*CmdLink, bool) {
+	link, ok := correlator.recordMap[recordIndex]
+	return link, ok
+}
+
+// 未收到响应的命令
+func (correlator *CmdCorrelator) UnansweredList() []*CmdLink {
+	linkList := []*CmdLink{}
+	for _, link := range correlator.LinkList {
+		if !link.Responded {
+			linkList = append(linkList, link)
+		}
+	}
+	return linkList
+}
+
+// 超过credit发送的命令
+func (correlator *CmdCorrelator) CreditExceededList() []*CmdLink {
+	linkList := []*CmdLink{}
+	for _, link := range correlator.LinkList {
+		if link.CreditExceeded {
+			linkList = append(linkList, link)
+		}
+	}
+	return linkList
+}
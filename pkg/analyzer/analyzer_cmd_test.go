@@ -0,0 +1,93 @@
+package analyzer
+
+import (
+	"testing"
+	"time"
+)
+
+var (
+	cmdTestReset            = []byte{0x03, 0x0C, 0x00}
+	cmdTestReadBdAddr       = []byte{0x09, 0x10, 0x00}
+	cmdTestResetComplete    = []byte{0x0E, 0x04, 0x01, 0x03, 0x0C, 0x00}
+	cmdTestReadBdAddrStatus = []byte{0x0F, 0x04, 0x00, 0x00, 0x09, 0x10} // Num_HCI_Command_Packets为0
+)
+
+func TestCmdCorrelatorCredit(t *testing.T) {
+	testList := []struct {
+		name              string
+		recordList        []Record
+		wantExceededIndex []int
+	}{
+		{
+			"credit unknown before first report",
+			[]Record{
+				cmdTestRecord(0, cmdTestReadBdAddr),
+				cmdTestRecord(1, cmdTestReadBdAddr),
+			},
+			[]int{},
+		},
+		{
+			"reset restarts at one credit",
+			[]Record{
+				cmdTestRecord(0, cmdTestReset),
+				cmdTestRecord(1, cmdTestReadBdAddr),
+			},
+			[]int{1},
+		},
+		{
+			"no credit left after command status",
+			[]Record{
+				cmdTestRecord(0, cmdTestReadBdAddr),
+				evtTestRecord(1, cmdTestReadBdAddrStatus),
+				cmdTestRecord(2, cmdTestReadBdAddr),
+			},
+			[]int{2},
+		},
+	}
+	for _, test := range testList {
+		correlator := NewCmdCorrelator()
+		for _, record := range test.recordList {
+			correlator.Feed(record)
+		}
+		exceededList := correlator.CreditExceededList()
+		if len(exceededList) != len(test.wantExceededIndex) {
+			t.Errorf("%s: %d commands exceeded credit, want %d", test.name, len(exceededList), len(test.wantExceededIndex))
+			continue
+		}
+		for index, link := range exceededList {
+			if link.CmdRecordIndex != test.wantExceededIndex[index] {
+				t.Errorf("%s: exceeded command at record %d, want %d", test.name, link.CmdRecordIndex, test.wantExceededIndex[index])
+			}
+		}
+	}
+}
+
+func TestCmdCorrelatorLatency(t *testing.T) {
+	testList := []struct {
+		name             string
+		cmdTimestampUs   uint64
+		rspTimestampUs   uint64
+		wantLatency      time.Duration
+		wantLatencyKnown bool
+	}{
+		{"response after command", 1000, 1250, 250 * time.Microsecond, true},
+		{"same timestamp", 1000, 1000, 0, true},
+		{"response timestamp before command", 1000, 900, 0, false},
+	}
+	for _, test := range testList {
+		correlator := NewCmdCorrelator()
+		cmdRecord := cmdTestRecord(0, cmdTestReset)
+		cmdRecord.TimestampUs = test.cmdTimestampUs
+		rspRecord := evtTestRecord(1, cmdTestResetComplete)
+		rspRecord.TimestampUs = test.rspTimestampUs
+		correlator.Feed(cmdRecord)
+		correlator.Feed(rspRecord)
+		link, ok := correlator.LinkByRecordIndex(1)
+		if !ok || !link.Responded {
+			t.Fatalf("%s: command not linked to its response", test.name)
+		}
+		if link.Latency != test.wantLatency || link.LatencyKnown != test.wantLatencyKnown {
+			t.Errorf("%s: Latency = %v (known %v), want %v (known %v)", test.name, link.Latency, link.LatencyKnown, test.wantLatency, test.wantLatencyKnown)
+		}
+	}
+}
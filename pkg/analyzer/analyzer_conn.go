@@ -0,0 +1,246 @@
+// 连接跟踪
+// 1. 根据Connection Complete/LE Connection Complete/LE Enhanced Connection Complete建立连接，Disconnection Complete断开连接
+// 2. handle断开后可能被新连接复用，按时间戳将handle解析到当时的连接
+// 3. LE连接记录连接参数/数据长度/PHY的变化历史
+// 4. BR/EDR连接记录发起方、角色和链路模式
+// 5. 记录连接加密状态变化历史和加密失败
+// 6. 记录对端版本和特性
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 连接的传输类型
+const (
+	CONN_TRANSPORT_BREDR = 0
+	CONN_TRANSPORT_LE    = 1
+)
+
+// 本端在连接中的角色
+const (
+	CONN_ROLE_MASTER  = 0x00
+	CONN_ROLE_SLAVE   = 0x01
+	CONN_ROLE_UNKNOWN = 0xFF
+)
+
+// 一个ACL连接
+type Conn struct {
+	ConnectionHandle uint16
+	Transport        uint8 // CONN_TRANSPORT_XXX
+	Role             uint8 // CONN_ROLE_XXX
+
+	PeerAddress                   [6]byte
+	PeerAddressType               uint8 // 仅LE
+	PeerResolvablePrivateAddress  [6]byte
+	LocalResolvablePrivateAddress [6]byte
+
+	// LE连接当前参数和变化历史
+	LeConnParams
+	ParamHistory    []ConnParamEntry
+	updateInitiator int  // 等待LE Connection Update Complete的请求发起方
+	dataLengthLocal bool // 本端发送了LE Set Data Length，等待LE Data Length Change
+	phyLocal        bool // 本端发送了LE Set PHY，等待LE PHY Update Complete
+
+	// BR/EDR连接对端Connection Request中的Class of Device和当前链路模式
+	ClassOfDevice hci.DeviceClass
+	Mode          uint8 // hci.CONN_MODE_XXX
+	ModeHistory   []ConnModeEntry
+
+	// 当前加密状态和变化历史
+	EncryptionEnabled uint8 // hci.ENCRYPTION_XXX
+	EncryptionKeySize uint8 // 仅Encryption Change v2上报
+	EncryptionHistory []ConnEncEntry
+
+	// 对端版本和特性
+	Remote ConnRemoteInfo
+
+	// 本端发起的连接，LE连接记录LE Create Connection/LE Extended Create Connection(取第一个PHY)请求的参数
+	Initiated  bool
+	Initiating hci.ConnectionInitialting
+
+	// 抓包开始前已经建立的连接，从数据包推断，没有对端信息
+	Implicit           bool
+	ConnectRecordIndex int
+	ConnectTimestampUs uint64
+
+	Disconnected          bool
+	DisconnectRecordIndex int
+	DisconnectTimestampUs uint64
+	DisconnectReason      hci.HciStatus
+}
+
+// 连接跟踪
+type ConnTracker struct {
+	ConnList              []*Conn // 按建立顺序
+	EncryptionFailureList []EncryptionFailure
+	handleConnMap         map[uint16][]*Conn // handle -> 使用过该handle的连接，按建立顺序
+	activeConnMap         map[uint16]*Conn
+
+	initiating        hci.ConnectionInitialting
+	initiatingPending bool
+
+	paramCmdHandleMap map[uint16]uint16 // OpCode -> 等待Command Status/Command Complete的参数更新命令的handle
+
+	bredrPendingMap map[[6]byte]*bredrConnPending // 对端地址 -> 等待Connection Complete的BR/EDR连接
+	encCmdHandleMap map[uint16]uint16             // OpCode -> 等待Command Status/Command Complete的加密命令的handle
+}
+
+func NewConnTracker() *ConnTracker {
+	return &ConnTracker{handleConnMap: map[uint16][]*Conn{}, activeConnMap: map[uint16]*Conn{}, paramCmdHandleMap: map[uint16]uint16{}, bredrPendingMap: map[[6]byte]*bredrConnPending{}, encCmdHandleMap: map[uint16]uint16{}}
+}
+
+func (tracker *ConnTracker) connAdd(record Record, conn *Conn) {
+	conn.ConnectRecordIndex = record.Index
+	conn.ConnectTimestampUs = record.TimestampUs
+	// 没有收到Disconnection Complete就被复用，旧连接按复用时间结束
+	if old, ok := tracker.activeConnMap[conn.ConnectionHandle]; ok {
+		old.Disconnected = true
+		old.DisconnectRecordIndex = record.Index
+		old.DisconnectTimestampUs = record.TimestampUs
+	}
+	tracker.ConnList = append(tracker.ConnList, conn)
+	tracker.handleConnMap[conn.ConnectionHandle] = append(tracker.handleConnMap[conn.ConnectionHandle], conn)
+	tracker.activeConnMap[conn.ConnectionHandle] = conn
+}
+
+func (tracker *ConnTracker) Feed(record Record) {
+	if hciCmd, cmd, ok := record.CmdParseResult(); ok {
+		tracker.paramCmdFeed(record, hciCmd, cmd)
+		tracker.bredrCmdFeed(cmd)
+		tracker.encCmdFeed(record, hciCmd, cmd)
+		switch pkt := cmd.Ret.(type) {
+		case hci.HciLeCreateConnection:
+			tracker.initiating, tracker.initiatingPending = pkt.ConnectionInitialting, true
+		case hci.HciLeExtendedCreateConnection:
+			for index := range pkt.ConnectionInitialtingList {
+				if pkt.InitialtingPhys&(0x01<<uint8(index)) != 0 {
+					tracker.initiating, tracker.initiatingPending = pkt.ConnectionInitialtingList[index], true
+					break
+				}
+			}
+		case hci.HciLeCreateConnectionCancel:
+			tracker.initiatingPending = false
+		}
+		return
+	}
+
+	if acl, ok := record.Acl(); ok {
+		if _, ok := tracker.activeConnMap[acl.Handle]; !ok {
+			conn := &Conn{ConnectionHandle: acl.Handle, Role: CONN_ROLE_UNKNOWN, Implicit: true}
+			reused := len(tracker.handleConnMap[acl.Handle]) > 0
+			tracker.connAdd(record, conn)
+			if !reused {
+				// 抓包开始前建立的连接，覆盖到抓包开始时刻
+				conn.ConnectTimestampUs = 0
+			}
+		}
+		tracker.paramAclFeed(record, acl)
+		tracker.remoteAclFeed(record, acl)
+		return
+	}
+
+	_, evt, ok := record.EvtParseResult()
+	if !ok {
+		return
+	}
+	tracker.paramEvtFeed(record, evt)
+	tracker.bredrEvtFeed(record, evt)
+	tracker.encEvtFeed(record, evt)
+	tracker.remoteEvtFeed(evt)
+	switch pkt := evt.Ret.(type) {
+	case hci.LeConnectionCompleteEvent:
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			tracker.initiatingPending = false
+			return
+		}
+		tracker.leConnAdd(record, &Conn{
+			ConnectionHandle: pkt.ConnectionHandle,
+			Transport:        CONN_TRANSPORT_LE,
+			Role:             pkt.Role,
+			PeerAddress:      pkt.PeerAddress,
+			PeerAddressType:  pkt.PeerAddressType,
+			LeConnParams: LeConnParams{
+				ConnInterval:       pkt.ConnInterval,
+				ConnLatency:        pkt.ConnLatency,
+				SupervisionTimeout: pkt.SupervisionTimeout,
+			},
+		})
+	case hci.LeEnhancedConnectionCompleteEvent:
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			tracker.initiatingPending = false
+			return
+		}
+		tracker.leConnAdd(record, &Conn{
+			ConnectionHandle:              pkt.ConnectionHandle,
+			Transport:                     CONN_TRANSPORT_LE,
+			Role:                          pkt.Role,
+			PeerAddress:                   pkt.PeerAddress,
+			PeerAddressType:               pkt.PeerAddressType,
+			PeerResolvablePrivateAddress:  pkt.PeerResolvablePrivateAddress,
+			LocalResolvablePrivateAddress: pkt.LocalResolvablePrivateAddress,
+			LeConnParams: LeConnParams{
+				ConnInterval:       pkt.ConnInterval,
+				ConnLatency:        pkt.ConnLatency,
+				SupervisionTimeout: pkt.SupervisionTimeout,
+			},
+		})
+	case hci.DisconnectionCompleteEvent:
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			return
+		}
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		conn.Disconnected = true
+		conn.DisconnectRecordIndex = record.Index
+		conn.DisconnectTimestampUs = record.TimestampUs
+		conn.DisconnectReason = pkt.Reason
+		delete(tracker.activeConnMap, pkt.ConnectionHandle)
+	}
+}
+
+// LE连接建立，本端为master时关联发起连接的命令
+func (tracker *ConnTracker) leConnAdd(record Record, conn *Conn) {
+	if conn.Role == CONN_ROLE_MASTER && tracker.initiatingPending {
+		conn.Initiated, conn.Initiating = true, tracker.initiating
+		tracker.initiatingPending = false
+	}
+	leConnParamsInit(conn)
+	tracker.connAdd(record, conn)
+	conn.paramEntryAdd(record, ConnParamEntry{Type: CONN_PARAM_ENTRY_CONNECTION_COMPLETE, Initiator: CONN_PARAM_INITIATOR_UNKNOWN})
+}
+
+// 获取handle当前对应的连接，用于边Feed边解析
+func (tracker *ConnTracker) Active(handle uint16) (*Conn, bool) {
+	conn, ok := tracker.activeConnMap[handle]
+	return conn, ok
+}
+
+// 解析timestampUs时刻handle对应的连接，handle在该时刻未使用时返回false
+// 连接建立和断开的时刻都算在连接内
+func (tracker *ConnTracker) Resolve(handle uint16, timestampUs uint64) (*Conn, bool) {
+	connList := tracker.handleConnMap[handle]
+	for index := len(connList) - 1; index >= 0; index-- {
+		conn := connList[index]
+		if conn.ConnectTimestampUs > timestampUs {
+			continue
+		}
+		if conn.Disconnected && conn.DisconnectTimestampUs < timestampUs {
+			return nil, false
+		}
+		return conn, true
+	}
+	return nil, false
+}
+
+// 解析ACL记录所属的连接
+func (tracker *ConnTracker) RecordResolve(record Record) (*Conn, bool) {
+	acl, ok := record.Acl()
+	if !ok {
+		return nil, false
+	}
+	return tracker.Resolve(acl.Handle, record.TimestampUs)
+}
@@ -0,0 +1,120 @@
+// BR/EDR连接建立过程和链路模式
+// 1. Create Connection为本端发起，本端为master；Connection Request + Accept Connection Request为对端发起，角色由Accept的Role决定
+// 2. Role Change可能在Connection Complete之前上报，按对端地址暂存
+// 3. 记录Mode Change(active/hold/sniff)的变化历史
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 链路模式变化记录
+type ConnModeEntry struct {
+	RecordIndex int
+	TimestampUs uint64
+	Status      hci.HciStatus
+	Mode        uint8  // hci.CONN_MODE_XXX
+	Interval    uint16 // 单位0.625ms
+}
+
+// Connection Complete之前按对端地址暂存的连接信息
+type bredrConnPending struct {
+	Initiated     bool
+	Role          uint8 // CONN_ROLE_XXX
+	ClassOfDevice hci.DeviceClass
+}
+
+// 连接建立相关命令
+func (tracker *ConnTracker) bredrCmdFeed(cmd hci.HciCmdPktParseResult) {
+	switch pkt := cmd.Ret.(type) {
+	case hci.HciCreateConnection:
+		tracker.bredrPendingMap[pkt.BdAddr] = &bredrConnPending{Initiated: true, Role: CONN_ROLE_MASTER}
+	case hci.HciAcceptConnectionRequest:
+		pending, ok := tracker.bredrPendingMap[pkt.BdAddr]
+		if !ok {
+			pending = &bredrConnPending{}
+			tracker.bredrPendingMap[pkt.BdAddr] = pending
+		}
+		pending.Role = CONN_ROLE_SLAVE
+		if pkt.Role == hci.ACCEPT_ROLE_BECOME_MASTER {
+			pending.Role = CONN_ROLE_MASTER
+		}
+	case hci.HciRejectConnectionRequest:
+		delete(tracker.bredrPendingMap, pkt.BdAddr)
+	}
+}
+
+// 按对端地址查找当前BR/EDR连接
+func (tracker *ConnTracker) bredrActive(bdAddr [6]byte) (*Conn, bool) {
+	for _, conn := range tracker.activeConnMap {
+		if conn.Transport == CONN_TRANSPORT_BREDR && !conn.Implicit && conn.PeerAddress == bdAddr {
+			return conn, true
+		}
+	}
+	return nil, false
+}
+
+// 连接建立相关事件
+func (tracker *ConnTracker) bredrEvtFeed(record Record, evt hci.HciEvtPktParseResult) {
+	switch pkt := evt.Ret.(type) {
+	case hci.ConnectionRequestEvent:
+		if pkt.LinkType != hci.LINK_TYPE_ACL {
+			return
+		}
+		// 对端发起时没有role switch本端为slave
+		tracker.bredrPendingMap[pkt.BdAddr] = &bredrConnPending{Role: CONN_ROLE_SLAVE, ClassOfDevice: pkt.DeviceClass}
+	case hci.RoleChangeEvent:
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			return
+		}
+		if conn, ok := tracker.bredrActive(pkt.BdAddr); ok {
+			conn.Role = pkt.NewRole
+			return
+		}
+		if pending, ok := tracker.bredrPendingMap[pkt.BdAddr]; ok {
+			pending.Role = pkt.NewRole
+		}
+	case hci.ConnectionCompleteEvent:
+		if pkt.LinkType != hci.LINK_TYPE_ACL {
+			return
+		}
+		pending, ok := tracker.bredrPendingMap[pkt.BdAddr]
+		delete(tracker.bredrPendingMap, pkt.BdAddr)
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			return
+		}
+		conn := &Conn{
+			ConnectionHandle: pkt.ConnectionHandle,
+			Transport:        CONN_TRANSPORT_BREDR,
+			Role:             CONN_ROLE_UNKNOWN,
+			PeerAddress:      pkt.BdAddr,
+			Mode:             hci.CONN_MODE_ACTIVE,
+		}
+		if ok {
+			conn.Initiated = pending.Initiated
+			conn.Role = pending.Role
+			conn.ClassOfDevice = pending.ClassOfDevice
+		}
+		tracker.connAdd(record, conn)
+		if pkt.EncryptionEnabled != hci.ENCRYPTION_OFF {
+			conn.EncryptionEnabled = pkt.EncryptionEnabled
+			conn.encEntryAdd(record, ConnEncEntry{Type: CONN_ENC_ENTRY_CONNECTION_COMPLETE})
+		}
+	case hci.ModeChangeEvent:
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		if pkt.Status == hci.HCI_STATUS_SUCCESS {
+			conn.Mode = pkt.CurrentMode
+		}
+		conn.ModeHistory = append(conn.ModeHistory, ConnModeEntry{
+			RecordIndex: record.Index,
+			TimestampUs: record.TimestampUs,
+			Status:      pkt.Status,
+			Mode:        pkt.CurrentMode,
+			Interval:    pkt.Interval,
+		})
+	}
+}
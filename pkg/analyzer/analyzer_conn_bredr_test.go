@@ -0,0 +1,59 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+func TestConnTrackerBredr(t *testing.T) {
+	recordList := []Record{
+		// 本端发起，Connection Complete之前role switch为slave
+		cmdTestRecord(0, []byte{0x05, 0x04, 0x0D, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x18, 0xCC, 0x01, 0x00, 0x00, 0x00, 0x01}),
+		evtTestRecord(1, []byte{0x12, 0x08, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, hci.BREDR_ROLE_SLAVE}),
+		evtTestRecord(2, []byte{0x03, 0x0B, 0x00, 0x41, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00}),
+		evtTestRecord(3, []byte{0x14, 0x06, 0x00, 0x41, 0x00, hci.CONN_MODE_SNIFF, 0x20, 0x03}),
+		evtTestRecord(4, []byte{0x14, 0x06, 0x0C, 0x41, 0x00, hci.CONN_MODE_ACTIVE, 0x00, 0x00}),
+		// 对端发起，本端Accept时要求成为master
+		evtTestRecord(5, []byte{0x04, 0x0A, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0x04, 0x04, 0x24, hci.LINK_TYPE_ACL}),
+		cmdTestRecord(6, []byte{0x09, 0x04, 0x07, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, hci.ACCEPT_ROLE_BECOME_MASTER}),
+		evtTestRecord(7, []byte{0x03, 0x0B, 0x00, 0x42, 0x00, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0xAA, 0x01, 0x00}),
+		// 对端发起，controller自动接受
+		evtTestRecord(8, []byte{0x04, 0x0A, 0xBB, 0xBB, 0xBB, 0xBB, 0xBB, 0xBB, 0x0C, 0x02, 0x5A, hci.LINK_TYPE_ACL}),
+		evtTestRecord(9, []byte{0x03, 0x0B, 0x00, 0x43, 0x00, 0xBB, 0xBB, 0xBB, 0xBB, 0xBB, 0xBB, 0x01, 0x00}),
+	}
+	tracker := NewConnTracker()
+	for _, record := range recordList {
+		tracker.Feed(record)
+	}
+	if len(tracker.ConnList) != 3 {
+		t.Fatalf("%d connections, want 3", len(tracker.ConnList))
+	}
+	testList := []struct {
+		name          string
+		conn          *Conn
+		wantInitiated bool
+		wantRole      uint8
+		wantCod       hci.DeviceClass
+	}{
+		{"local initiated, role switched", tracker.ConnList[0], true, CONN_ROLE_SLAVE, hci.DeviceClass{}},
+		{"remote initiated, become master", tracker.ConnList[1], false, CONN_ROLE_MASTER, hci.DeviceClassParse(0x240404)},
+		{"remote initiated, auto accepted", tracker.ConnList[2], false, CONN_ROLE_SLAVE, hci.DeviceClassParse(0x5A020C)},
+	}
+	for _, test := range testList {
+		if test.conn.Transport != CONN_TRANSPORT_BREDR || test.conn.Initiated != test.wantInitiated || test.conn.Role != test.wantRole || test.conn.ClassOfDevice != test.wantCod {
+			t.Errorf("%s: %+v", test.name, test.conn)
+		}
+	}
+
+	// 失败的Mode Change不改变当前模式
+	conn := tracker.ConnList[0]
+	wantModeHistory := []ConnModeEntry{
+		{RecordIndex: 3, Mode: hci.CONN_MODE_SNIFF, Interval: 800},
+		{RecordIndex: 4, Status: hci.HCI_STATUS_COMMAND_DISALLOWED, Mode: hci.CONN_MODE_ACTIVE},
+	}
+	if conn.Mode != hci.CONN_MODE_SNIFF || !reflect.DeepEqual(conn.ModeHistory, wantModeHistory) {
+		t.Errorf("mode %d history %+v, want sniff and %+v", conn.Mode, conn.ModeHistory, wantModeHistory)
+	}
+}
@@ -0,0 +1,200 @@
+// 连接加密状态
+// 1. 跟踪LE Enable Encryption/LE Long Term Key Request/Encryption Change/Encryption Key Refresh Complete，维护连接当前加密状态和变化历史
+// 2. 按变化历史判断某条ACL记录发送时连接是否已加密
+// 3. 记录加密失败(对端或本端没有key、命令执行失败、MIC错误断开)及对应的连接
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 加密历史记录类型
+const (
+	CONN_ENC_ENTRY_CONNECTION_COMPLETE    = 0 // BR/EDR连接建立时已加密
+	CONN_ENC_ENTRY_ENABLE_ENCRYPTION      = 1 // 本端LE Enable Encryption
+	CONN_ENC_ENTRY_LTK_REQUEST            = 2 // 对端开始加密，LE Long Term Key Request
+	CONN_ENC_ENTRY_LTK_REPLY              = 3
+	CONN_ENC_ENTRY_LTK_NEGATIVE_REPLY     = 4 // 本端没有LTK
+	CONN_ENC_ENTRY_ENCRYPTION_CHANGE      = 5
+	CONN_ENC_ENTRY_KEY_REFRESH_COMPLETE   = 6
+	CONN_ENC_ENTRY_MIC_FAILURE_DISCONNECT = 7 // 因MIC错误断开
+)
+
+var ConnEncEntryStrMap = map[int]string{
+	CONN_ENC_ENTRY_CONNECTION_COMPLETE:    "Connection Complete",
+	CONN_ENC_ENTRY_ENABLE_ENCRYPTION:      "LE Enable Encryption",
+	CONN_ENC_ENTRY_LTK_REQUEST:            "LE Long Term Key Request",
+	CONN_ENC_ENTRY_LTK_REPLY:              "LE Long Term Key Request Reply",
+	CONN_ENC_ENTRY_LTK_NEGATIVE_REPLY:     "LE Long Term Key Request Negative Reply",
+	CONN_ENC_ENTRY_ENCRYPTION_CHANGE:      "Encryption Change",
+	CONN_ENC_ENTRY_KEY_REFRESH_COMPLETE:   "Encryption Key Refresh Complete",
+	CONN_ENC_ENTRY_MIC_FAILURE_DISCONNECT: "MIC Failure Disconnect",
+}
+
+// 加密历史中的一条记录
+type ConnEncEntry struct {
+	RecordIndex       int
+	TimestampUs       uint64
+	Type              int           // CONN_ENC_ENTRY_XXX
+	Status            hci.HciStatus // 命令为Command Status/Command Complete的Status
+	EncryptionEnabled uint8         // 该记录之后连接的加密状态，hci.ENCRYPTION_XXX
+	EncryptionKeySize uint8
+}
+
+// 一次加密失败
+type EncryptionFailure struct {
+	RecordIndex     int
+	TimestampUs     uint64
+	Conn            *Conn
+	Type            int // CONN_ENC_ENTRY_XXX，失败的步骤
+	Status          hci.HciStatus
+	FirstEncryption bool // 该连接之前没有加密成功过，回连时一般为使用绑定的key加密
+}
+
+func (conn *Conn) encEntryAdd(record Record, entry ConnEncEntry) {
+	entry.RecordIndex = record.Index
+	entry.TimestampUs = record.TimestampUs
+	entry.EncryptionEnabled = conn.EncryptionEnabled
+	entry.EncryptionKeySize = conn.EncryptionKeySize
+	conn.EncryptionHistory = append(conn.EncryptionHistory, entry)
+}
+
+// 连接之前是否加密成功过
+func (conn *Conn) encEverEnabled() bool {
+	for _, entry := range conn.EncryptionHistory {
+		if entry.EncryptionEnabled != hci.ENCRYPTION_OFF {
+			return true
+		}
+	}
+	return false
+}
+
+func (tracker *ConnTracker) encFail(record Record, conn *Conn, entryType int, status hci.HciStatus) {
+	tracker.EncryptionFailureList = append(tracker.EncryptionFailureList, EncryptionFailure{
+		RecordIndex:     record.Index,
+		TimestampUs:     record.TimestampUs,
+		Conn:            conn,
+		Type:            entryType,
+		Status:          status,
+		FirstEncryption: !conn.encEverEnabled(),
+	})
+}
+
+// 加密相关命令
+func (tracker *ConnTracker) encCmdFeed(record Record, cmd hci.HciCmd, parsed hci.HciCmdPktParseResult) {
+	var handle uint16
+	var entryType int
+	switch pkt := parsed.Ret.(type) {
+	case hci.HciLeEnableEncryption:
+		handle, entryType = pkt.ConnectionHandle, CONN_ENC_ENTRY_ENABLE_ENCRYPTION
+	case hci.HciLeLongTermKeyRequestReply:
+		handle, entryType = pkt.ConnectionHandle, CONN_ENC_ENTRY_LTK_REPLY
+	case hci.HciLeLongTermKeyRequestNegativeReply:
+		handle, entryType = pkt.ConnectionHandle, CONN_ENC_ENTRY_LTK_NEGATIVE_REPLY
+	default:
+		return
+	}
+	conn, ok := tracker.activeConnMap[handle]
+	if !ok {
+		return
+	}
+	conn.encEntryAdd(record, ConnEncEntry{Type: entryType})
+	tracker.encCmdHandleMap[cmd.OpCode] = handle
+	// controller会回复对端PIN or Key Missing
+	if entryType == CONN_ENC_ENTRY_LTK_NEGATIVE_REPLY {
+		tracker.encFail(record, conn, entryType, hci.HCI_STATUS_PIN_OR_KEY_MISSING)
+	}
+}
+
+// 命令的Command Status/Command Complete
+func (tracker *ConnTracker) encCmdStatus(record Record, opCode uint16, status hci.HciStatus) {
+	handle, ok := tracker.encCmdHandleMap[opCode]
+	if !ok {
+		return
+	}
+	delete(tracker.encCmdHandleMap, opCode)
+	conn, ok := tracker.activeConnMap[handle]
+	if !ok {
+		return
+	}
+	// 回填到命令的记录上
+	for index := len(conn.EncryptionHistory) - 1; index >= 0; index-- {
+		entry := &conn.EncryptionHistory[index]
+		if entry.Type == CONN_ENC_ENTRY_ENABLE_ENCRYPTION || entry.Type == CONN_ENC_ENTRY_LTK_REPLY || entry.Type == CONN_ENC_ENTRY_LTK_NEGATIVE_REPLY {
+			entry.Status = status
+			if status != hci.HCI_STATUS_SUCCESS {
+				tracker.encFail(record, conn, entry.Type, status)
+			}
+			return
+		}
+	}
+}
+
+// 加密相关事件
+func (tracker *ConnTracker) encEvtFeed(record Record, evt hci.HciEvtPktParseResult) {
+	switch pkt := evt.Ret.(type) {
+	case hci.CommandStatusEvent:
+		tracker.encCmdStatus(record, pkt.CommandOpCode, pkt.Status)
+	case hci.CommandCompleteEvent:
+		tracker.encCmdStatus(record, pkt.CommandOpCode, pkt.Status)
+	case hci.LeLongTermKeyRequestEvent:
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok {
+			conn.encEntryAdd(record, ConnEncEntry{Type: CONN_ENC_ENTRY_LTK_REQUEST})
+		}
+	case hci.EncryptionChangeEvent:
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			tracker.encFail(record, conn, CONN_ENC_ENTRY_ENCRYPTION_CHANGE, pkt.Status)
+		} else {
+			conn.EncryptionEnabled = pkt.EncryptionEnabled
+			if pkt.EncryptionKeySizeV2 {
+				conn.EncryptionKeySize = pkt.EncryptionKeySize
+			}
+		}
+		conn.encEntryAdd(record, ConnEncEntry{Type: CONN_ENC_ENTRY_ENCRYPTION_CHANGE, Status: pkt.Status})
+	case hci.EncryptionKeyRefreshCompleteEvent:
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			tracker.encFail(record, conn, CONN_ENC_ENTRY_KEY_REFRESH_COMPLETE, pkt.Status)
+		} else if conn.EncryptionEnabled == hci.ENCRYPTION_OFF {
+			// 只有已加密的连接才会刷新key，抓包开始前已加密
+			conn.EncryptionEnabled = hci.ENCRYPTION_ON
+		}
+		conn.encEntryAdd(record, ConnEncEntry{Type: CONN_ENC_ENTRY_KEY_REFRESH_COMPLETE, Status: pkt.Status})
+	case hci.DisconnectionCompleteEvent:
+		if pkt.Status != hci.HCI_STATUS_SUCCESS || pkt.Reason != hci.HCI_STATUS_CONNECTION_TERMINATED_DUE_TO_MIC_FAILURE {
+			return
+		}
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok {
+			tracker.encFail(record, conn, CONN_ENC_ENTRY_MIC_FAILURE_DISCONNECT, pkt.Reason)
+			conn.encEntryAdd(record, ConnEncEntry{Type: CONN_ENC_ENTRY_MIC_FAILURE_DISCONNECT, Status: pkt.Reason})
+		}
+	}
+}
+
+// recordIndex记录之前连接是否已加密，known为false表示抓包开始前建立的连接在第一次加密状态变化前无法判断
+func (conn *Conn) EncryptedAt(recordIndex int) (encrypted bool, known bool) {
+	for index := len(conn.EncryptionHistory) - 1; index >= 0; index-- {
+		entry := conn.EncryptionHistory[index]
+		if entry.RecordIndex < recordIndex {
+			return entry.EncryptionEnabled != hci.ENCRYPTION_OFF, true
+		}
+	}
+	return false, !conn.Implicit
+}
+
+// ACL记录发送时所属连接是否已加密，ok为false表示无法解析连接或无法判断
+func (tracker *ConnTracker) RecordEncrypted(record Record) (encrypted bool, ok bool) {
+	conn, ok := tracker.RecordResolve(record)
+	if !ok {
+		return false, false
+	}
+	return conn.EncryptedAt(record.Index)
+}
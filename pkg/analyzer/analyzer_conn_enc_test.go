@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 本端为master的LE连接：本端加密成功，之后对端重新加密时本端没有LTK，最后因MIC错误断开
+func TestConnTrackerEncryption(t *testing.T) {
+	recordList := []Record{
+		evtTestRecord(0, []byte{
+			0x3E, 0x13, hci.LE_CONNECTION_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x00, 0x00,
+			0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x18, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00,
+		}),
+		cmdTestRecord(1, []byte{
+			0x19, 0x20, 0x1C, 0x40, 0x00, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x34, 0x12,
+			0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F,
+		}),
+		evtTestRecord(2, []byte{0x0F, 0x04, 0x00, 0x01, 0x19, 0x20}),
+		aclTestRecord(3, false, 0x02, connTestAttWrite),
+		evtTestRecord(4, []byte{0x08, 0x04, 0x00, 0x40, 0x00, hci.ENCRYPTION_ON}),
+		aclTestRecord(5, false, 0x02, connTestAttWrite),
+		evtTestRecord(6, []byte{0x3E, 0x0D, hci.LE_LONG_TERM_KEY_REQUEST_EVENT, 0x40, 0x00, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x34, 0x12}),
+		cmdTestRecord(7, []byte{0x1B, 0x20, 0x02, 0x40, 0x00}),
+		evtTestRecord(8, []byte{0x0E, 0x06, 0x01, 0x1B, 0x20, 0x00, 0x40, 0x00}),
+		evtTestRecord(9, []byte{0x05, 0x04, 0x00, 0x40, 0x00, 0x3D}),
+	}
+	tracker := NewConnTracker()
+	for _, record := range recordList {
+		tracker.Feed(record)
+	}
+	if len(tracker.ConnList) != 1 {
+		t.Fatalf("%d connections, want 1", len(tracker.ConnList))
+	}
+	conn := tracker.ConnList[0]
+
+	wantTypeList := []int{
+		CONN_ENC_ENTRY_ENABLE_ENCRYPTION,
+		CONN_ENC_ENTRY_ENCRYPTION_CHANGE,
+		CONN_ENC_ENTRY_LTK_REQUEST,
+		CONN_ENC_ENTRY_LTK_NEGATIVE_REPLY,
+		CONN_ENC_ENTRY_MIC_FAILURE_DISCONNECT,
+	}
+	if len(conn.EncryptionHistory) != len(wantTypeList) {
+		t.Fatalf("history %+v, want types %v", conn.EncryptionHistory, wantTypeList)
+	}
+	for index, entry := range conn.EncryptionHistory {
+		if entry.Type != wantTypeList[index] {
+			t.Errorf("history[%d] type %d, want %d", index, entry.Type, wantTypeList[index])
+		}
+	}
+	// Command Status回填到LE Enable Encryption记录上
+	if conn.EncryptionHistory[0].Status != hci.HCI_STATUS_SUCCESS || conn.EncryptionHistory[1].EncryptionEnabled != hci.ENCRYPTION_ON {
+		t.Errorf("history %+v", conn.EncryptionHistory)
+	}
+
+	testList := []struct {
+		name          string
+		record        Record
+		wantEncrypted bool
+	}{
+		{"before encryption change", recordList[3], false},
+		{"after encryption change", recordList[5], true},
+	}
+	for _, test := range testList {
+		encrypted, ok := tracker.RecordEncrypted(test.record)
+		if !ok || encrypted != test.wantEncrypted {
+			t.Errorf("%s: encrypted %v ok %v, want %v", test.name, encrypted, ok, test.wantEncrypted)
+		}
+	}
+
+	wantFailureList := []struct {
+		entryType int
+		status    hci.HciStatus
+	}{
+		{CONN_ENC_ENTRY_LTK_NEGATIVE_REPLY, hci.HCI_STATUS_PIN_OR_KEY_MISSING},
+		{CONN_ENC_ENTRY_MIC_FAILURE_DISCONNECT, hci.HCI_STATUS_CONNECTION_TERMINATED_DUE_TO_MIC_FAILURE},
+	}
+	if len(tracker.EncryptionFailureList) != len(wantFailureList) {
+		t.Fatalf("failures %+v, want %d", tracker.EncryptionFailureList, len(wantFailureList))
+	}
+	for index, failure := range tracker.EncryptionFailureList {
+		want := wantFailureList[index]
+		if failure.Conn != conn || failure.Type != want.entryType || failure.Status != want.status || failure.FirstEncryption {
+			t.Errorf("failure[%d] %+v, want type %d status %#x", index, failure, want.entryType, want.status)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+// LE连接参数历史
+// 1. 跟踪LE Connection Update Complete/LE Data Length Change/LE PHY Update Complete，维护连接当前参数
+// 2. 记录本端LE Connection Update、对端LE Remote Connection Parameter Request和L2CAP Connection Parameter Update请求
+// 3. 根据更新前的请求和命令判断参数变化的发起方
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 连接参数历史记录类型
+const (
+	CONN_PARAM_ENTRY_CONNECTION_COMPLETE        = 0 // 连接建立时的初始参数
+	CONN_PARAM_ENTRY_CONNECTION_UPDATE_REQUEST  = 1 // 本端LE Connection Update
+	CONN_PARAM_ENTRY_REMOTE_PARAMETER_REQUEST   = 2 // 对端LL_CONNECTION_PARAM_REQ，LE Remote Connection Parameter Request
+	CONN_PARAM_ENTRY_CONNECTION_UPDATE_COMPLETE = 3
+	CONN_PARAM_ENTRY_DATA_LENGTH_CHANGE         = 4
+	CONN_PARAM_ENTRY_PHY_UPDATE_COMPLETE        = 5
+	CONN_PARAM_ENTRY_L2CAP_UPDATE_REQUEST       = 6 // L2CAP Connection Parameter Update Request
+	CONN_PARAM_ENTRY_L2CAP_UPDATE_RESPONSE      = 7 // L2CAP Connection Parameter Update Response
+)
+
+// 发起方
+const (
+	CONN_PARAM_INITIATOR_UNKNOWN = 0 // 没有对应的请求，可能是controller自主发起
+	CONN_PARAM_INITIATOR_LOCAL   = 1
+	CONN_PARAM_INITIATOR_REMOTE  = 2
+)
+
+// LE连接参数，单位与事件中一致
+type LeConnParams struct {
+	ConnInterval       uint16 // 单位1.25ms
+	ConnLatency        uint16
+	SupervisionTimeout uint16 // 单位10ms
+	MaxTxOctets        uint16
+	MaxTxTime          uint16 // 单位us
+	MaxRxOctets        uint16
+	MaxRxTime          uint16
+	TxPhy              uint8 // hci.LE_PHY_XXX
+	RxPhy              uint8
+}
+
+// 连接参数历史中的一条记录
+type ConnParamEntry struct {
+	RecordIndex int
+	TimestampUs uint64
+	Type        int           // CONN_PARAM_ENTRY_XXX
+	Initiator   int           // CONN_PARAM_INITIATOR_XXX，请求/响应为发出请求的一方，更新完成为触发更新的一方
+	Status      hci.HciStatus // 更新完成事件的Status
+
+	// 请求的参数，仅请求记录有效
+	IntervalMin uint16
+	IntervalMax uint16
+	Latency     uint16
+	Timeout     uint16
+
+	L2capIdentifier uint8
+	L2capResult     uint16 // hci.L2CAP_CONNECTION_PARAMETERS_XXX，仅L2CAP响应有效
+
+	Params LeConnParams // 该记录之后连接的参数
+}
+
+// LE连接建立后默认的数据长度和PHY
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 6, Part B 4.5.10 Data Length Update
+func leConnParamsInit(conn *Conn) {
+	conn.MaxTxOctets, conn.MaxRxOctets = 27, 27
+	conn.MaxTxTime, conn.MaxRxTime = 328, 328
+	conn.TxPhy, conn.RxPhy = hci.LE_PHY_1M, hci.LE_PHY_1M
+}
+
+func (conn *Conn) paramEntryAdd(record Record, entry ConnParamEntry) {
+	entry.RecordIndex = record.Index
+	entry.TimestampUs = record.TimestampUs
+	entry.Params = conn.LeConnParams
+	conn.ParamHistory = append(conn.ParamHistory, entry)
+}
+
+// 参数更新相关命令，记录请求并标记本端发起
+func (tracker *ConnTracker) paramCmdFeed(record Record, cmd hci.HciCmd, parsed hci.HciCmdPktParseResult) {
+	switch pkt := parsed.Ret.(type) {
+	case hci.HciLeConnectionUpdate:
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		// 回复对端L2CAP请求时仍然记为对端发起
+		if conn.updateInitiator != CONN_PARAM_INITIATOR_REMOTE {
+			conn.updateInitiator = CONN_PARAM_INITIATOR_LOCAL
+		}
+		conn.paramEntryAdd(record, ConnParamEntry{
+			Type:        CONN_PARAM_ENTRY_CONNECTION_UPDATE_REQUEST,
+			Initiator:   CONN_PARAM_INITIATOR_LOCAL,
+			IntervalMin: pkt.ConnIntervalMin,
+			IntervalMax: pkt.ConnIntervalMax,
+			Latency:     pkt.ConnLatency,
+			Timeout:     pkt.SupervisionTimout,
+		})
+		tracker.paramCmdHandleMap[cmd.OpCode] = pkt.ConnectionHandle
+	case hci.HciLeRemoteConnectionParameterRequestNegativeReply:
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok {
+			conn.updateInitiator = CONN_PARAM_INITIATOR_UNKNOWN
+		}
+	case hci.HciLeSetDataLength:
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok {
+			conn.dataLengthLocal = true
+			tracker.paramCmdHandleMap[cmd.OpCode] = pkt.ConnectionHandle
+		}
+	case hci.HciLeSetPhy:
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok {
+			conn.phyLocal = true
+			tracker.paramCmdHandleMap[cmd.OpCode] = pkt.ConnectionHandle
+		}
+	}
+}
+
+// 命令执行失败时不会有对应的更新事件，清除本端发起标记
+func (tracker *ConnTracker) paramCmdStatus(opCode uint16, status hci.HciStatus) {
+	handle, ok := tracker.paramCmdHandleMap[opCode]
+	if !ok {
+		return
+	}
+	delete(tracker.paramCmdHandleMap, opCode)
+	conn, ok := tracker.activeConnMap[handle]
+	if !ok || status == hci.HCI_STATUS_SUCCESS {
+		return
+	}
+	_, ocf := hci.HciOpCodeSplit(opCode)
+	switch ocf {
+	case hci.HCI_LE_CONNECTION_UPDATE:
+		conn.updateInitiator = CONN_PARAM_INITIATOR_UNKNOWN
+	case hci.HCI_LE_SET_DATA_LENGTH:
+		conn.dataLengthLocal = false
+	case hci.HCI_LE_SET_PHY:
+		conn.phyLocal = false
+	}
+}
+
+// L2CAP LE信令信道上的连接参数更新请求/响应
+func (tracker *ConnTracker) paramAclFeed(record Record, acl hci.HciAcl) {
+	parsed, ok := acl.PayloadParsedResult.(hci.HciAclPktParseResult)
+	if !ok || parsed.Code != hci.HCI_PKT_RET_CODE_OK || parsed.ChannelId != hci.L2CAP_CID_LE_SIGNALING {
+		return
+	}
+	conn, ok := tracker.activeConnMap[acl.Handle]
+	if !ok {
+		return
+	}
+	sender := CONN_PARAM_INITIATOR_LOCAL
+	if record.IsReceived() {
+		sender = CONN_PARAM_INITIATOR_REMOTE
+	}
+	switch pkt := parsed.Ret.(type) {
+	case hci.L2capConnectionParameterUpdateRequest:
+		conn.updateInitiator = sender
+		conn.paramEntryAdd(record, ConnParamEntry{
+			Type:            CONN_PARAM_ENTRY_L2CAP_UPDATE_REQUEST,
+			Initiator:       sender,
+			IntervalMin:     pkt.IntervalMin,
+			IntervalMax:     pkt.IntervalMax,
+			Latency:         pkt.SlaveLatency,
+			Timeout:         pkt.TimeoutMultiplier,
+			L2capIdentifier: pkt.Identifier,
+		})
+	case hci.L2capConnectionParameterUpdateResponse:
+		requester := CONN_PARAM_INITIATOR_REMOTE
+		if sender == CONN_PARAM_INITIATOR_REMOTE {
+			requester = CONN_PARAM_INITIATOR_LOCAL
+		}
+		if pkt.Result != hci.L2CAP_CONNECTION_PARAMETERS_ACCEPTED {
+			conn.updateInitiator = CONN_PARAM_INITIATOR_UNKNOWN
+		}
+		conn.paramEntryAdd(record, ConnParamEntry{
+			Type:            CONN_PARAM_ENTRY_L2CAP_UPDATE_RESPONSE,
+			Initiator:       requester,
+			L2capIdentifier: pkt.Identifier,
+			L2capResult:     pkt.Result,
+		})
+	}
+}
+
+// 参数更新事件
+func (tracker *ConnTracker) paramEvtFeed(record Record, evt hci.HciEvtPktParseResult) {
+	switch pkt := evt.Ret.(type) {
+	case hci.CommandStatusEvent:
+		tracker.paramCmdStatus(pkt.CommandOpCode, pkt.Status)
+	case hci.CommandCompleteEvent:
+		tracker.paramCmdStatus(pkt.CommandOpCode, pkt.Status)
+	case hci.LeRemoteConnectionParameterRequestEvent:
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		conn.updateInitiator = CONN_PARAM_INITIATOR_REMOTE
+		conn.paramEntryAdd(record, ConnParamEntry{
+			Type:        CONN_PARAM_ENTRY_REMOTE_PARAMETER_REQUEST,
+			Initiator:   CONN_PARAM_INITIATOR_REMOTE,
+			IntervalMin: pkt.IntervalMin,
+			IntervalMax: pkt.IntervalMax,
+			Latency:     pkt.Latency,
+			Timeout:     pkt.Timeout,
+		})
+	case hci.LeConnectionUpdateCompleteEvent:
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		initiator := conn.updateInitiator
+		// 只有master能发起连接更新，本端为slave且没有请求时为对端发起
+		if initiator == CONN_PARAM_INITIATOR_UNKNOWN && conn.Role == CONN_ROLE_SLAVE {
+			initiator = CONN_PARAM_INITIATOR_REMOTE
+		}
+		conn.updateInitiator = CONN_PARAM_INITIATOR_UNKNOWN
+		if pkt.Status == hci.HCI_STATUS_SUCCESS {
+			conn.ConnInterval = pkt.ConnInterval
+			conn.ConnLatency = pkt.ConnLatency
+			conn.SupervisionTimeout = pkt.SupervisionTimeout
+		}
+		conn.paramEntryAdd(record, ConnParamEntry{Type: CONN_PARAM_ENTRY_CONNECTION_UPDATE_COMPLETE, Initiator: initiator, Status: pkt.Status})
+	case hci.LeDataLengthChangeEvent:
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		initiator := CONN_PARAM_INITIATOR_UNKNOWN
+		if conn.dataLengthLocal {
+			initiator = CONN_PARAM_INITIATOR_LOCAL
+		}
+		conn.dataLengthLocal = false
+		conn.MaxTxOctets, conn.MaxTxTime = pkt.MaxTxOctets, pkt.MaxTxTime
+		conn.MaxRxOctets, conn.MaxRxTime = pkt.MaxRxOctets, pkt.MaxRxTime
+		conn.paramEntryAdd(record, ConnParamEntry{Type: CONN_PARAM_ENTRY_DATA_LENGTH_CHANGE, Initiator: initiator})
+	case hci.LePhyUpdateCompleteEvent:
+		conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		initiator := CONN_PARAM_INITIATOR_UNKNOWN
+		if conn.phyLocal {
+			initiator = CONN_PARAM_INITIATOR_LOCAL
+		}
+		conn.phyLocal = false
+		if pkt.Status == hci.HCI_STATUS_SUCCESS {
+			conn.TxPhy, conn.RxPhy = pkt.TxPhy, pkt.RxPhy
+		}
+		conn.paramEntryAdd(record, ConnParamEntry{Type: CONN_PARAM_ENTRY_PHY_UPDATE_COMPLETE, Initiator: initiator, Status: pkt.Status})
+	}
+}
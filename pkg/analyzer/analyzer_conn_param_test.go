@@ -0,0 +1,134 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 本端为master的LE连接上依次发生本端数据长度更新、本端连接参数更新、对端连接参数请求和PHY更新
+func connParamTestRecordList() []Record {
+	return []Record{
+		evtTestRecord(0, []byte{
+			0x3E, 0x13, hci.LE_CONNECTION_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x00, 0x00,
+			0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x18, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00,
+		}),
+		cmdTestRecord(1, []byte{0x22, 0x20, 0x06, 0x40, 0x00, 0xFB, 0x00, 0x48, 0x08}),
+		evtTestRecord(2, []byte{0x0E, 0x06, 0x01, 0x22, 0x20, 0x00, 0x40, 0x00}),
+		evtTestRecord(3, []byte{0x3E, 0x0B, hci.LE_DATA_LENGTH_CHANGE_EVENT, 0x40, 0x00, 0xFB, 0x00, 0x48, 0x08, 0xFB, 0x00, 0x48, 0x08}),
+		cmdTestRecord(4, []byte{0x13, 0x20, 0x0E, 0x40, 0x00, 0x06, 0x00, 0x06, 0x00, 0x00, 0x00, 0x64, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		evtTestRecord(5, []byte{0x0F, 0x04, 0x00, 0x01, 0x13, 0x20}),
+		evtTestRecord(6, []byte{0x3E, 0x0A, hci.LE_CONNECTION_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x06, 0x00, 0x00, 0x00, 0x64, 0x00}),
+		evtTestRecord(7, []byte{0x3E, 0x0B, hci.LE_REMOTE_CONNECTION_PARAMETER_REQUEST_EVENT, 0x40, 0x00, 0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0x48, 0x00}),
+		cmdTestRecord(8, []byte{0x20, 0x20, 0x0E, 0x40, 0x00, 0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		evtTestRecord(9, []byte{0x3E, 0x0A, hci.LE_CONNECTION_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x20, 0x00, 0x00, 0x00, 0x48, 0x00}),
+		// controller自主发起的PHY更新
+		evtTestRecord(10, []byte{0x3E, 0x06, hci.LE_PHY_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, hci.LE_PHY_2M, hci.LE_PHY_2M}),
+		// LE Set PHY被拒绝后的PHY更新不算本端发起
+		cmdTestRecord(11, []byte{0x32, 0x20, 0x07, 0x40, 0x00, 0x00, 0x04, 0x04, 0x00, 0x00}),
+		evtTestRecord(12, []byte{0x0F, 0x04, 0x1A, 0x01, 0x32, 0x20}),
+		evtTestRecord(13, []byte{0x3E, 0x06, hci.LE_PHY_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, hci.LE_PHY_1M, hci.LE_PHY_1M}),
+	}
+}
+
+func TestConnTrackerParamHistory(t *testing.T) {
+	tracker := NewConnTracker()
+	for _, record := range connParamTestRecordList() {
+		tracker.Feed(record)
+	}
+	if len(tracker.ConnList) != 1 {
+		t.Fatalf("%d connections, want 1", len(tracker.ConnList))
+	}
+	conn := tracker.ConnList[0]
+	wantList := []struct {
+		recordIndex  int
+		entryType    int
+		initiator    int
+		intervalMin  uint16 // 请求的参数
+		connInterval uint16 // 该记录之后的参数
+		maxTxOctets  uint16
+		txPhy        uint8
+	}{
+		{0, CONN_PARAM_ENTRY_CONNECTION_COMPLETE, CONN_PARAM_INITIATOR_UNKNOWN, 0, 0x0018, 27, hci.LE_PHY_1M},
+		{3, CONN_PARAM_ENTRY_DATA_LENGTH_CHANGE, CONN_PARAM_INITIATOR_LOCAL, 0, 0x0018, 0x00FB, hci.LE_PHY_1M},
+		{4, CONN_PARAM_ENTRY_CONNECTION_UPDATE_REQUEST, CONN_PARAM_INITIATOR_LOCAL, 0x0006, 0x0018, 0x00FB, hci.LE_PHY_1M},
+		{6, CONN_PARAM_ENTRY_CONNECTION_UPDATE_COMPLETE, CONN_PARAM_INITIATOR_LOCAL, 0, 0x0006, 0x00FB, hci.LE_PHY_1M},
+		{7, CONN_PARAM_ENTRY_REMOTE_PARAMETER_REQUEST, CONN_PARAM_INITIATOR_REMOTE, 0x0018, 0x0006, 0x00FB, hci.LE_PHY_1M},
+		{9, CONN_PARAM_ENTRY_CONNECTION_UPDATE_COMPLETE, CONN_PARAM_INITIATOR_REMOTE, 0, 0x0020, 0x00FB, hci.LE_PHY_1M},
+		{10, CONN_PARAM_ENTRY_PHY_UPDATE_COMPLETE, CONN_PARAM_INITIATOR_UNKNOWN, 0, 0x0020, 0x00FB, hci.LE_PHY_2M},
+		{13, CONN_PARAM_ENTRY_PHY_UPDATE_COMPLETE, CONN_PARAM_INITIATOR_UNKNOWN, 0, 0x0020, 0x00FB, hci.LE_PHY_1M},
+	}
+	if len(conn.ParamHistory) != len(wantList) {
+		t.Fatalf("%d history entries, want %d: %+v", len(conn.ParamHistory), len(wantList), conn.ParamHistory)
+	}
+	for index, want := range wantList {
+		entry := conn.ParamHistory[index]
+		if entry.RecordIndex != want.recordIndex || entry.Type != want.entryType || entry.Initiator != want.initiator || entry.IntervalMin != want.intervalMin {
+			t.Errorf("entry %d: record %d type %d initiator %d interval min 0x%04X, want record %d type %d initiator %d interval min 0x%04X",
+				index, entry.RecordIndex, entry.Type, entry.Initiator, entry.IntervalMin, want.recordIndex, want.entryType, want.initiator, want.intervalMin)
+		}
+		if entry.Params.ConnInterval != want.connInterval || entry.Params.MaxTxOctets != want.maxTxOctets || entry.Params.TxPhy != want.txPhy {
+			t.Errorf("entry %d: params %+v, want interval 0x%04X tx octets %d tx phy %d", index, entry.Params, want.connInterval, want.maxTxOctets, want.txPhy)
+		}
+	}
+	if conn.ConnInterval != 0x0020 || conn.SupervisionTimeout != 0x0048 || conn.MaxRxTime != 0x0848 || conn.RxPhy != hci.LE_PHY_1M {
+		t.Errorf("current params %+v", conn.LeConnParams)
+	}
+}
+
+// 对端通过L2CAP请求更新参数，本端接受后发起LE Connection Update，第二次请求被拒绝
+func TestConnTrackerParamL2capUpdate(t *testing.T) {
+	recordList := []Record{
+		evtTestRecord(0, []byte{
+			0x3E, 0x13, hci.LE_CONNECTION_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x00, 0x00,
+			0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x18, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00,
+		}),
+		aclTestRecord(1, true, 0x02, []byte{0x0C, 0x00, 0x05, 0x00, 0x12, 0x07, 0x08, 0x00, 0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0x48, 0x00}),
+		aclTestRecord(2, false, 0x00, []byte{0x06, 0x00, 0x05, 0x00, 0x13, 0x07, 0x02, 0x00, 0x00, 0x00}),
+		cmdTestRecord(3, []byte{0x13, 0x20, 0x0E, 0x40, 0x00, 0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		evtTestRecord(4, []byte{0x0F, 0x04, 0x00, 0x01, 0x13, 0x20}),
+		evtTestRecord(5, []byte{0x3E, 0x0A, hci.LE_CONNECTION_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x20, 0x00, 0x00, 0x00, 0x48, 0x00}),
+		aclTestRecord(6, true, 0x02, []byte{0x0C, 0x00, 0x05, 0x00, 0x12, 0x08, 0x08, 0x00, 0x06, 0x00, 0x06, 0x00, 0x00, 0x00, 0x48, 0x00}),
+		aclTestRecord(7, false, 0x00, []byte{0x06, 0x00, 0x05, 0x00, 0x13, 0x08, 0x02, 0x00, 0x01, 0x00}),
+	}
+	tracker := NewConnTracker()
+	for _, record := range recordList {
+		tracker.Feed(record)
+	}
+	if len(tracker.ConnList) != 1 {
+		t.Fatalf("%d connections, want 1", len(tracker.ConnList))
+	}
+	conn := tracker.ConnList[0]
+	wantList := []struct {
+		recordIndex  int
+		entryType    int
+		initiator    int
+		intervalMin  uint16
+		identifier   uint8
+		result       uint16
+		connInterval uint16
+	}{
+		{0, CONN_PARAM_ENTRY_CONNECTION_COMPLETE, CONN_PARAM_INITIATOR_UNKNOWN, 0, 0, 0, 0x0018},
+		{1, CONN_PARAM_ENTRY_L2CAP_UPDATE_REQUEST, CONN_PARAM_INITIATOR_REMOTE, 0x0018, 0x07, 0, 0x0018},
+		{2, CONN_PARAM_ENTRY_L2CAP_UPDATE_RESPONSE, CONN_PARAM_INITIATOR_REMOTE, 0, 0x07, hci.L2CAP_CONNECTION_PARAMETERS_ACCEPTED, 0x0018},
+		{3, CONN_PARAM_ENTRY_CONNECTION_UPDATE_REQUEST, CONN_PARAM_INITIATOR_LOCAL, 0x0018, 0, 0, 0x0018},
+		// 本端的LE Connection Update是对L2CAP请求的回应，更新仍记为对端发起
+		{5, CONN_PARAM_ENTRY_CONNECTION_UPDATE_COMPLETE, CONN_PARAM_INITIATOR_REMOTE, 0, 0, 0, 0x0020},
+		{6, CONN_PARAM_ENTRY_L2CAP_UPDATE_REQUEST, CONN_PARAM_INITIATOR_REMOTE, 0x0006, 0x08, 0, 0x0020},
+		{7, CONN_PARAM_ENTRY_L2CAP_UPDATE_RESPONSE, CONN_PARAM_INITIATOR_REMOTE, 0, 0x08, hci.L2CAP_CONNECTION_PARAMETERS_REJECTED, 0x0020},
+	}
+	if len(conn.ParamHistory) != len(wantList) {
+		t.Fatalf("%d history entries, want %d: %+v", len(conn.ParamHistory), len(wantList), conn.ParamHistory)
+	}
+	for index, want := range wantList {
+		entry := conn.ParamHistory[index]
+		if entry.RecordIndex != want.recordIndex || entry.Type != want.entryType || entry.Initiator != want.initiator || entry.IntervalMin != want.intervalMin {
+			t.Errorf("entry %d: record %d type %d initiator %d interval min 0x%04X, want record %d type %d initiator %d interval min 0x%04X",
+				index, entry.RecordIndex, entry.Type, entry.Initiator, entry.IntervalMin, want.recordIndex, want.entryType, want.initiator, want.intervalMin)
+		}
+		if entry.L2capIdentifier != want.identifier || entry.L2capResult != want.result || entry.Params.ConnInterval != want.connInterval {
+			t.Errorf("entry %d: identifier %d result %d interval 0x%04X, want %d %d 0x%04X",
+				index, entry.L2capIdentifier, entry.L2capResult, entry.Params.ConnInterval, want.identifier, want.result, want.connInterval)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// 对端版本和特性
+// 1. 根据Read Remote Version Information Complete、Read Remote Supported/Extended Features Complete和LE Read Remote Features Complete记录对端版本和特性
+// 2. LE连接根据对端发送的SMP Pairing Request/Pairing Response中AuthReq的SC bit记录对端是否支持LE Secure Connections
+// 3. 按对端地址查询最近一次连接交换到的信息
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 连接中交换到的对端信息
+type ConnRemoteInfo struct {
+	VersionKnown     bool
+	Version          uint8 // LMP/LL版本
+	ManufacturerName uint16
+	Subversion       uint16
+
+	// BR/EDR，Read Remote Supported Features为page 0
+	LmpFeatureMap     map[uint8]uint64 // Page_Number -> features
+	MaximumPageNumber uint8
+
+	// LE，LE Read Remote Features Complete上报的是双方都支持的特性
+	LeFeaturesKnown bool
+	LeFeatures      uint64
+
+	// LE，对端SMP Pairing Request/Pairing Response中的AuthReq
+	SmpAuthReqKnown bool
+	SmpAuthReq      uint8 // hci.SMP_AUTH_REQ_XXX
+}
+
+func (info *ConnRemoteInfo) VersionString() string {
+	return hci.CoreVersionString(info.Version)
+}
+
+func (info *ConnRemoteInfo) ManufacturerString() string {
+	return hci.CompanyIdString(info.ManufacturerName)
+}
+
+func (info *ConnRemoteInfo) LmpFeatureList(pageNumber uint8) []string {
+	return hci.LmpFeatureList(pageNumber, info.LmpFeatureMap[pageNumber])
+}
+
+func (info *ConnRemoteInfo) LeFeatureList() []string {
+	return hci.LeFeatureList(info.LeFeatures)
+}
+
+// known为false表示没有读取到该page
+func (info *ConnRemoteInfo) LmpFeatureHas(pageNumber uint8, bit uint) (supported bool, known bool) {
+	features, ok := info.LmpFeatureMap[pageNumber]
+	return ok && features&(1<<bit) != 0, ok
+}
+
+func (info *ConnRemoteInfo) LeFeatureHas(bit uint) (supported bool, known bool) {
+	return info.LeFeaturesKnown && info.LeFeatures&(1<<bit) != 0, info.LeFeaturesKnown
+}
+
+func (info *ConnRemoteInfo) SupportsDataLengthExtension() (bool, bool) {
+	return info.LeFeatureHas(hci.LE_FEATURE_DATA_PACKET_LENGTH_EXT)
+}
+
+func (info *ConnRemoteInfo) Supports2mPhy() (bool, bool) {
+	return info.LeFeatureHas(hci.LE_FEATURE_2M_PHY)
+}
+
+func (info *ConnRemoteInfo) SupportsCodedPhy() (bool, bool) {
+	return info.LeFeatureHas(hci.LE_FEATURE_CODED_PHY)
+}
+
+// BR/EDR Secure Connections需要对端host(page 1)和controller(page 2)都支持
+// LE Secure Connections在SMP配对时协商，LL特性中没有对应bit，按对端AuthReq的SC bit判断，没有配对过程时known为false
+func (info *ConnRemoteInfo) SupportsSecureConnections() (bool, bool) {
+	if info.SmpAuthReqKnown {
+		return info.SmpAuthReq&hci.SMP_AUTH_REQ_SC != 0, true
+	}
+	host, hostKnown := info.LmpFeatureHas(1, hci.LMP_FEATURE_SECURE_CONNECTIONS_HOST)
+	controller, controllerKnown := info.LmpFeatureHas(2, hci.LMP_FEATURE_SECURE_CONNECTIONS_CONTROLLER)
+	if hostKnown && !host || controllerKnown && !controller {
+		return false, true
+	}
+	return host && controller, hostKnown && controllerKnown
+}
+
+func (info *ConnRemoteInfo) lmpFeatureSet(pageNumber uint8, features uint64) {
+	if info.LmpFeatureMap == nil {
+		info.LmpFeatureMap = map[uint8]uint64{}
+	}
+	info.LmpFeatureMap[pageNumber] = features
+}
+
+// 对端版本和特性相关事件
+func (tracker *ConnTracker) remoteEvtFeed(evt hci.HciEvtPktParseResult) {
+	switch pkt := evt.Ret.(type) {
+	case hci.ReadRemoteVersionInformationCompleteEvent:
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok && pkt.Status == hci.HCI_STATUS_SUCCESS {
+			conn.Remote.VersionKnown = true
+			conn.Remote.Version = pkt.Version
+			conn.Remote.ManufacturerName = pkt.ManufacturerName
+			conn.Remote.Subversion = pkt.Subversion
+		}
+	case hci.ReadRemoteSupportedFeaturesCompleteEvent:
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok && pkt.Status == hci.HCI_STATUS_SUCCESS {
+			conn.Remote.lmpFeatureSet(0, pkt.LmpFeatures)
+		}
+	case hci.ReadRemoteExtendedFeaturesCompleteEvent:
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok && pkt.Status == hci.HCI_STATUS_SUCCESS {
+			conn.Remote.lmpFeatureSet(pkt.PageNumber, pkt.ExtendedLmpFeatures)
+			conn.Remote.MaximumPageNumber = pkt.MaximumPageNumber
+		}
+	case hci.LeReadRemoteFeaturesCompleteEvent:
+		if conn, ok := tracker.activeConnMap[pkt.ConnectionHandle]; ok && pkt.Status == hci.HCI_STATUS_SUCCESS {
+			conn.Remote.LeFeaturesKnown, conn.Remote.LeFeatures = true, pkt.LeFeatures
+		}
+	}
+}
+
+// 对端发送的SMP Pairing Request/Pairing Response
+func (tracker *ConnTracker) remoteAclFeed(record Record, acl hci.HciAcl) {
+	conn, ok := tracker.activeConnMap[acl.Handle]
+	if !ok || !record.IsReceived() {
+		return
+	}
+	// BR/EDR连接上的SMP只用于cross-transport key derivation
+	parsed, _ := acl.PayloadParsedResult.(hci.HciAclPktParseResult)
+	if pkt, ok := parsed.Ret.(hci.SmpPairingFeature); ok && conn.Transport == CONN_TRANSPORT_LE {
+		conn.Remote.SmpAuthReqKnown, conn.Remote.SmpAuthReq = true, pkt.AuthReq
+	}
+}
+
+// 对端最近一次连接中交换到的版本和特性，ok为false表示没有交换到任何信息
+func (tracker *ConnTracker) PeerRemoteInfo(peerAddress [6]byte) (info ConnRemoteInfo, ok bool) {
+	for index := len(tracker.ConnList) - 1; index >= 0; index-- {
+		conn := tracker.ConnList[index]
+		if conn.Implicit || conn.PeerAddress != peerAddress {
+			continue
+		}
+		if conn.Remote.VersionKnown || conn.Remote.LeFeaturesKnown || conn.Remote.SmpAuthReqKnown || len(conn.Remote.LmpFeatureMap) > 0 {
+			return conn.Remote, true
+		}
+	}
+	return ConnRemoteInfo{}, false
+}
@@ -0,0 +1,42 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+func TestConnRemoteInfoLeSecureConnections(t *testing.T) {
+	peerAddress := [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}
+	leConnectionComplete := append(append([]byte{0x3e, 0x13, 0x01, 0x00, 0x40, 0x00, 0x00, 0x00}, peerAddress[:]...), 0x18, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00)
+	testList := []struct {
+		name          string
+		packetFlags   uint32
+		smpBuf        []byte
+		wantSupported bool
+		wantKnown     bool
+	}{
+		{"no pairing", 0x01, nil, false, false},
+		{"remote pairing request with SC", 0x01, []byte{0x01, 0x03, 0x00, 0x0d, 0x10, 0x03, 0x03}, true, true},
+		{"remote pairing response without SC", 0x01, []byte{0x02, 0x03, 0x00, 0x05, 0x10, 0x03, 0x03}, false, true},
+		{"local pairing request is ignored", 0x00, []byte{0x01, 0x03, 0x00, 0x0d, 0x10, 0x03, 0x03}, false, false},
+		{"pairing confirm is ignored", 0x01, append([]byte{0x03}, make([]byte, 16)...), false, false},
+	}
+	for _, test := range testList {
+		tracker := NewConnTracker()
+		tracker.Feed(Record{Index: 0, PacketFlags: 0x03, Parsed: hci.HciPktParse(hci.PKT_TYPE_HCI_EVT, leConnectionComplete)})
+		if test.smpBuf != nil {
+			l2capLen := len(test.smpBuf)
+			aclBuf := append([]byte{0x40, 0x20, byte(l2capLen + 4), 0x00, byte(l2capLen), 0x00, 0x06, 0x00}, test.smpBuf...)
+			tracker.Feed(Record{Index: 1, PacketFlags: test.packetFlags, Parsed: hci.HciPktParse(hci.PKT_TYPE_HCI_ACL, aclBuf)})
+		}
+		conn, ok := tracker.Active(0x0040)
+		if !ok {
+			t.Fatalf("%s: connection not found", test.name)
+		}
+		supported, known := conn.Remote.SupportsSecureConnections()
+		if supported != test.wantSupported || known != test.wantKnown {
+			t.Errorf("%s: SupportsSecureConnections() = %v, %v, want %v, %v", test.name, supported, known, test.wantSupported, test.wantKnown)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// handle 0x0040上的ATT Write Request，PB_Flag 0x02(首包)发送
+var connTestAttWrite = []byte{0x04, 0x00, 0x04, 0x00, 0x12, 0x03, 0x00, 0x01}
+
+// 同一个handle先后被抓包前建立的连接、BR/EDR连接、LE连接使用
+func connTestRecordList() []Record {
+	recordList := []Record{
+		aclTestRecord(0, false, 0x02, connTestAttWrite),
+		evtTestRecord(1, []byte{0x05, 0x04, 0x00, 0x40, 0x00, 0x13}),
+		evtTestRecord(2, []byte{0x03, 0x0B, 0x00, 0x40, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00}),
+		aclTestRecord(3, false, 0x02, connTestAttWrite),
+		evtTestRecord(4, []byte{0x05, 0x04, 0x00, 0x40, 0x00, 0x16}),
+		evtTestRecord(5, []byte{
+			0x3E, 0x1F, hci.LE_ENHANCED_CONNECTION_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x01, 0x00,
+			0xCC, 0xBB, 0xAA, 0x03, 0x02, 0x01, // Peer_Address
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0x18, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00,
+		}),
+		aclTestRecord(6, true, 0x02, connTestAttWrite),
+	}
+	for index := range recordList {
+		recordList[index].TimestampUs = uint64(index+1) * 100
+	}
+	return recordList
+}
+
+func TestConnTrackerResolve(t *testing.T) {
+	tracker := NewConnTracker()
+	for _, record := range connTestRecordList() {
+		tracker.Feed(record)
+	}
+	if len(tracker.ConnList) != 3 {
+		t.Fatalf("%d connections, want 3", len(tracker.ConnList))
+	}
+	testList := []struct {
+		name        string
+		handle      uint16
+		timestampUs uint64
+		wantIndex   int // ConnList中的位置，-1表示没有连接
+	}{
+		{"before capture", 0x0040, 50, 0},
+		{"implicit connection", 0x0040, 150, 0},
+		{"disconnect time is inside the connection", 0x0040, 200, 0},
+		{"between connections", 0x0040, 250, -1},
+		{"br/edr connection", 0x0040, 400, 1},
+		{"le connection", 0x0040, 700, 2},
+		{"unknown handle", 0x0041, 400, -1},
+	}
+	for _, test := range testList {
+		conn, ok := tracker.Resolve(test.handle, test.timestampUs)
+		if test.wantIndex < 0 {
+			if ok {
+				t.Errorf("%s: Resolve() = %+v, want no connection", test.name, conn)
+			}
+			continue
+		}
+		if !ok || conn != tracker.ConnList[test.wantIndex] {
+			t.Errorf("%s: Resolve() = %+v/%v, want ConnList[%d]", test.name, conn, ok, test.wantIndex)
+		}
+	}
+
+	implicit, bredr, le := tracker.ConnList[0], tracker.ConnList[1], tracker.ConnList[2]
+	if !implicit.Implicit || implicit.DisconnectReason != hci.HCI_STATUS_REMOTE_USER_TERMINATED_CONNECTION {
+		t.Errorf("implicit connection = %+v", implicit)
+	}
+	if bredr.Transport != CONN_TRANSPORT_BREDR || bredr.PeerAddress != [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66} {
+		t.Errorf("br/edr connection = %+v", bredr)
+	}
+	if le.Transport != CONN_TRANSPORT_LE || le.Role != CONN_ROLE_SLAVE || le.PeerAddress != [6]byte{0x01, 0x02, 0x03, 0xAA, 0xBB, 0xCC} || le.ConnInterval != 0x0018 {
+		t.Errorf("le connection = %+v", le)
+	}
+}
@@ -0,0 +1,171 @@
+// controller能力报告
+// 1. 从初始化阶段读取controller信息的命令的Command Complete中提取版本、厂商、地址、特性、支持的命令和缓冲区大小
+// 2. 跟踪Set Event Mask/LE Set Event Mask，命令收到成功的Command Complete后才生效，HCI Reset后恢复默认值
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+type ControllerProfile struct {
+	BdAddr      [6]byte
+	BdAddrKnown bool
+
+	VersionKnown     bool
+	HciVersion       uint8
+	HciRevision      uint16
+	LmpVersion       uint8
+	ManufacturerName uint16 // Company Identifier
+	LmpSubversion    uint16
+
+	LmpFeaturesKnown      bool
+	LmpFeatures           uint64
+	ExtendedLmpFeatureMap map[uint8]uint64 // Read Local Extended Features，Page_Number -> features
+	LeFeaturesKnown       bool
+	LeFeatures            uint64
+
+	SupportedCommandsKnown bool
+	SupportedCommands      [64]byte
+
+	// 未配置时为controller默认值
+	EventMask      uint64
+	EventMaskSet   bool
+	LeEventMask    uint64
+	LeEventMaskSet bool
+
+	BufferSizeKnown                bool
+	AclDataPacketLength            uint16
+	SynchronousDataPacketLength    uint8
+	TotalNumAclDataPackets         uint16
+	TotalNumSynchronousDataPackets uint16
+	LeBufferSizeKnown              bool
+	LeAclDataPacketLength          uint16 // 为0时与BR/EDR共用缓冲区
+	TotalNumLeAclDataPackets       uint8
+	IsoDataPacketLength            uint16
+	TotalNumIsoDataPackets         uint8
+
+	ResetCount int
+
+	pendingCmdMap map[uint16]interface{} // OpCode -> 等待Command Complete的命令参数
+}
+
+func NewControllerProfile() *ControllerProfile {
+	return &ControllerProfile{
+		ExtendedLmpFeatureMap: map[uint8]uint64{},
+		EventMask:             hci.EVENT_MASK_DEFAULT,
+		LeEventMask:           hci.LE_EVENT_MASK_DEFAULT,
+		pendingCmdMap:         map[uint16]interface{}{},
+	}
+}
+
+func (profile *ControllerProfile) Feed(record Record) {
+	if cmd, parsed, ok := record.CmdParseResult(); ok {
+		switch parsed.Ret.(type) {
+		case hci.HciSetEventMask, hci.HciLeSetEventMask:
+			profile.pendingCmdMap[cmd.OpCode] = parsed.Ret
+		}
+		return
+	}
+	_, evt, ok := record.EvtParseResult()
+	if !ok {
+		return
+	}
+	pkt, ok := evt.Ret.(hci.CommandCompleteEvent)
+	if !ok {
+		return
+	}
+	cmd, pending := profile.pendingCmdMap[pkt.CommandOpCode]
+	delete(profile.pendingCmdMap, pkt.CommandOpCode)
+	if pkt.Status != hci.HCI_STATUS_SUCCESS {
+		return
+	}
+	if pkt.OpCodeOgf == hci.HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD && pkt.OpCodeOcf == hci.HCI_RESET {
+		// HCI Reset后事件掩码恢复默认值，版本/特性等不变
+		profile.ResetCount++
+		profile.EventMask, profile.EventMaskSet = hci.EVENT_MASK_DEFAULT, false
+		profile.LeEventMask, profile.LeEventMaskSet = hci.LE_EVENT_MASK_DEFAULT, false
+		profile.pendingCmdMap = map[uint16]interface{}{}
+		return
+	}
+	if pending {
+		switch cmd := cmd.(type) {
+		case hci.HciSetEventMask:
+			profile.EventMask, profile.EventMaskSet = cmd.EventMask, true
+		case hci.HciLeSetEventMask:
+			profile.LeEventMask, profile.LeEventMaskSet = cmd.LeEventMask, true
+		}
+		return
+	}
+	profile.retParamFeed(pkt.ReturnParsedResult.Ret)
+}
+
+func (profile *ControllerProfile) retParamFeed(ret interface{}) {
+	switch ret := ret.(type) {
+	case hci.HciReadBdAddrRetParam:
+		profile.BdAddr, profile.BdAddrKnown = ret.BdAddr, true
+	case hci.HciReadLocalVersionInformationRetParam:
+		profile.VersionKnown = true
+		profile.HciVersion = ret.HciVersion
+		profile.HciRevision = ret.HciRevision
+		profile.LmpVersion = ret.LmpPalVersion
+		profile.ManufacturerName = ret.ManufacturerName
+		profile.LmpSubversion = ret.LmpPalSubversion
+	case hci.HciReadLocalSupportedFeaturesRetParam:
+		profile.LmpFeatures, profile.LmpFeaturesKnown = ret.LmpFeatures, true
+	case hci.HciReadLocalExtendedFeaturesRetParam:
+		profile.ExtendedLmpFeatureMap[ret.PageNumber] = ret.ExtendedLmpFeatures
+	case hci.HciLeReadLocalSupportedFeaturesRetParam:
+		profile.LeFeatures, profile.LeFeaturesKnown = ret.LeFeatures, true
+	case hci.HciReadLocalSupportedCommandsRetParam:
+		profile.SupportedCommands, profile.SupportedCommandsKnown = ret.SupportedCommands, true
+	case hci.HciReadBufferSizeRetParam:
+		profile.BufferSizeKnown = true
+		profile.AclDataPacketLength = ret.AclDataPacketLength
+		profile.SynchronousDataPacketLength = ret.SynchronousDataPacketLength
+		profile.TotalNumAclDataPackets = ret.TotalNumAclDataPackets
+		profile.TotalNumSynchronousDataPackets = ret.TotalNumSynchronousDataPackets
+	case hci.HciLeReadBufferSizeRetParam:
+		profile.LeBufferSizeKnown = true
+		profile.LeAclDataPacketLength = ret.LeAclDataPacketLength
+		profile.TotalNumLeAclDataPackets = ret.TotalNumLeAclDataPackets
+		profile.IsoDataPacketLength = ret.IsoDataPacketLength
+		profile.TotalNumIsoDataPackets = ret.TotalNumIsoDataPackets
+	}
+}
+
+func (profile *ControllerProfile) ManufacturerString() string {
+	return hci.CompanyIdString(profile.ManufacturerName)
+}
+
+func (profile *ControllerProfile) HciVersionString() string {
+	return hci.CoreVersionString(profile.HciVersion)
+}
+
+func (profile *ControllerProfile) LmpVersionString() string {
+	return hci.CoreVersionString(profile.LmpVersion)
+}
+
+func (profile *ControllerProfile) LeFeatureList() []string {
+	return hci.LeFeatureList(profile.LeFeatures)
+}
+
+func (profile *ControllerProfile) SupportedCommandList() []string {
+	return hci.SupportedCommandList(profile.SupportedCommands)
+}
+
+func (profile *ControllerProfile) EnabledEventList() []string {
+	return hci.EventMaskList(profile.EventMask)
+}
+
+func (profile *ControllerProfile) EnabledLeEventList() []string {
+	return hci.LeEventMaskList(profile.LeEventMask)
+}
+
+// page 0优先取Read Local Supported Features
+func (profile *ControllerProfile) LmpFeatureList(pageNumber uint8) []string {
+	if pageNumber == 0 && profile.LmpFeaturesKnown {
+		return hci.LmpFeatureList(0, profile.LmpFeatures)
+	}
+	return hci.LmpFeatureList(pageNumber, profile.ExtendedLmpFeatureMap[pageNumber])
+}
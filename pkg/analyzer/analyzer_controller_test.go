@@ -0,0 +1,45 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+func TestControllerProfile(t *testing.T) {
+	recordList := []Record{
+		evtTestRecord(0, []byte{0x0E, 0x0A, 0x01, 0x09, 0x10, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11}),
+		evtTestRecord(1, []byte{0x0E, 0x0C, 0x01, 0x01, 0x10, 0x00, 0x09, 0x34, 0x12, 0x09, 0x0F, 0x00, 0x78, 0x56}),
+		evtTestRecord(2, []byte{0x0E, 0x08, 0x01, 0x02, 0x20, 0x00, 0xFB, 0x00, 0x0F}),
+		// Set Event Mask成功后生效，LE Set Event Mask失败保持默认值
+		cmdTestRecord(3, []byte{0x01, 0x0C, 0x08, 0xFF, 0xFF, 0xFB, 0xFF, 0x07, 0xF8, 0xBF, 0x3D}),
+		evtTestRecord(4, []byte{0x0E, 0x04, 0x01, 0x01, 0x0C, 0x00}),
+		cmdTestRecord(5, []byte{0x01, 0x20, 0x08, 0x7F, 0x0A, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		evtTestRecord(6, []byte{0x0E, 0x04, 0x01, 0x01, 0x20, 0x12}),
+	}
+	profile := NewControllerProfile()
+	for _, record := range recordList {
+		profile.Feed(record)
+	}
+	if !profile.BdAddrKnown || profile.BdAddr != [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66} {
+		t.Errorf("bd_addr %v known %v", profile.BdAddr, profile.BdAddrKnown)
+	}
+	if !profile.VersionKnown || profile.HciVersionString() != "5.0" || profile.ManufacturerString() != "Broadcom Corporation" || profile.LmpSubversion != 0x5678 {
+		t.Errorf("version %+v", profile)
+	}
+	if !profile.LeBufferSizeKnown || profile.LeAclDataPacketLength != 251 || profile.TotalNumLeAclDataPackets != 15 {
+		t.Errorf("le buffer size %d/%d", profile.LeAclDataPacketLength, profile.TotalNumLeAclDataPackets)
+	}
+	if !profile.EventMaskSet || profile.EventMask != 0x3DBFF807FFFBFFFF {
+		t.Errorf("event mask %#x set %v", profile.EventMask, profile.EventMaskSet)
+	}
+	if profile.LeEventMaskSet || profile.LeEventMask != hci.LE_EVENT_MASK_DEFAULT {
+		t.Errorf("le event mask %#x set %v, want default", profile.LeEventMask, profile.LeEventMaskSet)
+	}
+
+	// HCI Reset后事件掩码恢复默认值，版本不变
+	profile.Feed(evtTestRecord(7, []byte{0x0E, 0x04, 0x01, 0x03, 0x0C, 0x00}))
+	if profile.ResetCount != 1 || profile.EventMaskSet || profile.EventMask != hci.EVENT_MASK_DEFAULT || !profile.VersionKnown {
+		t.Errorf("after reset %+v", profile)
+	}
+}
@@ -0,0 +1,235 @@
+// 扫描结果汇总
+// 1. 按广播地址(地址+地址类型)汇总LE Advertising Report/LE Extended Advertising Report/LE Directed Advertising Report
+// 2. 记录首次/最后发现时间、广播和扫描响应次数、RSSI变化、名称、服务UUID、厂商ID
+// 3. 广播数据和扫描响应数据合并，扩展广播分片按地址+SID拼接完整后再解析
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 一次RSSI采样
+type RssiSample struct {
+	RecordIndex int
+	TimestampUs uint64
+	Rssi        int8
+}
+
+// 一个广播者
+type Advertiser struct {
+	Address     [6]byte
+	AddressType uint8 // hci.LE_ADDRESS_TYPE_XXX
+
+	FirstSeenRecordIndex int
+	FirstSeenUs          uint64
+	LastSeenRecordIndex  int
+	LastSeenUs           uint64
+
+	AdvCount      int // 广播报告次数(不含扫描响应)
+	ScanRspCount  int
+	DirectedCount int // LE Directed Advertising Report次数
+	Connectable   bool
+
+	RssiList []RssiSample // RSSI不可用的报告不记录
+	RssiMin  int8
+	RssiMax  int8
+	rssiSum  int
+
+	NameList           []string // 去重，按首次出现顺序
+	ServiceUuidList    []string
+	ManufacturerIdList []uint16
+	TxPowerLevel       int8 // AD Type TX Power Level，HasTxPowerLevel为false时无效
+	HasTxPowerLevel    bool
+	Appearance         uint16
+	HasAppearance      bool
+
+	// 广播数据和扫描响应数据合并后，每种AD Type最后一次出现的值
+	AdStructMap map[uint8]hci.AdStruct
+}
+
+// RSSI平均值，没有RSSI采样时返回0
+func (advertiser *Advertiser) RssiAvg() float64 {
+	if len(advertiser.RssiList) == 0 {
+		return 0
+	}
+	return float64(advertiser.rssiSum) / float64(len(advertiser.RssiList))
+}
+
+// 优先返回Complete Local Name
+func (advertiser *Advertiser) Name() string {
+	if adStruct, ok := advertiser.AdStructMap[hci.AD_TYPE_COMPLETE_LOCAL_NAME]; ok {
+		if name, ok := adStruct.PayloadParsedResult.Ret.(hci.AdLocalName); ok {
+			return name.Name
+		}
+	}
+	if len(advertiser.NameList) > 0 {
+		return advertiser.NameList[len(advertiser.NameList)-1]
+	}
+	return ""
+}
+
+type advertiserKey struct {
+	Address     [6]byte
+	AddressType uint8
+}
+
+type extAdvFragmentKey struct {
+	advertiserKey
+	AdvertisingSid uint8
+	ScanResponse   bool
+}
+
+// 扫描结果汇总
+type DiscoveryInventory struct {
+	AdvertiserList []*Advertiser // 按首次发现顺序
+	advertiserMap  map[advertiserKey]*Advertiser
+	fragmentMap    map[extAdvFragmentKey][]byte // 未接收完整的扩展广播数据
+}
+
+func NewDiscoveryInventory() *DiscoveryInventory {
+	return &DiscoveryInventory{advertiserMap: map[advertiserKey]*Advertiser{}, fragmentMap: map[extAdvFragmentKey][]byte{}}
+}
+
+// 按地址查找，不区分地址类型
+func (inventory *DiscoveryInventory) Find(address [6]byte) []*Advertiser {
+	var advertiserList []*Advertiser
+	for _, advertiser := range inventory.AdvertiserList {
+		if advertiser.Address == address {
+			advertiserList = append(advertiserList, advertiser)
+		}
+	}
+	return advertiserList
+}
+
+func (inventory *DiscoveryInventory) advertiser(record Record, address [6]byte, addressType uint8) *Advertiser {
+	key := advertiserKey{Address: address, AddressType: addressType}
+	advertiser, ok := inventory.advertiserMap[key]
+	if !ok {
+		advertiser = &Advertiser{
+			Address:              address,
+			AddressType:          addressType,
+			FirstSeenRecordIndex: record.Index,
+			FirstSeenUs:          record.TimestampUs,
+			AdStructMap:          map[uint8]hci.AdStruct{},
+		}
+		inventory.advertiserMap[key] = advertiser
+		inventory.AdvertiserList = append(inventory.AdvertiserList, advertiser)
+	}
+	advertiser.LastSeenRecordIndex = record.Index
+	advertiser.LastSeenUs = record.TimestampUs
+	return advertiser
+}
+
+func (inventory *DiscoveryInventory) Feed(record Record) {
+	_, evt, ok := record.EvtParseResult()
+	if !ok {
+		return
+	}
+	switch pkt := evt.Ret.(type) {
+	case hci.LeAdvertisingReportEvent:
+		for _, report := range pkt.ReportList {
+			advertiser := inventory.advertiser(record, report.Address, report.AddressType)
+			scanRsp := report.EventType == hci.ADV_REPORT_EVENT_TYPE_SCAN_RSP
+			connectable := report.EventType == hci.ADV_REPORT_EVENT_TYPE_ADV_IND || report.EventType == hci.ADV_REPORT_EVENT_TYPE_ADV_DIRECT_IND
+			advertiser.reportAdd(record, scanRsp, connectable, report.Rssi)
+			advertiser.adStructListMerge(report.AdStructList)
+		}
+	case hci.LeExtendedAdvertisingReportEvent:
+		for _, report := range pkt.ReportList {
+			advertiser := inventory.advertiser(record, report.Address, report.AddressType)
+			scanRsp := report.EventType&hci.EXT_ADV_REPORT_EVENT_TYPE_SCAN_RESPONSE != 0
+			connectable := report.EventType&hci.EXT_ADV_REPORT_EVENT_TYPE_CONNECTABLE != 0
+			key := extAdvFragmentKey{
+				advertiserKey:  advertiserKey{Address: report.Address, AddressType: report.AddressType},
+				AdvertisingSid: report.AdvertisingSid,
+				ScanResponse:   scanRsp,
+			}
+			data := append(inventory.fragmentMap[key], report.Data...)
+			if report.DataStatus() == hci.EXT_ADV_REPORT_DATA_STATUS_INCOMPLETE_MORE_DATA {
+				inventory.fragmentMap[key] = data
+				continue
+			}
+			delete(inventory.fragmentMap, key)
+			advertiser.reportAdd(record, scanRsp, connectable, report.Rssi)
+			adStructList, _ := hci.AdStructListParse(data)
+			advertiser.adStructListMerge(adStructList)
+		}
+	case hci.LeDirectedAdvertisingReportEvent:
+		for _, report := range pkt.ReportList {
+			advertiser := inventory.advertiser(record, report.Address, report.AddressType)
+			advertiser.DirectedCount++
+			advertiser.Connectable = true
+			advertiser.rssiAdd(record, report.Rssi)
+		}
+	}
+}
+
+func (advertiser *Advertiser) reportAdd(record Record, scanRsp bool, connectable bool, rssi int8) {
+	if scanRsp {
+		advertiser.ScanRspCount++
+	} else {
+		advertiser.AdvCount++
+	}
+	if connectable {
+		advertiser.Connectable = true
+	}
+	advertiser.rssiAdd(record, rssi)
+}
+
+func (advertiser *Advertiser) rssiAdd(record Record, rssi int8) {
+	if rssi == hci.ADV_REPORT_RSSI_NOT_AVAILABLE {
+		return
+	}
+	if len(advertiser.RssiList) == 0 || rssi < advertiser.RssiMin {
+		advertiser.RssiMin = rssi
+	}
+	if len(advertiser.RssiList) == 0 || rssi > advertiser.RssiMax {
+		advertiser.RssiMax = rssi
+	}
+	advertiser.rssiSum += int(rssi)
+	advertiser.RssiList = append(advertiser.RssiList, RssiSample{RecordIndex: record.Index, TimestampUs: record.TimestampUs, Rssi: rssi})
+}
+
+func (advertiser *Advertiser) adStructListMerge(adStructList []hci.AdStruct) {
+	for _, adStruct := range adStructList {
+		advertiser.AdStructMap[adStruct.AdType] = adStruct
+		if adStruct.PayloadParsedResult.Code != hci.HCI_PKT_RET_CODE_OK {
+			continue
+		}
+		switch ad := adStruct.PayloadParsedResult.Ret.(type) {
+		case hci.AdLocalName:
+			advertiser.NameList = stringListAppendUnique(advertiser.NameList, ad.Name)
+		case hci.AdServiceUuidList:
+			if ad.Solicitation {
+				continue
+			}
+			for _, uuid := range ad.UuidList {
+				advertiser.ServiceUuidList = stringListAppendUnique(advertiser.ServiceUuidList, uuid)
+			}
+		case hci.AdServiceData:
+			advertiser.ServiceUuidList = stringListAppendUnique(advertiser.ServiceUuidList, ad.Uuid)
+		case hci.AdManufacturerSpecificData:
+			found := false
+			for _, companyId := range advertiser.ManufacturerIdList {
+				found = found || companyId == ad.CompanyId
+			}
+			if !found {
+				advertiser.ManufacturerIdList = append(advertiser.ManufacturerIdList, ad.CompanyId)
+			}
+		case hci.AdTxPowerLevel:
+			advertiser.TxPowerLevel, advertiser.HasTxPowerLevel = ad.TxPowerLevel, true
+		case hci.AdAppearance:
+			advertiser.Appearance, advertiser.HasAppearance = ad.Appearance, true
+		}
+	}
+}
+
+func stringListAppendUnique(list []string, str string) []string {
+	for _, item := range list {
+		if item == str {
+			return list
+		}
+	}
+	return append(list, str)
+}
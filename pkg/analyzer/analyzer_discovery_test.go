@@ -0,0 +1,65 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 地址11:22:33:44:55:66(public)的LE Advertising Report
+func discoveryTestLegacyReport(eventType uint8, data []byte, rssi int8) []byte {
+	buf := []byte{0x3E, byte(12 + len(data)), hci.LE_ADVERTISING_REPORT_EVENT, 0x01, eventType, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, byte(len(data))}
+	return append(append(buf, data...), byte(rssi))
+}
+
+// 地址11:22:33:44:55:66(public)、SID 1的LE Extended Advertising Report
+func discoveryTestExtReport(eventType uint16, data []byte, rssi int8) []byte {
+	buf := []byte{
+		0x3E, byte(26 + len(data)), hci.LE_EXTENDED_ADVERTISING_REPORT_EVENT, 0x01,
+		byte(eventType), byte(eventType >> 8), 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11,
+		0x01, 0x02, 0x01, 0x7F, byte(rssi), 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, byte(len(data)),
+	}
+	return append(buf, data...)
+}
+
+func TestDiscoveryInventoryFeed(t *testing.T) {
+	recordList := []Record{
+		evtTestRecord(0, discoveryTestLegacyReport(hci.ADV_REPORT_EVENT_TYPE_ADV_IND, []byte{0x02, 0x01, 0x06, 0x03, 0x03, 0x0F, 0x18}, -70)),
+		evtTestRecord(1, discoveryTestLegacyReport(hci.ADV_REPORT_EVENT_TYPE_SCAN_RSP, []byte{0x05, 0x08, 'b', 'a', 'n', 'd'}, -60)),
+		evtTestRecord(2, discoveryTestLegacyReport(hci.ADV_REPORT_EVENT_TYPE_ADV_IND, []byte{}, hci.ADV_REPORT_RSSI_NOT_AVAILABLE)),
+		// 扩展广播数据分两个报告上报，第一个Data Status为incomplete, more data to come
+		evtTestRecord(3, discoveryTestExtReport(0x0021, []byte{0x06, 0x09, 'b', 'a'}, -80)),
+		evtTestRecord(4, discoveryTestExtReport(0x0001, []byte{'n', 'd', '2', 0x04, 0xFF, 0x4C, 0x00, 0x01}, -50)),
+	}
+	for index := range recordList {
+		recordList[index].TimestampUs = uint64(index+1) * 100
+	}
+	inventory := NewDiscoveryInventory()
+	for _, record := range recordList {
+		inventory.Feed(record)
+	}
+
+	if len(inventory.AdvertiserList) != 1 {
+		t.Fatalf("%d advertisers, want 1", len(inventory.AdvertiserList))
+	}
+	advertiser := inventory.AdvertiserList[0]
+	if advertiser.AdvCount != 3 || advertiser.ScanRspCount != 1 || !advertiser.Connectable {
+		t.Errorf("AdvCount %d ScanRspCount %d Connectable %v, want 3, 1, true", advertiser.AdvCount, advertiser.ScanRspCount, advertiser.Connectable)
+	}
+	if advertiser.FirstSeenUs != 100 || advertiser.LastSeenUs != 500 {
+		t.Errorf("seen %d-%d us, want 100-500 us", advertiser.FirstSeenUs, advertiser.LastSeenUs)
+	}
+	if len(advertiser.RssiList) != 3 || advertiser.RssiMin != -70 || advertiser.RssiMax != -50 || advertiser.RssiAvg() != -60 {
+		t.Errorf("rssi %d samples min %d max %d avg %v, want 3 samples -70/-50/-60", len(advertiser.RssiList), advertiser.RssiMin, advertiser.RssiMax, advertiser.RssiAvg())
+	}
+	if !reflect.DeepEqual(advertiser.NameList, []string{"band", "band2"}) || advertiser.Name() != "band2" {
+		t.Errorf("NameList %v Name() %q, want [band band2] and band2", advertiser.NameList, advertiser.Name())
+	}
+	if !reflect.DeepEqual(advertiser.ServiceUuidList, []string{"180F"}) || !reflect.DeepEqual(advertiser.ManufacturerIdList, []uint16{0x004C}) {
+		t.Errorf("ServiceUuidList %v ManufacturerIdList %v", advertiser.ServiceUuidList, advertiser.ManufacturerIdList)
+	}
+	if found := inventory.Find([6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}); len(found) != 1 || found[0] != advertiser {
+		t.Errorf("Find() = %v", found)
+	}
+}
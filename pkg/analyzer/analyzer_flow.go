@@ -0,0 +1,384 @@
+// ACL流控和吞吐量分析
+// 1. 根据Read Buffer Size/LE Read Buffer Size获取controller的ACL缓冲区数量，LE缓冲区数量为0时与BR/EDR共用
+// 2. host每发送一个ACL包占用一个credit，Number Of Completed Packets和Disconnection Complete释放credit
+// 3. 标记credit用完后仍然发送的ACL包，记录credit为0的时间段
+// 4. 按连接统计收发字节数，计算平均吞吐量和1秒窗口内的峰值吞吐量
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// controller ACL缓冲区
+const (
+	ACL_POOL_BREDR = 0
+	ACL_POOL_LE    = 1
+	ACL_POOL_NONE  = -1 // handle的传输类型未知，不计入缓冲区
+)
+
+// 流控问题类型
+const (
+	ACL_FLOW_ISSUE_OVERRUN         = 0 // credit用完后仍然发送ACL包
+	ACL_FLOW_ISSUE_EXCESS_COMPLETE = 1 // 完成的包数多于已发送的包数，通常是抓包开始前发送的包
+)
+
+const throughputWindowUs = 1000000
+
+// controller ACL缓冲区
+type AclBufferPool struct {
+	Known               bool // 收到Read Buffer Size/LE Read Buffer Size的Command Complete
+	Shared              bool // LE与BR/EDR共用缓冲区，仅ACL_POOL_LE有效
+	DataPacketLength    uint16
+	TotalNumDataPackets int
+	Outstanding         int // 已发送未完成的包数
+	MaxOutstanding      int
+}
+
+// 可用credit，缓冲区数量未知时返回-1
+func (pool *AclBufferPool) Free() int {
+	if !pool.Known {
+		return -1
+	}
+	return pool.TotalNumDataPackets - pool.Outstanding
+}
+
+// 某一时刻的credit
+type AclCreditSample struct {
+	RecordIndex int
+	TimestampUs uint64
+	Outstanding int // 该handle已发送未完成的包数
+	PoolFree    int // 所在缓冲区的可用credit，未知时为-1
+}
+
+// 一个连接的流控和吞吐量
+type AclFlow struct {
+	ConnectionHandle uint16
+	Transport        uint8 // CONN_TRANSPORT_XXX，TransportKnown为false时无效
+	TransportKnown   bool
+
+	StartRecordIndex int
+	StartUs          uint64
+	Disconnected     bool
+	EndRecordIndex   int
+	EndUs            uint64
+
+	TxPacketCount int
+	TxBytes       uint64 // HciAcl.DataTotalLen之和
+	FirstTxUs     uint64
+	LastTxUs      uint64
+	RxPacketCount int
+	RxBytes       uint64
+	FirstRxUs     uint64
+	LastRxUs      uint64
+
+	PeakTxBytesPerSec uint64 // 1秒窗口内的最大发送字节数
+	PeakRxBytesPerSec uint64
+
+	Outstanding      int
+	MaxOutstanding   int
+	CompletedCount   int
+	CreditSampleList []AclCreditSample // 每次发送和完成后的credit
+
+	txWindowStartUs uint64
+	txWindowBytes   uint64
+	rxWindowStartUs uint64
+	rxWindowBytes   uint64
+}
+
+// 第一个包到最后一个包的平均发送吞吐量，单位bytes/sec
+func (flow *AclFlow) TxThroughput() float64 {
+	return throughput(flow.TxBytes, flow.FirstTxUs, flow.LastTxUs)
+}
+
+// 第一个包到最后一个包的平均接收吞吐量，单位bytes/sec
+func (flow *AclFlow) RxThroughput() float64 {
+	return throughput(flow.RxBytes, flow.FirstRxUs, flow.LastRxUs)
+}
+
+func throughput(bytes uint64, firstUs uint64, lastUs uint64) float64 {
+	if lastUs <= firstUs {
+		return 0
+	}
+	return float64(bytes) * 1000000 / float64(lastUs-firstUs)
+}
+
+// 流控问题
+type AclFlowIssue struct {
+	RecordIndex      int
+	TimestampUs      uint64
+	Type             int // ACL_FLOW_ISSUE_XXX
+	ConnectionHandle uint16
+	Pool             int
+	Outstanding      int // 发生时所在缓冲区已发送未完成的包数
+	Total            int
+}
+
+// credit为0的时间段
+type AclCreditStall struct {
+	Pool             int
+	StartRecordIndex int
+	StartUs          uint64
+	Ended            bool // false表示抓包结束时credit仍为0
+	EndRecordIndex   int
+	EndUs            uint64
+}
+
+// ACL流控和吞吐量分析
+type AclFlowAnalyzer struct {
+	PoolList  [2]AclBufferPool // 按ACL_POOL_XXX索引
+	FlowList  []*AclFlow       // 按第一次出现的顺序
+	IssueList []AclFlowIssue
+	StallList []AclCreditStall
+
+	flowMap          map[uint16]*AclFlow
+	handleTransport  map[uint16]uint8 // 连接建立事件中的传输类型
+	activeStallIndex [2]int           // 正在进行的AclCreditStall在StallList中的位置，-1表示没有
+}
+
+func NewAclFlowAnalyzer() *AclFlowAnalyzer {
+	return &AclFlowAnalyzer{
+		flowMap:          map[uint16]*AclFlow{},
+		handleTransport:  map[uint16]uint8{},
+		activeStallIndex: [2]int{-1, -1},
+	}
+}
+
+// handle使用的缓冲区
+func (analyzer *AclFlowAnalyzer) pool(flow *AclFlow) int {
+	if !flow.TransportKnown {
+		return ACL_POOL_NONE
+	}
+	if flow.Transport == CONN_TRANSPORT_LE && !analyzer.PoolList[ACL_POOL_LE].Shared {
+		return ACL_POOL_LE
+	}
+	return ACL_POOL_BREDR
+}
+
+func (analyzer *AclFlowAnalyzer) flow(record Record, handle uint16) *AclFlow {
+	flow, ok := analyzer.flowMap[handle]
+	if !ok {
+		flow = &AclFlow{ConnectionHandle: handle, StartRecordIndex: record.Index, StartUs: record.TimestampUs}
+		flow.Transport, flow.TransportKnown = analyzer.handleTransport[handle]
+		analyzer.flowMap[handle] = flow
+		analyzer.FlowList = append(analyzer.FlowList, flow)
+	}
+	return flow
+}
+
+func (analyzer *AclFlowAnalyzer) Feed(record Record) {
+	if acl, ok := record.Acl(); ok {
+		flow := analyzer.flow(record, acl.Handle)
+		if record.IsReceived() {
+			analyzer.rxFeed(record, flow, acl)
+		} else {
+			analyzer.txFeed(record, flow, acl)
+		}
+		return
+	}
+
+	_, evt, ok := record.EvtParseResult()
+	if !ok {
+		return
+	}
+	switch pkt := evt.Ret.(type) {
+	case hci.CommandCompleteEvent:
+		analyzer.bufferSizeFeed(record, pkt)
+	case hci.ConnectionCompleteEvent:
+		if pkt.Status == hci.HCI_STATUS_SUCCESS && pkt.LinkType == hci.LINK_TYPE_ACL {
+			analyzer.connAdd(record, pkt.ConnectionHandle, CONN_TRANSPORT_BREDR)
+		}
+	case hci.LeConnectionCompleteEvent:
+		if pkt.Status == hci.HCI_STATUS_SUCCESS {
+			analyzer.connAdd(record, pkt.ConnectionHandle, CONN_TRANSPORT_LE)
+		}
+	case hci.LeEnhancedConnectionCompleteEvent:
+		if pkt.Status == hci.HCI_STATUS_SUCCESS {
+			analyzer.connAdd(record, pkt.ConnectionHandle, CONN_TRANSPORT_LE)
+		}
+	case hci.NumberOfCompletedPacketsEvent:
+		for _, completed := range pkt.CompletedPacketsList {
+			// SCO/ISO handle不在flowMap中
+			if flow, ok := analyzer.flowMap[completed.ConnectionHandle]; ok {
+				analyzer.complete(record, flow, int(completed.NumCompletedPackets))
+			}
+		}
+	case hci.DisconnectionCompleteEvent:
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			return
+		}
+		// 断开后controller丢弃该handle未发送的包，host认为这些包已经完成
+		// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 4.3 Host Controller Data Flow Control
+		if flow, ok := analyzer.flowMap[pkt.ConnectionHandle]; ok {
+			analyzer.complete(record, flow, flow.Outstanding)
+			flow.Disconnected = true
+			flow.EndRecordIndex = record.Index
+			flow.EndUs = record.TimestampUs
+			delete(analyzer.flowMap, pkt.ConnectionHandle)
+		}
+		delete(analyzer.handleTransport, pkt.ConnectionHandle)
+	}
+}
+
+func (analyzer *AclFlowAnalyzer) connAdd(record Record, handle uint16, transport uint8) {
+	analyzer.handleTransport[handle] = transport
+	// 没有收到Disconnection Complete就被复用的handle，旧连接按复用时间结束
+	if old, ok := analyzer.flowMap[handle]; ok {
+		analyzer.complete(record, old, old.Outstanding)
+		old.Disconnected = true
+		old.EndRecordIndex = record.Index
+		old.EndUs = record.TimestampUs
+		delete(analyzer.flowMap, handle)
+	}
+	analyzer.flow(record, handle)
+}
+
+func (analyzer *AclFlowAnalyzer) bufferSizeFeed(record Record, pkt hci.CommandCompleteEvent) {
+	if pkt.OpCodeOgf == hci.HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD && pkt.OpCodeOcf == hci.HCI_RESET && pkt.Status == hci.HCI_STATUS_SUCCESS {
+		// HCI Reset后controller清空缓冲区，重新读取缓冲区大小
+		analyzer.PoolList = [2]AclBufferPool{}
+		for pool := range analyzer.activeStallIndex {
+			analyzer.stallEnd(record, pool)
+		}
+		for _, flow := range analyzer.flowMap {
+			flow.Outstanding = 0
+		}
+		return
+	}
+	switch ret := pkt.ReturnParsedResult.Ret.(type) {
+	case hci.HciReadBufferSizeRetParam:
+		if ret.Status != hci.HCI_STATUS_SUCCESS {
+			return
+		}
+		pool := &analyzer.PoolList[ACL_POOL_BREDR]
+		pool.Known = true
+		pool.DataPacketLength = ret.AclDataPacketLength
+		pool.TotalNumDataPackets = int(ret.TotalNumAclDataPackets)
+	case hci.HciLeReadBufferSizeRetParam:
+		if ret.Status != hci.HCI_STATUS_SUCCESS {
+			return
+		}
+		pool := &analyzer.PoolList[ACL_POOL_LE]
+		pool.Known = true
+		pool.Shared = ret.LeAclDataPacketLength == 0 || ret.TotalNumLeAclDataPackets == 0
+		pool.DataPacketLength = ret.LeAclDataPacketLength
+		pool.TotalNumDataPackets = int(ret.TotalNumLeAclDataPackets)
+	}
+}
+
+func (analyzer *AclFlowAnalyzer) txFeed(record Record, flow *AclFlow, acl hci.HciAcl) {
+	poolIndex := analyzer.pool(flow)
+	if poolIndex != ACL_POOL_NONE {
+		pool := &analyzer.PoolList[poolIndex]
+		if pool.Known && pool.Outstanding >= pool.TotalNumDataPackets {
+			analyzer.IssueList = append(analyzer.IssueList, AclFlowIssue{
+				RecordIndex:      record.Index,
+				TimestampUs:      record.TimestampUs,
+				Type:             ACL_FLOW_ISSUE_OVERRUN,
+				ConnectionHandle: flow.ConnectionHandle,
+				Pool:             poolIndex,
+				Outstanding:      pool.Outstanding,
+				Total:            pool.TotalNumDataPackets,
+			})
+		}
+		pool.Outstanding++
+		if pool.Outstanding > pool.MaxOutstanding {
+			pool.MaxOutstanding = pool.Outstanding
+		}
+		if pool.Known && pool.Outstanding >= pool.TotalNumDataPackets {
+			analyzer.stallStart(record, poolIndex)
+		}
+	}
+	flow.Outstanding++
+	if flow.Outstanding > flow.MaxOutstanding {
+		flow.MaxOutstanding = flow.Outstanding
+	}
+	analyzer.creditSampleAdd(record, flow, poolIndex)
+
+	if flow.TxPacketCount == 0 {
+		flow.FirstTxUs = record.TimestampUs
+	}
+	flow.TxPacketCount++
+	flow.TxBytes += uint64(acl.DataTotalLen)
+	flow.LastTxUs = record.TimestampUs
+	flow.txWindowStartUs, flow.txWindowBytes = windowAdd(record.TimestampUs, uint64(acl.DataTotalLen), flow.txWindowStartUs, flow.txWindowBytes, &flow.PeakTxBytesPerSec)
+}
+
+func (analyzer *AclFlowAnalyzer) rxFeed(record Record, flow *AclFlow, acl hci.HciAcl) {
+	if flow.RxPacketCount == 0 {
+		flow.FirstRxUs = record.TimestampUs
+	}
+	flow.RxPacketCount++
+	flow.RxBytes += uint64(acl.DataTotalLen)
+	flow.LastRxUs = record.TimestampUs
+	flow.rxWindowStartUs, flow.rxWindowBytes = windowAdd(record.TimestampUs, uint64(acl.DataTotalLen), flow.rxWindowStartUs, flow.rxWindowBytes, &flow.PeakRxBytesPerSec)
+}
+
+// 固定1秒窗口累计字节数，更新峰值
+func windowAdd(timestampUs uint64, bytes uint64, windowStartUs uint64, windowBytes uint64, peak *uint64) (uint64, uint64) {
+	if windowBytes == 0 || timestampUs-windowStartUs >= throughputWindowUs {
+		windowStartUs, windowBytes = timestampUs, 0
+	}
+	windowBytes += bytes
+	if windowBytes > *peak {
+		*peak = windowBytes
+	}
+	return windowStartUs, windowBytes
+}
+
+func (analyzer *AclFlowAnalyzer) complete(record Record, flow *AclFlow, num int) {
+	if num > flow.Outstanding {
+		analyzer.IssueList = append(analyzer.IssueList, AclFlowIssue{
+			RecordIndex:      record.Index,
+			TimestampUs:      record.TimestampUs,
+			Type:             ACL_FLOW_ISSUE_EXCESS_COMPLETE,
+			ConnectionHandle: flow.ConnectionHandle,
+			Pool:             analyzer.pool(flow),
+			Outstanding:      flow.Outstanding,
+			Total:            num,
+		})
+		num = flow.Outstanding
+	}
+	flow.Outstanding -= num
+	flow.CompletedCount += num
+	poolIndex := analyzer.pool(flow)
+	if poolIndex != ACL_POOL_NONE {
+		pool := &analyzer.PoolList[poolIndex]
+		pool.Outstanding -= num
+		if pool.Outstanding < 0 {
+			pool.Outstanding = 0
+		}
+		if !pool.Known || pool.Outstanding < pool.TotalNumDataPackets {
+			analyzer.stallEnd(record, poolIndex)
+		}
+	}
+	analyzer.creditSampleAdd(record, flow, poolIndex)
+}
+
+func (analyzer *AclFlowAnalyzer) creditSampleAdd(record Record, flow *AclFlow, poolIndex int) {
+	sample := AclCreditSample{RecordIndex: record.Index, TimestampUs: record.TimestampUs, Outstanding: flow.Outstanding, PoolFree: -1}
+	if poolIndex != ACL_POOL_NONE {
+		sample.PoolFree = analyzer.PoolList[poolIndex].Free()
+	}
+	flow.CreditSampleList = append(flow.CreditSampleList, sample)
+}
+
+func (analyzer *AclFlowAnalyzer) stallStart(record Record, poolIndex int) {
+	if analyzer.activeStallIndex[poolIndex] >= 0 {
+		return
+	}
+	analyzer.activeStallIndex[poolIndex] = len(analyzer.StallList)
+	analyzer.StallList = append(analyzer.StallList, AclCreditStall{Pool: poolIndex, StartRecordIndex: record.Index, StartUs: record.TimestampUs})
+}
+
+func (analyzer *AclFlowAnalyzer) stallEnd(record Record, poolIndex int) {
+	index := analyzer.activeStallIndex[poolIndex]
+	if index < 0 {
+		return
+	}
+	stall := &analyzer.StallList[index]
+	stall.Ended = true
+	stall.EndRecordIndex = record.Index
+	stall.EndUs = record.TimestampUs
+	analyzer.activeStallIndex[poolIndex] = -1
+}
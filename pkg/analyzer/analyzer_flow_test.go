@@ -0,0 +1,76 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+// controller只有2个ACL缓冲区，host在credit用完后又发送了一个包
+func flowTestRecordList() []Record {
+	// 10字节的ATT Write Request
+	data := []byte{0x06, 0x00, 0x04, 0x00, 0x12, 0x03, 0x00, 0x01, 0x02, 0x03}
+	recordList := []Record{
+		evtTestRecord(0, []byte{0x0E, 0x0B, 0x01, 0x05, 0x10, 0x00, 0xFD, 0x03, 0x40, 0x02, 0x00, 0x0A, 0x00}),
+		evtTestRecord(1, []byte{0x03, 0x0B, 0x00, 0x40, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00}),
+		aclTestRecord(2, false, 0x02, data),
+		aclTestRecord(3, false, 0x02, data),
+		aclTestRecord(4, false, 0x02, data),
+		evtTestRecord(5, []byte{0x13, 0x05, 0x01, 0x40, 0x00, 0x02, 0x00}),
+		aclTestRecord(6, true, 0x02, append([]byte{0x10, 0x00, 0x04, 0x00, 0x1B, 0x03, 0x00}, make([]byte, 13)...)),
+		evtTestRecord(7, []byte{0x05, 0x04, 0x00, 0x40, 0x00, 0x13}),
+	}
+	for index, timestampUs := range []uint64{0, 0, 0, 500000, 1000000, 1200000, 1300000, 2000000} {
+		recordList[index].TimestampUs = timestampUs
+	}
+	return recordList
+}
+
+func TestAclFlowAnalyzerCredit(t *testing.T) {
+	analyzer := NewAclFlowAnalyzer()
+	for _, record := range flowTestRecordList() {
+		analyzer.Feed(record)
+	}
+
+	pool := analyzer.PoolList[ACL_POOL_BREDR]
+	if !pool.Known || pool.TotalNumDataPackets != 2 || pool.Outstanding != 0 || pool.MaxOutstanding != 3 {
+		t.Errorf("br/edr pool = %+v", pool)
+	}
+	if len(analyzer.IssueList) != 1 {
+		t.Fatalf("%d issues, want 1: %+v", len(analyzer.IssueList), analyzer.IssueList)
+	}
+	if issue := analyzer.IssueList[0]; issue.Type != ACL_FLOW_ISSUE_OVERRUN || issue.RecordIndex != 4 || issue.Outstanding != 2 || issue.Total != 2 {
+		t.Errorf("issue = %+v", issue)
+	}
+	if len(analyzer.StallList) != 1 {
+		t.Fatalf("%d stalls, want 1: %+v", len(analyzer.StallList), analyzer.StallList)
+	}
+	if stall := analyzer.StallList[0]; !stall.Ended || stall.StartRecordIndex != 3 || stall.EndRecordIndex != 5 {
+		t.Errorf("stall = %+v", stall)
+	}
+
+	if len(analyzer.FlowList) != 1 {
+		t.Fatalf("%d flows, want 1", len(analyzer.FlowList))
+	}
+	flow := analyzer.FlowList[0]
+	if !flow.TransportKnown || flow.Transport != CONN_TRANSPORT_BREDR || !flow.Disconnected || flow.EndRecordIndex != 7 {
+		t.Errorf("flow = %+v", flow)
+	}
+	if flow.TxPacketCount != 3 || flow.TxBytes != 30 || flow.RxPacketCount != 1 || flow.RxBytes != 20 {
+		t.Errorf("tx %d packets %d bytes, rx %d packets %d bytes, want 3/30 and 1/20", flow.TxPacketCount, flow.TxBytes, flow.RxPacketCount, flow.RxBytes)
+	}
+	if flow.TxThroughput() != 30 || flow.PeakTxBytesPerSec != 20 || flow.RxThroughput() != 0 {
+		t.Errorf("tx %v bytes/sec peak %d, rx %v bytes/sec, want 30, 20, 0", flow.TxThroughput(), flow.PeakTxBytesPerSec, flow.RxThroughput())
+	}
+	// 断开时剩余未完成的包视为完成
+	if flow.Outstanding != 0 || flow.MaxOutstanding != 3 || flow.CompletedCount != 3 {
+		t.Errorf("outstanding %d max %d completed %d, want 0, 3, 3", flow.Outstanding, flow.MaxOutstanding, flow.CompletedCount)
+	}
+	wantFreeList := []int{1, 0, -1, 1, 2}
+	if len(flow.CreditSampleList) != len(wantFreeList) {
+		t.Fatalf("%d credit samples, want %d", len(flow.CreditSampleList), len(wantFreeList))
+	}
+	for index, want := range wantFreeList {
+		if flow.CreditSampleList[index].PoolFree != want {
+			t.Errorf("credit sample %d: free %d, want %d", index, flow.CreditSampleList[index].PoolFree, want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// LE Audio ISO通道分析
+// 1. 根据LE Create CIS/LE CIS Established建立CIS流，根据LE Create BIG Complete/LE BIG Sync Established建立BIS流
+// 2. CIS流在Disconnection Complete时结束，BIS流在LE Terminate BIG Complete/LE BIG Sync Lost时结束
+// 3. 按CIS/BIS handle和方向重组ISO SDU
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// ISO流类型
+const (
+	ISO_STREAM_TYPE_CIS = 0
+	ISO_STREAM_TYPE_BIS = 1
+)
+
+// 重组后的SDU
+type IsoSduRecord struct {
+	RecordIndex int // SDU最后一个分片对应的btsnoop记录
+	TimestampUs uint64
+	Sdu         hci.IsoSdu
+}
+
+// 重组失败的分片
+type IsoSduError struct {
+	RecordIndex int
+	TimestampUs uint64
+	Received    bool
+	Err         error
+}
+
+// 一路CIS/BIS
+type IsoStream struct {
+	Type                uint8  // ISO_STREAM_TYPE_XXX
+	ConnectionHandle    uint16 // CIS/BIS handle
+	AclConnectionHandle uint16 // CIS对应的ACL连接handle
+	BigHandle           uint8  // BIS所属BIG
+	BisIndex            int    // BIS在BIG中的序号，从0开始
+	CisEstablished      hci.LeCisEstablishedEvent
+	TxSduList           []IsoSduRecord // host -> controller
+	RxSduList           []IsoSduRecord // controller -> host
+	ErrorList           []IsoSduError
+}
+
+// ISO通道跟踪
+type IsoTracker struct {
+	StreamList  []*IsoStream
+	streamMap   map[uint16]*IsoStream
+	reassembler *hci.IsoSduReassembler
+}
+
+func NewIsoTracker() *IsoTracker {
+	return &IsoTracker{streamMap: map[uint16]*IsoStream{}, reassembler: hci.NewIsoSduReassembler()}
+}
+
+// 获取handle对应的流，不存在时新建
+func (tracker *IsoTracker) stream(handle uint16, streamType uint8) *IsoStream {
+	stream, ok := tracker.streamMap[handle]
+	if !ok || stream.Type != streamType {
+		stream = &IsoStream{Type: streamType, ConnectionHandle: handle}
+		tracker.streamMap[handle] = stream
+		tracker.StreamList = append(tracker.StreamList, stream)
+	}
+	return stream
+}
+
+func (tracker *IsoTracker) Feed(record Record) {
+	if _, cmd, ok := record.CmdParseResult(); ok {
+		if pkt, ok := cmd.Ret.(hci.HciLeCreateCis); ok {
+			for _, cis := range pkt.CisConnectionList {
+				stream := tracker.stream(cis.CisConnectionHandle, ISO_STREAM_TYPE_CIS)
+				stream.AclConnectionHandle = cis.AclConnectionHandle
+			}
+		}
+		return
+	}
+
+	if _, evt, ok := record.EvtParseResult(); ok {
+		switch pkt := evt.Ret.(type) {
+		case hci.LeCisEstablishedEvent:
+			if pkt.Status == hci.HCI_STATUS_SUCCESS {
+				tracker.stream(pkt.ConnectionHandle, ISO_STREAM_TYPE_CIS).CisEstablished = pkt
+			}
+		case hci.LeCreateBigCompleteEvent:
+			if pkt.Status == hci.HCI_STATUS_SUCCESS {
+				tracker.bigStreamCreate(pkt.BigHandle, pkt.ConnectionHandleList)
+			}
+		case hci.LeBigSyncEstablishedEvent:
+			if pkt.Status == hci.HCI_STATUS_SUCCESS {
+				tracker.bigStreamCreate(pkt.BigHandle, pkt.ConnectionHandleList)
+			}
+		case hci.LeBigTerminatedEvent:
+			tracker.bigStreamClose(pkt.BigHandle)
+		case hci.DisconnectionCompleteEvent:
+			if pkt.Status == hci.HCI_STATUS_SUCCESS {
+				delete(tracker.streamMap, pkt.ConnectionHandle)
+			}
+		}
+		return
+	}
+
+	if record.Parsed.Code != hci.HCI_PKT_RET_CODE_OK || record.Parsed.HciPktType != hci.PKT_TYPE_HCI_ISO {
+		return
+	}
+	pkt, ok := record.Parsed.Ret.(hci.HciIso)
+	if !ok {
+		return
+	}
+	stream, ok := tracker.streamMap[pkt.Handle]
+	if !ok {
+		// 抓包开始前已建立的ISO通道，类型未知时按CIS处理
+		stream = tracker.stream(pkt.Handle, ISO_STREAM_TYPE_CIS)
+	}
+	sdu, complete, err := tracker.reassembler.Push(record.IsReceived(), pkt)
+	if err != nil {
+		stream.ErrorList = append(stream.ErrorList, IsoSduError{RecordIndex: record.Index, TimestampUs: record.TimestampUs, Received: record.IsReceived(), Err: err})
+	}
+	if !complete {
+		return
+	}
+	sduRecord := IsoSduRecord{RecordIndex: record.Index, TimestampUs: record.TimestampUs, Sdu: sdu}
+	if record.IsReceived() {
+		stream.RxSduList = append(stream.RxSduList, sduRecord)
+	} else {
+		stream.TxSduList = append(stream.TxSduList, sduRecord)
+	}
+}
+
+func (tracker *IsoTracker) bigStreamCreate(bigHandle uint8, connectionHandleList []uint16) {
+	for index, handle := range connectionHandleList {
+		stream := tracker.stream(handle, ISO_STREAM_TYPE_BIS)
+		stream.BigHandle = bigHandle
+		stream.BisIndex = index
+	}
+}
+
+// BIG结束，BIS handle不再有效
+func (tracker *IsoTracker) bigStreamClose(bigHandle uint8) {
+	for handle, stream := range tracker.streamMap {
+		if stream.Type == ISO_STREAM_TYPE_BIS && stream.BigHandle == bigHandle {
+			delete(tracker.streamMap, handle)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// BIS handle 0x0010收到一个完整SDU
+var isoTestBisSdu = []byte{0x10, 0x20, 0x06, 0x00, 0x01, 0x00, 0x02, 0x00, 0xAA, 0xBB}
+
+// LE Create BIG Complete: BIG 0x01, BIS handle 0x0010/0x0011
+var isoTestBigCreate = []byte{
+	0x3E, 0x17, hci.LE_CREATE_BIG_COMPLETE_EVENT, 0x00, 0x01,
+	0x10, 0x00, 0x00, 0x20, 0x00, 0x00, 0x02, // BIG_Sync_Delay, Transport_Latency_BIG, PHY
+	0x02, 0x01, 0x00, 0x02, 0x28, 0x00, 0x0C, 0x00, // NSE, BN, PTO, IRC, Max_PDU, ISO_Interval
+	0x02, 0x10, 0x00, 0x11, 0x00,
+}
+
+func TestIsoTrackerBigTerminate(t *testing.T) {
+	testList := []struct {
+		name          string
+		terminate     []byte
+		wantStreamLen int
+		wantBisSduLen int
+	}{
+		{"terminate big complete", []byte{0x3E, 0x03, hci.LE_TERMINATE_BIG_COMPLETE_EVENT, 0x01, 0x16}, 3, 1},
+		{"big sync lost", []byte{0x3E, 0x03, hci.LE_BIG_SYNC_LOST_EVENT, 0x01, 0x08}, 3, 1},
+		{"other big", []byte{0x3E, 0x03, hci.LE_TERMINATE_BIG_COMPLETE_EVENT, 0x02, 0x16}, 2, 2},
+	}
+	for _, test := range testList {
+		tracker := NewIsoTracker()
+		for index, record := range []Record{
+			evtTestRecord(0, isoTestBigCreate),
+			isoTestRecord(1, isoTestBisSdu),
+			evtTestRecord(2, test.terminate),
+			isoTestRecord(3, isoTestBisSdu),
+		} {
+			if record.Parsed.Code != hci.HCI_PKT_RET_CODE_OK {
+				t.Fatalf("%s: record %d parse code %d", test.name, index, record.Parsed.Code)
+			}
+			tracker.Feed(record)
+		}
+		if len(tracker.StreamList) != test.wantStreamLen {
+			t.Fatalf("%s: %d streams, want %d", test.name, len(tracker.StreamList), test.wantStreamLen)
+		}
+		bis := tracker.StreamList[0]
+		if bis.Type != ISO_STREAM_TYPE_BIS || bis.BigHandle != 0x01 || bis.ConnectionHandle != 0x0010 {
+			t.Errorf("%s: first stream = %+v, want BIS 0x0010 of BIG 0x01", test.name, bis)
+		}
+		if test.wantBisSduLen == 1 {
+			// BIS已释放，结束后的SDU按未知handle新建CIS流
+			last := tracker.StreamList[len(tracker.StreamList)-1]
+			if last.Type != ISO_STREAM_TYPE_CIS || last.ConnectionHandle != 0x0010 || len(last.RxSduList) != 1 {
+				t.Errorf("%s: SDU after terminate went to %+v", test.name, last)
+			}
+		}
+		if len(bis.RxSduList) != test.wantBisSduLen {
+			t.Errorf("%s: BIS has %d SDUs, want %d", test.name, len(bis.RxSduList), test.wantBisSduLen)
+		}
+	}
+}
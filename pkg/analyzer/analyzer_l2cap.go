@@ -0,0 +1,341 @@
+// L2CAP动态信道跟踪
+// 1. 按ACL handle跟踪信令信道上的Connection/LE Credit Based Connection/Credit Based Connection和Disconnection，记录动态信道的PSM和两端CID
+// 2. Disconnection Complete关闭handle上的所有信道
+// 3. 将动态信道上的数据按PSM重新解析
+// 4. 跟踪Configuration Request/Response协商的模式，ERTM/Streaming Mode信道按控制字段和FCS解析，SAR分段重组为SDU后按PSM解析，记录REJ/SREJ/RNR和重传
+// 5. credit based信道跟踪credit，K-frame重组为SDU后按SPSM解析
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 信道状态
+const (
+	L2CAP_CHANNEL_STATE_CONNECTING = 0
+	L2CAP_CHANNEL_STATE_OPEN       = 1
+	L2CAP_CHANNEL_STATE_REFUSED    = 2 // 对端拒绝连接
+	L2CAP_CHANNEL_STATE_CLOSED     = 3
+)
+
+// 一个动态信道
+type L2capChannel struct {
+	Handle      uint16
+	Psm         uint16 // LE为SPSM
+	CreditBased bool   // LE Credit Based/Enhanced Credit Based
+	Initiated   bool   // 本端发起
+	LocalCid    uint16 // 本端分配的CID，对端发送的数据使用该CID
+	RemoteCid   uint16 // 对端分配的CID，本端发送的数据使用该CID
+	State       int    // L2CAP_CHANNEL_STATE_XXX
+	Result      uint16 // 连接响应的Result
+	PsmIndex    int    // 建立时该handle上已打开的相同PSM信道数
+
+	RequestRecordIndex int
+	RequestTimestampUs uint64
+	OpenRecordIndex    int
+	OpenTimestampUs    uint64
+	CloseRecordIndex   int
+	CloseTimestampUs   uint64
+
+	TxPduCount int
+	RxPduCount int
+
+	// 双方被接受的Configuration Request中的选项，LocalConfig为本端发送的
+	LocalConfig         hci.L2capConfigurationOptions
+	RemoteConfig        hci.L2capConfigurationOptions
+	localConfigPending  hci.L2capConfigurationOptions
+	remoteConfigPending hci.L2capConfigurationOptions
+	ertmRxMap           map[bool]*l2capErtmRx // 按方向(received)的SDU重组和TxSeq
+
+	// credit based信道双方的MTU/MPS(接收能力)和可用credit
+	LocalMtu  uint16
+	LocalMps  uint16
+	RemoteMtu uint16
+	RemoteMps uint16
+	TxCredits int // 本端还可以发送的K-frame数
+	RxCredits int // 对端还可以发送的K-frame数
+	cocRxMap  map[bool]*l2capCocRx
+	stallMap  map[bool]*L2capCreditStall // 按方向(received)未结束的stall
+}
+
+// 路由到PSM解析器的信道信息
+func (channel *L2capChannel) Info(received bool) hci.L2capChannelInfo {
+	info := hci.L2capChannelInfo{Handle: channel.Handle, Psm: channel.Psm, ChannelId: channel.RemoteCid, PsmIndex: channel.PsmIndex}
+	if received {
+		info.ChannelId = channel.LocalCid
+	}
+	return info
+}
+
+// 等待响应的连接请求，按handle、请求方向和Identifier匹配
+type l2capPendingKey struct {
+	handle     uint16
+	received   bool
+	identifier uint8
+}
+
+type l2capCidKey struct {
+	handle uint16
+	cid    uint16
+}
+
+// L2CAP动态信道跟踪
+type L2capChannelTracker struct {
+	ChannelList     []*L2capChannel // 按请求顺序
+	ErtmEventList   []L2capErtmEvent
+	CreditStallList []*L2capCreditStall // 路由时记录，通过L2capChannelRoute/RecordListParse返回的tracker获取
+	pendingMap      map[l2capPendingKey][]*L2capChannel
+	localCidMap     map[l2capCidKey]*L2capChannel // 已打开的信道
+	remoteCidMap    map[l2capCidKey]*L2capChannel
+}
+
+func NewL2capChannelTracker() *L2capChannelTracker {
+	return &L2capChannelTracker{
+		pendingMap:   map[l2capPendingKey][]*L2capChannel{},
+		localCidMap:  map[l2capCidKey]*L2capChannel{},
+		remoteCidMap: map[l2capCidKey]*L2capChannel{},
+	}
+}
+
+func (tracker *L2capChannelTracker) Feed(record Record) {
+	if _, evt, ok := record.EvtParseResult(); ok {
+		if pkt, ok := evt.Ret.(hci.DisconnectionCompleteEvent); ok && pkt.Status == hci.HCI_STATUS_SUCCESS {
+			tracker.handleClose(record, pkt.ConnectionHandle)
+		}
+		return
+	}
+	acl, ok := record.Acl()
+	if !ok {
+		return
+	}
+	parsed, ok := acl.PayloadParsedResult.(hci.HciAclPktParseResult)
+	if !ok || parsed.Code == hci.HCI_PKT_RET_CODE_FRAGMENT {
+		return
+	}
+	if commandList, ok := hci.L2capSignalingCommands(parsed); ok {
+		for _, command := range commandList {
+			if command.Code == hci.HCI_PKT_RET_CODE_OK {
+				tracker.signalingFeed(record, acl.Handle, command)
+			}
+		}
+		return
+	}
+	if channel, ok := tracker.channelResolve(acl.Handle, record.IsReceived(), parsed.ChannelId); ok {
+		if record.IsReceived() {
+			channel.RxPduCount++
+		} else {
+			channel.TxPduCount++
+		}
+		// 已经路由过的记录(RecordListParse的结果)
+		if frame, ok := parsed.Ret.(hci.L2capErtmFrame); ok {
+			tracker.ertmFeed(record, channel, frame)
+		}
+		if channel.CreditBased {
+			tracker.creditConsume(record, channel)
+		}
+	}
+}
+
+// 按L2CAP头中的CID查找已打开的信道
+func (tracker *L2capChannelTracker) channelResolve(handle uint16, received bool, cid uint16) (*L2capChannel, bool) {
+	if cid < hci.L2CAP_CID_DYNAMIC_START {
+		return nil, false
+	}
+	if received {
+		channel, ok := tracker.localCidMap[l2capCidKey{handle: handle, cid: cid}]
+		return channel, ok
+	}
+	channel, ok := tracker.remoteCidMap[l2capCidKey{handle: handle, cid: cid}]
+	return channel, ok
+}
+
+// ACL记录所属的动态信道
+func (tracker *L2capChannelTracker) RecordResolve(record Record) (*L2capChannel, bool) {
+	acl, ok := record.Acl()
+	if !ok {
+		return nil, false
+	}
+	parsed, ok := acl.PayloadParsedResult.(hci.HciAclPktParseResult)
+	if !ok {
+		return nil, false
+	}
+	return tracker.channelResolve(acl.Handle, record.IsReceived(), parsed.ChannelId)
+}
+
+func (tracker *L2capChannelTracker) channelRequest(record Record, handle uint16, identifier uint8, channel *L2capChannel) {
+	channel.Handle = handle
+	channel.Initiated = !record.IsReceived()
+	channel.State = L2CAP_CHANNEL_STATE_CONNECTING
+	channel.RequestRecordIndex = record.Index
+	channel.RequestTimestampUs = record.TimestampUs
+	key := l2capPendingKey{handle: handle, received: record.IsReceived(), identifier: identifier}
+	tracker.pendingMap[key] = append(tracker.pendingMap[key], channel)
+	tracker.ChannelList = append(tracker.ChannelList, channel)
+}
+
+// 取出响应对应的请求，响应方向与请求相反
+func (tracker *L2capChannelTracker) channelPendingTake(record Record, handle uint16, identifier uint8) []*L2capChannel {
+	key := l2capPendingKey{handle: handle, received: !record.IsReceived(), identifier: identifier}
+	channelList := tracker.pendingMap[key]
+	delete(tracker.pendingMap, key)
+	return channelList
+}
+
+// BR/EDR和LE的Result均为0表示成功
+func (tracker *L2capChannelTracker) channelResponse(record Record, handle uint16, channel *L2capChannel, responderCid uint16, result uint16) {
+	channel.Result = result
+	if result != hci.L2CAP_CONNECTION_SUCCESSFUL || responderCid == 0 {
+		channel.State = L2CAP_CHANNEL_STATE_REFUSED
+		return
+	}
+	if channel.Initiated {
+		channel.RemoteCid = responderCid
+	} else {
+		channel.LocalCid = responderCid
+	}
+	for _, opened := range tracker.localCidMap {
+		if opened.Handle == handle && opened.Psm == channel.Psm {
+			channel.PsmIndex++
+		}
+	}
+	channel.State = L2CAP_CHANNEL_STATE_OPEN
+	channel.OpenRecordIndex = record.Index
+	channel.OpenTimestampUs = record.TimestampUs
+	tracker.localCidMap[l2capCidKey{handle: handle, cid: channel.LocalCid}] = channel
+	tracker.remoteCidMap[l2capCidKey{handle: handle, cid: channel.RemoteCid}] = channel
+}
+
+func (tracker *L2capChannelTracker) channelClose(record Record, channel *L2capChannel) {
+	channel.State = L2CAP_CHANNEL_STATE_CLOSED
+	channel.CloseRecordIndex = record.Index
+	channel.CloseTimestampUs = record.TimestampUs
+	delete(tracker.localCidMap, l2capCidKey{handle: channel.Handle, cid: channel.LocalCid})
+	delete(tracker.remoteCidMap, l2capCidKey{handle: channel.Handle, cid: channel.RemoteCid})
+}
+
+// ACL连接断开，关闭所有信道，丢弃未响应的请求
+func (tracker *L2capChannelTracker) handleClose(record Record, handle uint16) {
+	for key, channel := range tracker.localCidMap {
+		if key.handle == handle {
+			tracker.channelClose(record, channel)
+		}
+	}
+	for key, channelList := range tracker.pendingMap {
+		if key.handle != handle {
+			continue
+		}
+		for _, channel := range channelList {
+			if channel.State == L2CAP_CHANNEL_STATE_CONNECTING {
+				channel.State = L2CAP_CHANNEL_STATE_CLOSED
+				channel.CloseRecordIndex = record.Index
+				channel.CloseTimestampUs = record.TimestampUs
+			}
+		}
+		delete(tracker.pendingMap, key)
+	}
+}
+
+// 信令命令
+func (tracker *L2capChannelTracker) signalingFeed(record Record, handle uint16, command hci.HciAclPktParseResult) {
+	switch pkt := command.Ret.(type) {
+	case hci.L2capConnectionRequest:
+		channel := &L2capChannel{Psm: pkt.Psm}
+		tracker.channelRequest(record, handle, pkt.Identifier, channel)
+		if channel.Initiated {
+			channel.LocalCid = pkt.SourceCid
+		} else {
+			channel.RemoteCid = pkt.SourceCid
+		}
+	case hci.L2capConnectionResponse:
+		// pending时对端会再发送一次响应
+		if pkt.Result == hci.L2CAP_CONNECTION_PENDING {
+			return
+		}
+		for _, channel := range tracker.channelPendingTake(record, handle, pkt.Identifier) {
+			tracker.channelResponse(record, handle, channel, pkt.DestinationCid, pkt.Result)
+		}
+	case hci.L2capLeCreditBasedConnectionRequest:
+		channel := &L2capChannel{Psm: pkt.Spsm, CreditBased: true}
+		tracker.channelRequest(record, handle, pkt.Identifier, channel)
+		channel.cocParams(channel.Initiated, pkt.Mtu, pkt.Mps, pkt.InitialCredits)
+		if channel.Initiated {
+			channel.LocalCid = pkt.SourceCid
+		} else {
+			channel.RemoteCid = pkt.SourceCid
+		}
+	case hci.L2capLeCreditBasedConnectionResponse:
+		for _, channel := range tracker.channelPendingTake(record, handle, pkt.Identifier) {
+			channel.cocParams(!channel.Initiated, pkt.Mtu, pkt.Mps, pkt.InitialCredits)
+			tracker.channelResponse(record, handle, channel, pkt.DestinationCid, pkt.Result)
+		}
+	case hci.L2capCreditBasedConnectionRequest:
+		for _, sourceCid := range pkt.SourceCidList {
+			channel := &L2capChannel{Psm: pkt.Spsm, CreditBased: true}
+			tracker.channelRequest(record, handle, pkt.Identifier, channel)
+			channel.cocParams(channel.Initiated, pkt.Mtu, pkt.Mps, pkt.InitialCredits)
+			if channel.Initiated {
+				channel.LocalCid = sourceCid
+			} else {
+				channel.RemoteCid = sourceCid
+			}
+		}
+	case hci.L2capCreditBasedConnectionResponse:
+		// 按请求中Source CID的顺序对应，Destination CID为0表示该信道被拒绝
+		for index, channel := range tracker.channelPendingTake(record, handle, pkt.Identifier) {
+			var destinationCid uint16
+			if index < len(pkt.DestinationCidList) {
+				destinationCid = pkt.DestinationCidList[index]
+			}
+			result := pkt.Result
+			if destinationCid == 0 && result == hci.L2CAP_LE_CONNECTION_SUCCESSFUL {
+				result = hci.L2CAP_LE_CONNECTION_REFUSED_NO_RESOURCES
+			}
+			channel.cocParams(!channel.Initiated, pkt.Mtu, pkt.Mps, pkt.InitialCredits)
+			tracker.channelResponse(record, handle, channel, destinationCid, result)
+		}
+	case hci.L2capFlowControlCreditInd:
+		tracker.creditFeed(record, handle, pkt)
+	case hci.L2capConfigurationRequest, hci.L2capConfigurationResponse:
+		tracker.configFeed(record, handle, command)
+	case hci.L2capDisconnection:
+		if !pkt.Response {
+			return
+		}
+		// 响应的CID与请求相同，Source CID为发送请求一端的CID
+		localCid := pkt.SourceCid
+		if !record.IsReceived() {
+			localCid = pkt.DestinationCid
+		}
+		if channel, ok := tracker.localCidMap[l2capCidKey{handle: handle, cid: localCid}]; ok {
+			tracker.channelClose(record, channel)
+		}
+	}
+}
+
+// 动态信道数据按PSM重新解析，结果填到ACL记录的PayloadParsedResult
+// 一次遍历完成信道跟踪和解析，ERTM帧解析后记录到返回的tracker，不需要再将记录Feed到新的tracker
+func L2capChannelRoute(recordList []Record) *L2capChannelTracker {
+	tracker := NewL2capChannelTracker()
+	for pos := range recordList {
+		record := &recordList[pos]
+		tracker.Feed(*record)
+		channel, ok := tracker.RecordResolve(*record)
+		if !ok {
+			continue
+		}
+		acl, _ := record.Acl()
+		parsed, _ := acl.PayloadParsedResult.(hci.HciAclPktParseResult)
+		frame, ok := parsed.Ret.(hci.L2capBasicFrame)
+		if !ok {
+			continue
+		}
+		routed := channel.frameParse(record.IsReceived(), frame)
+		if pkt, ok := routed.Ret.(hci.L2capErtmFrame); ok {
+			tracker.ertmFeed(*record, channel, pkt)
+		}
+		acl.PayloadParsedResult = routed
+		record.Parsed.Ret = acl
+	}
+	return tracker
+}
@@ -0,0 +1,119 @@
+// LE Credit Based/Enhanced Credit Based信道
+// 1. 连接请求/响应中发送方的MTU/MPS为其接收能力，Initial Credits为对端可以发送的K-frame数
+// 2. 每个K-frame消耗发送方一个credit，Flow Control Credit Ind为对端增加credit，credit为0的时间段记录为stall
+// 3. 按方向将K-frame重组为SDU，SDU按SPSM解析
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 一个方向credit用完的时间段
+type L2capCreditStall struct {
+	Channel          *L2capChannel
+	Received         bool // true为对端发送方向
+	StartRecordIndex int
+	StartTimestampUs uint64
+	Ended            bool // 收到新的credit
+	EndRecordIndex   int
+	EndTimestampUs   uint64
+	OverrunCount     int // stall期间仍然发送的K-frame数
+}
+
+// 一个方向上的SDU重组状态
+type l2capCocRx struct {
+	sdu       []byte
+	sduLength int
+	sduActive bool
+}
+
+// 连接请求/响应中的参数，local为本端发送的
+func (channel *L2capChannel) cocParams(local bool, mtu uint16, mps uint16, initialCredits uint16) {
+	if local {
+		channel.LocalMtu, channel.LocalMps, channel.RxCredits = mtu, mps, int(initialCredits)
+	} else {
+		channel.RemoteMtu, channel.RemoteMps, channel.TxCredits = mtu, mps, int(initialCredits)
+	}
+}
+
+// Flow Control Credit Ind的CID为发送方的CID，发送方为对端增加credit
+func (tracker *L2capChannelTracker) creditFeed(record Record, handle uint16, pkt hci.L2capFlowControlCreditInd) {
+	channel, ok := tracker.channelResolve(handle, !record.IsReceived(), pkt.Cid)
+	if !ok || !channel.CreditBased {
+		return
+	}
+	// 本端发送的为对端发送方向增加credit
+	received := !record.IsReceived()
+	if received {
+		channel.RxCredits += int(pkt.Credits)
+	} else {
+		channel.TxCredits += int(pkt.Credits)
+	}
+	if stall, ok := channel.stallMap[received]; ok && pkt.Credits > 0 {
+		stall.Ended, stall.EndRecordIndex, stall.EndTimestampUs = true, record.Index, record.TimestampUs
+		delete(channel.stallMap, received)
+	}
+}
+
+// 每个K-frame消耗一个credit
+func (tracker *L2capChannelTracker) creditConsume(record Record, channel *L2capChannel) {
+	received := record.IsReceived()
+	credits := &channel.TxCredits
+	if received {
+		credits = &channel.RxCredits
+	}
+	if stall, ok := channel.stallMap[received]; ok {
+		stall.OverrunCount++
+		return
+	}
+	*credits--
+	if *credits > 0 {
+		return
+	}
+	*credits = 0
+	if channel.stallMap == nil {
+		channel.stallMap = map[bool]*L2capCreditStall{}
+	}
+	stall := &L2capCreditStall{Channel: channel, Received: received, StartRecordIndex: record.Index, StartTimestampUs: record.TimestampUs}
+	channel.stallMap[received] = stall
+	tracker.CreditStallList = append(tracker.CreditStallList, stall)
+}
+
+func (channel *L2capChannel) cocRx(received bool) *l2capCocRx {
+	if channel.cocRxMap == nil {
+		channel.cocRxMap = map[bool]*l2capCocRx{}
+	}
+	rx, ok := channel.cocRxMap[received]
+	if !ok {
+		rx = &l2capCocRx{}
+		channel.cocRxMap[received] = rx
+	}
+	return rx
+}
+
+// K-frame重组为SDU，结果填到SDU最后一个K-frame上
+func (channel *L2capChannel) kFrameParse(received bool, frame hci.L2capBasicFrame) hci.HciAclPktParseResult {
+	rx := channel.cocRx(received)
+	parsed := hci.L2capKFrameParse(frame, !rx.sduActive)
+	pkt, ok := parsed.Ret.(hci.L2capKFrame)
+	if !ok {
+		return parsed
+	}
+	if pkt.SduLengthPresent {
+		rx.sdu, rx.sduLength, rx.sduActive = append([]byte{}, pkt.Payload...), int(pkt.SduLength), true
+	} else {
+		rx.sdu = append(rx.sdu, pkt.Payload...)
+	}
+	if len(rx.sdu) < rx.sduLength {
+		return parsed
+	}
+	if len(rx.sdu) > rx.sduLength {
+		pkt.SduParsedResult = hci.HciAclPktParseResult{Code: hci.HCI_PKT_RET_CODE_INVALID_LEN, ChannelId: pkt.ChannelId}
+	} else {
+		pkt.SduParsedResult = hci.L2capSduParse(channel.Info(received), rx.sdu)
+	}
+	rx.sdu, rx.sduActive = nil, false
+	parsed.Ret = pkt
+	return parsed
+}
@@ -0,0 +1,90 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 本端发起SPSM 0x0080的LE Credit Based信道，本端CID 0x0040(对端可发送2个K-frame)，对端CID 0x0041(本端可发送1个K-frame)
+func l2capCocTestSetup() []Record {
+	return []Record{
+		l2capTestRecord(0, false, hci.L2CAP_CID_LE_SIGNALING, []byte{0x14, 0x01, 0x0a, 0x00, 0x80, 0x00, 0x40, 0x00, 0x64, 0x00, 0x17, 0x00, 0x02, 0x00}),
+		l2capTestRecord(1, true, hci.L2CAP_CID_LE_SIGNALING, []byte{0x15, 0x01, 0x0a, 0x00, 0x41, 0x00, 0x64, 0x00, 0x17, 0x00, 0x01, 0x00, 0x00, 0x00}),
+	}
+}
+
+func TestL2capChannelRouteCoc(t *testing.T) {
+	recordList := append(l2capCocTestSetup(),
+		l2capTestRecord(2, false, 0x0041, []byte{0x05, 0x00, 0x01, 0x02}),                                            // 第一个K-frame，SDU Length 5
+		l2capTestRecord(3, false, 0x0041, []byte{0x03, 0x04, 0x05}),                                                  // credit用完后仍然发送
+		l2capTestRecord(4, true, hci.L2CAP_CID_LE_SIGNALING, []byte{0x16, 0x02, 0x04, 0x00, 0x41, 0x00, 0x03, 0x00}), // 对端增加3个credit
+		l2capTestRecord(5, true, 0x0040, []byte{0x02, 0x00, 0xaa, 0xbb}),                                             // 单个K-frame的SDU
+		l2capTestRecord(6, true, 0x0040, []byte{0x02, 0x00, 0xcc}),                                                   // SDU长度不符
+		l2capTestRecord(7, true, 0x0040, []byte{0xdd, 0xee}),
+	)
+	tracker := L2capChannelRoute(recordList)
+
+	if len(tracker.ChannelList) != 1 {
+		t.Fatalf("got %d channels, want 1", len(tracker.ChannelList))
+	}
+	channel := tracker.ChannelList[0]
+	if !channel.CreditBased || channel.LocalMtu != 100 || channel.RemoteMps != 23 {
+		t.Errorf("channel CreditBased %v LocalMtu %d RemoteMps %d", channel.CreditBased, channel.LocalMtu, channel.RemoteMps)
+	}
+	// 对端发送的3个K-frame消耗了2个初始credit，stall期间的K-frame不计
+	if channel.TxCredits != 3 || channel.RxCredits != 0 {
+		t.Errorf("TxCredits %d RxCredits %d, want 3 0", channel.TxCredits, channel.RxCredits)
+	}
+
+	wantStallList := []L2capCreditStall{
+		{Received: false, StartRecordIndex: 2, Ended: true, EndRecordIndex: 4, OverrunCount: 1},
+		{Received: true, StartRecordIndex: 6, OverrunCount: 1},
+	}
+	if len(tracker.CreditStallList) != len(wantStallList) {
+		t.Fatalf("got %d credit stalls, want %d", len(tracker.CreditStallList), len(wantStallList))
+	}
+	for index, want := range wantStallList {
+		got := tracker.CreditStallList[index]
+		if got.Channel != channel || got.Received != want.Received || got.StartRecordIndex != want.StartRecordIndex ||
+			got.Ended != want.Ended || got.EndRecordIndex != want.EndRecordIndex || got.OverrunCount != want.OverrunCount {
+			t.Errorf("credit stall %d: got %+v, want %+v", index, *got, want)
+		}
+	}
+
+	// kFrameParse: SDU结果只填在最后一个K-frame上
+	testList := []struct {
+		pos      int
+		wantCode int    // SduParsedResult的Code，-1为没有SDU结果
+		wantSdu  []byte // 仅wantCode为HCI_PKT_RET_CODE_OK时检查
+	}{
+		{2, -1, nil},
+		{3, hci.HCI_PKT_RET_CODE_OK, []byte{0x01, 0x02, 0x03, 0x04, 0x05}},
+		{5, hci.HCI_PKT_RET_CODE_OK, []byte{0xaa, 0xbb}},
+		{6, -1, nil},
+		{7, hci.HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		pkt, ok := aclTestParsed(t, recordList[test.pos]).Ret.(hci.L2capKFrame)
+		if !ok {
+			t.Fatalf("record %d: not a k-frame", test.pos)
+		}
+		sdu, ok := pkt.SduParsedResult.(hci.HciAclPktParseResult)
+		if test.wantCode < 0 {
+			if ok {
+				t.Errorf("record %d: unexpected sdu %+v", test.pos, sdu)
+			}
+			continue
+		}
+		if !ok || sdu.Code != test.wantCode {
+			t.Errorf("record %d: sdu %+v, want code %d", test.pos, sdu, test.wantCode)
+			continue
+		}
+		if test.wantCode != hci.HCI_PKT_RET_CODE_OK {
+			continue
+		}
+		if frame, ok := sdu.Ret.(hci.L2capChannelFrame); !ok || string(frame.Payload) != string(test.wantSdu) {
+			t.Errorf("record %d: sdu %+v, want payload % x", test.pos, sdu, test.wantSdu)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+// L2CAP信道配置和Enhanced Retransmission/Streaming Mode
+// 1. 双方的Configuration Request在收到成功的Configuration Response后生效，带continuation flag的请求合并
+// 2. ERTM/Streaming Mode默认使用FCS，双方都请求No FCS时不使用；任一方使用Extended Window Size时使用扩展控制字段
+// 3. 按方向将I-frame的SAR分段重组为SDU，ERTM下TxSeq已经发送过的I-frame为重传，不参与重组
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// ERTM事件类型
+const (
+	L2CAP_ERTM_EVENT_REJ            = 0 // 请求从ReqSeq开始重传
+	L2CAP_ERTM_EVENT_SREJ           = 1 // 请求重传ReqSeq
+	L2CAP_ERTM_EVENT_RNR            = 2 // 接收端忙
+	L2CAP_ERTM_EVENT_RETRANSMISSION = 3 // TxSeq已经发送过的I-frame
+	L2CAP_ERTM_EVENT_FCS_ERROR      = 4
+)
+
+var L2capErtmEventStrMap = map[int]string{
+	L2CAP_ERTM_EVENT_REJ:            "REJ",
+	L2CAP_ERTM_EVENT_SREJ:           "SREJ",
+	L2CAP_ERTM_EVENT_RNR:            "RNR",
+	L2CAP_ERTM_EVENT_RETRANSMISSION: "Retransmission",
+	L2CAP_ERTM_EVENT_FCS_ERROR:      "FCS Error",
+}
+
+// 重传相关的帧
+type L2capErtmEvent struct {
+	RecordIndex int
+	TimestampUs uint64
+	Channel     *L2capChannel
+	Received    bool
+	Type        int // L2CAP_ERTM_EVENT_XXX
+	TxSeq       uint16
+	ReqSeq      uint16
+}
+
+// 一个方向上的SDU重组状态
+type l2capErtmRx struct {
+	sdu        []byte
+	sduLength  int
+	sduActive  bool
+	nextTxSeq  uint16
+	txSeqKnown bool
+}
+
+func l2capConfigMerge(dst *hci.L2capConfigurationOptions, src hci.L2capConfigurationOptions) {
+	dst.OptionList = append(dst.OptionList, src.OptionList...)
+	if src.MtuPresent {
+		dst.MtuPresent, dst.Mtu = true, src.Mtu
+	}
+	if src.FlushTimeoutPresent {
+		dst.FlushTimeoutPresent, dst.FlushTimeout = true, src.FlushTimeout
+	}
+	if src.RetransmissionPresent {
+		dst.RetransmissionPresent, dst.Retransmission = true, src.Retransmission
+	}
+	if src.FcsPresent {
+		dst.FcsPresent, dst.Fcs = true, src.Fcs
+	}
+	if src.ExtendedWindowSizePresent {
+		dst.ExtendedWindowSizePresent, dst.ExtendedWindowSize = true, src.ExtendedWindowSize
+	}
+}
+
+// Configuration Request的DCID为接收请求一端的CID，Configuration Response的SCID为接收响应一端的CID
+func (tracker *L2capChannelTracker) configFeed(record Record, handle uint16, command hci.HciAclPktParseResult) {
+	switch pkt := command.Ret.(type) {
+	case hci.L2capConfigurationRequest:
+		channel, ok := tracker.channelResolve(handle, record.IsReceived(), pkt.DestinationCid)
+		if !ok {
+			return
+		}
+		if record.IsReceived() {
+			l2capConfigMerge(&channel.remoteConfigPending, pkt.Options)
+		} else {
+			l2capConfigMerge(&channel.localConfigPending, pkt.Options)
+		}
+	case hci.L2capConfigurationResponse:
+		channel, ok := tracker.channelResolve(handle, record.IsReceived(), pkt.SourceCid)
+		if !ok || pkt.Result == hci.L2CAP_CONFIGURATION_PENDING || pkt.Flags&hci.L2CAP_CONFIGURATION_FLAG_CONTINUATION != 0 {
+			return
+		}
+		// 收到响应为本端请求的结果，失败时请求方会重新发送请求
+		pending, config := &channel.remoteConfigPending, &channel.RemoteConfig
+		if record.IsReceived() {
+			pending, config = &channel.localConfigPending, &channel.LocalConfig
+		}
+		if pkt.Result == hci.L2CAP_CONFIGURATION_SUCCESS {
+			*config = *pending
+		}
+		*pending = hci.L2capConfigurationOptions{}
+	}
+}
+
+// 协商的模式，hci.L2CAP_MODE_XXX
+func (channel *L2capChannel) Mode() uint8 {
+	if channel.LocalConfig.RetransmissionPresent {
+		return channel.LocalConfig.Retransmission.Mode
+	}
+	if channel.RemoteConfig.RetransmissionPresent {
+		return channel.RemoteConfig.Retransmission.Mode
+	}
+	return hci.L2CAP_MODE_BASIC
+}
+
+func (channel *L2capChannel) FcsEnabled() bool {
+	mode := channel.Mode()
+	if mode != hci.L2CAP_MODE_ENHANCED_RETRANSMISSION && mode != hci.L2CAP_MODE_STREAMING {
+		return false
+	}
+	localNoFcs := channel.LocalConfig.FcsPresent && channel.LocalConfig.Fcs == hci.L2CAP_FCS_NONE
+	remoteNoFcs := channel.RemoteConfig.FcsPresent && channel.RemoteConfig.Fcs == hci.L2CAP_FCS_NONE
+	return !(localNoFcs && remoteNoFcs)
+}
+
+func (channel *L2capChannel) ExtendedControl() bool {
+	return channel.LocalConfig.ExtendedWindowSizePresent || channel.RemoteConfig.ExtendedWindowSizePresent
+}
+
+func (channel *L2capChannel) ertmRx(received bool) *l2capErtmRx {
+	if channel.ertmRxMap == nil {
+		channel.ertmRxMap = map[bool]*l2capErtmRx{}
+	}
+	rx, ok := channel.ertmRxMap[received]
+	if !ok {
+		rx = &l2capErtmRx{}
+		channel.ertmRxMap[received] = rx
+	}
+	return rx
+}
+
+// 按信道模式解析B-frame payload
+func (channel *L2capChannel) frameParse(received bool, frame hci.L2capBasicFrame) hci.HciAclPktParseResult {
+	if channel.CreditBased {
+		return channel.kFrameParse(received, frame)
+	}
+	mode := channel.Mode()
+	if mode != hci.L2CAP_MODE_ENHANCED_RETRANSMISSION && mode != hci.L2CAP_MODE_STREAMING {
+		return hci.L2capDynamicChannelParse(channel.Info(received), frame.Payload)
+	}
+	parsed := hci.L2capErtmFrameParse(frame, channel.ExtendedControl(), channel.FcsEnabled())
+	parsed.ChannelId = frame.ChannelId
+	pkt, ok := parsed.Ret.(hci.L2capErtmFrame)
+	if !ok || pkt.FrameType != hci.L2CAP_FRAME_TYPE_I || (pkt.FcsPresent && !pkt.FcsValid) {
+		return parsed
+	}
+	rx := channel.ertmRx(received)
+	modulo := uint16(hci.L2CAP_ENHANCED_SEQ_MODULO)
+	if pkt.ExtendedControl {
+		modulo = hci.L2CAP_EXTENDED_SEQ_MODULO
+	}
+	if mode == hci.L2CAP_MODE_ENHANCED_RETRANSMISSION && rx.txSeqKnown {
+		// 在期望的TxSeq之前半个序号空间内的为已经发送过的
+		behind := (rx.nextTxSeq + modulo - pkt.TxSeq) % modulo
+		if behind != 0 && behind <= modulo/2 {
+			pkt.Retransmission = true
+			parsed.Ret = pkt
+			return parsed
+		}
+	}
+	rx.nextTxSeq, rx.txSeqKnown = (pkt.TxSeq+1)%modulo, true
+	switch pkt.Sar {
+	case hci.L2CAP_SAR_UNSEGMENTED:
+		rx.sduActive = false
+		pkt.SduParsedResult = hci.L2capDynamicChannelParse(channel.Info(received), pkt.Payload)
+	case hci.L2CAP_SAR_START:
+		rx.sdu = append([]byte{}, pkt.Payload...)
+		rx.sduLength, rx.sduActive = int(pkt.SduLength), true
+	case hci.L2CAP_SAR_CONTINUATION:
+		if rx.sduActive {
+			rx.sdu = append(rx.sdu, pkt.Payload...)
+		}
+	case hci.L2CAP_SAR_END:
+		// 没有收到start分段或长度不符时SDU无效
+		if !rx.sduActive || len(rx.sdu)+len(pkt.Payload) != rx.sduLength {
+			pkt.SduParsedResult = hci.HciAclPktParseResult{Code: hci.HCI_PKT_RET_CODE_INVALID_LEN, ChannelId: pkt.ChannelId}
+		} else {
+			pkt.SduParsedResult = hci.L2capDynamicChannelParse(channel.Info(received), append(rx.sdu, pkt.Payload...))
+		}
+		rx.sdu, rx.sduActive = nil, false
+	}
+	parsed.Ret = pkt
+	return parsed
+}
+
+// 记录S-frame的REJ/SREJ/RNR、重传的I-frame和FCS错误
+func (tracker *L2capChannelTracker) ertmFeed(record Record, channel *L2capChannel, frame hci.L2capErtmFrame) {
+	event := L2capErtmEvent{
+		RecordIndex: record.Index,
+		TimestampUs: record.TimestampUs,
+		Channel:     channel,
+		Received:    record.IsReceived(),
+		TxSeq:       frame.TxSeq,
+		ReqSeq:      frame.ReqSeq,
+	}
+	switch {
+	case frame.FcsPresent && !frame.FcsValid:
+		event.Type = L2CAP_ERTM_EVENT_FCS_ERROR
+	case frame.FrameType == hci.L2CAP_FRAME_TYPE_I && frame.Retransmission:
+		event.Type = L2CAP_ERTM_EVENT_RETRANSMISSION
+	case frame.FrameType == hci.L2CAP_FRAME_TYPE_S && frame.SupervisoryFunction == hci.L2CAP_SUPERVISORY_REJ:
+		event.Type = L2CAP_ERTM_EVENT_REJ
+	case frame.FrameType == hci.L2CAP_FRAME_TYPE_S && frame.SupervisoryFunction == hci.L2CAP_SUPERVISORY_SREJ:
+		event.Type = L2CAP_ERTM_EVENT_SREJ
+	case frame.FrameType == hci.L2CAP_FRAME_TYPE_S && frame.SupervisoryFunction == hci.L2CAP_SUPERVISORY_RNR:
+		event.Type = L2CAP_ERTM_EVENT_RNR
+	default:
+		return
+	}
+	tracker.ErtmEventList = append(tracker.ErtmEventList, event)
+}
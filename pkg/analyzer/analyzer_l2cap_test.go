@@ -0,0 +1,159 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 本端建立两个AVDTP信道(信令、媒体)，对端建立SDP信道，ACL断开后信道关闭
+func l2capChannelTestRecordList() []Record {
+	const (
+		tx = hci.HCI_ACL_PB_FLAG_FIRST_NON_FLUSHABLE
+		rx = hci.HCI_ACL_PB_FLAG_FIRST_FLUSHABLE
+	)
+	return []Record{
+		aclTestRecord(0, false, tx, []byte{0x08, 0x00, 0x01, 0x00, 0x02, 0x01, 0x04, 0x00, 0x19, 0x00, 0x41, 0x00}),
+		aclTestRecord(1, true, rx, []byte{0x0C, 0x00, 0x01, 0x00, 0x03, 0x01, 0x08, 0x00, 0x50, 0x00, 0x41, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		aclTestRecord(2, false, tx, []byte{0x02, 0x00, 0x50, 0x00, 0x00, 0x01}),
+		aclTestRecord(3, false, tx, []byte{0x08, 0x00, 0x01, 0x00, 0x02, 0x02, 0x04, 0x00, 0x19, 0x00, 0x42, 0x00}),
+		aclTestRecord(4, true, rx, []byte{0x0C, 0x00, 0x01, 0x00, 0x03, 0x02, 0x08, 0x00, 0x51, 0x00, 0x42, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		aclTestRecord(5, true, rx, []byte{0x0D, 0x00, 0x42, 0x00, 0x80, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x01, 0xAA}),
+		aclTestRecord(6, true, rx, []byte{0x08, 0x00, 0x01, 0x00, 0x02, 0x03, 0x04, 0x00, 0x01, 0x00, 0x60, 0x00}),
+		aclTestRecord(7, false, tx, []byte{0x0C, 0x00, 0x01, 0x00, 0x03, 0x03, 0x08, 0x00, 0x43, 0x00, 0x60, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		aclTestRecord(8, true, rx, []byte{0x07, 0x00, 0x43, 0x00, 0x02, 0x00, 0x01, 0x00, 0x02, 0xAB, 0xCD}),
+		evtTestRecord(9, []byte{0x05, 0x04, 0x00, 0x40, 0x00, 0x13}),
+		aclTestRecord(10, false, tx, []byte{0x02, 0x00, 0x50, 0x00, 0x00, 0x01}),
+	}
+}
+
+func TestL2capChannelTracker(t *testing.T) {
+	tracker := NewL2capChannelTracker()
+	for _, record := range l2capChannelTestRecordList() {
+		tracker.Feed(record)
+	}
+	wantList := []struct {
+		psm                 uint16
+		initiated           bool
+		localCid, remoteCid uint16
+		psmIndex            int
+		openRecordIndex     int
+		txPduCount          int
+		rxPduCount          int
+	}{
+		{hci.L2CAP_PSM_AVDTP, true, 0x0041, 0x0050, 0, 1, 1, 0},
+		{hci.L2CAP_PSM_AVDTP, true, 0x0042, 0x0051, 1, 4, 0, 1},
+		{hci.L2CAP_PSM_SDP, false, 0x0043, 0x0060, 0, 7, 0, 1},
+	}
+	if len(tracker.ChannelList) != len(wantList) {
+		t.Fatalf("%d channels, want %d", len(tracker.ChannelList), len(wantList))
+	}
+	for index, want := range wantList {
+		channel := tracker.ChannelList[index]
+		if channel.Handle != 0x0040 || channel.Psm != want.psm || channel.Initiated != want.initiated || channel.LocalCid != want.localCid || channel.RemoteCid != want.remoteCid || channel.PsmIndex != want.psmIndex {
+			t.Errorf("channel %d: %+v, want %+v", index, *channel, want)
+		}
+		// 断开ACL连接后的数据不再计数
+		if channel.State != L2CAP_CHANNEL_STATE_CLOSED || channel.OpenRecordIndex != want.openRecordIndex || channel.CloseRecordIndex != 9 || channel.TxPduCount != want.txPduCount || channel.RxPduCount != want.rxPduCount {
+			t.Errorf("channel %d: state %d open %d close %d tx %d rx %d, want closed at 9 open %d tx %d rx %d", index,
+				channel.State, channel.OpenRecordIndex, channel.CloseRecordIndex, channel.TxPduCount, channel.RxPduCount, want.openRecordIndex, want.txPduCount, want.rxPduCount)
+		}
+	}
+}
+
+func TestL2capChannelRoute(t *testing.T) {
+	recordList := l2capChannelTestRecordList()
+	L2capReassemble(recordList)
+	L2capChannelRoute(recordList)
+	testList := []struct {
+		index         int
+		wantChannelId uint16
+		want          interface{}
+	}{
+		// 同一handle上第一个AVDTP信道按信令解析，第二个按媒体包解析
+		{2, 0x0050, hci.AvdtpSignal{PacketType: hci.AV_PACKET_TYPE_SINGLE, MessageType: hci.AVDTP_MESSAGE_TYPE_COMMAND, SignalIdentifier: hci.AVDTP_DISCOVER, Parameters: []byte{}}},
+		{5, 0x0042, hci.AvdtpMediaPacket{Version: 2, PayloadType: 0x60, SequenceNumber: 1, Timestamp: 0x10, Ssrc: 1, Payload: []byte{0xAA}}},
+		{8, 0x0043, hci.SdpPdu{PduId: hci.SDP_SERVICE_SEARCH_REQUEST, TransactionId: 0x0001, ParameterLength: 2, Parameters: []byte{0xAB, 0xCD}}},
+		// 信道已关闭，保留原始L2CAP帧
+		{10, 0x0050, hci.L2capBasicFrame{Length: 2, ChannelId: 0x0050, Payload: []byte{0x00, 0x01}}},
+	}
+	for _, test := range testList {
+		parsed := aclTestParsed(t, recordList[test.index])
+		if parsed.Code != hci.HCI_PKT_RET_CODE_OK || parsed.ChannelId != test.wantChannelId {
+			t.Errorf("record %d: code %d channel %#04x, want channel %#04x", test.index, parsed.Code, parsed.ChannelId, test.wantChannelId)
+			continue
+		}
+		if !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("record %d: %+v, want %+v", test.index, parsed.Ret, test.want)
+		}
+	}
+}
+
+// handle 0x0040上包含完整L2CAP PDU的ACL记录
+func l2capTestRecord(index int, received bool, cid uint16, payload []byte) Record {
+	data := append([]byte{byte(len(payload)), byte(len(payload) >> 8), byte(cid), byte(cid >> 8)}, payload...)
+	return aclTestRecord(index, received, hci.HCI_ACL_PB_FLAG_FIRST_FLUSHABLE, data)
+}
+
+// 本端发起PSM 0x1003的信道，本端CID 0x0040，对端CID 0x0041，双方配置ERTM、No FCS
+func l2capErtmTestSetup() []Record {
+	ertmOptions := []byte{0x04, 0x09, 0x03, 0x0a, 0x03, 0xd0, 0x07, 0xe0, 0x2e, 0xf4, 0x01, 0x05, 0x01, 0x00}
+	return []Record{
+		l2capTestRecord(0, false, hci.L2CAP_CID_SIGNALING, []byte{0x02, 0x01, 0x04, 0x00, 0x03, 0x10, 0x40, 0x00}),
+		l2capTestRecord(1, true, hci.L2CAP_CID_SIGNALING, []byte{0x03, 0x01, 0x08, 0x00, 0x41, 0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		l2capTestRecord(2, false, hci.L2CAP_CID_SIGNALING, append([]byte{0x04, 0x02, 0x12, 0x00, 0x41, 0x00, 0x00, 0x00}, ertmOptions...)),
+		l2capTestRecord(3, true, hci.L2CAP_CID_SIGNALING, []byte{0x05, 0x02, 0x06, 0x00, 0x40, 0x00, 0x00, 0x00, 0x00, 0x00}),
+		l2capTestRecord(4, true, hci.L2CAP_CID_SIGNALING, append([]byte{0x04, 0x03, 0x12, 0x00, 0x40, 0x00, 0x00, 0x00}, ertmOptions...)),
+		l2capTestRecord(5, false, hci.L2CAP_CID_SIGNALING, []byte{0x05, 0x03, 0x06, 0x00, 0x41, 0x00, 0x00, 0x00, 0x00, 0x00}),
+	}
+}
+
+func TestL2capChannelRouteErtm(t *testing.T) {
+	recordList := append(l2capErtmTestSetup(),
+		l2capTestRecord(6, true, 0x0040, []byte{0x00, 0x00, 0xaa}),             // I-frame TxSeq 0
+		l2capTestRecord(7, true, 0x0040, []byte{0x00, 0x00, 0xaa}),             // 重传TxSeq 0
+		l2capTestRecord(8, false, 0x0041, []byte{0x05, 0x01}),                  // REJ ReqSeq 1
+		l2capTestRecord(9, true, 0x0040, []byte{0x02, 0x40, 0x04, 0x00, 0xbb}), // SAR start TxSeq 1，SDU Length 4
+		l2capTestRecord(10, true, 0x0040, []byte{0x04, 0x80, 0xcc, 0xdd, 0xee}),
+	)
+	tracker := L2capChannelRoute(recordList)
+
+	if len(tracker.ChannelList) != 1 {
+		t.Fatalf("got %d channels, want 1", len(tracker.ChannelList))
+	}
+	channel := tracker.ChannelList[0]
+	if channel.Mode() != hci.L2CAP_MODE_ENHANCED_RETRANSMISSION || channel.FcsEnabled() {
+		t.Fatalf("channel mode %d fcs %v, want ERTM without FCS", channel.Mode(), channel.FcsEnabled())
+	}
+
+	wantEventList := []struct {
+		recordIndex int
+		eventType   int
+	}{
+		{7, L2CAP_ERTM_EVENT_RETRANSMISSION},
+		{8, L2CAP_ERTM_EVENT_REJ},
+	}
+	if len(tracker.ErtmEventList) != len(wantEventList) {
+		t.Fatalf("got %d ertm events, want %d", len(tracker.ErtmEventList), len(wantEventList))
+	}
+	for index, want := range wantEventList {
+		got := tracker.ErtmEventList[index]
+		if got.RecordIndex != want.recordIndex || got.Type != want.eventType || got.Channel != channel {
+			t.Errorf("ertm event %d: record %d %s, want record %d %s", index, got.RecordIndex, L2capErtmEventStrMap[got.Type], want.recordIndex, L2capErtmEventStrMap[want.eventType])
+		}
+	}
+
+	// 未分段的SDU和SAR start/end重组后的SDU
+	wantSduMap := map[int][]byte{6: {0xaa}, 10: {0xbb, 0xcc, 0xdd, 0xee}}
+	for pos, wantSdu := range wantSduMap {
+		pkt, ok := aclTestParsed(t, recordList[pos]).Ret.(hci.L2capErtmFrame)
+		if !ok {
+			t.Fatalf("record %d: not an ertm frame", pos)
+		}
+		sdu, _ := pkt.SduParsedResult.(hci.HciAclPktParseResult)
+		if frame, ok := sdu.Ret.(hci.L2capChannelFrame); !ok || string(frame.Payload) != string(wantSdu) {
+			t.Errorf("record %d: sdu %+v, want payload % x", pos, sdu, wantSdu)
+		}
+	}
+}
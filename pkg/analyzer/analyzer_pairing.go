@@ -0,0 +1,470 @@
+// BR/EDR配对时间线
+// 1. 按对端地址记录Secure Simple Pairing/legacy PIN配对/已有link key鉴权过程中的请求、回复和完成事件
+// 2. 根据双方IO Capability推断SSP的association model，没有IO Capability时按出现的请求事件推断
+// 3. 配对失败时定位失败的步骤: 本端Negative Reply、回复命令执行失败、对端拒绝已保存的link key或完成事件
+// 4. 对端发起的已有link key鉴权没有Authentication Complete，在Encryption Change或连接断开时结束
+
+package analyzer
+
+import (
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// 配对方式
+const (
+	PAIRING_METHOD_UNKNOWN    = 0
+	PAIRING_METHOD_SSP        = 1
+	PAIRING_METHOD_LEGACY_PIN = 2
+	PAIRING_METHOD_LINK_KEY   = 3 // 使用已保存的link key鉴权，没有重新配对
+)
+
+var PairingMethodStrMap = map[int]string{
+	PAIRING_METHOD_UNKNOWN:    "Unknown",
+	PAIRING_METHOD_SSP:        "Secure Simple Pairing",
+	PAIRING_METHOD_LEGACY_PIN: "Legacy PIN",
+	PAIRING_METHOD_LINK_KEY:   "Stored Link Key",
+}
+
+// SSP association model
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part C] 5.2.2.6 IO Capability Mapping to Authentication Stage 1
+const (
+	ASSOCIATION_MODEL_UNKNOWN            = 0
+	ASSOCIATION_MODEL_JUST_WORKS         = 1
+	ASSOCIATION_MODEL_NUMERIC_COMPARISON = 2
+	ASSOCIATION_MODEL_PASSKEY_ENTRY      = 3
+	ASSOCIATION_MODEL_OOB                = 4
+)
+
+var AssociationModelStrMap = map[int]string{
+	ASSOCIATION_MODEL_UNKNOWN:            "Unknown",
+	ASSOCIATION_MODEL_JUST_WORKS:         "Just Works",
+	ASSOCIATION_MODEL_NUMERIC_COMPARISON: "Numeric Comparison",
+	ASSOCIATION_MODEL_PASSKEY_ENTRY:      "Passkey Entry",
+	ASSOCIATION_MODEL_OOB:                "Out of Band",
+}
+
+// 配对步骤，命令为本端发出，事件为controller上报
+const (
+	PAIRING_STEP_AUTHENTICATION_REQUESTED                 = 0
+	PAIRING_STEP_LINK_KEY_REQUEST                         = 1
+	PAIRING_STEP_LINK_KEY_REQUEST_REPLY                   = 2
+	PAIRING_STEP_LINK_KEY_REQUEST_NEGATIVE_REPLY          = 3
+	PAIRING_STEP_PIN_CODE_REQUEST                         = 4
+	PAIRING_STEP_PIN_CODE_REQUEST_REPLY                   = 5
+	PAIRING_STEP_PIN_CODE_REQUEST_NEGATIVE_REPLY          = 6
+	PAIRING_STEP_IO_CAPABILITY_REQUEST                    = 7
+	PAIRING_STEP_IO_CAPABILITY_REQUEST_REPLY              = 8
+	PAIRING_STEP_IO_CAPABILITY_REQUEST_NEGATIVE_REPLY     = 9
+	PAIRING_STEP_IO_CAPABILITY_RESPONSE                   = 10
+	PAIRING_STEP_USER_CONFIRMATION_REQUEST                = 11
+	PAIRING_STEP_USER_CONFIRMATION_REQUEST_REPLY          = 12
+	PAIRING_STEP_USER_CONFIRMATION_REQUEST_NEGATIVE_REPLY = 13
+	PAIRING_STEP_USER_PASSKEY_REQUEST                     = 14
+	PAIRING_STEP_USER_PASSKEY_REQUEST_REPLY               = 15
+	PAIRING_STEP_USER_PASSKEY_REQUEST_NEGATIVE_REPLY      = 16
+	PAIRING_STEP_USER_PASSKEY_NOTIFICATION                = 17
+	PAIRING_STEP_REMOTE_OOB_DATA_REQUEST                  = 18
+	PAIRING_STEP_REMOTE_OOB_DATA_REQUEST_REPLY            = 19
+	PAIRING_STEP_REMOTE_OOB_DATA_REQUEST_NEGATIVE_REPLY   = 20
+	PAIRING_STEP_SIMPLE_PAIRING_COMPLETE                  = 21
+	PAIRING_STEP_LINK_KEY_NOTIFICATION                    = 22
+	PAIRING_STEP_AUTHENTICATION_COMPLETE                  = 23
+	PAIRING_STEP_DISCONNECTION                            = 24 // 配对未结束时连接断开
+	PAIRING_STEP_ENCRYPTION_CHANGE                        = 25 // 对端发起的鉴权在开启加密后结束
+)
+
+var PairingStepStrMap = map[int]string{
+	PAIRING_STEP_AUTHENTICATION_REQUESTED:                 "Authentication Requested",
+	PAIRING_STEP_LINK_KEY_REQUEST:                         "Link Key Request",
+	PAIRING_STEP_LINK_KEY_REQUEST_REPLY:                   "Link Key Request Reply",
+	PAIRING_STEP_LINK_KEY_REQUEST_NEGATIVE_REPLY:          "Link Key Request Negative Reply",
+	PAIRING_STEP_PIN_CODE_REQUEST:                         "PIN Code Request",
+	PAIRING_STEP_PIN_CODE_REQUEST_REPLY:                   "PIN Code Request Reply",
+	PAIRING_STEP_PIN_CODE_REQUEST_NEGATIVE_REPLY:          "PIN Code Request Negative Reply",
+	PAIRING_STEP_IO_CAPABILITY_REQUEST:                    "IO Capability Request",
+	PAIRING_STEP_IO_CAPABILITY_REQUEST_REPLY:              "IO Capability Request Reply",
+	PAIRING_STEP_IO_CAPABILITY_REQUEST_NEGATIVE_REPLY:     "IO Capability Request Negative Reply",
+	PAIRING_STEP_IO_CAPABILITY_RESPONSE:                   "IO Capability Response",
+	PAIRING_STEP_USER_CONFIRMATION_REQUEST:                "User Confirmation Request",
+	PAIRING_STEP_USER_CONFIRMATION_REQUEST_REPLY:          "User Confirmation Request Reply",
+	PAIRING_STEP_USER_CONFIRMATION_REQUEST_NEGATIVE_REPLY: "User Confirmation Request Negative Reply",
+	PAIRING_STEP_USER_PASSKEY_REQUEST:                     "User Passkey Request",
+	PAIRING_STEP_USER_PASSKEY_REQUEST_REPLY:               "User Passkey Request Reply",
+	PAIRING_STEP_USER_PASSKEY_REQUEST_NEGATIVE_REPLY:      "User Passkey Request Negative Reply",
+	PAIRING_STEP_USER_PASSKEY_NOTIFICATION:                "User Passkey Notification",
+	PAIRING_STEP_REMOTE_OOB_DATA_REQUEST:                  "Remote OOB Data Request",
+	PAIRING_STEP_REMOTE_OOB_DATA_REQUEST_REPLY:            "Remote OOB Data Request Reply",
+	PAIRING_STEP_REMOTE_OOB_DATA_REQUEST_NEGATIVE_REPLY:   "Remote OOB Data Request Negative Reply",
+	PAIRING_STEP_SIMPLE_PAIRING_COMPLETE:                  "Simple Pairing Complete",
+	PAIRING_STEP_LINK_KEY_NOTIFICATION:                    "Link Key Notification",
+	PAIRING_STEP_AUTHENTICATION_COMPLETE:                  "Authentication Complete",
+	PAIRING_STEP_DISCONNECTION:                            "Disconnection",
+	PAIRING_STEP_ENCRYPTION_CHANGE:                        "Encryption Change",
+}
+
+// Link Control命令OCF -> 配对步骤
+var pairingCmdStepMap = map[uint16]int{
+	hci.HCI_AUTHENTICATION_REQUESTED:                 PAIRING_STEP_AUTHENTICATION_REQUESTED,
+	hci.HCI_LINK_KEY_REQUEST_REPLY:                   PAIRING_STEP_LINK_KEY_REQUEST_REPLY,
+	hci.HCI_LINK_KEY_REQUEST_NEGATIVE_REPLY:          PAIRING_STEP_LINK_KEY_REQUEST_NEGATIVE_REPLY,
+	hci.HCI_PIN_CODE_REQUEST_REPLY:                   PAIRING_STEP_PIN_CODE_REQUEST_REPLY,
+	hci.HCI_PIN_CODE_REQUEST_NEGATIVE_REPLY:          PAIRING_STEP_PIN_CODE_REQUEST_NEGATIVE_REPLY,
+	hci.HCI_IO_CAPABILITY_REQUEST_REPLY:              PAIRING_STEP_IO_CAPABILITY_REQUEST_REPLY,
+	hci.HCI_IO_CAPABILITY_REQUEST_NEGATIVE_REPLY:     PAIRING_STEP_IO_CAPABILITY_REQUEST_NEGATIVE_REPLY,
+	hci.HCI_USER_CONFIRMATION_REQUEST_REPLY:          PAIRING_STEP_USER_CONFIRMATION_REQUEST_REPLY,
+	hci.HCI_USER_CONFIRMATION_REQUEST_NEGATIVE_REPLY: PAIRING_STEP_USER_CONFIRMATION_REQUEST_NEGATIVE_REPLY,
+	hci.HCI_USER_PASSKEY_REQUEST_REPLY:               PAIRING_STEP_USER_PASSKEY_REQUEST_REPLY,
+	hci.HCI_USER_PASSKEY_REQUEST_NEGATIVE_REPLY:      PAIRING_STEP_USER_PASSKEY_REQUEST_NEGATIVE_REPLY,
+	hci.HCI_REMOTE_OOB_DATA_REQUEST_REPLY:            PAIRING_STEP_REMOTE_OOB_DATA_REQUEST_REPLY,
+	hci.HCI_REMOTE_OOB_DATA_REQUEST_NEGATIVE_REPLY:   PAIRING_STEP_REMOTE_OOB_DATA_REQUEST_NEGATIVE_REPLY,
+}
+
+// 配对过程中的一个步骤
+type PairingStep struct {
+	RecordIndex int
+	TimestampUs uint64
+	Step        int           // PAIRING_STEP_XXX
+	Status      hci.HciStatus // 命令为Command Complete/Command Status的Status，完成事件为事件的Status，断开为Reason
+}
+
+// SSP中一方的IO能力
+type PairingIoCapability struct {
+	Known                      bool
+	IoCapability               uint8 // hci.IO_CAPABILITY_XXX
+	OobDataPresent             uint8
+	AuthenticationRequirements uint8 // hci.AUTH_REQ_XXX
+}
+
+// 与一个对端的一次配对/鉴权过程
+type PairingSession struct {
+	PeerAddress      [6]byte
+	ConnectionHandle uint16
+	HandleKnown      bool
+	Method           int  // PAIRING_METHOD_XXX
+	LocalInitiated   bool // 本端发送了Authentication Requested
+	StepList         []PairingStep
+
+	LocalIo          PairingIoCapability
+	RemoteIo         PairingIoCapability
+	AssociationModel int    // ASSOCIATION_MODEL_XXX，仅SSP
+	NumericValue     uint32 // User Confirmation Request的数值或passkey
+
+	LinkKeyType  uint8 // hci.LINK_KEY_TYPE_XXX
+	LinkKeyKnown bool
+
+	Finished   bool
+	Success    bool
+	Status     hci.HciStatus // 失败原因
+	FailedStep int           // 失败步骤在StepList中的索引，-1为没有失败
+}
+
+// 等待Command Complete/Command Status的回复命令
+type pairingPendingCmd struct {
+	session   *PairingSession
+	stepIndex int
+}
+
+// BR/EDR配对时间线
+type PairingTimeline struct {
+	SessionList []*PairingSession // 按开始顺序
+
+	activeSessionMap map[[6]byte]*PairingSession
+	handleAddrMap    map[uint16][6]byte
+	pendingCmdMap    map[uint16]pairingPendingCmd // OpCode -> 回复命令
+}
+
+func NewPairingTimeline() *PairingTimeline {
+	return &PairingTimeline{
+		activeSessionMap: map[[6]byte]*PairingSession{},
+		handleAddrMap:    map[uint16][6]byte{},
+		pendingCmdMap:    map[uint16]pairingPendingCmd{},
+	}
+}
+
+// 获取对端当前的配对过程，没有时新建
+func (timeline *PairingTimeline) sessionGet(bdAddr [6]byte) *PairingSession {
+	if session, ok := timeline.activeSessionMap[bdAddr]; ok {
+		return session
+	}
+	session := &PairingSession{PeerAddress: bdAddr, FailedStep: -1}
+	for handle, addr := range timeline.handleAddrMap {
+		if addr == bdAddr {
+			session.ConnectionHandle, session.HandleKnown = handle, true
+		}
+	}
+	timeline.SessionList = append(timeline.SessionList, session)
+	timeline.activeSessionMap[bdAddr] = session
+	return session
+}
+
+func (session *PairingSession) stepAdd(record Record, step int, status hci.HciStatus) int {
+	session.StepList = append(session.StepList, PairingStep{RecordIndex: record.Index, TimestampUs: record.TimestampUs, Step: step, Status: status})
+	return len(session.StepList) - 1
+}
+
+// 只记录第一个失败的步骤
+func (session *PairingSession) stepFail(stepIndex int) {
+	if session.FailedStep < 0 {
+		session.FailedStep = stepIndex
+	}
+}
+
+func (session *PairingSession) stepFind(step int) int {
+	for index := len(session.StepList) - 1; index >= 0; index-- {
+		if session.StepList[index].Step == step {
+			return index
+		}
+	}
+	return -1
+}
+
+// 配对结束，stepIndex为结束的步骤
+func (timeline *PairingTimeline) finish(session *PairingSession, stepIndex int, status hci.HciStatus) {
+	session.Finished = true
+	session.Status = status
+	session.Success = status == hci.HCI_STATUS_SUCCESS && session.FailedStep < 0
+	if status != hci.HCI_STATUS_SUCCESS {
+		// 对端没有本端提供的link key对应的key
+		if replyIndex := session.stepFind(PAIRING_STEP_LINK_KEY_REQUEST_REPLY); status == hci.HCI_STATUS_PIN_OR_KEY_MISSING && replyIndex >= 0 {
+			session.stepFail(replyIndex)
+		}
+		session.stepFail(stepIndex)
+	}
+	delete(timeline.activeSessionMap, session.PeerAddress)
+}
+
+// 推断association model，双方IO Capability都已知时按映射表，否则按出现的请求事件
+func (session *PairingSession) associationModelUpdate(step int) {
+	local, remote := session.LocalIo, session.RemoteIo
+	if local.Known && remote.Known {
+		session.AssociationModel = associationModelMap(local, remote)
+		return
+	}
+	switch step {
+	case PAIRING_STEP_REMOTE_OOB_DATA_REQUEST:
+		session.AssociationModel = ASSOCIATION_MODEL_OOB
+	case PAIRING_STEP_USER_PASSKEY_REQUEST, PAIRING_STEP_USER_PASSKEY_NOTIFICATION:
+		session.AssociationModel = ASSOCIATION_MODEL_PASSKEY_ENTRY
+	case PAIRING_STEP_USER_CONFIRMATION_REQUEST:
+		// Just Works也通过User Confirmation Request上报，只知道一方时按该方能否显示确认区分
+		session.AssociationModel = ASSOCIATION_MODEL_NUMERIC_COMPARISON
+		for _, io := range []PairingIoCapability{local, remote} {
+			if io.Known && io.IoCapability != hci.IO_CAPABILITY_DISPLAY_YES_NO {
+				session.AssociationModel = ASSOCIATION_MODEL_JUST_WORKS
+			}
+		}
+	}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part C] 5.2.2.6 IO Capability Mapping to Authentication Stage 1
+func associationModelMap(local PairingIoCapability, remote PairingIoCapability) int {
+	if local.OobDataPresent != 0 || remote.OobDataPresent != 0 {
+		return ASSOCIATION_MODEL_OOB
+	}
+	// 双方都不要求MITM保护
+	if local.AuthenticationRequirements&0x01 == 0 && remote.AuthenticationRequirements&0x01 == 0 {
+		return ASSOCIATION_MODEL_JUST_WORKS
+	}
+	ioCapList := []uint8{local.IoCapability, remote.IoCapability}
+	keyboard, displayYesNo := 0, 0
+	for _, ioCap := range ioCapList {
+		switch ioCap {
+		case hci.IO_CAPABILITY_NO_INPUT_NO_OUTPUT:
+			return ASSOCIATION_MODEL_JUST_WORKS
+		case hci.IO_CAPABILITY_KEYBOARD_ONLY:
+			keyboard++
+		case hci.IO_CAPABILITY_DISPLAY_YES_NO:
+			displayYesNo++
+		}
+	}
+	if keyboard > 0 {
+		return ASSOCIATION_MODEL_PASSKEY_ENTRY
+	}
+	// DisplayOnly一方自动确认，数值比较不提供MITM保护
+	if displayYesNo == 2 {
+		return ASSOCIATION_MODEL_NUMERIC_COMPARISON
+	}
+	return ASSOCIATION_MODEL_JUST_WORKS
+}
+
+func (timeline *PairingTimeline) Feed(record Record) {
+	if hciCmd, cmd, ok := record.CmdParseResult(); ok {
+		timeline.cmdFeed(record, hciCmd, cmd)
+		return
+	}
+	_, evt, ok := record.EvtParseResult()
+	if !ok {
+		return
+	}
+	switch pkt := evt.Ret.(type) {
+	case hci.CommandStatusEvent:
+		timeline.cmdStatus(pkt.CommandOpCode, pkt.Status)
+	case hci.CommandCompleteEvent:
+		timeline.cmdStatus(pkt.CommandOpCode, pkt.Status)
+	case hci.ConnectionCompleteEvent:
+		if pkt.Status != hci.HCI_STATUS_SUCCESS || pkt.LinkType != hci.LINK_TYPE_ACL {
+			return
+		}
+		timeline.handleAddrMap[pkt.ConnectionHandle] = pkt.BdAddr
+		if session, ok := timeline.activeSessionMap[pkt.BdAddr]; ok {
+			session.ConnectionHandle, session.HandleKnown = pkt.ConnectionHandle, true
+		}
+	case hci.DisconnectionCompleteEvent:
+		if pkt.Status != hci.HCI_STATUS_SUCCESS {
+			return
+		}
+		bdAddr, ok := timeline.handleAddrMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		delete(timeline.handleAddrMap, pkt.ConnectionHandle)
+		if session, ok := timeline.activeSessionMap[bdAddr]; ok {
+			timeline.finish(session, session.stepAdd(record, PAIRING_STEP_DISCONNECTION, pkt.Reason), pkt.Reason)
+		}
+	case hci.EncryptionChangeEvent:
+		// 本端发起时在Authentication Complete结束
+		bdAddr, ok := timeline.handleAddrMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		if session, ok := timeline.activeSessionMap[bdAddr]; ok && !session.LocalInitiated {
+			timeline.finish(session, session.stepAdd(record, PAIRING_STEP_ENCRYPTION_CHANGE, pkt.Status), pkt.Status)
+		}
+	case hci.LinkKeyRequestEvent:
+		session := timeline.sessionGet(pkt.BdAddr)
+		if session.Method == PAIRING_METHOD_UNKNOWN {
+			session.Method = PAIRING_METHOD_LINK_KEY
+		}
+		session.stepAdd(record, PAIRING_STEP_LINK_KEY_REQUEST, hci.HCI_STATUS_SUCCESS)
+	case hci.PinCodeRequestEvent:
+		session := timeline.sessionGet(pkt.BdAddr)
+		session.Method = PAIRING_METHOD_LEGACY_PIN
+		session.stepAdd(record, PAIRING_STEP_PIN_CODE_REQUEST, hci.HCI_STATUS_SUCCESS)
+	case hci.IoCapabilityRequestEvent:
+		session := timeline.sessionGet(pkt.BdAddr)
+		session.Method = PAIRING_METHOD_SSP
+		session.stepAdd(record, PAIRING_STEP_IO_CAPABILITY_REQUEST, hci.HCI_STATUS_SUCCESS)
+	case hci.IoCapabilityResponseEvent:
+		// 对端发起时IO Capability Response在IO Capability Request之前
+		session := timeline.sessionGet(pkt.BdAddr)
+		session.Method = PAIRING_METHOD_SSP
+		session.RemoteIo = PairingIoCapability{Known: true, IoCapability: pkt.IoCapability, OobDataPresent: pkt.OobDataPresent, AuthenticationRequirements: pkt.AuthenticationRequirements}
+		session.stepAdd(record, PAIRING_STEP_IO_CAPABILITY_RESPONSE, hci.HCI_STATUS_SUCCESS)
+		session.associationModelUpdate(PAIRING_STEP_IO_CAPABILITY_RESPONSE)
+	case hci.UserConfirmationRequestEvent:
+		timeline.sspRequestAdd(record, pkt.BdAddr, PAIRING_STEP_USER_CONFIRMATION_REQUEST).NumericValue = pkt.NumericValue
+	case hci.UserPasskeyRequestEvent:
+		timeline.sspRequestAdd(record, pkt.BdAddr, PAIRING_STEP_USER_PASSKEY_REQUEST)
+	case hci.UserPasskeyNotificationEvent:
+		timeline.sspRequestAdd(record, pkt.BdAddr, PAIRING_STEP_USER_PASSKEY_NOTIFICATION).NumericValue = pkt.Passkey
+	case hci.RemoteOobDataRequestEvent:
+		timeline.sspRequestAdd(record, pkt.BdAddr, PAIRING_STEP_REMOTE_OOB_DATA_REQUEST)
+	case hci.SimplePairingCompleteEvent:
+		session := timeline.sessionGet(pkt.BdAddr)
+		session.Method = PAIRING_METHOD_SSP
+		stepIndex := session.stepAdd(record, PAIRING_STEP_SIMPLE_PAIRING_COMPLETE, pkt.Status)
+		if pkt.Status == hci.HCI_STATUS_SUCCESS {
+			return
+		}
+		// 本端发起鉴权时等待Authentication Complete
+		session.stepFail(stepIndex)
+		if !session.LocalInitiated {
+			timeline.finish(session, stepIndex, pkt.Status)
+		}
+	case hci.LinkKeyNotificationEvent:
+		session := timeline.sessionGet(pkt.BdAddr)
+		session.LinkKeyType, session.LinkKeyKnown = pkt.KeyType, true
+		stepIndex := session.stepAdd(record, PAIRING_STEP_LINK_KEY_NOTIFICATION, hci.HCI_STATUS_SUCCESS)
+		// 本端发起鉴权时等待Authentication Complete
+		if !session.LocalInitiated {
+			timeline.finish(session, stepIndex, hci.HCI_STATUS_SUCCESS)
+		}
+	case hci.AuthenticationCompleteEvent:
+		bdAddr, ok := timeline.handleAddrMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		session := timeline.sessionGet(bdAddr)
+		timeline.finish(session, session.stepAdd(record, PAIRING_STEP_AUTHENTICATION_COMPLETE, pkt.Status), pkt.Status)
+	}
+}
+
+// SSP authentication stage 1的请求事件
+func (timeline *PairingTimeline) sspRequestAdd(record Record, bdAddr [6]byte, step int) *PairingSession {
+	session := timeline.sessionGet(bdAddr)
+	session.Method = PAIRING_METHOD_SSP
+	session.stepAdd(record, step, hci.HCI_STATUS_SUCCESS)
+	session.associationModelUpdate(step)
+	return session
+}
+
+// 本端Authentication Requested和各请求的回复命令
+func (timeline *PairingTimeline) cmdFeed(record Record, hciCmd hci.HciCmd, cmd hci.HciCmdPktParseResult) {
+	if cmd.OpCodeOgf != hci.HCI_CMD_OGF_LINK_CONTROL_CMD {
+		return
+	}
+	step, ok := pairingCmdStepMap[cmd.OpCodeOcf]
+	if !ok {
+		return
+	}
+	var session *PairingSession
+	switch pkt := cmd.Ret.(type) {
+	case hci.HciConnectionHandleCmd:
+		bdAddr, ok := timeline.handleAddrMap[pkt.ConnectionHandle]
+		if !ok {
+			return
+		}
+		session = timeline.sessionGet(bdAddr)
+		session.LocalInitiated = true
+	case hci.HciBdAddrCmd:
+		session = timeline.sessionGet(pkt.BdAddr)
+	case hci.HciLinkKeyRequestReply:
+		session = timeline.sessionGet(pkt.BdAddr)
+	case hci.HciPinCodeRequestReply:
+		session = timeline.sessionGet(pkt.BdAddr)
+	case hci.HciIoCapabilityRequestReply:
+		session = timeline.sessionGet(pkt.BdAddr)
+		session.LocalIo = PairingIoCapability{Known: true, IoCapability: pkt.IoCapability, OobDataPresent: pkt.OobDataPresent, AuthenticationRequirements: pkt.AuthenticationRequirements}
+		session.associationModelUpdate(step)
+	case hci.HciIoCapabilityRequestNegativeReply:
+		session = timeline.sessionGet(pkt.BdAddr)
+	case hci.HciUserPasskeyRequestReply:
+		session = timeline.sessionGet(pkt.BdAddr)
+		session.NumericValue = pkt.NumericValue
+	case hci.HciRemoteOobDataRequestReply:
+		session = timeline.sessionGet(pkt.BdAddr)
+	default:
+		return
+	}
+	stepIndex := session.stepAdd(record, step, hci.HCI_STATUS_SUCCESS)
+	switch step {
+	case PAIRING_STEP_LINK_KEY_REQUEST_NEGATIVE_REPLY:
+		// 没有保存的link key，接下来重新配对
+		if session.Method == PAIRING_METHOD_LINK_KEY {
+			session.Method = PAIRING_METHOD_UNKNOWN
+		}
+	case PAIRING_STEP_PIN_CODE_REQUEST_NEGATIVE_REPLY, PAIRING_STEP_IO_CAPABILITY_REQUEST_NEGATIVE_REPLY,
+		PAIRING_STEP_USER_CONFIRMATION_REQUEST_NEGATIVE_REPLY, PAIRING_STEP_USER_PASSKEY_REQUEST_NEGATIVE_REPLY,
+		PAIRING_STEP_REMOTE_OOB_DATA_REQUEST_NEGATIVE_REPLY:
+		session.stepFail(stepIndex)
+	}
+	timeline.pendingCmdMap[hciCmd.OpCode] = pairingPendingCmd{session: session, stepIndex: stepIndex}
+}
+
+// 回复命令执行失败时标记为失败步骤
+func (timeline *PairingTimeline) cmdStatus(opCode uint16, status hci.HciStatus) {
+	pending, ok := timeline.pendingCmdMap[opCode]
+	if !ok {
+		return
+	}
+	delete(timeline.pendingCmdMap, opCode)
+	pending.session.StepList[pending.stepIndex].Status = status
+	if status == hci.HCI_STATUS_SUCCESS {
+		return
+	}
+	pending.session.stepFail(pending.stepIndex)
+	// Authentication Requested失败时不会有Authentication Complete
+	if pending.session.StepList[pending.stepIndex].Step == PAIRING_STEP_AUTHENTICATION_REQUESTED && !pending.session.Finished {
+		timeline.finish(pending.session, pending.stepIndex, status)
+	}
+}
@@ -0,0 +1,58 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+func pairingRecord(index int, hciPktType byte, buf []byte) Record {
+	packetFlags := uint32(0x03)
+	if hciPktType == hci.PKT_TYPE_HCI_CMD {
+		packetFlags = 0x02
+	}
+	return Record{Index: index, PacketFlags: packetFlags, Parsed: hci.HciPktParse(hciPktType, buf)}
+}
+
+// 对端发起、使用已保存link key的鉴权
+func TestPairingTimelineRemoteStoredLinkKey(t *testing.T) {
+	bdAddr := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}
+	connectionComplete := append(append([]byte{0x03, 0x0b, 0x00, 0x40, 0x00}, bdAddr...), 0x01, 0x00)
+	linkKeyRequest := append([]byte{0x17, 0x06}, bdAddr...)
+	linkKeyRequestReply := append(append([]byte{0x0b, 0x04, 0x16}, bdAddr...), make([]byte, 16)...)
+	linkKeyRequestReplyComplete := append([]byte{0x0e, 0x0a, 0x01, 0x0b, 0x04, 0x00}, bdAddr...)
+	testList := []struct {
+		name       string
+		last       []byte
+		wantStep   int
+		wantStatus hci.HciStatus
+	}{
+		{"encryption change", []byte{0x08, 0x04, 0x00, 0x40, 0x00, 0x01}, PAIRING_STEP_ENCRYPTION_CHANGE, hci.HCI_STATUS_SUCCESS},
+		{"encryption change failed", []byte{0x08, 0x04, 0x06, 0x40, 0x00, 0x00}, PAIRING_STEP_ENCRYPTION_CHANGE, hci.HCI_STATUS_PIN_OR_KEY_MISSING},
+		{"disconnection", []byte{0x05, 0x04, 0x00, 0x40, 0x00, 0x13}, PAIRING_STEP_DISCONNECTION, 0x13},
+	}
+	for _, test := range testList {
+		timeline := NewPairingTimeline()
+		timeline.Feed(pairingRecord(0, hci.PKT_TYPE_HCI_EVT, connectionComplete))
+		timeline.Feed(pairingRecord(1, hci.PKT_TYPE_HCI_EVT, linkKeyRequest))
+		timeline.Feed(pairingRecord(2, hci.PKT_TYPE_HCI_CMD, linkKeyRequestReply))
+		timeline.Feed(pairingRecord(3, hci.PKT_TYPE_HCI_EVT, linkKeyRequestReplyComplete))
+		timeline.Feed(pairingRecord(4, hci.PKT_TYPE_HCI_EVT, test.last))
+		if len(timeline.SessionList) != 1 {
+			t.Fatalf("%s: got %d sessions, want 1", test.name, len(timeline.SessionList))
+		}
+		session := timeline.SessionList[0]
+		if !session.Finished || session.Method != PAIRING_METHOD_LINK_KEY || session.LocalInitiated {
+			t.Errorf("%s: Finished=%v Method=%d LocalInitiated=%v", test.name, session.Finished, session.Method, session.LocalInitiated)
+		}
+		if session.Status != test.wantStatus || session.Success != (test.wantStatus == hci.HCI_STATUS_SUCCESS) {
+			t.Errorf("%s: Status=%#x Success=%v, want %#x", test.name, session.Status, session.Success, test.wantStatus)
+		}
+		if last := session.StepList[len(session.StepList)-1]; last.Step != test.wantStep || last.RecordIndex != 4 {
+			t.Errorf("%s: last step %s at %d, want %s at 4", test.name, PairingStepStrMap[last.Step], last.RecordIndex, PairingStepStrMap[test.wantStep])
+		}
+		if len(timeline.activeSessionMap) != 0 {
+			t.Errorf("%s: session still active", test.name)
+		}
+	}
+}
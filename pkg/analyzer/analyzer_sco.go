@@ -0,0 +1,409 @@
+// SCO/eSCO通话音频提取
+// 1. 根据Write Voice Setting和(Enhanced) Setup/Accept Synchronous Connection确定HCI上SCO数据的编码
+// 2. 根据Synchronous Connection Complete关联SCO handle和空口编码(Air Mode)
+// 3. 收集每个通话的SCO数据，解码后输出WAV
+
+package analyzer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+
+	"wangdalian/btsnooper/pkg/codec"
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// HCI上SCO数据的编码
+const (
+	SCO_CODEC_UNKNOWN     = 0
+	SCO_CODEC_LINEAR_PCM  = 1
+	SCO_CODEC_U_LAW       = 2
+	SCO_CODEC_A_LAW       = 3
+	SCO_CODEC_CVSD        = 4
+	SCO_CODEC_MSBC        = 5
+	SCO_CODEC_LC3         = 6
+	SCO_CODEC_TRANSPARENT = 7 // 透传数据，编码未知
+)
+
+var ScoCodecStrMap = map[int]string{
+	SCO_CODEC_UNKNOWN:     "Unknown",
+	SCO_CODEC_LINEAR_PCM:  "Linear PCM",
+	SCO_CODEC_U_LAW:       "u-law",
+	SCO_CODEC_A_LAW:       "A-law",
+	SCO_CODEC_CVSD:        "CVSD",
+	SCO_CODEC_MSBC:        "mSBC",
+	SCO_CODEC_LC3:         "LC3",
+	SCO_CODEC_TRANSPARENT: "Transparent",
+}
+
+const (
+	SCO_SAMPLE_RATE_NARROWBAND = 8000
+)
+
+// HCI上SCO数据格式
+type ScoDataFormat struct {
+	Codec         int   // SCO_CODEC_XXX
+	SampleSize    int   // 线性PCM采样位数: 8/16
+	PcmDataFormat uint8 // hci.PCM_DATA_FORMAT_XXX
+	SampleRate    int
+}
+
+// 一个SCO数据包
+type ScoFrame struct {
+	RecordIndex      int
+	TimestampUs      uint64
+	PacketStatusFlag uint8 // hci.HCI_SYNC_PKT_STATUS_XXX
+	Data             []byte
+}
+
+// 一路SCO/eSCO通话，对应一个同步连接handle的生命周期
+type ScoCall struct {
+	ConnectionHandle      uint16
+	BdAddr                [6]byte
+	LinkType              uint8         // hci.LINK_TYPE_XXX
+	AirMode               uint8         // hci.AIR_MODE_XXX
+	TxFormat              ScoDataFormat // host -> controller
+	RxFormat              ScoDataFormat // controller -> host
+	ConnectTimestampUs    uint64
+	DisconnectTimestampUs uint64 // 0表示抓包结束时未断开
+	TxFrameList           []ScoFrame
+	RxFrameList           []ScoFrame
+}
+
+// SCO通话跟踪
+type ScoTracker struct {
+	CallList      []*ScoCall
+	voiceSetting  uint16
+	pendingSetup  interface{} // 最近一次同步连接建立/接受命令，用于确定下一个Synchronous Connection Complete的数据格式
+	activeCallMap map[uint16]*ScoCall
+}
+
+func NewScoTracker() *ScoTracker {
+	return &ScoTracker{voiceSetting: hci.VOICE_SETTING_DEFAULT, activeCallMap: map[uint16]*ScoCall{}}
+}
+
+// 根据Voice Setting确定数据格式
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 6.12 Voice Setting
+func voiceSettingDataFormat(voiceSetting uint16) ScoDataFormat {
+	format := ScoDataFormat{SampleRate: SCO_SAMPLE_RATE_NARROWBAND}
+	if voiceSetting&hci.VOICE_SETTING_AIR_CODING_MASK == hci.VOICE_SETTING_AIR_CODING_TRANSPARENT {
+		format.Codec = SCO_CODEC_TRANSPARENT
+		return format
+	}
+	switch voiceSetting & hci.VOICE_SETTING_INPUT_CODING_MASK {
+	case hci.VOICE_SETTING_INPUT_CODING_U_LAW:
+		format.Codec = SCO_CODEC_U_LAW
+	case hci.VOICE_SETTING_INPUT_CODING_A_LAW:
+		format.Codec = SCO_CODEC_A_LAW
+	case hci.VOICE_SETTING_INPUT_CODING_LINEAR:
+		format.Codec = SCO_CODEC_LINEAR_PCM
+		format.SampleSize = 8
+		if voiceSetting&hci.VOICE_SETTING_INPUT_SAMPLE_SIZE_16BIT != 0 {
+			format.SampleSize = 16
+		}
+		switch voiceSetting & hci.VOICE_SETTING_INPUT_DATA_FORMAT_MASK {
+		case hci.VOICE_SETTING_INPUT_DATA_FORMAT_ONES_COMPLEMENT:
+			format.PcmDataFormat = hci.PCM_DATA_FORMAT_ONES_COMPLEMENT
+		case hci.VOICE_SETTING_INPUT_DATA_FORMAT_TWOS_COMPLEMENT:
+			format.PcmDataFormat = hci.PCM_DATA_FORMAT_TWOS_COMPLEMENT
+		case hci.VOICE_SETTING_INPUT_DATA_FORMAT_SIGN_MAGNITUDE:
+			format.PcmDataFormat = hci.PCM_DATA_FORMAT_SIGN_MAGNITUDE
+		case hci.VOICE_SETTING_INPUT_DATA_FORMAT_UNSIGNED:
+			format.PcmDataFormat = hci.PCM_DATA_FORMAT_UNSIGNED
+		}
+	default:
+		format.Codec = SCO_CODEC_UNKNOWN
+	}
+	return format
+}
+
+// 根据Enhanced Setup/Accept的Coding Format确定数据格式
+// hciCodingFormat为HCI上的编码，airCodingFormat为空口编码(HCI上为透传时使用)
+func enhancedDataFormat(hciCodingFormat hci.CodingFormat, airCodingFormat hci.CodingFormat, codedDataSize uint16, pcmDataFormat uint8, bandwidth uint32) ScoDataFormat {
+	format := ScoDataFormat{SampleRate: SCO_SAMPLE_RATE_NARROWBAND}
+	codingFormat := hciCodingFormat.CodingFormat
+	if codingFormat == hci.CODING_FORMAT_TRANSPARENT {
+		codingFormat = airCodingFormat.CodingFormat
+	}
+	switch codingFormat {
+	case hci.CODING_FORMAT_U_LAW:
+		format.Codec = SCO_CODEC_U_LAW
+	case hci.CODING_FORMAT_A_LAW:
+		format.Codec = SCO_CODEC_A_LAW
+	case hci.CODING_FORMAT_CVSD:
+		format.Codec = SCO_CODEC_CVSD
+	case hci.CODING_FORMAT_LINEAR_PCM:
+		format.Codec = SCO_CODEC_LINEAR_PCM
+		format.SampleSize = int(codedDataSize)
+		format.PcmDataFormat = pcmDataFormat
+		if format.SampleSize > 0 && bandwidth > 0 {
+			format.SampleRate = int(bandwidth) * 8 / format.SampleSize
+		}
+	case hci.CODING_FORMAT_MSBC:
+		format.Codec = SCO_CODEC_MSBC
+		format.SampleRate = codec.MSBC_SAMPLE_RATE
+	case hci.CODING_FORMAT_LC3:
+		format.Codec = SCO_CODEC_LC3
+	case hci.CODING_FORMAT_TRANSPARENT:
+		format.Codec = SCO_CODEC_TRANSPARENT
+	default:
+		format.Codec = SCO_CODEC_UNKNOWN
+	}
+	return format
+}
+
+// 确定新建通话两个方向的数据格式
+func (tracker *ScoTracker) dataFormat() (ScoDataFormat, ScoDataFormat) {
+	switch pkt := tracker.pendingSetup.(type) {
+	case hci.HciSetupSynchronousConnection:
+		return voiceSettingDataFormat(pkt.VoiceSetting), voiceSettingDataFormat(pkt.VoiceSetting)
+	case hci.HciAcceptSynchronousConnectionRequest:
+		return voiceSettingDataFormat(pkt.VoiceSetting), voiceSettingDataFormat(pkt.VoiceSetting)
+	case hci.HciEnhancedSetupSynchronousConnection:
+		return enhancedTxDataFormat(pkt.EnhancedSynchronousConnectionParameters), enhancedRxDataFormat(pkt.EnhancedSynchronousConnectionParameters)
+	case hci.HciEnhancedAcceptSynchronousConnectionRequest:
+		return enhancedTxDataFormat(pkt.EnhancedSynchronousConnectionParameters), enhancedRxDataFormat(pkt.EnhancedSynchronousConnectionParameters)
+	}
+	return voiceSettingDataFormat(tracker.voiceSetting), voiceSettingDataFormat(tracker.voiceSetting)
+}
+
+func enhancedTxDataFormat(param hci.EnhancedSynchronousConnectionParameters) ScoDataFormat {
+	return enhancedDataFormat(param.InputCodingFormat, param.TransmitCodingFormat, param.InputCodedDataSize, param.InputPcmDataFormat, param.InputBandwidth)
+}
+
+func enhancedRxDataFormat(param hci.EnhancedSynchronousConnectionParameters) ScoDataFormat {
+	return enhancedDataFormat(param.OutputCodingFormat, param.ReceiveCodingFormat, param.OutputCodedDataSize, param.OutputPcmDataFormat, param.OutputBandwidth)
+}
+
+func (tracker *ScoTracker) Feed(record Record) {
+	if _, cmd, ok := record.CmdParseResult(); ok {
+		switch pkt := cmd.Ret.(type) {
+		case hci.HciWriteVoiceSetting:
+			tracker.voiceSetting = pkt.VoiceSetting
+		case hci.HciSetupSynchronousConnection, hci.HciAcceptSynchronousConnectionRequest,
+			hci.HciEnhancedSetupSynchronousConnection, hci.HciEnhancedAcceptSynchronousConnectionRequest:
+			tracker.pendingSetup = pkt
+		}
+		return
+	}
+
+	if _, evt, ok := record.EvtParseResult(); ok {
+		switch pkt := evt.Ret.(type) {
+		case hci.SynchronousConnectionCompleteEvent:
+			if pkt.Status == hci.HCI_STATUS_SUCCESS {
+				call := &ScoCall{
+					ConnectionHandle:   pkt.ConnectionHandle,
+					BdAddr:             pkt.BdAddr,
+					LinkType:           pkt.LinkType,
+					AirMode:            pkt.AirMode,
+					ConnectTimestampUs: record.TimestampUs,
+				}
+				call.TxFormat, call.RxFormat = tracker.dataFormat()
+				tracker.activeCallMap[pkt.ConnectionHandle] = call
+				tracker.CallList = append(tracker.CallList, call)
+			}
+			tracker.pendingSetup = nil
+		case hci.DisconnectionCompleteEvent:
+			if call, ok := tracker.activeCallMap[pkt.ConnectionHandle]; ok && pkt.Status == hci.HCI_STATUS_SUCCESS {
+				call.DisconnectTimestampUs = record.TimestampUs
+				delete(tracker.activeCallMap, pkt.ConnectionHandle)
+			}
+		}
+		return
+	}
+
+	if pkt, ok := record.Sync(); ok {
+		call, ok := tracker.activeCallMap[pkt.Handle]
+		if !ok {
+			// 抓包开始时通话已经建立，只能按当前Voice Setting处理
+			call = &ScoCall{ConnectionHandle: pkt.Handle, ConnectTimestampUs: record.TimestampUs}
+			call.TxFormat, call.RxFormat = tracker.dataFormat()
+			tracker.activeCallMap[pkt.Handle] = call
+			tracker.CallList = append(tracker.CallList, call)
+		}
+		frame := ScoFrame{
+			RecordIndex:      record.Index,
+			TimestampUs:      record.TimestampUs,
+			PacketStatusFlag: pkt.PacketStatusFlag,
+			Data:             pkt.Data,
+		}
+		if record.IsReceived() {
+			call.RxFrameList = append(call.RxFrameList, frame)
+		} else {
+			call.TxFrameList = append(call.TxFrameList, frame)
+		}
+	}
+}
+
+// 获取某个方向的数据格式，透传数据中检测到mSBC帧时按mSBC处理
+func (call *ScoCall) DataFormat(received bool) ScoDataFormat {
+	format := call.TxFormat
+	frameList := call.TxFrameList
+	if received {
+		format = call.RxFormat
+		frameList = call.RxFrameList
+	}
+	if format.Codec == SCO_CODEC_TRANSPARENT || (format.Codec == SCO_CODEC_UNKNOWN && call.AirMode == hci.AIR_MODE_TRANSPARENT) {
+		if len(msbcStreamDecode(scoFrameListConcat(frameList, 1))) > 0 {
+			format.Codec = SCO_CODEC_MSBC
+			format.SampleRate = codec.MSBC_SAMPLE_RATE
+		}
+	}
+	return format
+}
+
+// 拼接SCO数据，No Data的包用0填充
+func scoFrameListConcat(frameList []ScoFrame, bytesPerSample int) []byte {
+	if bytesPerSample < 1 {
+		bytesPerSample = 1
+	}
+	buf := []byte{}
+	for _, frame := range frameList {
+		if frame.PacketStatusFlag == hci.HCI_SYNC_PKT_STATUS_NO_DATA {
+			buf = append(buf, make([]byte, len(frame.Data)/bytesPerSample*bytesPerSample)...)
+			continue
+		}
+		buf = append(buf, frame.Data...)
+	}
+	return buf
+}
+
+// 线性PCM转换为16bit有符号PCM
+func linearPcmDecode(buf []byte, sampleSize int, pcmDataFormat uint8) []int16 {
+	bytesPerSample := sampleSize / 8
+	if bytesPerSample != 1 && bytesPerSample != 2 {
+		return nil
+	}
+	signBit := uint16(1) << uint(sampleSize-1)
+	pcm := make([]int16, 0, len(buf)/bytesPerSample)
+	for index := 0; index+bytesPerSample <= len(buf); index += bytesPerSample {
+		raw := uint16(buf[index])
+		if bytesPerSample == 2 {
+			raw = binary.LittleEndian.Uint16(buf[index:])
+		}
+		var value int32
+		switch pcmDataFormat {
+		case hci.PCM_DATA_FORMAT_UNSIGNED:
+			value = int32(raw) - int32(signBit)
+		case hci.PCM_DATA_FORMAT_SIGN_MAGNITUDE:
+			value = int32(raw & (signBit - 1))
+			if raw&signBit != 0 {
+				value = -value
+			}
+		case hci.PCM_DATA_FORMAT_ONES_COMPLEMENT:
+			value = int32(raw)
+			if raw&signBit != 0 {
+				value = value - int32(signBit)*2 + 1
+			}
+		default:
+			value = int32(raw)
+			if raw&signBit != 0 {
+				value = value - int32(signBit)*2
+			}
+		}
+		pcm = append(pcm, int16(value<<uint(16-sampleSize)))
+	}
+	return pcm
+}
+
+// 检查H2 header
+// HFP 1.7 5.7.1 H2 Synchronization Header
+func isMsbcH2Header(buf []byte) bool {
+	if len(buf) < codec.MSBC_H2_HEADER_LEN || buf[0] != codec.MSBC_H2_HEADER_SYNC {
+		return false
+	}
+	for _, seq := range codec.MsbcH2HeaderSeqList {
+		if buf[1] == seq {
+			return true
+		}
+	}
+	return false
+}
+
+// 从透传数据中查找mSBC帧解码，无法解码的数据按丢帧补静音
+func msbcStreamDecode(buf []byte) []int16 {
+	dec := codec.NewSbcDecoder()
+	pcm := []int16{}
+	lostLen := 0
+	index := 0
+	for index+codec.MSBC_FRAME_LEN <= len(buf) {
+		frameIndex := -1
+		if isMsbcH2Header(buf[index:]) && index+codec.MSBC_H2_HEADER_LEN+codec.MSBC_FRAME_LEN <= len(buf) {
+			frameIndex = index + codec.MSBC_H2_HEADER_LEN
+		} else if codec.IsMsbcFrame(buf[index:]) {
+			frameIndex = index
+		}
+		if frameIndex >= 0 {
+			samples, err := dec.MsbcFrameDecode(buf[frameIndex : frameIndex+codec.MSBC_FRAME_LEN])
+			if err == nil {
+				if len(pcm) > 0 {
+					pcm = append(pcm, make([]int16, lostLen/codec.MSBC_H2_PACKET_LEN*codec.MSBC_SAMPLES_PER_FRAME)...)
+				}
+				lostLen = 0
+				pcm = append(pcm, samples...)
+				index = frameIndex + codec.MSBC_FRAME_LEN
+				continue
+			}
+		}
+		index++
+		lostLen++
+	}
+	return pcm
+}
+
+// 解码某个方向的通话音频，返回16bit PCM和采样率
+func (call *ScoCall) Decode(received bool) ([]int16, int, error) {
+	format := call.DataFormat(received)
+	frameList := call.TxFrameList
+	if received {
+		frameList = call.RxFrameList
+	}
+	switch format.Codec {
+	case SCO_CODEC_LINEAR_PCM:
+		return linearPcmDecode(scoFrameListConcat(frameList, format.SampleSize/8), format.SampleSize, format.PcmDataFormat), format.SampleRate, nil
+	case SCO_CODEC_U_LAW, SCO_CODEC_A_LAW:
+		buf := scoFrameListConcat(frameList, 1)
+		pcm := make([]int16, len(buf))
+		for index, value := range buf {
+			if format.Codec == SCO_CODEC_U_LAW {
+				pcm[index] = codec.ULawDecode(value)
+			} else {
+				pcm[index] = codec.ALawDecode(value)
+			}
+		}
+		return pcm, format.SampleRate, nil
+	case SCO_CODEC_CVSD:
+		return codec.NewCvsdDecoder().Decode(scoFrameListConcat(frameList, 1)), codec.CVSD_SAMPLE_RATE, nil
+	case SCO_CODEC_MSBC:
+		return msbcStreamDecode(scoFrameListConcat(frameList, 1)), codec.MSBC_SAMPLE_RATE, nil
+	}
+	return nil, 0, fmt.Errorf("sco codec not support: %s", ScoCodecStrMap[format.Codec])
+}
+
+// 每路通话每个方向输出一个WAV文件，返回输出的文件列表
+// 文件名: sco_<通话序号>_<handle>_<tx|rx>.wav，不支持解码的编码(LC3等)跳过
+func (tracker *ScoTracker) ExportWav(dirPath string) ([]string, error) {
+	filePathList := []string{}
+	for callIndex, call := range tracker.CallList {
+		for _, received := range []bool{false, true} {
+			if (received && len(call.RxFrameList) <= 0) || (!received && len(call.TxFrameList) <= 0) {
+				continue
+			}
+			pcm, sampleRate, err := call.Decode(received)
+			if err != nil || len(pcm) <= 0 {
+				continue
+			}
+			direction := "tx"
+			if received {
+				direction = "rx"
+			}
+			filePath := filepath.Join(dirPath, fmt.Sprintf("sco_%d_%04x_%s.wav", callIndex, call.ConnectionHandle, direction))
+			if err := codec.WavFileWrite(filePath, sampleRate, pcm); err != nil {
+				return filePathList, err
+			}
+			filePathList = append(filePathList, filePath)
+		}
+	}
+	return filePathList, nil
+}
@@ -0,0 +1,126 @@
+// 失败状态汇总
+// 1. 遍历所有已解析事件中名为Status的hci.HciStatus字段，记录非Success的值及其上下文
+// 2. Command Complete的Status和Connection_Handle取自按命令解析后的Return_Parameters，未解析时取Return_Parameters第一个字节
+// 3. Reason(如Disconnection Complete的0x13/0x16)表示断开原因而非失败，单独记录在ReasonList
+
+package analyzer
+
+import (
+	"reflect"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+var hciStatusType = reflect.TypeOf(hci.HCI_STATUS_SUCCESS)
+
+// 一个非Success的状态
+type StatusFailure struct {
+	RecordIndex         int
+	TimestampUs         uint64
+	EventCode           uint8
+	SubEventCode        uint8
+	CommandOpCode       uint16 // Command Complete/Command Status对应的命令，其他事件为0
+	ConnectionHandle    uint16
+	HasConnectionHandle bool        // 事件中是否带Connection_Handle
+	Field               string      // 字段名，Status或Reason
+	Ret                 interface{} // Command Complete按命令解析后的Return_Parameters，如hci.HciConnectionHandleRetParam
+	Status              hci.HciStatus
+	Evt                 interface{} // 事件解析结果，如hci.DisconnectionCompleteEvent
+}
+
+// 失败状态收集
+type StatusCollector struct {
+	FailureList []StatusFailure
+	ReasonList  []StatusFailure // 非Success的Reason字段
+}
+
+func NewStatusCollector() *StatusCollector {
+	return &StatusCollector{}
+}
+
+func (collector *StatusCollector) Feed(record Record) {
+	_, evt, ok := record.EvtParseResult()
+	if !ok || evt.Ret == nil {
+		return
+	}
+	failure := StatusFailure{
+		RecordIndex:  record.Index,
+		TimestampUs:  record.TimestampUs,
+		EventCode:    evt.EventCode,
+		SubEventCode: evt.SubEventCode,
+		Evt:          evt.Ret,
+	}
+	value := reflect.ValueOf(evt.Ret)
+	switch pkt := evt.Ret.(type) {
+	case hci.CommandCompleteEvent:
+		failure.CommandOpCode = pkt.CommandOpCode
+		if pkt.ReturnParsedResult.Code != hci.HCI_PKT_RET_CODE_OK || pkt.ReturnParsedResult.Ret == nil {
+			if len(pkt.ReturnParameters) > 0 {
+				collector.statusAdd(failure, "Status", pkt.Status)
+			}
+			return
+		}
+		failure.Ret = pkt.ReturnParsedResult.Ret
+		value = reflect.ValueOf(pkt.ReturnParsedResult.Ret)
+	case hci.CommandStatusEvent:
+		failure.CommandOpCode = pkt.CommandOpCode
+	}
+	if handle, ok := statusConnectionHandleGet(value); ok {
+		failure.ConnectionHandle, failure.HasConnectionHandle = handle, true
+	}
+	statusFieldWalk(value, func(field string, status hci.HciStatus) {
+		switch field {
+		case "Status":
+			collector.statusAdd(failure, field, status)
+		case "Reason":
+			if status != hci.HCI_STATUS_SUCCESS {
+				failure.Field, failure.Status = field, status
+				collector.ReasonList = append(collector.ReasonList, failure)
+			}
+		}
+	})
+}
+
+func (collector *StatusCollector) statusAdd(failure StatusFailure, field string, status hci.HciStatus) {
+	if status == hci.HCI_STATUS_SUCCESS {
+		return
+	}
+	failure.Field, failure.Status = field, status
+	collector.FailureList = append(collector.FailureList, failure)
+}
+
+// 按Status取值分组
+func (collector *StatusCollector) FailureListByStatus() map[hci.HciStatus][]StatusFailure {
+	failureMap := map[hci.HciStatus][]StatusFailure{}
+	for _, failure := range collector.FailureList {
+		failureMap[failure.Status] = append(failureMap[failure.Status], failure)
+	}
+	return failureMap
+}
+
+// 遍历结构体(包括匿名嵌入的结构体)中所有hci.HciStatus字段
+func statusFieldWalk(value reflect.Value, visit func(field string, status hci.HciStatus)) {
+	if value.Kind() != reflect.Struct {
+		return
+	}
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Type().Field(index)
+		switch {
+		case field.Type == hciStatusType:
+			visit(field.Name, hci.HciStatus(value.Field(index).Uint()))
+		case field.Anonymous:
+			statusFieldWalk(value.Field(index), visit)
+		}
+	}
+}
+
+func statusConnectionHandleGet(value reflect.Value) (uint16, bool) {
+	if value.Kind() != reflect.Struct {
+		return 0, false
+	}
+	field := value.FieldByName("ConnectionHandle")
+	if !field.IsValid() || field.Kind() != reflect.Uint16 {
+		return 0, false
+	}
+	return uint16(field.Uint()), true
+}
@@ -0,0 +1,66 @@
+package analyzer
+
+import (
+	"testing"
+)
+
+func TestStatusCollectorFeed(t *testing.T) {
+	testList := []struct {
+		name        string
+		evtBuf      []byte
+		wantFailure []StatusFailure
+		wantReason  []StatusFailure
+	}{
+		{
+			name:       "disconnection complete, remote user terminated",
+			evtBuf:     []byte{0x05, 0x04, 0x00, 0x40, 0x00, 0x13},
+			wantReason: []StatusFailure{{Field: "Reason", Status: 0x13, ConnectionHandle: 0x0040, HasConnectionHandle: true}},
+		},
+		{
+			name:        "disconnection complete failed",
+			evtBuf:      []byte{0x05, 0x04, 0x0c, 0x40, 0x00, 0x16},
+			wantFailure: []StatusFailure{{Field: "Status", Status: 0x0c, ConnectionHandle: 0x0040, HasConnectionHandle: true}},
+			wantReason:  []StatusFailure{{Field: "Reason", Status: 0x16, ConnectionHandle: 0x0040, HasConnectionHandle: true}},
+		},
+		{
+			name:        "command complete, parsed return parameters",
+			evtBuf:      []byte{0x0e, 0x07, 0x01, 0x05, 0x14, 0x02, 0x40, 0x00, 0xc4},
+			wantFailure: []StatusFailure{{Field: "Status", Status: 0x02, CommandOpCode: 0x1405, ConnectionHandle: 0x0040, HasConnectionHandle: true}},
+		},
+		{
+			name:        "command complete, unparsed return parameters",
+			evtBuf:      []byte{0x0e, 0x04, 0x01, 0x03, 0x0c, 0x0c},
+			wantFailure: []StatusFailure{{Field: "Status", Status: 0x0c, CommandOpCode: 0x0c03}},
+		},
+		{
+			name:   "command complete, success",
+			evtBuf: []byte{0x0e, 0x07, 0x01, 0x05, 0x14, 0x00, 0x40, 0x00, 0xc4},
+		},
+		{
+			name:   "command complete, no return parameters",
+			evtBuf: []byte{0x0e, 0x03, 0x01, 0x00, 0x00},
+		},
+	}
+	for _, test := range testList {
+		collector := NewStatusCollector()
+		collector.Feed(evtTestRecord(1, test.evtBuf))
+		statusFailureListCheck(t, test.name+" FailureList", collector.FailureList, test.wantFailure)
+		statusFailureListCheck(t, test.name+" ReasonList", collector.ReasonList, test.wantReason)
+	}
+}
+
+func statusFailureListCheck(t *testing.T, name string, gotList []StatusFailure, wantList []StatusFailure) {
+	if len(gotList) != len(wantList) {
+		t.Errorf("%s: got %d entries, want %d", name, len(gotList), len(wantList))
+		return
+	}
+	for index, want := range wantList {
+		got := gotList[index]
+		if got.Field != want.Field || got.Status != want.Status || got.CommandOpCode != want.CommandOpCode ||
+			got.ConnectionHandle != want.ConnectionHandle || got.HasConnectionHandle != want.HasConnectionHandle {
+			t.Errorf("%s[%d]: got %s %#x opcode %#x handle %#x/%v, want %s %#x opcode %#x handle %#x/%v", name, index,
+				got.Field, uint8(got.Status), got.CommandOpCode, got.ConnectionHandle, got.HasConnectionHandle,
+				want.Field, uint8(want.Status), want.CommandOpCode, want.ConnectionHandle, want.HasConnectionHandle)
+		}
+	}
+}
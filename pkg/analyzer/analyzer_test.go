@@ -0,0 +1,145 @@
+package analyzer
+
+import (
+	"testing"
+
+	"wangdalian/btsnooper/pkg/hci"
+)
+
+// host发送的命令记录
+func cmdTestRecord(index int, buf []byte) Record {
+	return Record{Index: index, PacketFlags: 0x02, Parsed: hci.HciPktParse(hci.PKT_TYPE_HCI_CMD, buf)}
+}
+
+// controller上报的事件记录
+func evtTestRecord(index int, buf []byte) Record {
+	return Record{Index: index, PacketFlags: 0x03, Parsed: hci.HciPktParse(hci.PKT_TYPE_HCI_EVT, buf)}
+}
+
+// handle 0x0040上的ACL记录，received为controller上报
+func aclTestRecord(index int, received bool, pbFlag uint8, data []byte) Record {
+	packetFlags := uint32(0x00)
+	if received {
+		packetFlags = 0x01
+	}
+	buf := append([]byte{0x40, 0x00 | pbFlag<<4, byte(len(data)), byte(len(data) >> 8)}, data...)
+	return Record{Index: index, PacketFlags: packetFlags, Parsed: hci.HciPktParse(hci.PKT_TYPE_HCI_ACL, buf)}
+}
+
+// controller上报的ISO数据记录
+func isoTestRecord(index int, buf []byte) Record {
+	return Record{Index: index, PacketFlags: 0x01, Parsed: hci.HciPktParse(hci.PKT_TYPE_HCI_ISO, buf)}
+}
+
+// ACL记录的L2CAP解析结果
+func aclTestParsed(t *testing.T, record Record) hci.HciAclPktParseResult {
+	acl, ok := record.Acl()
+	if !ok {
+		t.Fatalf("record %d: not an acl record", record.Index)
+	}
+	parsed, _ := acl.PayloadParsedResult.(hci.HciAclPktParseResult)
+	return parsed
+}
+
+func TestL2capReassemble(t *testing.T) {
+	const (
+		start        = hci.HCI_ACL_PB_FLAG_FIRST_FLUSHABLE
+		continuation = hci.HCI_ACL_PB_FLAG_CONTINUING_FRAGMENT
+	)
+	type aclWant struct {
+		code   int   // L2CAP解析结果Code
+		opCode uint8 // 仅code为HCI_PKT_RET_CODE_OK时检查
+		err    bool  // 是否有L2capReassemblyErr
+	}
+	fragment := aclWant{code: hci.HCI_PKT_RET_CODE_FRAGMENT}
+	writeRequest := aclWant{code: hci.HCI_PKT_RET_CODE_OK, opCode: hci.ATT_WRITE_REQUEST}
+	disconnectionComplete := evtTestRecord(0, []byte{0x05, 0x04, 0x00, 0x40, 0x00, 0x13})
+	testList := []struct {
+		name       string
+		recordList []Record
+		wantMap    map[int]aclWant // recordList位置 -> 期望结果，事件记录只检查err
+	}{
+		{
+			name:       "complete first fragment",
+			recordList: []Record{aclTestRecord(0, true, start, []byte{0x05, 0x00, 0x04, 0x00, 0x12, 0x03, 0x00, 0xaa, 0xbb})},
+			wantMap:    map[int]aclWant{0: writeRequest},
+		},
+		{
+			name: "first fragment and continuation",
+			recordList: []Record{
+				aclTestRecord(0, true, start, []byte{0x05, 0x00, 0x04, 0x00, 0x12, 0x03}),
+				aclTestRecord(1, true, continuation, []byte{0x00, 0xaa, 0xbb}),
+			},
+			wantMap: map[int]aclWant{0: fragment, 1: writeRequest},
+		},
+		{
+			name: "basic l2cap header split across fragments",
+			recordList: []Record{
+				aclTestRecord(0, true, start, []byte{0x05, 0x00}),
+				aclTestRecord(1, true, continuation, []byte{0x04, 0x00, 0x12}),
+				aclTestRecord(2, true, continuation, []byte{0x03, 0x00, 0xaa, 0xbb}),
+			},
+			wantMap: map[int]aclWant{0: fragment, 1: fragment, 2: writeRequest},
+		},
+		{
+			name: "directions reassembled separately",
+			recordList: []Record{
+				aclTestRecord(0, false, start, []byte{0x05, 0x00, 0x04, 0x00, 0x12, 0x03}),
+				aclTestRecord(1, true, start, []byte{0x03, 0x00, 0x04, 0x00, 0x12, 0x03, 0x00}),
+				aclTestRecord(2, false, continuation, []byte{0x00, 0xaa, 0xbb}),
+			},
+			wantMap: map[int]aclWant{0: fragment, 1: writeRequest, 2: writeRequest},
+		},
+		{
+			name:       "orphan continuation",
+			recordList: []Record{aclTestRecord(0, true, continuation, []byte{0x00, 0xaa, 0xbb})},
+			wantMap:    map[int]aclWant{0: {code: hci.HCI_PKT_RET_CODE_FRAGMENT, err: true}},
+		},
+		{
+			name: "truncated by new first fragment",
+			recordList: []Record{
+				aclTestRecord(0, true, start, []byte{0x05, 0x00, 0x04, 0x00, 0x12}),
+				aclTestRecord(1, true, start, []byte{0x03, 0x00, 0x04, 0x00, 0x12, 0x03, 0x00}),
+			},
+			wantMap: map[int]aclWant{0: fragment, 1: {code: hci.HCI_PKT_RET_CODE_OK, opCode: hci.ATT_WRITE_REQUEST, err: true}},
+		},
+		{
+			name: "truncated by disconnection",
+			recordList: []Record{
+				aclTestRecord(0, true, start, []byte{0x05, 0x00, 0x04, 0x00, 0x12}),
+				disconnectionComplete,
+			},
+			wantMap: map[int]aclWant{0: fragment, 1: {err: true}},
+		},
+		{
+			name:       "truncated at end of capture",
+			recordList: []Record{aclTestRecord(0, true, start, []byte{0x05, 0x00, 0x04, 0x00, 0x12})},
+			wantMap:    map[int]aclWant{0: {code: hci.HCI_PKT_RET_CODE_FRAGMENT, err: true}},
+		},
+		{
+			name: "continuation longer than basic l2cap header length",
+			recordList: []Record{
+				aclTestRecord(0, true, start, []byte{0x05, 0x00, 0x04, 0x00, 0x12, 0x03}),
+				aclTestRecord(1, true, continuation, []byte{0x00, 0xaa, 0xbb, 0xcc}),
+			},
+			// 多余的数据丢弃，按Length解析
+			wantMap: map[int]aclWant{0: fragment, 1: {code: hci.HCI_PKT_RET_CODE_OK, opCode: hci.ATT_WRITE_REQUEST, err: true}},
+		},
+	}
+	for _, test := range testList {
+		L2capReassemble(test.recordList)
+		for pos, want := range test.wantMap {
+			record := test.recordList[pos]
+			if gotErr := record.L2capReassemblyErr != nil; gotErr != want.err {
+				t.Errorf("%s: record %d L2capReassemblyErr = %v, want error %v", test.name, pos, record.L2capReassemblyErr, want.err)
+			}
+			if _, ok := record.Acl(); !ok {
+				continue
+			}
+			parsed := aclTestParsed(t, record)
+			if parsed.Code != want.code || want.code == hci.HCI_PKT_RET_CODE_OK && parsed.OpCode != want.opCode {
+				t.Errorf("%s: record %d parsed code %d opcode %#x, want code %d opcode %#x", test.name, pos, parsed.Code, parsed.OpCode, want.code, want.opCode)
+			}
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
+	"time"
 )
 
 // BtsnooperFileHeader DataType 类型定义
@@ -38,6 +39,15 @@ var (
 	BTSOP_VERNUM uint32 = 0x01
 )
 
+// PacketRecord PacketFlags 定义
+const (
+	PACKET_FLAG_DIRECTION_RECEIVED = 0x01 // 0: Sent(host -> controller), 1: Received(controller -> host)
+	PACKET_FLAG_COMMAND_EVENT      = 0x02 // 0: Data, 1: Command/Event
+)
+
+// 时间戳(PacketRecord.TimestampMs字段，单位实际为微秒)从公元0年1月1日开始，该值为到Unix时间起点的微秒数
+const TIMESTAMP_UNIX_EPOCH_OFFSET_US = 0x00DCDDB30F2F8000
+
 // 文件头定义
 type FileHeader struct {
 	Identy   [8]byte // 文件标识，固定为：62 74 73 6E 6F 6F 70 00
@@ -55,6 +65,27 @@ type PacketRecord struct {
 	Payload     []byte
 }
 
+// 是否是controller上报的数据
+func (pkt PacketRecord) IsReceived() bool {
+	return PacketFlagsIsReceived(pkt.PacketFlags)
+}
+
+// 时间戳转换为time.Time
+func (pkt PacketRecord) Time() time.Time {
+	return TimestampTime(pkt.TimestampMs)
+}
+
+// PacketFlags方向为controller -> host
+func PacketFlagsIsReceived(packetFlags uint32) bool {
+	return packetFlags&PACKET_FLAG_DIRECTION_RECEIVED != 0
+}
+
+// btsnoop时间戳(微秒)转换为time.Time
+func TimestampTime(timestampUs uint64) time.Time {
+	us := int64(timestampUs - TIMESTAMP_UNIX_EPOCH_OFFSET_US)
+	return time.Unix(us/1000000, us%1000000*1000)
+}
+
 // 解析后的内容
 type FileParser struct {
 	FileHeader       FileHeader     // 文件头
@@ -0,0 +1,40 @@
+package btsnoop
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacketRecordTime(t *testing.T) {
+	testList := []struct {
+		name        string
+		timestampUs uint64
+		want        time.Time
+	}{
+		{"unix epoch", TIMESTAMP_UNIX_EPOCH_OFFSET_US, time.Unix(0, 0)},
+		{"data/btsnoop_hci.log first record", 63815001455923245, time.Date(2022, 3, 8, 13, 17, 35, 923245000, time.UTC)},
+	}
+	for _, test := range testList {
+		got := PacketRecord{TimestampMs: test.timestampUs}.Time()
+		if !got.Equal(test.want) {
+			t.Errorf("%s: Time() = %v, want %v", test.name, got.UTC(), test.want)
+		}
+	}
+}
+
+func TestPacketRecordIsReceived(t *testing.T) {
+	testList := []struct {
+		packetFlags uint32
+		want        bool
+	}{
+		{0x00, false},
+		{PACKET_FLAG_DIRECTION_RECEIVED, true},
+		{PACKET_FLAG_COMMAND_EVENT, false},
+		{PACKET_FLAG_COMMAND_EVENT | PACKET_FLAG_DIRECTION_RECEIVED, true},
+	}
+	for _, test := range testList {
+		if got := (PacketRecord{PacketFlags: test.packetFlags}).IsReceived(); got != test.want {
+			t.Errorf("PacketFlags %#x: IsReceived() = %v, want %v", test.packetFlags, got, test.want)
+		}
+	}
+}
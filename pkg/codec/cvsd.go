@@ -0,0 +1,79 @@
+// CVSD解码
+
+package codec
+
+// CVSD参数
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part B] 9.2 CVSD CODEC
+const (
+	CVSD_BIT_RATE         = 64000
+	CVSD_DECIMATION       = 8 // 64kHz解码后降采样到8kHz
+	CVSD_SAMPLE_RATE      = CVSD_BIT_RATE / CVSD_DECIMATION
+	cvsdJ                 = 4
+	cvsdK                 = 4
+	cvsdStepMin           = 10
+	cvsdStepMax           = 1280
+	cvsdAccumulatorMax    = 32767
+	cvsdAccumulatorMin    = -32768
+	cvsdAccumulatorDecay  = 1.0 - 1.0/32
+	cvsdStepDecay         = 1.0 - 1.0/1024
+	cvsdRunMask           = (1 << cvsdK) - 1
+	cvsdRunAllOnes        = (1 << cvsdJ) - 1
+	cvsdBitsPerByte       = 8
+	cvsdSamplePerByte8kHz = cvsdBitsPerByte / CVSD_DECIMATION
+)
+
+// CVSD解码器，保存跨包的累加器状态
+type CvsdDecoder struct {
+	accumulator float64
+	step        float64
+	history     uint8 // 最近K个bit
+}
+
+func NewCvsdDecoder() *CvsdDecoder {
+	return &CvsdDecoder{step: cvsdStepMin}
+}
+
+// 解码CVSD bit流(LSB first)，输出8kHz 16bit PCM
+func (dec *CvsdDecoder) Decode(buf []byte) []int16 {
+	pcm := make([]int16, 0, len(buf)*cvsdSamplePerByte8kHz)
+	var sum float64
+	count := 0
+	for _, value := range buf {
+		for bit := 0; bit < cvsdBitsPerByte; bit++ {
+			b := (value >> uint(bit)) & 0x01
+			dec.history = ((dec.history << 1) | b) & cvsdRunMask
+			if dec.history == 0 || dec.history == cvsdRunAllOnes {
+				dec.step += cvsdStepMin
+				if dec.step > cvsdStepMax {
+					dec.step = cvsdStepMax
+				}
+			} else {
+				dec.step *= cvsdStepDecay
+				if dec.step < cvsdStepMin {
+					dec.step = cvsdStepMin
+				}
+			}
+			if b != 0 {
+				dec.accumulator += dec.step
+			} else {
+				dec.accumulator -= dec.step
+			}
+			if dec.accumulator > cvsdAccumulatorMax {
+				dec.accumulator = cvsdAccumulatorMax
+			} else if dec.accumulator < cvsdAccumulatorMin {
+				dec.accumulator = cvsdAccumulatorMin
+			}
+			dec.accumulator *= cvsdAccumulatorDecay
+
+			// 简单均值滤波后降采样
+			sum += dec.accumulator
+			count++
+			if count == CVSD_DECIMATION {
+				pcm = append(pcm, int16(sum/CVSD_DECIMATION))
+				sum = 0
+				count = 0
+			}
+		}
+	}
+	return pcm
+}
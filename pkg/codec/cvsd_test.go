@@ -0,0 +1,36 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCvsdDecode(t *testing.T) {
+	testList := []struct {
+		name string
+		buf  []byte
+		want []int16
+	}{
+		{"empty", []byte{}, []int16{}},
+		{"all ones ramp up", []byte{0xFF, 0xFF, 0xFF, 0xFF}, []int16{81, 562, 1490, 2767}},
+		{"all zeros ramp down", []byte{0x00, 0x00, 0x00, 0x00}, []int16{-178, -845, -1918, -3307}},
+		{"alternating bits stay near zero", []byte{0x55, 0x55, 0x55, 0x55, 0x55, 0x55}, []int16{4, 3, 2, 1, 1, 1}},
+	}
+	for _, test := range testList {
+		got := NewCvsdDecoder().Decode(test.buf)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: Decode() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// 解码器状态跨包保持，分包解码与整包解码结果一致
+func TestCvsdDecodeSplit(t *testing.T) {
+	buf := []byte{0xFF, 0x0F, 0x00, 0x3C, 0x55, 0xAA, 0xF0, 0x01}
+	want := NewCvsdDecoder().Decode(buf)
+	dec := NewCvsdDecoder()
+	got := append(dec.Decode(buf[:3]), dec.Decode(buf[3:])...)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("split Decode() = %v, want %v", got, want)
+	}
+}
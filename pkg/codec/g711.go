@@ -0,0 +1,35 @@
+// G.711 u-law/A-law解码
+
+package codec
+
+// ITU-T G.711 u-law转16bit线性PCM
+func ULawDecode(sample uint8) int16 {
+	sample = ^sample
+	sign := sample & 0x80
+	exponent := (sample >> 4) & 0x07
+	mantissa := sample & 0x0f
+	magnitude := ((int32(mantissa) << 3) + 0x84) << exponent
+	magnitude -= 0x84
+	if sign != 0 {
+		return int16(-magnitude)
+	}
+	return int16(magnitude)
+}
+
+// ITU-T G.711 A-law转16bit线性PCM
+func ALawDecode(sample uint8) int16 {
+	sample ^= 0x55
+	sign := sample & 0x80
+	exponent := (sample >> 4) & 0x07
+	mantissa := int32(sample & 0x0f)
+	var magnitude int32
+	if exponent == 0 {
+		magnitude = (mantissa << 4) + 8
+	} else {
+		magnitude = ((mantissa << 4) + 0x108) << (exponent - 1)
+	}
+	if sign == 0 {
+		return int16(-magnitude)
+	}
+	return int16(magnitude)
+}
@@ -0,0 +1,41 @@
+package codec
+
+import "testing"
+
+func TestULawDecode(t *testing.T) {
+	testList := []struct {
+		sample uint8
+		want   int16
+	}{
+		{0xFF, 0},
+		{0x7F, 0},
+		{0x80, 32124},
+		{0x00, -32124},
+		{0xF0, 120},
+		{0x70, -120},
+	}
+	for _, test := range testList {
+		if got := ULawDecode(test.sample); got != test.want {
+			t.Errorf("ULawDecode(%#02x) = %d, want %d", test.sample, got, test.want)
+		}
+	}
+}
+
+func TestALawDecode(t *testing.T) {
+	testList := []struct {
+		sample uint8
+		want   int16
+	}{
+		{0xD5, 8},
+		{0x55, -8},
+		{0xAA, 32256},
+		{0x2A, -32256},
+		{0xC5, 264},
+		{0x45, -264},
+	}
+	for _, test := range testList {
+		if got := ALawDecode(test.sample); got != test.want {
+			t.Errorf("ALawDecode(%#02x) = %d, want %d", test.sample, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// mSBC解码
+
+package codec
+
+import (
+	"fmt"
+	"math"
+)
+
+// mSBC固定参数: 16kHz, Mono, 15 blocks, 8 subbands, Loudness, bitpool 26
+// HFP 1.7 5.7.4 mSBC coding
+const (
+	MSBC_SAMPLE_RATE       = 16000
+	MSBC_SYNC_WORD         = 0xAD
+	MSBC_FRAME_LEN         = 57
+	MSBC_BLOCKS            = 15
+	MSBC_SUBBANDS          = 8
+	MSBC_BITPOOL           = 26
+	MSBC_SAMPLES_PER_FRAME = MSBC_BLOCKS * MSBC_SUBBANDS
+)
+
+// HFP 1.7 5.7.1 H2 Synchronization Header: 0x01 + 序号(SN0/SN1双写)
+const (
+	MSBC_H2_HEADER_LEN   = 2
+	MSBC_H2_HEADER_SYNC  = 0x01
+	MSBC_H2_PACKET_LEN   = 60 // H2 header + mSBC帧 + 1字节填充
+	msbcScaleFactorStart = 4
+	sbcCrcInit           = 0x0f
+	sbcCrcPolynomial     = 0x1d
+	sbcSynthesisGain     = -8 // 合成窗口系数 D[i] = -8 * C[i]
+)
+
+// H2 header第二字节，低4bit固定为8，高4bit为序号
+var MsbcH2HeaderSeqList = [4]uint8{0x08, 0x38, 0xC8, 0xF8}
+
+// 16kHz 8 subbands的loudness偏移
+// A2DP 1.3 12.6.3 Bit Allocation
+var sbcOffset8Fs16 = [MSBC_SUBBANDS]int{-2, 0, 0, 0, 0, 0, 0, 1}
+
+// 8 subbands原型滤波器系数
+// A2DP 1.3 12.8 Table 12.24 Proto_8_80
+var sbcProto8 = [80]float64{
+	0.00000000e+00, 1.56575398e-04, 3.43256425e-04, 5.54620202e-04,
+	8.23919506e-04, 1.13992507e-03, 1.47640169e-03, 1.78371725e-03,
+	2.01182542e-03, 2.10371989e-03, 1.99454554e-03, 1.61656283e-03,
+	9.02154502e-04, -1.78805361e-04, -1.64973098e-03, -3.49717454e-03,
+	5.65949473e-03, 8.02941163e-03, 1.04584443e-02, 1.27472335e-02,
+	1.46525263e-02, 1.59045603e-02, 1.62208471e-02, 1.53184106e-02,
+	1.29371806e-02, 8.85757540e-03, 2.92408442e-03, -4.91578024e-03,
+	-1.46404076e-02, -2.61098752e-02, -3.90751381e-02, -5.31873032e-02,
+	6.79989431e-02, 8.29847578e-02, 9.75753918e-02, 1.11196689e-01,
+	1.23264548e-01, 1.33264415e-01, 1.40753505e-01, 1.45389847e-01,
+	1.46955068e-01, 1.45389847e-01, 1.40753505e-01, 1.33264415e-01,
+	1.23264548e-01, 1.11196689e-01, 9.75753918e-02, 8.29847578e-02,
+	-6.79989431e-02, -5.31873032e-02, -3.90751381e-02, -2.61098752e-02,
+	-1.46404076e-02, -4.91578024e-03, 2.92408442e-03, 8.85757540e-03,
+	1.29371806e-02, 1.53184106e-02, 1.62208471e-02, 1.59045603e-02,
+	1.46525263e-02, 1.27472335e-02, 1.04584443e-02, 8.02941163e-03,
+	-5.65949473e-03, -3.49717454e-03, -1.64973098e-03, -1.78805361e-04,
+	9.02154502e-04, 1.61656283e-03, 1.99454554e-03, 2.10371989e-03,
+	2.01182542e-03, 1.78371725e-03, 1.47640169e-03, 1.13992507e-03,
+	8.23919506e-04, 5.54620202e-04, 3.43256425e-04, 1.56575398e-04,
+}
+
+// SBC解码器，保存合成滤波器状态
+type SbcDecoder struct {
+	v [160]float64
+}
+
+func NewSbcDecoder() *SbcDecoder {
+	return &SbcDecoder{}
+}
+
+type sbcBitReader struct {
+	buf    []byte
+	bitPos int
+}
+
+func (r *sbcBitReader) read(bits int) (int, error) {
+	value := 0
+	for index := 0; index < bits; index++ {
+		if r.bitPos/8 >= len(r.buf) {
+			return 0, fmt.Errorf("sbc frame too short")
+		}
+		bit := (r.buf[r.bitPos/8] >> uint(7-r.bitPos%8)) & 0x01
+		value = (value << 1) | int(bit)
+		r.bitPos++
+	}
+	return value, nil
+}
+
+// A2DP 1.3 12.6.4 CRC Check
+func sbcCrc8(buf []byte, bitLen int) uint8 {
+	crc := uint8(sbcCrcInit)
+	for index := 0; index < bitLen; index++ {
+		bit := (buf[index/8] >> uint(7-index%8)) & 0x01
+		top := crc >> 7
+		crc <<= 1
+		if top^bit != 0 {
+			crc ^= sbcCrcPolynomial
+		}
+	}
+	return crc
+}
+
+// Mono Loudness bit分配
+// A2DP 1.3 12.6.3 Bit Allocation
+func sbcBitAllocation(scaleFactorList [MSBC_SUBBANDS]int, bitpool int) [MSBC_SUBBANDS]int {
+	bitneed := [MSBC_SUBBANDS]int{}
+	maxBitneed := 0
+	for sb := 0; sb < MSBC_SUBBANDS; sb++ {
+		if scaleFactorList[sb] == 0 {
+			bitneed[sb] = -5
+		} else {
+			loudness := scaleFactorList[sb] - sbcOffset8Fs16[sb]
+			if loudness > 0 {
+				bitneed[sb] = loudness / 2
+			} else {
+				bitneed[sb] = loudness
+			}
+		}
+		if bitneed[sb] > maxBitneed {
+			maxBitneed = bitneed[sb]
+		}
+	}
+
+	bitcount := 0
+	slicecount := 0
+	bitslice := maxBitneed + 1
+	for {
+		bitslice--
+		bitcount += slicecount
+		slicecount = 0
+		for sb := 0; sb < MSBC_SUBBANDS; sb++ {
+			if bitneed[sb] > bitslice+1 && bitneed[sb] < bitslice+16 {
+				slicecount++
+			} else if bitneed[sb] == bitslice+1 {
+				slicecount += 2
+			}
+		}
+		if bitcount+slicecount >= bitpool {
+			break
+		}
+	}
+	if bitcount+slicecount == bitpool {
+		bitcount += slicecount
+		bitslice--
+	}
+
+	bits := [MSBC_SUBBANDS]int{}
+	for sb := 0; sb < MSBC_SUBBANDS; sb++ {
+		if bitneed[sb] < bitslice+2 {
+			bits[sb] = 0
+		} else {
+			bits[sb] = bitneed[sb] - bitslice
+			if bits[sb] > 16 {
+				bits[sb] = 16
+			}
+		}
+	}
+	for sb := 0; bitcount < bitpool && sb < MSBC_SUBBANDS; sb++ {
+		if bits[sb] >= 2 && bits[sb] < 16 {
+			bits[sb]++
+			bitcount++
+		} else if bitneed[sb] == bitslice+1 && bitpool > bitcount+1 {
+			bits[sb] = 2
+			bitcount += 2
+		}
+	}
+	for sb := 0; bitcount < bitpool && sb < MSBC_SUBBANDS; sb++ {
+		if bits[sb] < 16 {
+			bits[sb]++
+			bitcount++
+		}
+	}
+	return bits
+}
+
+// 8 subbands合成滤波器，输入一个block的subband采样，输出8个PCM采样
+// A2DP 1.3 12.9.1 Synthesis Filter
+func (dec *SbcDecoder) synthesis(sbSampleList [MSBC_SUBBANDS]float64) [MSBC_SUBBANDS]float64 {
+	copy(dec.v[16:], dec.v[:144])
+	for k := 0; k < 16; k++ {
+		sum := 0.0
+		for i := 0; i < MSBC_SUBBANDS; i++ {
+			sum += math.Cos((float64(i)+0.5)*(float64(k)+4)*math.Pi/8) * sbSampleList[i]
+		}
+		dec.v[k] = sum
+	}
+	u := [80]float64{}
+	for i := 0; i < 5; i++ {
+		for j := 0; j < 8; j++ {
+			u[i*16+j] = dec.v[i*32+j]
+			u[i*16+8+j] = dec.v[i*32+24+j]
+		}
+	}
+	out := [MSBC_SUBBANDS]float64{}
+	for j := 0; j < MSBC_SUBBANDS; j++ {
+		sum := 0.0
+		for i := 0; i < 10; i++ {
+			sum += u[j+8*i] * sbcProto8[j+8*i] * sbcSynthesisGain
+		}
+		out[j] = sum
+	}
+	return out
+}
+
+// 检查是否是mSBC帧头
+func IsMsbcFrame(frame []byte) bool {
+	return len(frame) >= MSBC_FRAME_LEN && frame[0] == MSBC_SYNC_WORD && frame[1] == 0x00 && frame[2] == 0x00
+}
+
+// 解码一个57字节mSBC帧，输出120个16kHz 16bit PCM采样
+// A2DP 1.3 12.6 Frame Header/Scale Factors/Audio Samples
+func (dec *SbcDecoder) MsbcFrameDecode(frame []byte) ([]int16, error) {
+	if !IsMsbcFrame(frame) {
+		return nil, fmt.Errorf("invalid msbc frame")
+	}
+	reader := &sbcBitReader{buf: frame, bitPos: msbcScaleFactorStart * 8}
+	scaleFactorList := [MSBC_SUBBANDS]int{}
+	for sb := 0; sb < MSBC_SUBBANDS; sb++ {
+		scaleFactor, err := reader.read(4)
+		if err != nil {
+			return nil, err
+		}
+		scaleFactorList[sb] = scaleFactor
+	}
+	crcBuf := []byte{frame[1], frame[2], frame[4], frame[5], frame[6], frame[7]}
+	if sbcCrc8(crcBuf, len(crcBuf)*8) != frame[3] {
+		return nil, fmt.Errorf("msbc frame crc error")
+	}
+
+	bits := sbcBitAllocation(scaleFactorList, MSBC_BITPOOL)
+	pcm := make([]int16, 0, MSBC_SAMPLES_PER_FRAME)
+	for blk := 0; blk < MSBC_BLOCKS; blk++ {
+		sbSampleList := [MSBC_SUBBANDS]float64{}
+		for sb := 0; sb < MSBC_SUBBANDS; sb++ {
+			if bits[sb] == 0 {
+				continue
+			}
+			audioSample, err := reader.read(bits[sb])
+			if err != nil {
+				return nil, err
+			}
+			levels := float64(int(1)<<uint(bits[sb]) - 1)
+			scale := math.Pow(2, float64(scaleFactorList[sb]+1))
+			sbSampleList[sb] = scale * ((float64(audioSample)*2+1)/levels - 1)
+		}
+		for _, sample := range dec.synthesis(sbSampleList) {
+			if sample > math.MaxInt16 {
+				sample = math.MaxInt16
+			} else if sample < math.MinInt16 {
+				sample = math.MinInt16
+			}
+			pcm = append(pcm, int16(sample))
+		}
+	}
+	return pcm, nil
+}
@@ -0,0 +1,63 @@
+package codec
+
+import (
+	"testing"
+)
+
+// HFP mSBC静音帧: scale factor全0，解码结果全0
+var msbcSilenceFrame = []byte{
+	0xAD, 0x00, 0x00, 0xC5, 0x00, 0x00, 0x00, 0x00, 0x77, 0x6D, 0xB6, 0xDD, 0xDB, 0x6D, 0xB7, 0x76,
+	0xDB, 0x6D, 0xDD, 0xB6, 0xDB, 0x77, 0x6D, 0xB6, 0xDD, 0xDB, 0x6D, 0xB7, 0x76, 0xDB, 0x6D, 0xDD,
+	0xB6, 0xDB, 0x77, 0x6D, 0xB6, 0xDD, 0xDB, 0x6D, 0xB7, 0x76, 0xDB, 0x6D, 0xDD, 0xB6, 0xDB, 0x77,
+	0x6D, 0xB6, 0xDD, 0xDB, 0x6D, 0xB7, 0x76, 0xDB, 0x6C,
+}
+
+func TestIsMsbcFrame(t *testing.T) {
+	testList := []struct {
+		name  string
+		frame []byte
+		want  bool
+	}{
+		{"silence frame", msbcSilenceFrame, true},
+		{"too short", msbcSilenceFrame[:MSBC_FRAME_LEN-1], false},
+		{"bad sync word", append([]byte{0x9C}, msbcSilenceFrame[1:]...), false},
+		{"reserved byte set", append([]byte{0xAD, 0x01}, msbcSilenceFrame[2:]...), false},
+	}
+	for _, test := range testList {
+		if got := IsMsbcFrame(test.frame); got != test.want {
+			t.Errorf("%s: IsMsbcFrame() = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestMsbcFrameDecode(t *testing.T) {
+	pcm, err := NewSbcDecoder().MsbcFrameDecode(msbcSilenceFrame)
+	if err != nil {
+		t.Fatalf("MsbcFrameDecode() error: %v", err)
+	}
+	if len(pcm) != MSBC_SAMPLES_PER_FRAME {
+		t.Fatalf("MsbcFrameDecode() returned %d samples, want %d", len(pcm), MSBC_SAMPLES_PER_FRAME)
+	}
+	for index, sample := range pcm {
+		if sample != 0 {
+			t.Fatalf("sample %d = %d, want 0", index, sample)
+		}
+	}
+}
+
+func TestMsbcFrameDecodeError(t *testing.T) {
+	crcError := append([]byte{}, msbcSilenceFrame...)
+	crcError[3] ^= 0xFF
+	testList := []struct {
+		name  string
+		frame []byte
+	}{
+		{"not msbc", msbcSilenceFrame[:10]},
+		{"crc error", crcError},
+	}
+	for _, test := range testList {
+		if _, err := NewSbcDecoder().MsbcFrameDecode(test.frame); err == nil {
+			t.Errorf("%s: MsbcFrameDecode() error = nil", test.name)
+		}
+	}
+}
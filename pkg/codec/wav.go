@@ -0,0 +1,49 @@
+// WAV文件输出
+
+package codec
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+)
+
+const (
+	wavHeaderLen     = 44
+	wavFmtChunkLen   = 16
+	wavFormatPcm     = 1
+	wavBitsPerSample = 16
+)
+
+// 写入单声道16bit PCM WAV
+func WavWrite(w io.Writer, sampleRate int, pcm []int16) error {
+	dataLen := uint32(len(pcm) * wavBitsPerSample / 8)
+	header := make([]byte, wavHeaderLen)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], wavHeaderLen-8+dataLen)
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], wavFmtChunkLen)
+	binary.LittleEndian.PutUint16(header[20:], wavFormatPcm)
+	binary.LittleEndian.PutUint16(header[22:], 1)
+	binary.LittleEndian.PutUint32(header[24:], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(sampleRate*wavBitsPerSample/8))
+	binary.LittleEndian.PutUint16(header[32:], wavBitsPerSample/8)
+	binary.LittleEndian.PutUint16(header[34:], wavBitsPerSample)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], dataLen)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, pcm)
+}
+
+// 写入WAV文件
+func WavFileWrite(filePath string, sampleRate int, pcm []int16) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return WavWrite(file, sampleRate, pcm)
+}
@@ -13,19 +13,28 @@ const (
 	PKT_TYPE_HCI_ACL  = 0x02
 	PKT_TYPE_HCI_SYNC = 0x03
 	PKT_TYPE_HCI_EVT  = 0x04
+	PKT_TYPE_HCI_ISO  = 0x05 // BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part A 2 PROTOCOL
 )
 
 // HCI ACL DATA数据格式
 // BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 5.4.2 HCI ACL Data Packets
 type HciAcl struct {
 	Handle              uint16
-	PbFlag              uint8
+	PbFlag              uint8 // HCI_ACL_PB_FLAG_XXX
 	BcFlag              uint8
 	DataTotalLen        uint16
 	Data                []byte
 	PayloadParsedResult interface{} // Data解析后的结果
 }
 
+// HciAcl PB_Flag
+const (
+	HCI_ACL_PB_FLAG_FIRST_NON_FLUSHABLE = 0x00 // host -> controller
+	HCI_ACL_PB_FLAG_CONTINUING_FRAGMENT = 0x01
+	HCI_ACL_PB_FLAG_FIRST_FLUSHABLE     = 0x02
+	HCI_ACL_PB_FLAG_COMPLETE_PDU        = 0x03 // 仅AMP使用
+)
+
 // BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E]
 // 5.4.1 HCI Command Packet
 type HciCmd struct {
@@ -37,10 +46,50 @@ type HciCmd struct {
 	PayloadParsedResult interface{} // Data解析后的结果
 }
 
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 5.4.3 HCI Synchronous Data Packets
 type HciSync struct {
-	// TODO
+	Handle           uint16
+	PacketStatusFlag uint8 // HCI_SYNC_PKT_STATUS_XXX
+	DataTotalLen     uint8
+	Data             []byte
 }
 
+// HciSync Packet_Status_Flag
+const (
+	HCI_SYNC_PKT_STATUS_CORRECTLY_RECEIVED = 0x00
+	HCI_SYNC_PKT_STATUS_POSSIBLY_INVALID   = 0x01
+	HCI_SYNC_PKT_STATUS_NO_DATA            = 0x02
+	HCI_SYNC_PKT_STATUS_PARTIALLY_LOST     = 0x03
+)
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 5.4.5 HCI ISO Data packets
+type HciIso struct {
+	Handle               uint16 // CIS/BIS连接handle
+	PbFlag               uint8  // HCI_ISO_PB_FLAG_XXX
+	TsFlag               uint8  // 1表示包含TimeStamp
+	DataTotalLen         uint16
+	TimeStamp            uint32 // 单位微秒，TsFlag为1时有效
+	PacketSequenceNumber uint16 // 首包/完整SDU有效
+	IsoSduLength         uint16 // 首包/完整SDU有效
+	PacketStatusFlag     uint8  // HCI_ISO_PKT_STATUS_XXX，controller上报的首包/完整SDU有效
+	Data                 []byte // ISO_SDU_Fragment
+}
+
+// HciIso PB_Flag
+const (
+	HCI_ISO_PB_FLAG_FIRST_FRAGMENT        = 0x00
+	HCI_ISO_PB_FLAG_CONTINUATION_FRAGMENT = 0x01
+	HCI_ISO_PB_FLAG_COMPLETE_SDU          = 0x02
+	HCI_ISO_PB_FLAG_LAST_FRAGMENT         = 0x03
+)
+
+// HciIso Packet_Status_Flag
+const (
+	HCI_ISO_PKT_STATUS_VALID            = 0x00
+	HCI_ISO_PKT_STATUS_POSSIBLY_INVALID = 0x01
+	HCI_ISO_PKT_STATUS_LOST             = 0x02
+)
+
 type HciEvt struct {
 	EventCode            uint8
 	ParameterTotalLength uint8
@@ -51,6 +100,8 @@ type HciEvt struct {
 const (
 	HCI_PKT_RET_CODE_OK          = 0
 	HCI_PKT_RET_CODE_NOT_SUPPORT = 1001 // 不支持
+	HCI_PKT_RET_CODE_INVALID_LEN = 1002 // 数据长度不足
+	HCI_PKT_RET_CODE_FRAGMENT    = 1003 // L2CAP PDU分片，需要重组后解析
 )
 
 type HciPktParseResult struct {
@@ -61,9 +112,11 @@ type HciPktParseResult struct {
 type HciPktParser func(hciPktType byte, hciPayloadBuf []byte) HciPktParseResult
 
 var HciPktParserMap map[int]HciPktParser = map[int]HciPktParser{
-	PKT_TYPE_HCI_ACL: HciPktAclParser,
-	PKT_TYPE_HCI_CMD: HciPktCmdParser,
-	PKT_TYPE_HCI_EVT: HciPktEvtParser,
+	PKT_TYPE_HCI_ACL:  HciPktAclParser,
+	PKT_TYPE_HCI_CMD:  HciPktCmdParser,
+	PKT_TYPE_HCI_SYNC: HciPktSyncParser,
+	PKT_TYPE_HCI_EVT:  HciPktEvtParser,
+	PKT_TYPE_HCI_ISO:  HciPktIsoParser,
 }
 
 func HciPktParse(hciPktType byte, hciPayloadBuf []byte) HciPktParseResult {
@@ -94,7 +147,7 @@ func HciPktCmdParser(hciPktType byte, hciPayloadBuf []byte) HciPktParseResult {
 	pkt := HciCmd{}
 	pkt.OpCode = binary.LittleEndian.Uint16(hciPayloadBuf)
 	pkt.OpCodeOcf = pkt.OpCode & 0x03ff
-	pkt.OpCodeOgf = uint8(pkt.OpCode >> 10 & 0xfc)
+	pkt.OpCodeOgf = uint8(pkt.OpCode >> 10 & 0x3f)
 	pkt.ParamTotalLen = hciPayloadBuf[2]
 	pkt.Data = make([]byte, pkt.ParamTotalLen)
 	copy(pkt.Data, hciPayloadBuf[3:])
@@ -102,14 +155,76 @@ func HciPktCmdParser(hciPktType byte, hciPayloadBuf []byte) HciPktParseResult {
 	return HciPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
 }
 
+// 只解析包含完整L2CAP PDU的首包，其他分片结果为HCI_PKT_RET_CODE_FRAGMENT，由L2capReassembler重组后解析
 func HciPktAclParser(hciPktType byte, hciPayloadBuf []byte) HciPktParseResult {
+	if len(hciPayloadBuf) < 4 {
+		return HciPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
 	pkt := HciAcl{}
 	pkt.Handle = binary.LittleEndian.Uint16(hciPayloadBuf) & 0x0fff
-	pkt.PbFlag = (hciPayloadBuf[0] & 0xf0) >> 0x04
-	pkt.BcFlag = (hciPayloadBuf[0] & 0xf0) >> 0x06
+	pkt.PbFlag = (hciPayloadBuf[1] >> 0x04) & 0x03
+	pkt.BcFlag = (hciPayloadBuf[1] >> 0x06) & 0x03
 	pkt.DataTotalLen = binary.LittleEndian.Uint16(hciPayloadBuf[2:])
 	pkt.Data = make([]byte, pkt.DataTotalLen)
 	copy(pkt.Data, hciPayloadBuf[4:])
-	pkt.PayloadParsedResult = HciAclPktParse(pkt.Data)
+	if L2capPduComplete(pkt) {
+		pkt.PayloadParsedResult = HciAclPktParse(pkt.Data)
+	} else {
+		pkt.PayloadParsedResult = HciAclPktParseResult{Code: HCI_PKT_RET_CODE_FRAGMENT}
+	}
+	return HciPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+func HciPktSyncParser(hciPktType byte, hciPayloadBuf []byte) HciPktParseResult {
+	if len(hciPayloadBuf) < 3 {
+		return HciPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciSync{}
+	pkt.Handle = binary.LittleEndian.Uint16(hciPayloadBuf) & 0x0fff
+	pkt.PacketStatusFlag = (hciPayloadBuf[1] >> 0x04) & 0x03
+	pkt.DataTotalLen = hciPayloadBuf[2]
+	pkt.Data = make([]byte, pkt.DataTotalLen)
+	copy(pkt.Data, hciPayloadBuf[3:])
+	return HciPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+func HciPktIsoParser(hciPktType byte, hciPayloadBuf []byte) HciPktParseResult {
+	if len(hciPayloadBuf) < 4 {
+		return HciPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciIso{}
+	pktIndex := 0
+	pkt.Handle = binary.LittleEndian.Uint16(hciPayloadBuf[pktIndex:]) & 0x0fff
+	pkt.PbFlag = (hciPayloadBuf[pktIndex+1] >> 0x04) & 0x03
+	pkt.TsFlag = (hciPayloadBuf[pktIndex+1] >> 0x06) & 0x01
+	pktIndex += binary.Size(pkt.Handle)
+	pkt.DataTotalLen = binary.LittleEndian.Uint16(hciPayloadBuf[pktIndex:]) & 0x3fff
+	pktIndex += binary.Size(pkt.DataTotalLen)
+
+	headerLen := 0
+	if pkt.TsFlag != 0 {
+		headerLen += binary.Size(pkt.TimeStamp)
+	}
+	hasSduHeader := pkt.PbFlag == HCI_ISO_PB_FLAG_FIRST_FRAGMENT || pkt.PbFlag == HCI_ISO_PB_FLAG_COMPLETE_SDU
+	if hasSduHeader {
+		headerLen += binary.Size(pkt.PacketSequenceNumber) + binary.Size(pkt.IsoSduLength)
+	}
+	if len(hciPayloadBuf[pktIndex:]) < headerLen || int(pkt.DataTotalLen) < headerLen {
+		return HciPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	if pkt.TsFlag != 0 {
+		pkt.TimeStamp = binary.LittleEndian.Uint32(hciPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(pkt.TimeStamp)
+	}
+	if hasSduHeader {
+		pkt.PacketSequenceNumber = binary.LittleEndian.Uint16(hciPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(pkt.PacketSequenceNumber)
+		sduLength := binary.LittleEndian.Uint16(hciPayloadBuf[pktIndex:])
+		pkt.IsoSduLength = sduLength & 0x0fff
+		pkt.PacketStatusFlag = uint8(sduLength>>14) & 0x03
+		pktIndex += binary.Size(sduLength)
+	}
+	pkt.Data = make([]byte, int(pkt.DataTotalLen)-headerLen)
+	copy(pkt.Data, hciPayloadBuf[pktIndex:])
 	return HciPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
 }
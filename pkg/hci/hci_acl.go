@@ -4,71 +4,198 @@ package hci
 
 import (
 	"encoding/binary"
+	"fmt"
 )
 
+// L2CAP固定信道
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part A] 2.1 CHANNEL IDENTIFIERS
 const (
-	ATT_WRITE_REQUEST = 0x12
+	L2CAP_CID_NULL           = 0x0000
+	L2CAP_CID_SIGNALING      = 0x0001 // BR/EDR信令信道
+	L2CAP_CID_CONNECTIONLESS = 0x0002
+	L2CAP_CID_AMP_MANAGER    = 0x0003
+	L2CAP_CID_ATT            = 0x0004
+	L2CAP_CID_LE_SIGNALING   = 0x0005
+	L2CAP_CID_SMP            = 0x0006
+	L2CAP_CID_BREDR_SMP      = 0x0007
+	L2CAP_CID_DYNAMIC_START  = 0x0040 // 0x0040之后为动态分配的信道
 )
 
+// ChannelId为信令信道时OpCode为信令命令码(BR/EDR信令信道为第一条命令)，L2CAP_CID_ATT时为ATT OpCode，SMP信道为SMP Code，其他信道为0
 type HciAclPktParseResult struct {
-	Code   int
-	OpCode uint8
-	Ret    interface{}
+	Code      int
+	ChannelId uint16
+	OpCode    uint8
+	Ret       interface{}
 }
 
-type AttPktParser func(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult
-
-var AttPktParserMap map[int]AttPktParser = map[int]AttPktParser{
-	ATT_WRITE_REQUEST: AttPktWriteRequestParser,
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part A] 3.1 CONNECTION-ORIENTED CHANNELS IN BASIC L2CAP MODE
+type L2capBasicFrame struct {
+	Length    uint16
+	ChannelId uint16
+	Payload   []byte // Information payload
 }
 
-func AttPktDefaultParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
-	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
-}
+// 按ChannelId解析信道上的数据，未注册的信道返回L2capBasicFrame
+type L2capChannelParser func(ChannelId uint16, l2capPayloadBuf []byte) HciAclPktParseResult
 
-type AttWriteRequest struct {
-	OpCode uint8
-	Handle uint16
-	Value  []byte
+var L2capChannelParserMap map[int]L2capChannelParser = map[int]L2capChannelParser{
+	L2CAP_CID_SIGNALING:      L2capBrEdrSignalingParse,
+	L2CAP_CID_CONNECTIONLESS: L2capConnectionlessParse,
+	L2CAP_CID_ATT:            AttPktParse,
+	L2CAP_CID_LE_SIGNALING:   L2capLeSignalingParse,
+	L2CAP_CID_SMP:            SmpPktParse,
+	L2CAP_CID_BREDR_SMP:      SmpPktParse,
 }
 
-func AttPktWriteRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
-	pkt := AttWriteRequest{}
-	pkt.OpCode = OpCode
-
-	pktIndex := 0
-	pkt.Handle = binary.LittleEndian.Uint16(attPayloadBuf)
-	pktIndex += binary.Size(pkt.Handle)
-	pkt.Value = make([]byte, len(attPayloadBuf[pktIndex:]))
-	copy(pkt.Value, attPayloadBuf[pktIndex:])
+func L2capChannelDefaultParser(ChannelId uint16, l2capPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capBasicFrame{Length: uint16(len(l2capPayloadBuf)), ChannelId: ChannelId, Payload: l2capPayloadBuf}
 	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
 }
 
 func ConnectionOrientedChannelsInBasicFrame(hciAclPktPayloadBuf []byte) HciAclPktParseResult {
+	if len(hciAclPktPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
 	pktIndex := 0
 	Length := binary.LittleEndian.Uint16(hciAclPktPayloadBuf[pktIndex:])
 	pktIndex += binary.Size(Length)
 	ChannelId := binary.LittleEndian.Uint16(hciAclPktPayloadBuf[pktIndex:])
 	pktIndex += binary.Size(ChannelId)
+	if len(hciAclPktPayloadBuf[pktIndex:]) < int(Length) {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN, ChannelId: ChannelId}
+	}
 	payloadBuf := make([]byte, Length)
 	copy(payloadBuf, hciAclPktPayloadBuf[pktIndex:])
-
-	// ATT OpCode
-	// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part F] 3.3.1 Attribute PDU Format
-	attPktIndex := 0
-	var OpCode uint8 = payloadBuf[attPktIndex] & 0x3f
-	attPktIndex += binary.Size(OpCode)
-	// AuthenticationSignature := payloadBuf[0] >> 0x07
-	attPayloadBuf := payloadBuf[attPktIndex:]
-	parser, ok := AttPktParserMap[int(OpCode)]
+	parser, ok := L2capChannelParserMap[int(ChannelId)]
 	if !ok {
-		parser = AttPktDefaultParser
+		parser = L2capChannelDefaultParser
 	}
-	parsed := parser(OpCode, attPayloadBuf)
-	parsed.OpCode = OpCode
+	parsed := parser(ChannelId, payloadBuf)
+	parsed.ChannelId = ChannelId
 	return parsed
 }
 
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part A] 3.2 CONNECTIONLESS DATA CHANNEL IN BASIC L2CAP MODE
+type L2capGroupFrame struct {
+	Psm     uint16
+	Payload []byte
+}
+
+// G-frame，PSM至少2字节，每个字节最低bit为1表示后面还有字节
+func L2capConnectionlessParse(ChannelId uint16, l2capPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capGroupFrame{}
+	pktIndex := 0
+	for shift := uint(0); ; shift += 8 {
+		if pktIndex >= len(l2capPayloadBuf) {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		octet := l2capPayloadBuf[pktIndex]
+		pktIndex++
+		if shift < 16 {
+			pkt.Psm |= uint16(octet) << shift
+		}
+		if pktIndex >= 2 && octet&0x01 == 0 {
+			break
+		}
+	}
+	pkt.Payload = l2capPayloadBuf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// ACL包是否为包含完整L2CAP PDU的首包
+func L2capPduComplete(pkt HciAcl) bool {
+	if pkt.PbFlag == HCI_ACL_PB_FLAG_CONTINUING_FRAGMENT || len(pkt.Data) < 4 {
+		return false
+	}
+	return len(pkt.Data)-4 >= int(binary.LittleEndian.Uint16(pkt.Data))
+}
+
+// 重组后的L2CAP PDU
+type L2capPdu struct {
+	Handle        uint16
+	Length        uint16 // Basic L2CAP header中的Length
+	ChannelId     uint16
+	Data          []byte // 完整的B-frame，含Basic L2CAP header
+	FragmentCount int
+}
+
+type l2capReassemblyKey struct {
+	handle   uint16
+	received bool
+}
+
+// L2CAP PDU重组，按handle和方向分别重组
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part A] 7.2 FRAGMENTATION AND RECOMBINATION
+type L2capReassembler struct {
+	pendingMap map[l2capReassemblyKey]*L2capPdu
+}
+
+func NewL2capReassembler() *L2capReassembler {
+	return &L2capReassembler{pendingMap: map[l2capReassemblyKey]*L2capPdu{}}
+}
+
+// 输入一个ACL包，PDU重组完成时返回true
+// 异常的分片(无首包的后续分片、PDU未结束又收到首包、长度超过Length)返回error，已缓存的未完成PDU丢弃
+func (reassembler *L2capReassembler) Push(received bool, pkt HciAcl) (L2capPdu, bool, error) {
+	key := l2capReassemblyKey{handle: pkt.Handle, received: received}
+	pending, hasPending := reassembler.pendingMap[key]
+
+	var err error
+	if pkt.PbFlag != HCI_ACL_PB_FLAG_CONTINUING_FRAGMENT {
+		if hasPending {
+			err = fmt.Errorf("acl handle %#x: l2cap pdu incomplete, %d/%d bytes received", pkt.Handle, len(pending.Data), int(pending.Length)+4)
+			delete(reassembler.pendingMap, key)
+		}
+		pending = &L2capPdu{Handle: pkt.Handle, Data: append([]byte{}, pkt.Data...), FragmentCount: 1}
+	} else {
+		if !hasPending {
+			return L2capPdu{}, false, fmt.Errorf("acl handle %#x: continuing fragment without start fragment", pkt.Handle)
+		}
+		pending.Data = append(pending.Data, pkt.Data...)
+		pending.FragmentCount++
+	}
+
+	// Basic L2CAP header可能被拆分到多个分片
+	if len(pending.Data) < 4 {
+		reassembler.pendingMap[key] = pending
+		return L2capPdu{}, false, err
+	}
+	pending.Length = binary.LittleEndian.Uint16(pending.Data)
+	pending.ChannelId = binary.LittleEndian.Uint16(pending.Data[2:])
+	if len(pending.Data)-4 < int(pending.Length) {
+		reassembler.pendingMap[key] = pending
+		return L2capPdu{}, false, err
+	}
+	delete(reassembler.pendingMap, key)
+	if len(pending.Data)-4 > int(pending.Length) && err == nil {
+		err = fmt.Errorf("acl handle %#x: l2cap pdu length %d, expect %d", pkt.Handle, len(pending.Data)-4, pending.Length)
+	}
+	return *pending, true, err
+}
+
+// 连接断开时丢弃handle上未完成的PDU，有未完成的PDU时返回error
+func (reassembler *L2capReassembler) Flush(handle uint16) error {
+	var err error
+	for _, received := range []bool{false, true} {
+		key := l2capReassemblyKey{handle: handle, received: received}
+		if pending, ok := reassembler.pendingMap[key]; ok {
+			err = fmt.Errorf("acl handle %#x: l2cap pdu incomplete on disconnection, %d/%d bytes received", handle, len(pending.Data), int(pending.Length)+4)
+			delete(reassembler.pendingMap, key)
+		}
+	}
+	return err
+}
+
+// handle上某个方向未完成的PDU，抓包结束时使用
+func (reassembler *L2capReassembler) Pending(handle uint16, received bool) (L2capPdu, bool) {
+	pending, ok := reassembler.pendingMap[l2capReassemblyKey{handle: handle, received: received}]
+	if !ok {
+		return L2capPdu{}, false
+	}
+	return *pending, true
+}
+
 // BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part A] 3 DATA PACKET FORMAT
 func HciAclPktParse(hciAclPktPayloadBuf []byte) HciAclPktParseResult {
 	// TODO: 其他报文类型处理
@@ -0,0 +1,64 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHciAclPktParseChannelId(t *testing.T) {
+	testList := []struct {
+		name          string
+		buf           []byte // 含Basic L2CAP header
+		wantCode      int
+		wantChannelId uint16
+		wantOpCode    uint8
+		want          interface{}
+	}{
+		{
+			"att write request", []byte{0x05, 0x00, 0x04, 0x00, 0x12, 0x03, 0x00, 0xAA, 0xBB},
+			HCI_PKT_RET_CODE_OK, L2CAP_CID_ATT, ATT_WRITE_REQUEST,
+			AttWriteRequest{OpCode: ATT_WRITE_REQUEST, Handle: 0x0003, Value: []byte{0xAA, 0xBB}},
+		},
+		{
+			"smp pairing request", []byte{0x07, 0x00, 0x06, 0x00, 0x01, 0x03, 0x00, 0x0D, 0x10, 0x03, 0x03},
+			HCI_PKT_RET_CODE_OK, L2CAP_CID_SMP, SMP_PAIRING_REQUEST,
+			SmpPairingFeature{IoCapability: 0x03, AuthReq: 0x0D, MaximumEncryptionKeySize: 0x10, InitiatorKeyDistribution: 0x03, ResponderKeyDistribution: 0x03},
+		},
+		{
+			"connectionless, 2 octet psm", []byte{0x04, 0x00, 0x02, 0x00, 0x01, 0x00, 0xAA, 0xBB},
+			HCI_PKT_RET_CODE_OK, L2CAP_CID_CONNECTIONLESS, 0,
+			L2capGroupFrame{Psm: 0x0001, Payload: []byte{0xAA, 0xBB}},
+		},
+		{
+			"connectionless, extended psm", []byte{0x04, 0x00, 0x02, 0x00, 0x01, 0x01, 0x02, 0xAA},
+			HCI_PKT_RET_CODE_OK, L2CAP_CID_CONNECTIONLESS, 0,
+			L2capGroupFrame{Psm: 0x0101, Payload: []byte{0xAA}},
+		},
+		{"connectionless, psm truncated", []byte{0x02, 0x00, 0x02, 0x00, 0x01, 0x01}, HCI_PKT_RET_CODE_INVALID_LEN, L2CAP_CID_CONNECTIONLESS, 0, nil},
+		{
+			"fixed channel without parser", []byte{0x02, 0x00, 0x03, 0x00, 0x01, 0x02},
+			HCI_PKT_RET_CODE_OK, L2CAP_CID_AMP_MANAGER, 0,
+			L2capBasicFrame{Length: 2, ChannelId: L2CAP_CID_AMP_MANAGER, Payload: []byte{0x01, 0x02}},
+		},
+		{
+			"dynamic channel is not decoded as att", []byte{0x03, 0x00, 0x40, 0x00, 0x12, 0x03, 0x00},
+			HCI_PKT_RET_CODE_OK, 0x0040, 0,
+			L2capBasicFrame{Length: 3, ChannelId: 0x0040, Payload: []byte{0x12, 0x03, 0x00}},
+		},
+		{"payload shorter than length", []byte{0x05, 0x00, 0x04, 0x00, 0x12, 0x03}, HCI_PKT_RET_CODE_INVALID_LEN, L2CAP_CID_ATT, 0, nil},
+		{"header truncated", []byte{0x05, 0x00, 0x04}, HCI_PKT_RET_CODE_INVALID_LEN, 0, 0, nil},
+	}
+	for _, test := range testList {
+		parsed := HciAclPktParse(test.buf)
+		if parsed.Code != test.wantCode || parsed.ChannelId != test.wantChannelId {
+			t.Errorf("%s: code %d channel %#04x, want %d %#04x", test.name, parsed.Code, parsed.ChannelId, test.wantCode, test.wantChannelId)
+			continue
+		}
+		if test.want == nil {
+			continue
+		}
+		if parsed.OpCode != test.wantOpCode || !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: opcode %#x %+v, want %#x %+v", test.name, parsed.OpCode, parsed.Ret, test.wantOpCode, test.want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+// AD Structure处理，LE广播数据/扫描响应数据与BR/EDR EIR数据格式相同
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part C] 11 ADVERTISING AND SCAN RESPONSE DATA FORMAT
+// Supplement to the Bluetooth Core Specification | CSS v9 Part A DATA TYPES SPECIFICATION
+
+package hci
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// AD Type
+// https://www.bluetooth.com/specifications/assigned-numbers/generic-access-profile/
+const (
+	AD_TYPE_FLAGS                                 = 0x01
+	AD_TYPE_INCOMPLETE_LIST_16BIT_SERVICE_UUID    = 0x02
+	AD_TYPE_COMPLETE_LIST_16BIT_SERVICE_UUID      = 0x03
+	AD_TYPE_INCOMPLETE_LIST_32BIT_SERVICE_UUID    = 0x04
+	AD_TYPE_COMPLETE_LIST_32BIT_SERVICE_UUID      = 0x05
+	AD_TYPE_INCOMPLETE_LIST_128BIT_SERVICE_UUID   = 0x06
+	AD_TYPE_COMPLETE_LIST_128BIT_SERVICE_UUID     = 0x07
+	AD_TYPE_SHORTENED_LOCAL_NAME                  = 0x08
+	AD_TYPE_COMPLETE_LOCAL_NAME                   = 0x09
+	AD_TYPE_TX_POWER_LEVEL                        = 0x0A
+	AD_TYPE_CLASS_OF_DEVICE                       = 0x0D
+	AD_TYPE_LIST_16BIT_SERVICE_SOLICITATION_UUID  = 0x14
+	AD_TYPE_LIST_128BIT_SERVICE_SOLICITATION_UUID = 0x15
+	AD_TYPE_SERVICE_DATA_16BIT_UUID               = 0x16
+	AD_TYPE_APPEARANCE                            = 0x19
+	AD_TYPE_LIST_32BIT_SERVICE_SOLICITATION_UUID  = 0x1F
+	AD_TYPE_SERVICE_DATA_32BIT_UUID               = 0x20
+	AD_TYPE_SERVICE_DATA_128BIT_UUID              = 0x21
+	AD_TYPE_MANUFACTURER_SPECIFIC_DATA            = 0xFF
+)
+
+// Flags AD Type各bit
+// CSS v9 Part A 1.3 FLAGS
+const (
+	AD_FLAG_LE_LIMITED_DISCOVERABLE_MODE      = 0x01
+	AD_FLAG_LE_GENERAL_DISCOVERABLE_MODE      = 0x02
+	AD_FLAG_BR_EDR_NOT_SUPPORTED              = 0x04
+	AD_FLAG_SIMULTANEOUS_LE_BR_EDR_CONTROLLER = 0x08
+	AD_FLAG_SIMULTANEOUS_LE_BR_EDR_HOST       = 0x10
+)
+
+// 一个AD Structure: Length(1) + AD Type(1) + AD Data(Length-1)
+type AdStruct struct {
+	Length              uint8
+	AdType              uint8
+	Data                []byte
+	PayloadParsedResult AdStructParseResult // Data解析后的结果
+}
+
+type AdStructParseResult struct {
+	Code   int
+	AdType uint8
+	Ret    interface{}
+}
+type AdStructParser func(AdType uint8, adDataBuf []byte) AdStructParseResult
+
+var AdStructParserMap map[uint8]AdStructParser = map[uint8]AdStructParser{
+	AD_TYPE_FLAGS: AdFlagsParser,
+	AD_TYPE_INCOMPLETE_LIST_16BIT_SERVICE_UUID:    AdServiceUuidListParser,
+	AD_TYPE_COMPLETE_LIST_16BIT_SERVICE_UUID:      AdServiceUuidListParser,
+	AD_TYPE_INCOMPLETE_LIST_32BIT_SERVICE_UUID:    AdServiceUuidListParser,
+	AD_TYPE_COMPLETE_LIST_32BIT_SERVICE_UUID:      AdServiceUuidListParser,
+	AD_TYPE_INCOMPLETE_LIST_128BIT_SERVICE_UUID:   AdServiceUuidListParser,
+	AD_TYPE_COMPLETE_LIST_128BIT_SERVICE_UUID:     AdServiceUuidListParser,
+	AD_TYPE_LIST_16BIT_SERVICE_SOLICITATION_UUID:  AdServiceUuidListParser,
+	AD_TYPE_LIST_32BIT_SERVICE_SOLICITATION_UUID:  AdServiceUuidListParser,
+	AD_TYPE_LIST_128BIT_SERVICE_SOLICITATION_UUID: AdServiceUuidListParser,
+	AD_TYPE_SHORTENED_LOCAL_NAME:                  AdLocalNameParser,
+	AD_TYPE_COMPLETE_LOCAL_NAME:                   AdLocalNameParser,
+	AD_TYPE_TX_POWER_LEVEL:                        AdTxPowerLevelParser,
+	AD_TYPE_CLASS_OF_DEVICE:                       AdClassOfDeviceParser,
+	AD_TYPE_SERVICE_DATA_16BIT_UUID:               AdServiceDataParser,
+	AD_TYPE_SERVICE_DATA_32BIT_UUID:               AdServiceDataParser,
+	AD_TYPE_SERVICE_DATA_128BIT_UUID:              AdServiceDataParser,
+	AD_TYPE_APPEARANCE:                            AdAppearanceParser,
+	AD_TYPE_MANUFACTURER_SPECIFIC_DATA:            AdManufacturerSpecificDataParser,
+}
+
+// 拆分AD Structure列表，Length为0时表示后面是填充数据
+// 长度越界时返回已经解析的部分和错误
+func AdStructListParse(buf []byte) ([]AdStruct, error) {
+	var adStructList []AdStruct
+	bufIndex := 0
+	for bufIndex < len(buf) {
+		length := buf[bufIndex]
+		if length == 0 {
+			break
+		}
+		if bufIndex+1+int(length) > len(buf) {
+			return adStructList, fmt.Errorf("ad struct length %d exceeds remaining %d at offset %d", length, len(buf)-bufIndex-1, bufIndex)
+		}
+		adStruct := AdStruct{Length: length, AdType: buf[bufIndex+1]}
+		adStruct.Data = make([]byte, length-1)
+		copy(adStruct.Data, buf[bufIndex+2:bufIndex+1+int(length)])
+		adStruct.PayloadParsedResult = AdStructParse(adStruct.AdType, adStruct.Data)
+		adStructList = append(adStructList, adStruct)
+		bufIndex += 1 + int(length)
+	}
+	return adStructList, nil
+}
+
+func AdStructParse(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	parser, ok := AdStructParserMap[AdType]
+	if !ok {
+		parser = AdStructDefaultParser
+	}
+	parsed := parser(AdType, adDataBuf)
+	parsed.AdType = AdType
+	return parsed
+}
+
+func AdStructDefaultParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
+}
+
+// CSS v9 Part A 1.3 FLAGS
+type AdFlags struct {
+	Flags uint8 // AD_FLAG_XXX
+}
+
+func AdFlagsParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	if len(adDataBuf) < 1 {
+		return AdStructParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: AdFlags{Flags: adDataBuf[0]}}
+}
+
+// CSS v9 Part A 1.1 SERVICE UUID / 1.10 SERVICE SOLICITATION
+type AdServiceUuidList struct {
+	Complete     bool // 仅Service UUID有效，是否是完整列表
+	Solicitation bool
+	UuidList     []string
+}
+
+func AdServiceUuidListParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	pkt := AdServiceUuidList{}
+	uuidSize := 2
+	switch AdType {
+	case AD_TYPE_INCOMPLETE_LIST_32BIT_SERVICE_UUID, AD_TYPE_COMPLETE_LIST_32BIT_SERVICE_UUID, AD_TYPE_LIST_32BIT_SERVICE_SOLICITATION_UUID:
+		uuidSize = 4
+	case AD_TYPE_INCOMPLETE_LIST_128BIT_SERVICE_UUID, AD_TYPE_COMPLETE_LIST_128BIT_SERVICE_UUID, AD_TYPE_LIST_128BIT_SERVICE_SOLICITATION_UUID:
+		uuidSize = 16
+	}
+	switch AdType {
+	case AD_TYPE_COMPLETE_LIST_16BIT_SERVICE_UUID, AD_TYPE_COMPLETE_LIST_32BIT_SERVICE_UUID, AD_TYPE_COMPLETE_LIST_128BIT_SERVICE_UUID:
+		pkt.Complete = true
+	case AD_TYPE_LIST_16BIT_SERVICE_SOLICITATION_UUID, AD_TYPE_LIST_32BIT_SERVICE_SOLICITATION_UUID, AD_TYPE_LIST_128BIT_SERVICE_SOLICITATION_UUID:
+		pkt.Solicitation = true
+	}
+	if len(adDataBuf)%uuidSize != 0 {
+		return AdStructParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for bufIndex := 0; bufIndex < len(adDataBuf); bufIndex += uuidSize {
+		pkt.UuidList = append(pkt.UuidList, UuidString(adDataBuf[bufIndex:bufIndex+uuidSize]))
+	}
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// CSS v9 Part A 1.2 LOCAL NAME
+type AdLocalName struct {
+	Complete bool // false表示Shortened Local Name
+	Name     string
+}
+
+func AdLocalNameParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	pkt := AdLocalName{Complete: AdType == AD_TYPE_COMPLETE_LOCAL_NAME, Name: nullTerminatedStringParse(adDataBuf)}
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// CSS v9 Part A 1.5 TX POWER LEVEL
+type AdTxPowerLevel struct {
+	TxPowerLevel int8 // 单位dBm
+}
+
+func AdTxPowerLevelParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	if len(adDataBuf) < 1 {
+		return AdStructParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: AdTxPowerLevel{TxPowerLevel: int8(adDataBuf[0])}}
+}
+
+// CSS v9 Part A 1.6 SECURE SIMPLE PAIRING OUT OF BAND (Class of Device)
+type AdClassOfDevice struct {
+	ClassOfDevice uint32
+	DeviceClass   DeviceClass
+}
+
+func AdClassOfDeviceParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	if len(adDataBuf) < 3 {
+		return AdStructParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	classOfDevice := Uint24Parse(adDataBuf)
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: AdClassOfDevice{ClassOfDevice: classOfDevice, DeviceClass: DeviceClassParse(classOfDevice)}}
+}
+
+// CSS v9 Part A 1.11 SERVICE DATA
+type AdServiceData struct {
+	Uuid string
+	Data []byte
+}
+
+func AdServiceDataParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	uuidSize := 2
+	switch AdType {
+	case AD_TYPE_SERVICE_DATA_32BIT_UUID:
+		uuidSize = 4
+	case AD_TYPE_SERVICE_DATA_128BIT_UUID:
+		uuidSize = 16
+	}
+	if len(adDataBuf) < uuidSize {
+		return AdStructParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := AdServiceData{Uuid: UuidString(adDataBuf[:uuidSize])}
+	pkt.Data = make([]byte, len(adDataBuf[uuidSize:]))
+	copy(pkt.Data, adDataBuf[uuidSize:])
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// CSS v9 Part A 1.12 APPEARANCE
+type AdAppearance struct {
+	Appearance uint16 // 高10bit为Category，低6bit为Sub-category
+}
+
+func AdAppearanceParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	if len(adDataBuf) < 2 {
+		return AdStructParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: AdAppearance{Appearance: binary.LittleEndian.Uint16(adDataBuf)}}
+}
+
+// CSS v9 Part A 1.4 MANUFACTURER SPECIFIC DATA
+type AdManufacturerSpecificData struct {
+	CompanyId uint16
+	Data      []byte
+}
+
+func AdManufacturerSpecificDataParser(AdType uint8, adDataBuf []byte) AdStructParseResult {
+	if len(adDataBuf) < 2 {
+		return AdStructParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := AdManufacturerSpecificData{CompanyId: binary.LittleEndian.Uint16(adDataBuf)}
+	pkt.Data = make([]byte, len(adDataBuf[2:]))
+	copy(pkt.Data, adDataBuf[2:])
+	return AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
@@ -0,0 +1,671 @@
+// LE广播/扫描命令和广播报告事件处理
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// LE Advertising Report Event_Type
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.2 LE Advertising Report Event
+const (
+	ADV_REPORT_EVENT_TYPE_ADV_IND         = 0x00
+	ADV_REPORT_EVENT_TYPE_ADV_DIRECT_IND  = 0x01
+	ADV_REPORT_EVENT_TYPE_ADV_SCAN_IND    = 0x02
+	ADV_REPORT_EVENT_TYPE_ADV_NONCONN_IND = 0x03
+	ADV_REPORT_EVENT_TYPE_SCAN_RSP        = 0x04
+)
+
+// LE Extended Advertising Report Event_Type各bit
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.7.65.13 LE Extended Advertising Report Event
+const (
+	EXT_ADV_REPORT_EVENT_TYPE_CONNECTABLE   = 0x0001
+	EXT_ADV_REPORT_EVENT_TYPE_SCANNABLE     = 0x0002
+	EXT_ADV_REPORT_EVENT_TYPE_DIRECTED      = 0x0004
+	EXT_ADV_REPORT_EVENT_TYPE_SCAN_RESPONSE = 0x0008
+	EXT_ADV_REPORT_EVENT_TYPE_LEGACY        = 0x0010
+	EXT_ADV_REPORT_EVENT_TYPE_DATA_STATUS   = 0x0060 // EXT_ADV_REPORT_DATA_STATUS_XXX
+)
+
+// Event_Type bit5-6 Data Status
+const (
+	EXT_ADV_REPORT_DATA_STATUS_COMPLETE             = 0x00
+	EXT_ADV_REPORT_DATA_STATUS_INCOMPLETE_MORE_DATA = 0x01
+	EXT_ADV_REPORT_DATA_STATUS_INCOMPLETE_TRUNCATED = 0x02
+)
+
+// Address_Type
+const (
+	LE_ADDRESS_TYPE_PUBLIC          = 0x00
+	LE_ADDRESS_TYPE_RANDOM          = 0x01
+	LE_ADDRESS_TYPE_PUBLIC_IDENTITY = 0x02
+	LE_ADDRESS_TYPE_RANDOM_IDENTITY = 0x03
+	LE_ADDRESS_TYPE_ANONYMOUS       = 0xFF // 仅Extended Advertising Report
+)
+
+// Primary_PHY/Secondary_PHY
+const (
+	LE_PHY_NONE  = 0x00 // Secondary_PHY: 没有使用secondary advertising channel
+	LE_PHY_1M    = 0x01
+	LE_PHY_2M    = 0x02
+	LE_PHY_CODED = 0x03
+)
+
+// RSSI不可用
+const ADV_REPORT_RSSI_NOT_AVAILABLE = 127
+
+// Advertising_SID不可用
+const EXT_ADV_REPORT_SID_NOT_AVAILABLE = 0xFF
+
+// TX_Power不可用
+const EXT_ADV_REPORT_TX_POWER_NOT_AVAILABLE = 127
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.2 LE Advertising Report Event
+type LeAdvertisingReport struct {
+	EventType    uint8 // ADV_REPORT_EVENT_TYPE_XXX
+	AddressType  uint8 // LE_ADDRESS_TYPE_XXX
+	Address      [6]byte
+	DataLength   uint8
+	Data         []byte
+	AdStructList []AdStruct
+	Rssi         int8 // 单位dBm
+}
+
+type LeAdvertisingReportEvent struct {
+	SubEventCode uint8
+	NumReports   uint8
+	ReportList   []LeAdvertisingReport
+}
+
+// 多个report按顺序依次排列
+func LeAdvertisingReportEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeAdvertisingReportEvent{}
+	if len(hciEvtPktPayloadBuf) < 2 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.NumReports = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumReports)
+	for index := 0; index < int(pkt.NumReports); index++ {
+		report := LeAdvertisingReport{}
+		if len(hciEvtPktPayloadBuf[pktIndex:]) < 10 {
+			return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		report.EventType = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.EventType)
+		report.AddressType = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.AddressType)
+		report.Address = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(report.Address)
+		report.DataLength = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.DataLength)
+		if len(hciEvtPktPayloadBuf[pktIndex:]) < int(report.DataLength)+1 {
+			return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		report.Data = make([]byte, report.DataLength)
+		copy(report.Data, hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += int(report.DataLength)
+		report.AdStructList, _ = AdStructListParse(report.Data)
+		report.Rssi = int8(hciEvtPktPayloadBuf[pktIndex])
+		pktIndex += binary.Size(report.Rssi)
+		pkt.ReportList = append(pkt.ReportList, report)
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.11 LE Direct Advertising Report Event
+type LeDirectedAdvertisingReport struct {
+	EventType         uint8 // 固定为ADV_REPORT_EVENT_TYPE_ADV_DIRECT_IND
+	AddressType       uint8
+	Address           [6]byte
+	DirectAddressType uint8
+	DirectAddress     [6]byte
+	Rssi              int8
+}
+
+type LeDirectedAdvertisingReportEvent struct {
+	SubEventCode uint8
+	NumReports   uint8
+	ReportList   []LeDirectedAdvertisingReport
+}
+
+// 扫描参数Scanning_Filter_Policy为0x02/0x03时，目标地址为无法解析的RPA的定向广播通过该事件上报
+func LeDirectedAdvertisingReportEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeDirectedAdvertisingReportEvent{}
+	if len(hciEvtPktPayloadBuf) < 2 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.NumReports = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumReports)
+	if len(hciEvtPktPayloadBuf[pktIndex:]) < int(pkt.NumReports)*16 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for index := 0; index < int(pkt.NumReports); index++ {
+		report := LeDirectedAdvertisingReport{}
+		report.EventType = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.EventType)
+		report.AddressType = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.AddressType)
+		report.Address = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(report.Address)
+		report.DirectAddressType = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.DirectAddressType)
+		report.DirectAddress = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(report.DirectAddress)
+		report.Rssi = int8(hciEvtPktPayloadBuf[pktIndex])
+		pktIndex += binary.Size(report.Rssi)
+		pkt.ReportList = append(pkt.ReportList, report)
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.7.65.13 LE Extended Advertising Report Event
+type LeExtendedAdvertisingReport struct {
+	EventType                   uint16 // EXT_ADV_REPORT_EVENT_TYPE_XXX
+	AddressType                 uint8
+	Address                     [6]byte
+	PrimaryPhy                  uint8 // LE_PHY_XXX
+	SecondaryPhy                uint8
+	AdvertisingSid              uint8
+	TxPower                     int8 // 单位dBm
+	Rssi                        int8 // 单位dBm
+	PeriodicAdvertisingInterval uint16
+	DirectAddressType           uint8
+	DirectAddress               [6]byte
+	DataLength                  uint8
+	Data                        []byte
+	AdStructList                []AdStruct // DataStatus不是COMPLETE时可能只有部分AD Structure
+}
+
+// Event_Type中的Data Status
+func (report LeExtendedAdvertisingReport) DataStatus() uint8 {
+	return uint8(report.EventType&EXT_ADV_REPORT_EVENT_TYPE_DATA_STATUS) >> 5
+}
+
+type LeExtendedAdvertisingReportEvent struct {
+	SubEventCode uint8
+	NumReports   uint8
+	ReportList   []LeExtendedAdvertisingReport
+}
+
+// 扩展扫描时使用，legacy广播也通过该事件上报(Event_Type带EXT_ADV_REPORT_EVENT_TYPE_LEGACY)
+func LeExtendedAdvertisingReportEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeExtendedAdvertisingReportEvent{}
+	if len(hciEvtPktPayloadBuf) < 2 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.NumReports = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumReports)
+	for index := 0; index < int(pkt.NumReports); index++ {
+		report := LeExtendedAdvertisingReport{}
+		if len(hciEvtPktPayloadBuf[pktIndex:]) < 24 {
+			return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		report.EventType = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(report.EventType)
+		report.AddressType = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.AddressType)
+		report.Address = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(report.Address)
+		report.PrimaryPhy = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.PrimaryPhy)
+		report.SecondaryPhy = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.SecondaryPhy)
+		report.AdvertisingSid = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.AdvertisingSid)
+		report.TxPower = int8(hciEvtPktPayloadBuf[pktIndex])
+		pktIndex += binary.Size(report.TxPower)
+		report.Rssi = int8(hciEvtPktPayloadBuf[pktIndex])
+		pktIndex += binary.Size(report.Rssi)
+		report.PeriodicAdvertisingInterval = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(report.PeriodicAdvertisingInterval)
+		report.DirectAddressType = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.DirectAddressType)
+		report.DirectAddress = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(report.DirectAddress)
+		report.DataLength = hciEvtPktPayloadBuf[pktIndex]
+		pktIndex += binary.Size(report.DataLength)
+		if len(hciEvtPktPayloadBuf[pktIndex:]) < int(report.DataLength) {
+			return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		report.Data = make([]byte, report.DataLength)
+		copy(report.Data, hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += int(report.DataLength)
+		report.AdStructList, _ = AdStructListParse(report.Data)
+		pkt.ReportList = append(pkt.ReportList, report)
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// Advertising_Type
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.5 LE Set Advertising Parameters Command
+const (
+	ADV_TYPE_ADV_IND                  = 0x00
+	ADV_TYPE_ADV_DIRECT_IND_HIGH_DUTY = 0x01
+	ADV_TYPE_ADV_SCAN_IND             = 0x02
+	ADV_TYPE_ADV_NONCONN_IND          = 0x03
+	ADV_TYPE_ADV_DIRECT_IND_LOW_DUTY  = 0x04
+)
+
+// Advertising_Event_Properties各bit
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.53 LE Set Extended Advertising Parameters Command
+const (
+	EXT_ADV_PROP_CONNECTABLE        = 0x0001
+	EXT_ADV_PROP_SCANNABLE          = 0x0002
+	EXT_ADV_PROP_DIRECTED           = 0x0004
+	EXT_ADV_PROP_HIGH_DUTY_DIRECTED = 0x0008
+	EXT_ADV_PROP_LEGACY             = 0x0010
+	EXT_ADV_PROP_ANONYMOUS          = 0x0020
+	EXT_ADV_PROP_INCLUDE_TX_POWER   = 0x0040
+)
+
+// Own_Address_Type
+const (
+	OWN_ADDRESS_TYPE_PUBLIC        = 0x00
+	OWN_ADDRESS_TYPE_RANDOM        = 0x01
+	OWN_ADDRESS_TYPE_RPA_OR_PUBLIC = 0x02
+	OWN_ADDRESS_TYPE_RPA_OR_RANDOM = 0x03
+)
+
+// LE_Scan_Type
+const (
+	LE_SCAN_TYPE_PASSIVE = 0x00
+	LE_SCAN_TYPE_ACTIVE  = 0x01
+)
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.4 LE Set Random Address Command
+type HciLeSetRandomAddress struct {
+	RandomAddress [6]byte
+}
+
+func HciLeSetRandomAddressParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 6 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: HciLeSetRandomAddress{RandomAddress: BdAddrParse(hciCmdPktPayloadBuf)}}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.5 LE Set Advertising Parameters Command
+type HciLeSetAdvertisingParameters struct {
+	AdvertisingIntervalMin  uint16 // 单位0.625ms
+	AdvertisingIntervalMax  uint16
+	AdvertisingType         uint8 // ADV_TYPE_XXX
+	OwnAddressType          uint8 // OWN_ADDRESS_TYPE_XXX
+	PeerAddressType         uint8
+	PeerAddress             [6]byte
+	AdvertisingChannelMap   uint8
+	AdvertisingFilterPolicy uint8
+}
+
+func HciLeSetAdvertisingParametersParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetAdvertisingParameters{}
+	if len(hciCmdPktPayloadBuf) < 15 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.AdvertisingIntervalMin = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.AdvertisingIntervalMin)
+	pkt.AdvertisingIntervalMax = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.AdvertisingIntervalMax)
+	pkt.AdvertisingType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.AdvertisingType)
+	pkt.OwnAddressType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.OwnAddressType)
+	pkt.PeerAddressType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.PeerAddressType)
+	pkt.PeerAddress = BdAddrParse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.PeerAddress)
+	pkt.AdvertisingChannelMap = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.AdvertisingChannelMap)
+	pkt.AdvertisingFilterPolicy = hciCmdPktPayloadBuf[bufIndex]
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// LE Set Advertising Data/LE Set Scan Response Data
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.7 LE Set Advertising Data Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.8 LE Set Scan Response Data Command
+type HciLeSetAdvertisingData struct {
+	ScanResponse bool // true表示LE Set Scan Response Data
+	DataLength   uint8
+	Data         []byte // 固定31字节，只保留DataLength部分
+	AdStructList []AdStruct
+}
+
+func HciLeSetAdvertisingDataParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetAdvertisingData{ScanResponse: OpCodeOcf == HCI_LE_SET_SCAN_RESPONSE_DATA}
+	if len(hciCmdPktPayloadBuf) < 1 || len(hciCmdPktPayloadBuf[1:]) < int(hciCmdPktPayloadBuf[0]) {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.DataLength = hciCmdPktPayloadBuf[0]
+	pkt.Data = make([]byte, pkt.DataLength)
+	copy(pkt.Data, hciCmdPktPayloadBuf[1:])
+	pkt.AdStructList, _ = AdStructListParse(pkt.Data)
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.9 LE Set Advertise Enable Command
+type HciLeSetAdvertisingEnable struct {
+	AdvertisingEnable uint8
+}
+
+func HciLeSetAdvertisingEnableParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 1 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: HciLeSetAdvertisingEnable{AdvertisingEnable: hciCmdPktPayloadBuf[0]}}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.10 LE Set Scan Parameters Command
+type HciLeSetScanParameters struct {
+	LeScanType           uint8  // LE_SCAN_TYPE_XXX
+	LeScanInterval       uint16 // 单位0.625ms
+	LeScanWindow         uint16
+	OwnAddressType       uint8
+	ScanningFilterPolicy uint8
+}
+
+func HciLeSetScanParametersParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetScanParameters{}
+	if len(hciCmdPktPayloadBuf) < 7 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.LeScanType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.LeScanType)
+	pkt.LeScanInterval = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.LeScanInterval)
+	pkt.LeScanWindow = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.LeScanWindow)
+	pkt.OwnAddressType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.OwnAddressType)
+	pkt.ScanningFilterPolicy = hciCmdPktPayloadBuf[bufIndex]
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.11 LE Set Scan Enable Command
+type HciLeSetScanEnable struct {
+	LeScanEnable     uint8
+	FilterDuplicates uint8
+}
+
+func HciLeSetScanEnableParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 2 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: HciLeSetScanEnable{LeScanEnable: hciCmdPktPayloadBuf[0], FilterDuplicates: hciCmdPktPayloadBuf[1]}}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.52 LE Set Advertising Set Random Address Command
+type HciLeSetAdvertisingSetRandomAddress struct {
+	AdvertisingHandle uint8
+	RandomAddress     [6]byte
+}
+
+func HciLeSetAdvertisingSetRandomAddressParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 7 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciLeSetAdvertisingSetRandomAddress{AdvertisingHandle: hciCmdPktPayloadBuf[0], RandomAddress: BdAddrParse(hciCmdPktPayloadBuf[1:])}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.53 LE Set Extended Advertising Parameters Command
+type HciLeSetExtendedAdvertisingParameters struct {
+	AdvertisingHandle             uint8
+	AdvertisingEventProperties    uint16 // EXT_ADV_PROP_XXX
+	PrimaryAdvertisingIntervalMin uint32 // 3字节，单位0.625ms
+	PrimaryAdvertisingIntervalMax uint32
+	PrimaryAdvertisingChannelMap  uint8
+	OwnAddressType                uint8
+	PeerAddressType               uint8
+	PeerAddress                   [6]byte
+	AdvertisingFilterPolicy       uint8
+	AdvertisingTxPower            int8 // 127表示host没有偏好
+	PrimaryAdvertisingPhy         uint8
+	SecondaryAdvertisingMaxSkip   uint8
+	SecondaryAdvertisingPhy       uint8
+	AdvertisingSid                uint8
+	ScanRequestNotificationEnable uint8
+}
+
+func HciLeSetExtendedAdvertisingParametersParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetExtendedAdvertisingParameters{}
+	if len(hciCmdPktPayloadBuf) < 25 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.AdvertisingHandle = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.AdvertisingHandle)
+	pkt.AdvertisingEventProperties = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.AdvertisingEventProperties)
+	pkt.PrimaryAdvertisingIntervalMin = Uint24Parse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += 3
+	pkt.PrimaryAdvertisingIntervalMax = Uint24Parse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += 3
+	pkt.PrimaryAdvertisingChannelMap = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.PrimaryAdvertisingChannelMap)
+	pkt.OwnAddressType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.OwnAddressType)
+	pkt.PeerAddressType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.PeerAddressType)
+	pkt.PeerAddress = BdAddrParse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.PeerAddress)
+	pkt.AdvertisingFilterPolicy = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.AdvertisingFilterPolicy)
+	pkt.AdvertisingTxPower = int8(hciCmdPktPayloadBuf[bufIndex])
+	bufIndex += binary.Size(pkt.AdvertisingTxPower)
+	pkt.PrimaryAdvertisingPhy = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.PrimaryAdvertisingPhy)
+	pkt.SecondaryAdvertisingMaxSkip = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.SecondaryAdvertisingMaxSkip)
+	pkt.SecondaryAdvertisingPhy = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.SecondaryAdvertisingPhy)
+	pkt.AdvertisingSid = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.AdvertisingSid)
+	pkt.ScanRequestNotificationEnable = hciCmdPktPayloadBuf[bufIndex]
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// Operation
+const (
+	EXT_ADV_DATA_OPERATION_INTERMEDIATE_FRAGMENT = 0x00
+	EXT_ADV_DATA_OPERATION_FIRST_FRAGMENT        = 0x01
+	EXT_ADV_DATA_OPERATION_LAST_FRAGMENT         = 0x02
+	EXT_ADV_DATA_OPERATION_COMPLETE              = 0x03
+	EXT_ADV_DATA_OPERATION_UNCHANGED             = 0x04
+)
+
+// LE Set Extended Advertising Data/LE Set Extended Scan Response Data
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.54 LE Set Extended Advertising Data Command
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.55 LE Set Extended Scan Response Data Command
+type HciLeSetExtendedAdvertisingData struct {
+	ScanResponse       bool // true表示LE Set Extended Scan Response Data
+	AdvertisingHandle  uint8
+	Operation          uint8 // EXT_ADV_DATA_OPERATION_XXX
+	FragmentPreference uint8
+	DataLength         uint8
+	Data               []byte
+	AdStructList       []AdStruct // 仅Operation为COMPLETE时解析
+}
+
+func HciLeSetExtendedAdvertisingDataParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetExtendedAdvertisingData{ScanResponse: OpCodeOcf == HCI_LE_SET_EXTENDED_SCAN_RESPONSE_DATA}
+	if len(hciCmdPktPayloadBuf) < 4 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.AdvertisingHandle = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.AdvertisingHandle)
+	pkt.Operation = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Operation)
+	pkt.FragmentPreference = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.FragmentPreference)
+	pkt.DataLength = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.DataLength)
+	if len(hciCmdPktPayloadBuf[bufIndex:]) < int(pkt.DataLength) {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Data = make([]byte, pkt.DataLength)
+	copy(pkt.Data, hciCmdPktPayloadBuf[bufIndex:])
+	if pkt.Operation == EXT_ADV_DATA_OPERATION_COMPLETE {
+		pkt.AdStructList, _ = AdStructListParse(pkt.Data)
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// 单个广播集的使能参数
+type ExtendedAdvertisingSet struct {
+	AdvertisingHandle            uint8
+	Duration                     uint16 // 单位10ms，0表示一直广播
+	MaxExtendedAdvertisingEvents uint8
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.56 LE Set Extended Advertising Enable Command
+type HciLeSetExtendedAdvertisingEnable struct {
+	Enable  uint8
+	NumSets uint8 // Enable为0且NumSets为0时表示关闭所有广播集
+	SetList []ExtendedAdvertisingSet
+}
+
+func HciLeSetExtendedAdvertisingEnableParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetExtendedAdvertisingEnable{}
+	if len(hciCmdPktPayloadBuf) < 2 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.Enable = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Enable)
+	pkt.NumSets = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.NumSets)
+	if len(hciCmdPktPayloadBuf[bufIndex:]) < int(pkt.NumSets)*4 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for index := 0; index < int(pkt.NumSets); index++ {
+		set := ExtendedAdvertisingSet{}
+		set.AdvertisingHandle = hciCmdPktPayloadBuf[bufIndex]
+		bufIndex += binary.Size(set.AdvertisingHandle)
+		set.Duration = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+		bufIndex += binary.Size(set.Duration)
+		set.MaxExtendedAdvertisingEvents = hciCmdPktPayloadBuf[bufIndex]
+		bufIndex += binary.Size(set.MaxExtendedAdvertisingEvents)
+		pkt.SetList = append(pkt.SetList, set)
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// 单个PHY的扫描参数
+type ExtendedScanPhyParameters struct {
+	ScanType     uint8  // LE_SCAN_TYPE_XXX
+	ScanInterval uint16 // 单位0.625ms
+	ScanWindow   uint16
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.64 LE Set Extended Scan Parameters Command
+type HciLeSetExtendedScanParameters struct {
+	OwnAddressType       uint8
+	ScanningFilterPolicy uint8
+	ScanningPhys         uint8 // bit0: 1M, bit2: Coded
+
+	// 按ScanningPhys bit位置存放，bit未置1的项无效
+	PhyParametersList [3]ExtendedScanPhyParameters
+}
+
+func HciLeSetExtendedScanParametersParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetExtendedScanParameters{}
+	if len(hciCmdPktPayloadBuf) < 3 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.OwnAddressType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.OwnAddressType)
+	pkt.ScanningFilterPolicy = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.ScanningFilterPolicy)
+	pkt.ScanningPhys = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.ScanningPhys)
+	for index := 0; index < len(pkt.PhyParametersList); index++ {
+		if pkt.ScanningPhys&(0x01<<uint8(index)) == 0 {
+			continue
+		}
+		if len(hciCmdPktPayloadBuf[bufIndex:]) < 5 {
+			return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		phy := ExtendedScanPhyParameters{}
+		phy.ScanType = hciCmdPktPayloadBuf[bufIndex]
+		bufIndex += binary.Size(phy.ScanType)
+		phy.ScanInterval = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+		bufIndex += binary.Size(phy.ScanInterval)
+		phy.ScanWindow = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+		bufIndex += binary.Size(phy.ScanWindow)
+		pkt.PhyParametersList[index] = phy
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.65 LE Set Extended Scan Enable Command
+type HciLeSetExtendedScanEnable struct {
+	Enable           uint8
+	FilterDuplicates uint8
+	Duration         uint16 // 单位10ms，0表示一直扫描
+	Period           uint16 // 单位1.28s
+}
+
+func HciLeSetExtendedScanEnableParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetExtendedScanEnable{}
+	if len(hciCmdPktPayloadBuf) < 6 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.Enable = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Enable)
+	pkt.FilterDuplicates = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.FilterDuplicates)
+	pkt.Duration = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.Duration)
+	pkt.Period = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.7.65.18 LE Advertising Set Terminated Event
+type LeAdvertisingSetTerminatedEvent struct {
+	SubEventCode                          uint8
+	Status                                HciStatus // 0x3C: Duration超时，0x43: 达到Max_Extended_Advertising_Events
+	AdvertisingHandle                     uint8
+	ConnectionHandle                      uint16 // Status为Success时表示广播因建立连接而结束
+	NumCompletedExtendedAdvertisingEvents uint8
+}
+
+func LeAdvertisingSetTerminatedEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeAdvertisingSetTerminatedEvent{}
+	if len(hciEvtPktPayloadBuf) < 6 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.AdvertisingHandle = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.AdvertisingHandle)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.NumCompletedExtendedAdvertisingEvents = hciEvtPktPayloadBuf[pktIndex]
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.7.65.17 LE Scan Timeout Event
+// 扩展扫描Duration到期，没有参数
+type LeScanTimeoutEvent struct {
+	SubEventCode uint8
+}
+
+func LeScanTimeoutEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: LeScanTimeoutEvent{SubEventCode: hciEvtPktPayloadBuf[0]}}
+}
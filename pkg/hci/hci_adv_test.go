@@ -0,0 +1,165 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdStructListParse(t *testing.T) {
+	testList := []struct {
+		name    string
+		buf     []byte
+		want    []interface{} // 每个AD Structure的解析结果，nil表示未支持的AD Type
+		wantErr bool
+	}{
+		{
+			name: "flags, uuid list, name, tx power, manufacturer data",
+			buf: []byte{
+				0x02, 0x01, 0x06,
+				0x05, 0x03, 0x0D, 0x18, 0x0F, 0x18,
+				0x05, 0x09, 'b', 'a', 'n', 'd',
+				0x02, 0x0A, 0xF4,
+				0x05, 0xFF, 0x4C, 0x00, 0x02, 0x15,
+			},
+			want: []interface{}{
+				AdFlags{Flags: AD_FLAG_LE_GENERAL_DISCOVERABLE_MODE | AD_FLAG_BR_EDR_NOT_SUPPORTED},
+				AdServiceUuidList{Complete: true, UuidList: []string{"180D", "180F"}},
+				AdLocalName{Complete: true, Name: "band"},
+				AdTxPowerLevel{TxPowerLevel: -12},
+				AdManufacturerSpecificData{CompanyId: 0x004C, Data: []byte{0x02, 0x15}},
+			},
+		},
+		{
+			name: "service data, appearance, unknown type, zero padding",
+			buf: []byte{
+				0x04, 0x16, 0x0F, 0x18, 0x64,
+				0x03, 0x19, 0xC1, 0x03,
+				0x02, 0x2A, 0x01,
+				0x00, 0x00, 0x00,
+			},
+			want: []interface{}{
+				AdServiceData{Uuid: "180F", Data: []byte{0x64}},
+				AdAppearance{Appearance: 0x03C1},
+				nil,
+			},
+		},
+		{
+			name:    "length exceeds buffer",
+			buf:     []byte{0x02, 0x01, 0x06, 0x05, 0x09, 'b', 'a'},
+			want:    []interface{}{AdFlags{Flags: 0x06}},
+			wantErr: true,
+		},
+	}
+	for _, test := range testList {
+		adStructList, err := AdStructListParse(test.buf)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: err = %v, want error %v", test.name, err, test.wantErr)
+		}
+		if len(adStructList) != len(test.want) {
+			t.Errorf("%s: %d ad structs, want %d", test.name, len(adStructList), len(test.want))
+			continue
+		}
+		for index, adStruct := range adStructList {
+			if !reflect.DeepEqual(adStruct.PayloadParsedResult.Ret, test.want[index]) {
+				t.Errorf("%s: ad struct %d = %+v, want %+v", test.name, index, adStruct.PayloadParsedResult.Ret, test.want[index])
+			}
+		}
+	}
+}
+
+func TestLeAdvertisingReportEventParse(t *testing.T) {
+	testList := []struct {
+		name     string
+		buf      []byte // LE Meta Event参数，从Subevent_Code开始
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"legacy report",
+			[]byte{LE_ADVERTISING_REPORT_EVENT, 0x01, 0x00, 0x01, 0x66, 0x55, 0x44, 0x33, 0x22, 0xC1, 0x03, 0x02, 0x01, 0x06, 0xB5},
+			HCI_PKT_RET_CODE_OK,
+			LeAdvertisingReportEvent{SubEventCode: LE_ADVERTISING_REPORT_EVENT, NumReports: 1, ReportList: []LeAdvertisingReport{{
+				EventType: ADV_REPORT_EVENT_TYPE_ADV_IND, AddressType: LE_ADDRESS_TYPE_RANDOM, Address: [6]byte{0xC1, 0x22, 0x33, 0x44, 0x55, 0x66},
+				DataLength: 3, Data: []byte{0x02, 0x01, 0x06},
+				AdStructList: []AdStruct{{Length: 2, AdType: AD_TYPE_FLAGS, Data: []byte{0x06}, PayloadParsedResult: AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, AdType: AD_TYPE_FLAGS, Ret: AdFlags{Flags: 0x06}}}},
+				Rssi:         -75,
+			}}},
+		},
+		{
+			"legacy report without rssi",
+			[]byte{LE_ADVERTISING_REPORT_EVENT, 0x01, 0x00, 0x01, 0x66, 0x55, 0x44, 0x33, 0x22, 0xC1, 0x03, 0x02, 0x01, 0x06},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"second report missing",
+			[]byte{LE_ADVERTISING_REPORT_EVENT, 0x02, 0x04, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x00, 0xB5},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"directed report",
+			[]byte{LE_DIRECTED_ADVERTISING_REPORT_EVENT, 0x01, 0x01, 0x01, 0x66, 0x55, 0x44, 0x33, 0x22, 0xC1, 0x01, 0x06, 0x05, 0x04, 0x03, 0x02, 0x41, 0xB0},
+			HCI_PKT_RET_CODE_OK,
+			LeDirectedAdvertisingReportEvent{SubEventCode: LE_DIRECTED_ADVERTISING_REPORT_EVENT, NumReports: 1, ReportList: []LeDirectedAdvertisingReport{{
+				EventType: ADV_REPORT_EVENT_TYPE_ADV_DIRECT_IND, AddressType: LE_ADDRESS_TYPE_RANDOM, Address: [6]byte{0xC1, 0x22, 0x33, 0x44, 0x55, 0x66},
+				DirectAddressType: LE_ADDRESS_TYPE_RANDOM, DirectAddress: [6]byte{0x41, 0x02, 0x03, 0x04, 0x05, 0x06}, Rssi: -80,
+			}}},
+		},
+		{
+			"directed report truncated",
+			[]byte{LE_DIRECTED_ADVERTISING_REPORT_EVENT, 0x01, 0x01, 0x01, 0x66, 0x55, 0x44, 0x33, 0x22, 0xC1, 0x01},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"extended report, scan response on 2M",
+			[]byte{
+				LE_EXTENDED_ADVERTISING_REPORT_EVENT, 0x01,
+				0x1B, 0x00, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, // Event_Type: connectable, scannable, scan response, legacy
+				0x01, 0x02, 0x03, 0x7F, 0xC4, 0x00, 0x00, // PHY, SID, TX_Power不可用, RSSI, Periodic_Advertising_Interval
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Direct_Address
+			},
+			HCI_PKT_RET_CODE_OK,
+			LeExtendedAdvertisingReportEvent{SubEventCode: LE_EXTENDED_ADVERTISING_REPORT_EVENT, NumReports: 1, ReportList: []LeExtendedAdvertisingReport{{
+				EventType:   EXT_ADV_REPORT_EVENT_TYPE_CONNECTABLE | EXT_ADV_REPORT_EVENT_TYPE_SCANNABLE | EXT_ADV_REPORT_EVENT_TYPE_SCAN_RESPONSE | EXT_ADV_REPORT_EVENT_TYPE_LEGACY,
+				AddressType: LE_ADDRESS_TYPE_PUBLIC, Address: [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66},
+				PrimaryPhy: LE_PHY_1M, SecondaryPhy: LE_PHY_2M, AdvertisingSid: 0x03, TxPower: EXT_ADV_REPORT_TX_POWER_NOT_AVAILABLE, Rssi: -60,
+				Data: []byte{},
+			}}},
+		},
+		{
+			"extended report data truncated",
+			[]byte{
+				LE_EXTENDED_ADVERTISING_REPORT_EVENT, 0x01,
+				0x20, 0x00, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11,
+				0x01, 0x00, 0xFF, 0x7F, 0xC4, 0x00, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x02, 0x01,
+			},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+	}
+	for _, test := range testList {
+		parsed := HciEvtPktParse(HCI_EVT_LE_META_EVENT, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
+
+func TestLeExtendedAdvertisingReportDataStatus(t *testing.T) {
+	testList := []struct {
+		eventType uint16
+		want      uint8
+	}{
+		{0x0013, EXT_ADV_REPORT_DATA_STATUS_COMPLETE},
+		{0x0020, EXT_ADV_REPORT_DATA_STATUS_INCOMPLETE_MORE_DATA},
+		{0x0041, EXT_ADV_REPORT_DATA_STATUS_INCOMPLETE_TRUNCATED},
+	}
+	for _, test := range testList {
+		if got := (LeExtendedAdvertisingReport{EventType: test.eventType}).DataStatus(); got != test.want {
+			t.Errorf("Event_Type %#04x: DataStatus() = %d, want %d", test.eventType, got, test.want)
+		}
+	}
+}
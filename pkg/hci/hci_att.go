@@ -0,0 +1,674 @@
+// ATT PDU解析
+// OpCode保留完整的8bit，bit6为Command Flag，bit7为Authentication Signature Flag
+// Value等变长字段直接引用L2CAP payload(ConnectionOrientedChannelsInBasicFrame中已复制)，不再复制
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.8 Attribute Opcode summary
+const (
+	ATT_ERROR_RESPONSE                     = 0x01
+	ATT_EXCHANGE_MTU_REQUEST               = 0x02
+	ATT_EXCHANGE_MTU_RESPONSE              = 0x03
+	ATT_FIND_INFORMATION_REQUEST           = 0x04
+	ATT_FIND_INFORMATION_RESPONSE          = 0x05
+	ATT_FIND_BY_TYPE_VALUE_REQUEST         = 0x06
+	ATT_FIND_BY_TYPE_VALUE_RESPONSE        = 0x07
+	ATT_READ_BY_TYPE_REQUEST               = 0x08
+	ATT_READ_BY_TYPE_RESPONSE              = 0x09
+	ATT_READ_REQUEST                       = 0x0A
+	ATT_READ_RESPONSE                      = 0x0B
+	ATT_READ_BLOB_REQUEST                  = 0x0C
+	ATT_READ_BLOB_RESPONSE                 = 0x0D
+	ATT_READ_MULTIPLE_REQUEST              = 0x0E
+	ATT_READ_MULTIPLE_RESPONSE             = 0x0F
+	ATT_READ_BY_GROUP_TYPE_REQUEST         = 0x10
+	ATT_READ_BY_GROUP_TYPE_RESPONSE        = 0x11
+	ATT_WRITE_REQUEST                      = 0x12
+	ATT_WRITE_RESPONSE                     = 0x13
+	ATT_PREPARE_WRITE_REQUEST              = 0x16
+	ATT_PREPARE_WRITE_RESPONSE             = 0x17
+	ATT_EXECUTE_WRITE_REQUEST              = 0x18
+	ATT_EXECUTE_WRITE_RESPONSE             = 0x19
+	ATT_HANDLE_VALUE_NOTIFICATION          = 0x1B
+	ATT_HANDLE_VALUE_INDICATION            = 0x1D
+	ATT_HANDLE_VALUE_CONFIRMATION          = 0x1E
+	ATT_READ_MULTIPLE_VARIABLE_REQUEST     = 0x20
+	ATT_READ_MULTIPLE_VARIABLE_RESPONSE    = 0x21
+	ATT_MULTIPLE_HANDLE_VALUE_NOTIFICATION = 0x23
+	ATT_WRITE_COMMAND                      = 0x52
+	ATT_SIGNED_WRITE_COMMAND               = 0xD2
+)
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.3.1 Attribute PDU format
+const (
+	ATT_OPCODE_COMMAND_FLAG          = 0x40
+	ATT_OPCODE_AUTHENTICATION_FLAG   = 0x80
+	ATT_AUTHENTICATION_SIGNATURE_LEN = 12
+)
+
+var AttOpCodeStrMap = map[int]string{
+	ATT_ERROR_RESPONSE:                     "ATT_ERROR_RSP",
+	ATT_EXCHANGE_MTU_REQUEST:               "ATT_EXCHANGE_MTU_REQ",
+	ATT_EXCHANGE_MTU_RESPONSE:              "ATT_EXCHANGE_MTU_RSP",
+	ATT_FIND_INFORMATION_REQUEST:           "ATT_FIND_INFORMATION_REQ",
+	ATT_FIND_INFORMATION_RESPONSE:          "ATT_FIND_INFORMATION_RSP",
+	ATT_FIND_BY_TYPE_VALUE_REQUEST:         "ATT_FIND_BY_TYPE_VALUE_REQ",
+	ATT_FIND_BY_TYPE_VALUE_RESPONSE:        "ATT_FIND_BY_TYPE_VALUE_RSP",
+	ATT_READ_BY_TYPE_REQUEST:               "ATT_READ_BY_TYPE_REQ",
+	ATT_READ_BY_TYPE_RESPONSE:              "ATT_READ_BY_TYPE_RSP",
+	ATT_READ_REQUEST:                       "ATT_READ_REQ",
+	ATT_READ_RESPONSE:                      "ATT_READ_RSP",
+	ATT_READ_BLOB_REQUEST:                  "ATT_READ_BLOB_REQ",
+	ATT_READ_BLOB_RESPONSE:                 "ATT_READ_BLOB_RSP",
+	ATT_READ_MULTIPLE_REQUEST:              "ATT_READ_MULTIPLE_REQ",
+	ATT_READ_MULTIPLE_RESPONSE:             "ATT_READ_MULTIPLE_RSP",
+	ATT_READ_BY_GROUP_TYPE_REQUEST:         "ATT_READ_BY_GROUP_TYPE_REQ",
+	ATT_READ_BY_GROUP_TYPE_RESPONSE:        "ATT_READ_BY_GROUP_TYPE_RSP",
+	ATT_WRITE_REQUEST:                      "ATT_WRITE_REQ",
+	ATT_WRITE_RESPONSE:                     "ATT_WRITE_RSP",
+	ATT_PREPARE_WRITE_REQUEST:              "ATT_PREPARE_WRITE_REQ",
+	ATT_PREPARE_WRITE_RESPONSE:             "ATT_PREPARE_WRITE_RSP",
+	ATT_EXECUTE_WRITE_REQUEST:              "ATT_EXECUTE_WRITE_REQ",
+	ATT_EXECUTE_WRITE_RESPONSE:             "ATT_EXECUTE_WRITE_RSP",
+	ATT_HANDLE_VALUE_NOTIFICATION:          "ATT_HANDLE_VALUE_NTF",
+	ATT_HANDLE_VALUE_INDICATION:            "ATT_HANDLE_VALUE_IND",
+	ATT_HANDLE_VALUE_CONFIRMATION:          "ATT_HANDLE_VALUE_CFM",
+	ATT_READ_MULTIPLE_VARIABLE_REQUEST:     "ATT_READ_MULTIPLE_VARIABLE_REQ",
+	ATT_READ_MULTIPLE_VARIABLE_RESPONSE:    "ATT_READ_MULTIPLE_VARIABLE_RSP",
+	ATT_MULTIPLE_HANDLE_VALUE_NOTIFICATION: "ATT_MULTIPLE_HANDLE_VALUE_NTF",
+	ATT_WRITE_COMMAND:                      "ATT_WRITE_CMD",
+	ATT_SIGNED_WRITE_COMMAND:               "ATT_SIGNED_WRITE_CMD",
+}
+
+// ATT_ERROR_RSP的Error Code
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.1.1 ATT_ERROR_RSP
+const (
+	ATT_ERROR_INVALID_HANDLE                     = 0x01
+	ATT_ERROR_READ_NOT_PERMITTED                 = 0x02
+	ATT_ERROR_WRITE_NOT_PERMITTED                = 0x03
+	ATT_ERROR_INVALID_PDU                        = 0x04
+	ATT_ERROR_INSUFFICIENT_AUTHENTICATION        = 0x05
+	ATT_ERROR_REQUEST_NOT_SUPPORTED              = 0x06
+	ATT_ERROR_INVALID_OFFSET                     = 0x07
+	ATT_ERROR_INSUFFICIENT_AUTHORIZATION         = 0x08
+	ATT_ERROR_PREPARE_QUEUE_FULL                 = 0x09
+	ATT_ERROR_ATTRIBUTE_NOT_FOUND                = 0x0A
+	ATT_ERROR_ATTRIBUTE_NOT_LONG                 = 0x0B
+	ATT_ERROR_ENCRYPTION_KEY_SIZE_TOO_SHORT      = 0x0C
+	ATT_ERROR_INVALID_ATTRIBUTE_VALUE_LENGTH     = 0x0D
+	ATT_ERROR_UNLIKELY_ERROR                     = 0x0E
+	ATT_ERROR_INSUFFICIENT_ENCRYPTION            = 0x0F
+	ATT_ERROR_UNSUPPORTED_GROUP_TYPE             = 0x10
+	ATT_ERROR_INSUFFICIENT_RESOURCES             = 0x11
+	ATT_ERROR_DATABASE_OUT_OF_SYNC               = 0x12
+	ATT_ERROR_VALUE_NOT_ALLOWED                  = 0x13
+	ATT_ERROR_APPLICATION_ERROR_START            = 0x80 // 0x80-0x9F为上层应用定义
+	ATT_ERROR_APPLICATION_ERROR_END              = 0x9F
+	ATT_ERROR_COMMON_PROFILE_SERVICE_ERROR_START = 0xE0 // 0xE0-0xFF在CSS中定义
+)
+
+var AttErrorStrMap = map[int]string{
+	ATT_ERROR_INVALID_HANDLE:                 "Invalid Handle",
+	ATT_ERROR_READ_NOT_PERMITTED:             "Read Not Permitted",
+	ATT_ERROR_WRITE_NOT_PERMITTED:            "Write Not Permitted",
+	ATT_ERROR_INVALID_PDU:                    "Invalid PDU",
+	ATT_ERROR_INSUFFICIENT_AUTHENTICATION:    "Insufficient Authentication",
+	ATT_ERROR_REQUEST_NOT_SUPPORTED:          "Request Not Supported",
+	ATT_ERROR_INVALID_OFFSET:                 "Invalid Offset",
+	ATT_ERROR_INSUFFICIENT_AUTHORIZATION:     "Insufficient Authorization",
+	ATT_ERROR_PREPARE_QUEUE_FULL:             "Prepare Queue Full",
+	ATT_ERROR_ATTRIBUTE_NOT_FOUND:            "Attribute Not Found",
+	ATT_ERROR_ATTRIBUTE_NOT_LONG:             "Attribute Not Long",
+	ATT_ERROR_ENCRYPTION_KEY_SIZE_TOO_SHORT:  "Encryption Key Size Too Short",
+	ATT_ERROR_INVALID_ATTRIBUTE_VALUE_LENGTH: "Invalid Attribute Value Length",
+	ATT_ERROR_UNLIKELY_ERROR:                 "Unlikely Error",
+	ATT_ERROR_INSUFFICIENT_ENCRYPTION:        "Insufficient Encryption",
+	ATT_ERROR_UNSUPPORTED_GROUP_TYPE:         "Unsupported Group Type",
+	ATT_ERROR_INSUFFICIENT_RESOURCES:         "Insufficient Resources",
+	ATT_ERROR_DATABASE_OUT_OF_SYNC:           "Database Out Of Sync",
+	ATT_ERROR_VALUE_NOT_ALLOWED:              "Value Not Allowed",
+}
+
+// ATT_FIND_INFORMATION_RSP的Format
+const (
+	ATT_FIND_INFORMATION_FORMAT_16BIT  = 0x01
+	ATT_FIND_INFORMATION_FORMAT_128BIT = 0x02
+)
+
+// ATT_EXECUTE_WRITE_REQ的Flags
+const (
+	ATT_EXECUTE_WRITE_CANCEL = 0x00 // 取消所有prepared write
+	ATT_EXECUTE_WRITE_COMMIT = 0x01 // 写入所有prepared value
+)
+
+type AttPktParser func(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult
+
+var AttPktParserMap map[int]AttPktParser = map[int]AttPktParser{
+	ATT_ERROR_RESPONSE:                     AttErrorResponseParser,
+	ATT_EXCHANGE_MTU_REQUEST:               AttExchangeMtuParser,
+	ATT_EXCHANGE_MTU_RESPONSE:              AttExchangeMtuParser,
+	ATT_FIND_INFORMATION_REQUEST:           AttFindInformationRequestParser,
+	ATT_FIND_INFORMATION_RESPONSE:          AttFindInformationResponseParser,
+	ATT_FIND_BY_TYPE_VALUE_REQUEST:         AttFindByTypeValueRequestParser,
+	ATT_FIND_BY_TYPE_VALUE_RESPONSE:        AttFindByTypeValueResponseParser,
+	ATT_READ_BY_TYPE_REQUEST:               AttReadByTypeRequestParser,
+	ATT_READ_BY_TYPE_RESPONSE:              AttReadByTypeResponseParser,
+	ATT_READ_REQUEST:                       AttReadRequestParser,
+	ATT_READ_RESPONSE:                      AttReadResponseParser,
+	ATT_READ_BLOB_REQUEST:                  AttReadBlobRequestParser,
+	ATT_READ_BLOB_RESPONSE:                 AttReadResponseParser,
+	ATT_READ_MULTIPLE_REQUEST:              AttReadMultipleRequestParser,
+	ATT_READ_MULTIPLE_RESPONSE:             AttReadResponseParser,
+	ATT_READ_BY_GROUP_TYPE_REQUEST:         AttReadByTypeRequestParser,
+	ATT_READ_BY_GROUP_TYPE_RESPONSE:        AttReadByGroupTypeResponseParser,
+	ATT_WRITE_REQUEST:                      AttPktWriteRequestParser,
+	ATT_WRITE_RESPONSE:                     AttEmptyPduParser,
+	ATT_PREPARE_WRITE_REQUEST:              AttPrepareWriteParser,
+	ATT_PREPARE_WRITE_RESPONSE:             AttPrepareWriteParser,
+	ATT_EXECUTE_WRITE_REQUEST:              AttExecuteWriteRequestParser,
+	ATT_EXECUTE_WRITE_RESPONSE:             AttEmptyPduParser,
+	ATT_HANDLE_VALUE_NOTIFICATION:          AttHandleValueParser,
+	ATT_HANDLE_VALUE_INDICATION:            AttHandleValueParser,
+	ATT_HANDLE_VALUE_CONFIRMATION:          AttEmptyPduParser,
+	ATT_READ_MULTIPLE_VARIABLE_REQUEST:     AttReadMultipleRequestParser,
+	ATT_READ_MULTIPLE_VARIABLE_RESPONSE:    AttReadMultipleVariableResponseParser,
+	ATT_MULTIPLE_HANDLE_VALUE_NOTIFICATION: AttMultipleHandleValueNotificationParser,
+	ATT_WRITE_COMMAND:                      AttWriteCommandParser,
+	ATT_SIGNED_WRITE_COMMAND:               AttWriteCommandParser,
+}
+
+func AttPktDefaultParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
+}
+
+// ATT信道(L2CAP_CID_ATT，BR/EDR上PSM为ATT的信道，EATT信道的SDU)
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.3.1 Attribute PDU format
+func AttPktParse(ChannelId uint16, l2capPayloadBuf []byte) HciAclPktParseResult {
+	if len(l2capPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	attPktIndex := 0
+	var OpCode uint8 = l2capPayloadBuf[attPktIndex]
+	attPktIndex += binary.Size(OpCode)
+	attPayloadBuf := l2capPayloadBuf[attPktIndex:]
+	parser, ok := AttPktParserMap[int(OpCode)]
+	if !ok {
+		parser = AttPktDefaultParser
+	}
+	parsed := parser(OpCode, attPayloadBuf)
+	parsed.OpCode = OpCode
+	return parsed
+}
+
+// ATT_WRITE_RSP/ATT_EXECUTE_WRITE_RSP/ATT_HANDLE_VALUE_CFM没有参数
+type AttEmptyPdu struct {
+	OpCode uint8
+}
+
+func AttEmptyPduParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: AttEmptyPdu{OpCode: OpCode}}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.1.1 ATT_ERROR_RSP
+type AttErrorResponse struct {
+	RequestOpCode uint8 // 出错的请求
+	Handle        uint16
+	ErrorCode     uint8 // ATT_ERROR_XXX
+}
+
+func AttErrorResponseParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttErrorResponse{}
+	if len(attPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.RequestOpCode = attPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.RequestOpCode)
+	pkt.Handle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Handle)
+	pkt.ErrorCode = attPayloadBuf[pktIndex]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.2 MTU exchange
+type AttExchangeMtu struct {
+	Response bool   // ATT_EXCHANGE_MTU_RSP
+	Mtu      uint16 // 请求为Client Rx MTU，响应为Server Rx MTU
+}
+
+func AttExchangeMtuParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttExchangeMtu{Response: OpCode == ATT_EXCHANGE_MTU_RESPONSE}
+	if len(attPayloadBuf) < 2 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Mtu = binary.LittleEndian.Uint16(attPayloadBuf)
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.3.1 ATT_FIND_INFORMATION_REQ
+type AttFindInformationRequest struct {
+	StartingHandle uint16
+	EndingHandle   uint16
+}
+
+func AttFindInformationRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttFindInformationRequest{}
+	if len(attPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.StartingHandle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.StartingHandle)
+	pkt.EndingHandle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+type AttHandleUuid struct {
+	Handle uint16
+	Uuid   string
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.3.2 ATT_FIND_INFORMATION_RSP
+type AttFindInformationResponse struct {
+	Format          uint8 // ATT_FIND_INFORMATION_FORMAT_XXX
+	InformationList []AttHandleUuid
+}
+
+func AttFindInformationResponseParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttFindInformationResponse{}
+	if len(attPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Format = attPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.Format)
+	uuidSize := 2
+	switch pkt.Format {
+	case ATT_FIND_INFORMATION_FORMAT_16BIT:
+	case ATT_FIND_INFORMATION_FORMAT_128BIT:
+		uuidSize = 16
+	default:
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
+	}
+	for ; pktIndex+2+uuidSize <= len(attPayloadBuf); pktIndex += 2 + uuidSize {
+		information := AttHandleUuid{Handle: binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])}
+		information.Uuid = UuidString(attPayloadBuf[pktIndex+2 : pktIndex+2+uuidSize])
+		pkt.InformationList = append(pkt.InformationList, information)
+	}
+	if pktIndex != len(attPayloadBuf) {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.3.3 ATT_FIND_BY_TYPE_VALUE_REQ
+type AttFindByTypeValueRequest struct {
+	StartingHandle uint16
+	EndingHandle   uint16
+	AttributeType  uint16 // 16bit UUID
+	Value          []byte
+}
+
+func AttFindByTypeValueRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttFindByTypeValueRequest{}
+	if len(attPayloadBuf) < 6 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.StartingHandle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.StartingHandle)
+	pkt.EndingHandle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.EndingHandle)
+	pkt.AttributeType = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.AttributeType)
+	pkt.Value = attPayloadBuf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+type AttHandlesInformation struct {
+	FoundAttributeHandle uint16
+	GroupEndHandle       uint16
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.3.4 ATT_FIND_BY_TYPE_VALUE_RSP
+type AttFindByTypeValueResponse struct {
+	HandlesInformationList []AttHandlesInformation
+}
+
+func AttFindByTypeValueResponseParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttFindByTypeValueResponse{}
+	if len(attPayloadBuf)%4 != 0 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for pktIndex := 0; pktIndex < len(attPayloadBuf); pktIndex += 4 {
+		pkt.HandlesInformationList = append(pkt.HandlesInformationList, AttHandlesInformation{
+			FoundAttributeHandle: binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:]),
+			GroupEndHandle:       binary.LittleEndian.Uint16(attPayloadBuf[pktIndex+2:]),
+		})
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// ATT_READ_BY_TYPE_REQ/ATT_READ_BY_GROUP_TYPE_REQ，GroupType区分
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.4.1 ATT_READ_BY_TYPE_REQ / 3.4.4.9 ATT_READ_BY_GROUP_TYPE_REQ
+type AttReadByTypeRequest struct {
+	GroupType      bool
+	StartingHandle uint16
+	EndingHandle   uint16
+	AttributeType  string // 16bit或128bit UUID
+}
+
+func AttReadByTypeRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttReadByTypeRequest{GroupType: OpCode == ATT_READ_BY_GROUP_TYPE_REQUEST}
+	if len(attPayloadBuf) != 6 && len(attPayloadBuf) != 20 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.StartingHandle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.StartingHandle)
+	pkt.EndingHandle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.EndingHandle)
+	pkt.AttributeType = UuidString(attPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+type AttHandleValue struct {
+	Handle uint16
+	Value  []byte
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.4.2 ATT_READ_BY_TYPE_RSP
+type AttReadByTypeResponse struct {
+	Length            uint8 // 每个Handle Value的长度
+	AttributeDataList []AttHandleValue
+}
+
+func AttReadByTypeResponseParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttReadByTypeResponse{}
+	if len(attPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Length = attPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.Length)
+	length := int(pkt.Length)
+	if length < 2 || len(attPayloadBuf[pktIndex:])%length != 0 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for ; pktIndex < len(attPayloadBuf); pktIndex += length {
+		pkt.AttributeDataList = append(pkt.AttributeDataList, AttHandleValue{
+			Handle: binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:]),
+			Value:  attPayloadBuf[pktIndex+2 : pktIndex+length],
+		})
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.4.3 ATT_READ_REQ
+type AttReadRequest struct {
+	Handle uint16
+}
+
+func AttReadRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttReadRequest{}
+	if len(attPayloadBuf) < 2 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Handle = binary.LittleEndian.Uint16(attPayloadBuf)
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// ATT_READ_RSP/ATT_READ_BLOB_RSP/ATT_READ_MULTIPLE_RSP，OpCode区分
+// ATT_READ_BLOB_RSP为Part Attribute Value，ATT_READ_MULTIPLE_RSP为多个Attribute Value拼接，长度由上层确定
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.4.4 ATT_READ_RSP / 3.4.4.6 ATT_READ_BLOB_RSP / 3.4.4.8 ATT_READ_MULTIPLE_RSP
+type AttReadResponse struct {
+	OpCode uint8
+	Value  []byte
+}
+
+func AttReadResponseParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttReadResponse{OpCode: OpCode, Value: attPayloadBuf}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.4.5 ATT_READ_BLOB_REQ
+type AttReadBlobRequest struct {
+	Handle uint16
+	Offset uint16
+}
+
+func AttReadBlobRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttReadBlobRequest{}
+	if len(attPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Handle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Handle)
+	pkt.Offset = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// ATT_READ_MULTIPLE_REQ/ATT_READ_MULTIPLE_VARIABLE_REQ，Variable区分
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.4.7 ATT_READ_MULTIPLE_REQ / 3.4.4.11 ATT_READ_MULTIPLE_VARIABLE_REQ
+type AttReadMultipleRequest struct {
+	Variable     bool
+	SetOfHandles []uint16
+}
+
+func AttReadMultipleRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttReadMultipleRequest{Variable: OpCode == ATT_READ_MULTIPLE_VARIABLE_REQUEST}
+	if len(attPayloadBuf) < 4 || len(attPayloadBuf)%2 != 0 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for pktIndex := 0; pktIndex < len(attPayloadBuf); pktIndex += 2 {
+		pkt.SetOfHandles = append(pkt.SetOfHandles, binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:]))
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.4.12 ATT_READ_MULTIPLE_VARIABLE_RSP
+type AttReadMultipleVariableResponse struct {
+	LengthValueList []AttLengthValue
+}
+
+// Length为属性值的完整长度，超过ATT_MTU时最后一个Value被截断
+type AttLengthValue struct {
+	Length uint16
+	Value  []byte
+}
+
+func AttReadMultipleVariableResponseParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttReadMultipleVariableResponse{}
+	pktIndex := 0
+	for pktIndex+2 <= len(attPayloadBuf) {
+		tuple := AttLengthValue{Length: binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])}
+		pktIndex += binary.Size(tuple.Length)
+		valueEnd := pktIndex + int(tuple.Length)
+		if valueEnd > len(attPayloadBuf) {
+			valueEnd = len(attPayloadBuf)
+		}
+		tuple.Value = attPayloadBuf[pktIndex:valueEnd]
+		pktIndex = valueEnd
+		pkt.LengthValueList = append(pkt.LengthValueList, tuple)
+	}
+	if pktIndex != len(attPayloadBuf) {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+type AttGroupData struct {
+	Handle         uint16
+	EndGroupHandle uint16
+	Value          []byte
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.4.10 ATT_READ_BY_GROUP_TYPE_RSP
+type AttReadByGroupTypeResponse struct {
+	Length            uint8 // 每个Attribute Data的长度
+	AttributeDataList []AttGroupData
+}
+
+func AttReadByGroupTypeResponseParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttReadByGroupTypeResponse{}
+	if len(attPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Length = attPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.Length)
+	length := int(pkt.Length)
+	if length < 4 || len(attPayloadBuf[pktIndex:])%length != 0 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for ; pktIndex < len(attPayloadBuf); pktIndex += length {
+		pkt.AttributeDataList = append(pkt.AttributeDataList, AttGroupData{
+			Handle:         binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:]),
+			EndGroupHandle: binary.LittleEndian.Uint16(attPayloadBuf[pktIndex+2:]),
+			Value:          attPayloadBuf[pktIndex+4 : pktIndex+length],
+		})
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.5.1 ATT_WRITE_REQ
+type AttWriteRequest struct {
+	OpCode uint8
+	Handle uint16
+	Value  []byte
+}
+
+func AttPktWriteRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttWriteRequest{}
+	if len(attPayloadBuf) < 2 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.OpCode = OpCode
+
+	pktIndex := 0
+	pkt.Handle = binary.LittleEndian.Uint16(attPayloadBuf)
+	pktIndex += binary.Size(pkt.Handle)
+	pkt.Value = attPayloadBuf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// ATT_WRITE_CMD/ATT_SIGNED_WRITE_CMD，Signed区分
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.5.3 ATT_WRITE_CMD / 3.4.5.4 ATT_SIGNED_WRITE_CMD
+type AttWriteCommand struct {
+	Signed                  bool
+	Handle                  uint16
+	Value                   []byte
+	AuthenticationSignature []byte // 仅ATT_SIGNED_WRITE_CMD
+}
+
+func AttWriteCommandParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttWriteCommand{Signed: OpCode&ATT_OPCODE_AUTHENTICATION_FLAG != 0}
+	valueEnd := len(attPayloadBuf)
+	if pkt.Signed {
+		valueEnd -= ATT_AUTHENTICATION_SIGNATURE_LEN
+	}
+	if valueEnd < 2 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Handle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Handle)
+	pkt.Value = attPayloadBuf[pktIndex:valueEnd]
+	if pkt.Signed {
+		pkt.AuthenticationSignature = attPayloadBuf[valueEnd:]
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// ATT_PREPARE_WRITE_REQ/ATT_PREPARE_WRITE_RSP，响应回显请求的参数
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.6.1 ATT_PREPARE_WRITE_REQ / 3.4.6.2 ATT_PREPARE_WRITE_RSP
+type AttPrepareWrite struct {
+	Response  bool
+	Handle    uint16
+	Offset    uint16
+	PartValue []byte
+}
+
+func AttPrepareWriteParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttPrepareWrite{Response: OpCode == ATT_PREPARE_WRITE_RESPONSE}
+	if len(attPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Handle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Handle)
+	pkt.Offset = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Offset)
+	pkt.PartValue = attPayloadBuf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.6.3 ATT_EXECUTE_WRITE_REQ
+type AttExecuteWriteRequest struct {
+	Flags uint8 // ATT_EXECUTE_WRITE_XXX
+}
+
+func AttExecuteWriteRequestParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	if len(attPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: AttExecuteWriteRequest{Flags: attPayloadBuf[0]}}
+}
+
+// ATT_HANDLE_VALUE_NTF/ATT_HANDLE_VALUE_IND，Indication区分
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.7.1 ATT_HANDLE_VALUE_NTF / 3.4.7.2 ATT_HANDLE_VALUE_IND
+type AttHandleValueNotification struct {
+	Indication bool
+	Handle     uint16
+	Value      []byte
+}
+
+func AttHandleValueParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttHandleValueNotification{Indication: OpCode == ATT_HANDLE_VALUE_INDICATION}
+	if len(attPayloadBuf) < 2 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Handle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Handle)
+	pkt.Value = attPayloadBuf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+type AttHandleLengthValue struct {
+	Handle uint16
+	Length uint16
+	Value  []byte
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part F 3.4.7.4 ATT_MULTIPLE_HANDLE_VALUE_NTF
+type AttMultipleHandleValueNotification struct {
+	HandleLengthValueList []AttHandleLengthValue
+}
+
+func AttMultipleHandleValueNotificationParser(OpCode uint8, attPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AttMultipleHandleValueNotification{}
+	pktIndex := 0
+	for pktIndex+4 <= len(attPayloadBuf) {
+		tuple := AttHandleLengthValue{}
+		tuple.Handle = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(tuple.Handle)
+		tuple.Length = binary.LittleEndian.Uint16(attPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(tuple.Length)
+		if pktIndex+int(tuple.Length) > len(attPayloadBuf) {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		tuple.Value = attPayloadBuf[pktIndex : pktIndex+int(tuple.Length)]
+		pktIndex += int(tuple.Length)
+		pkt.HandleLengthValueList = append(pkt.HandleLengthValueList, tuple)
+	}
+	if pktIndex != len(attPayloadBuf) {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
@@ -0,0 +1,124 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttPktParse(t *testing.T) {
+	signature := []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c}
+	testList := []struct {
+		name       string
+		buf        []byte // ATT PDU，含OpCode
+		wantCode   int
+		wantOpCode uint8
+		want       interface{} // 仅wantCode为HCI_PKT_RET_CODE_OK时比较
+	}{
+		{
+			name:       "write command",
+			buf:        []byte{0x52, 0x03, 0x00, 0xaa, 0xbb},
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			wantOpCode: ATT_WRITE_COMMAND,
+			want:       AttWriteCommand{Handle: 0x0003, Value: []byte{0xaa, 0xbb}},
+		},
+		{
+			name:       "signed write command",
+			buf:        append([]byte{0xd2, 0x03, 0x00, 0xaa}, signature...),
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			wantOpCode: ATT_SIGNED_WRITE_COMMAND,
+			want:       AttWriteCommand{Signed: true, Handle: 0x0003, Value: []byte{0xaa}, AuthenticationSignature: signature},
+		},
+		{
+			name:       "signed write command without signature",
+			buf:        []byte{0xd2, 0x03, 0x00, 0xaa},
+			wantCode:   HCI_PKT_RET_CODE_INVALID_LEN,
+			wantOpCode: ATT_SIGNED_WRITE_COMMAND,
+		},
+		{
+			name:       "write request",
+			buf:        []byte{0x12, 0x18, 0x00, 0x01, 0x00},
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			wantOpCode: ATT_WRITE_REQUEST,
+			want:       AttWriteRequest{OpCode: ATT_WRITE_REQUEST, Handle: 0x0018, Value: []byte{0x01, 0x00}},
+		},
+		{
+			name:       "read by type request 16bit uuid",
+			buf:        []byte{0x08, 0x01, 0x00, 0xff, 0xff, 0x03, 0x28},
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			wantOpCode: ATT_READ_BY_TYPE_REQUEST,
+			want:       AttReadByTypeRequest{StartingHandle: 0x0001, EndingHandle: 0xffff, AttributeType: "2803"},
+		},
+		{
+			name:       "read by type request invalid uuid length",
+			buf:        []byte{0x08, 0x01, 0x00, 0xff, 0xff, 0x03, 0x28, 0x00},
+			wantCode:   HCI_PKT_RET_CODE_INVALID_LEN,
+			wantOpCode: ATT_READ_BY_TYPE_REQUEST,
+		},
+		{
+			name:       "read by type response",
+			buf:        []byte{0x09, 0x07, 0x02, 0x00, 0x02, 0x03, 0x00, 0x00, 0x2a, 0x04, 0x00, 0x02, 0x05, 0x00, 0x01, 0x2a},
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			wantOpCode: ATT_READ_BY_TYPE_RESPONSE,
+			want: AttReadByTypeResponse{Length: 7, AttributeDataList: []AttHandleValue{
+				{Handle: 0x0002, Value: []byte{0x02, 0x03, 0x00, 0x00, 0x2a}},
+				{Handle: 0x0004, Value: []byte{0x02, 0x05, 0x00, 0x01, 0x2a}},
+			}},
+		},
+		{
+			name:       "read by type response partial attribute data",
+			buf:        []byte{0x09, 0x07, 0x02, 0x00, 0x02, 0x03, 0x00, 0x00, 0x2a, 0x04, 0x00},
+			wantCode:   HCI_PKT_RET_CODE_INVALID_LEN,
+			wantOpCode: ATT_READ_BY_TYPE_RESPONSE,
+		},
+		{
+			name:       "read multiple variable request",
+			buf:        []byte{0x20, 0x03, 0x00, 0x05, 0x00},
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			wantOpCode: ATT_READ_MULTIPLE_VARIABLE_REQUEST,
+			want:       AttReadMultipleRequest{Variable: true, SetOfHandles: []uint16{0x0003, 0x0005}},
+		},
+		{
+			name:       "read multiple variable response, last value truncated",
+			buf:        []byte{0x21, 0x02, 0x00, 0xaa, 0xbb, 0x05, 0x00, 0xcc, 0xdd},
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			wantOpCode: ATT_READ_MULTIPLE_VARIABLE_RESPONSE,
+			want: AttReadMultipleVariableResponse{LengthValueList: []AttLengthValue{
+				{Length: 2, Value: []byte{0xaa, 0xbb}},
+				{Length: 5, Value: []byte{0xcc, 0xdd}},
+			}},
+		},
+		{
+			name:       "read multiple variable response, dangling length byte",
+			buf:        []byte{0x21, 0x01, 0x00, 0xaa, 0x01},
+			wantCode:   HCI_PKT_RET_CODE_INVALID_LEN,
+			wantOpCode: ATT_READ_MULTIPLE_VARIABLE_RESPONSE,
+		},
+		{
+			name:       "multiple handle value notification",
+			buf:        []byte{0x23, 0x03, 0x00, 0x01, 0x00, 0xaa, 0x07, 0x00, 0x00, 0x00, 0x09, 0x00, 0x02, 0x00, 0xbb, 0xcc},
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			wantOpCode: ATT_MULTIPLE_HANDLE_VALUE_NOTIFICATION,
+			want: AttMultipleHandleValueNotification{HandleLengthValueList: []AttHandleLengthValue{
+				{Handle: 0x0003, Length: 1, Value: []byte{0xaa}},
+				{Handle: 0x0007, Length: 0, Value: []byte{}},
+				{Handle: 0x0009, Length: 2, Value: []byte{0xbb, 0xcc}},
+			}},
+		},
+		{
+			name:       "multiple handle value notification, value shorter than length",
+			buf:        []byte{0x23, 0x03, 0x00, 0x02, 0x00, 0xaa},
+			wantCode:   HCI_PKT_RET_CODE_INVALID_LEN,
+			wantOpCode: ATT_MULTIPLE_HANDLE_VALUE_NOTIFICATION,
+		},
+	}
+	for _, test := range testList {
+		parsed := AttPktParse(L2CAP_CID_ATT, test.buf)
+		if parsed.Code != test.wantCode || parsed.OpCode != test.wantOpCode {
+			t.Errorf("%s: code %d opcode %#x, want code %d opcode %#x", test.name, parsed.Code, parsed.OpCode, test.wantCode, test.wantOpCode)
+			continue
+		}
+		if test.wantCode == HCI_PKT_RET_CODE_OK && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
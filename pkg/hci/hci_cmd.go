@@ -7,21 +7,122 @@ import (
 )
 
 const (
-	HCI_CMD_OGF_LE_CONTROLLER_CMD = 0x08
+	HCI_CMD_OGF_LINK_CONTROL_CMD        = 0x01
+	HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD = 0x03
+	HCI_CMD_OGF_INFORMATIONAL_PARAM_CMD = 0x04
+	HCI_CMD_OGF_STATUS_PARAM_CMD        = 0x05
+	HCI_CMD_OGF_LE_CONTROLLER_CMD       = 0x08
 )
 
+// HCI_CMD_OGF_LINK_CONTROL_CMD
 const (
-	HCI_LE_EXTENDED_CREATE_CONNECTION = 0x0043
-	// ...
+	HCI_INQUIRY                                        = 0x0001
+	HCI_INQUIRY_CANCEL                                 = 0x0002
+	HCI_CREATE_CONNECTION                              = 0x0005
+	HCI_DISCONNECT                                     = 0x0006
+	HCI_ACCEPT_CONNECTION_REQUEST                      = 0x0009
+	HCI_REJECT_CONNECTION_REQUEST                      = 0x000A
+	HCI_LINK_KEY_REQUEST_REPLY                         = 0x000B
+	HCI_LINK_KEY_REQUEST_NEGATIVE_REPLY                = 0x000C
+	HCI_PIN_CODE_REQUEST_REPLY                         = 0x000D
+	HCI_PIN_CODE_REQUEST_NEGATIVE_REPLY                = 0x000E
+	HCI_AUTHENTICATION_REQUESTED                       = 0x0011
+	HCI_REMOTE_NAME_REQUEST                            = 0x0019
+	HCI_REMOTE_NAME_REQUEST_CANCEL                     = 0x001A
+	HCI_READ_REMOTE_SUPPORTED_FEATURES                 = 0x001B
+	HCI_READ_REMOTE_EXTENDED_FEATURES                  = 0x001C
+	HCI_READ_REMOTE_VERSION_INFORMATION                = 0x001D
+	HCI_SETUP_SYNCHRONOUS_CONNECTION                   = 0x0028
+	HCI_ACCEPT_SYNCHRONOUS_CONNECTION_REQUEST          = 0x0029
+	HCI_IO_CAPABILITY_REQUEST_REPLY                    = 0x002B
+	HCI_USER_CONFIRMATION_REQUEST_REPLY                = 0x002C
+	HCI_USER_CONFIRMATION_REQUEST_NEGATIVE_REPLY       = 0x002D
+	HCI_USER_PASSKEY_REQUEST_REPLY                     = 0x002E
+	HCI_USER_PASSKEY_REQUEST_NEGATIVE_REPLY            = 0x002F
+	HCI_REMOTE_OOB_DATA_REQUEST_REPLY                  = 0x0030
+	HCI_REMOTE_OOB_DATA_REQUEST_NEGATIVE_REPLY         = 0x0033
+	HCI_IO_CAPABILITY_REQUEST_NEGATIVE_REPLY           = 0x0034
+	HCI_ENHANCED_SETUP_SYNCHRONOUS_CONNECTION          = 0x003D
+	HCI_ENHANCED_ACCEPT_SYNCHRONOUS_CONNECTION_REQUEST = 0x003E
 )
 
-// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.12 LE Create Connection Command
-// HCI_LE_Create_Connection
+// HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD
+const (
+	HCI_SET_EVENT_MASK                   = 0x0001
+	HCI_RESET                            = 0x0003
+	HCI_READ_LOCAL_NAME                  = 0x0014
+	HCI_READ_CLASS_OF_DEVICE             = 0x0023
+	HCI_WRITE_VOICE_SETTING              = 0x0026
+	HCI_HOST_NUMBER_OF_COMPLETED_PACKETS = 0x0035
+)
 
-// 连接参数，针对不同的连接模式配置
-type ConnectionInitialting struct {
-	ScanInterval      uint16
-	ScanWindow        uint16
+// HCI_CMD_OGF_INFORMATIONAL_PARAM_CMD
+const (
+	HCI_READ_LOCAL_VERSION_INFORMATION = 0x0001
+	HCI_READ_LOCAL_SUPPORTED_COMMANDS  = 0x0002
+	HCI_READ_LOCAL_SUPPORTED_FEATURES  = 0x0003
+	HCI_READ_LOCAL_EXTENDED_FEATURES   = 0x0004
+	HCI_READ_BUFFER_SIZE               = 0x0005
+	HCI_READ_BD_ADDR                   = 0x0009
+)
+
+// HCI_CMD_OGF_STATUS_PARAM_CMD
+const (
+	HCI_READ_RSSI = 0x0005
+)
+
+// HCI_CMD_OGF_LE_CONTROLLER_CMD
+const (
+	HCI_LE_SET_EVENT_MASK                                     = 0x0001
+	HCI_LE_READ_BUFFER_SIZE                                   = 0x0002
+	HCI_LE_READ_LOCAL_SUPPORTED_FEATURES                      = 0x0003
+	HCI_LE_SET_RANDOM_ADDRESS                                 = 0x0005
+	HCI_LE_SET_ADVERTISING_PARAMETERS                         = 0x0006
+	HCI_LE_READ_ADVERTISING_PHYSICAL_CHANNEL_TX_POWER         = 0x0007
+	HCI_LE_SET_ADVERTISING_DATA                               = 0x0008
+	HCI_LE_SET_SCAN_RESPONSE_DATA                             = 0x0009
+	HCI_LE_SET_ADVERTISING_ENABLE                             = 0x000A
+	HCI_LE_SET_SCAN_PARAMETERS                                = 0x000B
+	HCI_LE_SET_SCAN_ENABLE                                    = 0x000C
+	HCI_LE_CREATE_CONNECTION                                  = 0x000D
+	HCI_LE_CREATE_CONNECTION_CANCEL                           = 0x000E
+	HCI_LE_READ_FILTER_ACCEPT_LIST_SIZE                       = 0x000F
+	HCI_LE_CONNECTION_UPDATE                                  = 0x0013
+	HCI_LE_READ_REMOTE_FEATURES                               = 0x0016
+	HCI_LE_RAND                                               = 0x0018
+	HCI_LE_ENABLE_ENCRYPTION                                  = 0x0019
+	HCI_LE_LONG_TERM_KEY_REQUEST_REPLY                        = 0x001A
+	HCI_LE_LONG_TERM_KEY_REQUEST_NEGATIVE_REPLY               = 0x001B
+	HCI_LE_READ_SUPPORTED_STATES                              = 0x001C
+	HCI_LE_REMOTE_CONNECTION_PARAMETER_REQUEST_REPLY          = 0x0020
+	HCI_LE_REMOTE_CONNECTION_PARAMETER_REQUEST_NEGATIVE_REPLY = 0x0021
+	HCI_LE_SET_DATA_LENGTH                                    = 0x0022
+	HCI_LE_READ_SUGGESTED_DEFAULT_DATA_LENGTH                 = 0x0023
+	HCI_LE_READ_RESOLVING_LIST_SIZE                           = 0x002A
+	HCI_LE_READ_MAXIMUM_DATA_LENGTH                           = 0x002F
+	HCI_LE_SET_PHY                                            = 0x0032
+	HCI_LE_SET_ADVERTISING_SET_RANDOM_ADDRESS                 = 0x0035
+	HCI_LE_SET_EXTENDED_ADVERTISING_PARAMETERS                = 0x0036
+	HCI_LE_SET_EXTENDED_ADVERTISING_DATA                      = 0x0037
+	HCI_LE_SET_EXTENDED_SCAN_RESPONSE_DATA                    = 0x0038
+	HCI_LE_SET_EXTENDED_ADVERTISING_ENABLE                    = 0x0039
+	HCI_LE_READ_MAXIMUM_ADVERTISING_DATA_LENGTH               = 0x003A
+	HCI_LE_READ_NUMBER_OF_SUPPORTED_ADVERTISING_SETS          = 0x003B
+	HCI_LE_SET_EXTENDED_SCAN_PARAMETERS                       = 0x0041
+	HCI_LE_SET_EXTENDED_SCAN_ENABLE                           = 0x0042
+	HCI_LE_EXTENDED_CREATE_CONNECTION                         = 0x0043
+	HCI_LE_READ_TRANSMIT_POWER                                = 0x004B
+	HCI_LE_READ_BUFFER_SIZE_V2                                = 0x0060
+	HCI_LE_SET_CIG_PARAMETERS                                 = 0x0062
+	HCI_LE_CREATE_CIS                                         = 0x0064
+	HCI_LE_CREATE_BIG                                         = 0x0068
+	HCI_LE_BIG_CREATE_SYNC                                    = 0x006B
+	// ...
+)
+
+// Conn_Interval_Min到Maximum_CE_Length
+// 建立连接、LE Connection Update和LE Remote Connection Parameter Request Reply共用
+type ConnectionParameters struct {
 	ConnIntervalMin   uint16
 	ConnIntervalMax   uint16
 	ConnLatency       uint16
@@ -30,6 +131,31 @@ type ConnectionInitialting struct {
 	MaximumCeLength   uint16
 }
 
+// 连接参数，针对不同的连接模式配置
+// LE Create Connection和LE Extended Create Connection共用
+type ConnectionInitialting struct {
+	ScanInterval uint16
+	ScanWindow   uint16
+	ConnectionParameters
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.12 LE Create Connection Command
+// HCI_LE_Create_Connection
+type HciLeCreateConnection struct {
+	InitiatorFilterPolicy uint8
+	PeerAddressType       uint8
+	PeerAddress           [6]byte
+	OwnAddressType        uint8
+
+	// 报文中LE_Scan_Interval/LE_Scan_Window在Initiator_Filter_Policy之前，连接参数在Own_Address_Type之后
+	ConnectionInitialting
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.13 LE Create Connection Cancel Command
+// HCI_LE_Create_Connection_Cancel，没有参数
+type HciLeCreateConnectionCancel struct {
+}
+
 // BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.66 LE Extended Create Connection Command
 // HCI_LE_Extended_Create_Connection
 type HciLeExtendedCreateConnection struct {
@@ -53,8 +179,72 @@ type HciCmdPktParser func(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf
 
 // 二维parser map
 var HciCmdPktParserMap map[uint8]map[uint16]HciCmdPktParser = map[uint8]map[uint16]HciCmdPktParser{
+	HCI_CMD_OGF_LINK_CONTROL_CMD: {
+		HCI_INQUIRY:                                        HciInquiryParser,
+		HCI_INQUIRY_CANCEL:                                 HciInquiryCancelParser,
+		HCI_CREATE_CONNECTION:                              HciCreateConnectionParser,
+		HCI_DISCONNECT:                                     HciDisconnectParser,
+		HCI_ACCEPT_CONNECTION_REQUEST:                      HciAcceptConnectionRequestParser,
+		HCI_REJECT_CONNECTION_REQUEST:                      HciRejectConnectionRequestParser,
+		HCI_LINK_KEY_REQUEST_REPLY:                         HciLinkKeyRequestReplyParser,
+		HCI_LINK_KEY_REQUEST_NEGATIVE_REPLY:                HciBdAddrCmdParser,
+		HCI_PIN_CODE_REQUEST_REPLY:                         HciPinCodeRequestReplyParser,
+		HCI_PIN_CODE_REQUEST_NEGATIVE_REPLY:                HciBdAddrCmdParser,
+		HCI_AUTHENTICATION_REQUESTED:                       HciConnectionHandleCmdParser,
+		HCI_REMOTE_NAME_REQUEST:                            HciRemoteNameRequestParser,
+		HCI_REMOTE_NAME_REQUEST_CANCEL:                     HciRemoteNameRequestCancelParser,
+		HCI_READ_REMOTE_SUPPORTED_FEATURES:                 HciConnectionHandleCmdParser,
+		HCI_READ_REMOTE_EXTENDED_FEATURES:                  HciReadRemoteExtendedFeaturesParser,
+		HCI_READ_REMOTE_VERSION_INFORMATION:                HciConnectionHandleCmdParser,
+		HCI_IO_CAPABILITY_REQUEST_REPLY:                    HciIoCapabilityRequestReplyParser,
+		HCI_USER_CONFIRMATION_REQUEST_REPLY:                HciBdAddrCmdParser,
+		HCI_USER_CONFIRMATION_REQUEST_NEGATIVE_REPLY:       HciBdAddrCmdParser,
+		HCI_USER_PASSKEY_REQUEST_REPLY:                     HciUserPasskeyRequestReplyParser,
+		HCI_USER_PASSKEY_REQUEST_NEGATIVE_REPLY:            HciBdAddrCmdParser,
+		HCI_REMOTE_OOB_DATA_REQUEST_REPLY:                  HciRemoteOobDataRequestReplyParser,
+		HCI_REMOTE_OOB_DATA_REQUEST_NEGATIVE_REPLY:         HciBdAddrCmdParser,
+		HCI_IO_CAPABILITY_REQUEST_NEGATIVE_REPLY:           HciIoCapabilityRequestNegativeReplyParser,
+		HCI_SETUP_SYNCHRONOUS_CONNECTION:                   HciSetupSynchronousConnectionParser,
+		HCI_ACCEPT_SYNCHRONOUS_CONNECTION_REQUEST:          HciAcceptSynchronousConnectionRequestParser,
+		HCI_ENHANCED_SETUP_SYNCHRONOUS_CONNECTION:          HciEnhancedSetupSynchronousConnectionParser,
+		HCI_ENHANCED_ACCEPT_SYNCHRONOUS_CONNECTION_REQUEST: HciEnhancedAcceptSynchronousConnectionRequestParser,
+	},
+	HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD: {
+		HCI_SET_EVENT_MASK:      HciSetEventMaskParser,
+		HCI_WRITE_VOICE_SETTING: HciWriteVoiceSettingParser,
+	},
 	HCI_CMD_OGF_LE_CONTROLLER_CMD: {
-		HCI_LE_EXTENDED_CREATE_CONNECTION: HciLeExtendedCreateConnectionParser,
+		HCI_LE_SET_EVENT_MASK:                                     HciLeSetEventMaskParser,
+		HCI_LE_SET_RANDOM_ADDRESS:                                 HciLeSetRandomAddressParser,
+		HCI_LE_SET_ADVERTISING_PARAMETERS:                         HciLeSetAdvertisingParametersParser,
+		HCI_LE_SET_ADVERTISING_DATA:                               HciLeSetAdvertisingDataParser,
+		HCI_LE_SET_SCAN_RESPONSE_DATA:                             HciLeSetAdvertisingDataParser,
+		HCI_LE_SET_ADVERTISING_ENABLE:                             HciLeSetAdvertisingEnableParser,
+		HCI_LE_SET_SCAN_PARAMETERS:                                HciLeSetScanParametersParser,
+		HCI_LE_SET_SCAN_ENABLE:                                    HciLeSetScanEnableParser,
+		HCI_LE_SET_ADVERTISING_SET_RANDOM_ADDRESS:                 HciLeSetAdvertisingSetRandomAddressParser,
+		HCI_LE_SET_EXTENDED_ADVERTISING_PARAMETERS:                HciLeSetExtendedAdvertisingParametersParser,
+		HCI_LE_SET_EXTENDED_ADVERTISING_DATA:                      HciLeSetExtendedAdvertisingDataParser,
+		HCI_LE_SET_EXTENDED_SCAN_RESPONSE_DATA:                    HciLeSetExtendedAdvertisingDataParser,
+		HCI_LE_SET_EXTENDED_ADVERTISING_ENABLE:                    HciLeSetExtendedAdvertisingEnableParser,
+		HCI_LE_SET_EXTENDED_SCAN_PARAMETERS:                       HciLeSetExtendedScanParametersParser,
+		HCI_LE_SET_EXTENDED_SCAN_ENABLE:                           HciLeSetExtendedScanEnableParser,
+		HCI_LE_CREATE_CONNECTION:                                  HciLeCreateConnectionParser,
+		HCI_LE_CREATE_CONNECTION_CANCEL:                           HciLeCreateConnectionCancelParser,
+		HCI_LE_EXTENDED_CREATE_CONNECTION:                         HciLeExtendedCreateConnectionParser,
+		HCI_LE_CONNECTION_UPDATE:                                  HciLeConnectionUpdateParser,
+		HCI_LE_READ_REMOTE_FEATURES:                               HciConnectionHandleCmdParser,
+		HCI_LE_ENABLE_ENCRYPTION:                                  HciLeEnableEncryptionParser,
+		HCI_LE_LONG_TERM_KEY_REQUEST_REPLY:                        HciLeLongTermKeyRequestReplyParser,
+		HCI_LE_LONG_TERM_KEY_REQUEST_NEGATIVE_REPLY:               HciLeLongTermKeyRequestNegativeReplyParser,
+		HCI_LE_REMOTE_CONNECTION_PARAMETER_REQUEST_REPLY:          HciLeRemoteConnectionParameterRequestReplyParser,
+		HCI_LE_REMOTE_CONNECTION_PARAMETER_REQUEST_NEGATIVE_REPLY: HciLeRemoteConnectionParameterRequestNegativeReplyParser,
+		HCI_LE_SET_DATA_LENGTH:                                    HciLeSetDataLengthParser,
+		HCI_LE_SET_PHY:                                            HciLeSetPhyParser,
+		HCI_LE_SET_CIG_PARAMETERS:                                 HciLeSetCigParametersParser,
+		HCI_LE_CREATE_CIS:                                         HciLeCreateCisParser,
+		HCI_LE_CREATE_BIG:                                         HciLeCreateBigParser,
+		HCI_LE_BIG_CREATE_SYNC:                                    HciLeBigCreateSyncParser,
 	},
 }
 
@@ -73,8 +263,55 @@ func HciCmdPktDefaultParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadB
 	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
 }
 
+// 返回解析的字节数
+func connectionParametersParse(buf []byte, conn *ConnectionParameters) int {
+	bufIndex := 0
+	conn.ConnIntervalMin = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(conn.ConnIntervalMin)
+	conn.ConnIntervalMax = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(conn.ConnIntervalMax)
+	conn.ConnLatency = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(conn.ConnLatency)
+	conn.SupervisionTimout = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(conn.SupervisionTimout)
+	conn.MinimumCeLength = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(conn.MinimumCeLength)
+	conn.MaximumCeLength = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(conn.MaximumCeLength)
+	return bufIndex
+}
+
+func HciLeCreateConnectionParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeCreateConnection{}
+	if len(hciCmdPktPayloadBuf) < 25 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.ScanInterval = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ScanInterval)
+	pkt.ScanWindow = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ScanWindow)
+	pkt.InitiatorFilterPolicy = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.InitiatorFilterPolicy)
+	pkt.PeerAddressType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.PeerAddressType)
+	pkt.PeerAddress = BdAddrParse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.PeerAddress)
+	pkt.OwnAddressType = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.OwnAddressType)
+	connectionParametersParse(hciCmdPktPayloadBuf[bufIndex:], &pkt.ConnectionParameters)
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+func HciLeCreateConnectionCancelParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: HciLeCreateConnectionCancel{}}
+}
+
 func HciLeExtendedCreateConnectionParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
 	pkt := HciLeExtendedCreateConnection{}
+	if len(hciCmdPktPayloadBuf) < 10 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
 	bufIndex := 0
 	pkt.InitiatingFilterPolicy = hciCmdPktPayloadBuf[bufIndex]
 	bufIndex += binary.Size(pkt.InitiatingFilterPolicy)
@@ -89,25 +326,17 @@ func HciLeExtendedCreateConnectionParser(OpCodeOgf uint8, OpCodeOcf uint16, hciC
 	bufIndex += binary.Size(pkt.PeerAddress)
 	pkt.InitialtingPhys = hciCmdPktPayloadBuf[bufIndex]
 	bufIndex += binary.Size(pkt.InitialtingPhys)
-	for index := 0; index < binary.Size(pkt.InitialtingPhys); index++ {
+	for index := 0; index < len(pkt.ConnectionInitialtingList); index++ {
 		if pkt.InitialtingPhys&(0x01<<uint8(index)) != 0 {
+			if len(hciCmdPktPayloadBuf[bufIndex:]) < 16 {
+				return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+			}
 			conn := ConnectionInitialting{}
 			conn.ScanInterval = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
 			bufIndex += binary.Size(conn.ScanInterval)
 			conn.ScanWindow = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
 			bufIndex += binary.Size(conn.ScanWindow)
-			conn.ConnIntervalMin = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
-			bufIndex += binary.Size(conn.ConnIntervalMin)
-			conn.ConnIntervalMax = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
-			bufIndex += binary.Size(conn.ConnIntervalMax)
-			conn.ConnLatency = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
-			bufIndex += binary.Size(conn.ConnLatency)
-			conn.SupervisionTimout = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
-			bufIndex += binary.Size(conn.SupervisionTimout)
-			conn.MinimumCeLength = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
-			bufIndex += binary.Size(conn.MinimumCeLength)
-			conn.MaximumCeLength = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
-			bufIndex += binary.Size(conn.MaximumCeLength)
+			bufIndex += connectionParametersParse(hciCmdPktPayloadBuf[bufIndex:], &conn.ConnectionParameters)
 			pkt.ConnectionInitialtingList[index] = conn
 		}
 	}
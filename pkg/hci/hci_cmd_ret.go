@@ -0,0 +1,557 @@
+// Command Complete Return_Parameters处理
+// Return_Parameters的格式由对应的命令决定，parser按命令的OGF/OCF索引
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+type HciCmdRetParamParseResult struct {
+	Code      int
+	OpCodeOgf uint8
+	OpCodeOcf uint16
+	Ret       interface{}
+}
+type HciCmdRetParamParser func(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult
+
+// 二维parser map，与HciCmdPktParserMap索引一致
+var HciCmdRetParamParserMap map[uint8]map[uint16]HciCmdRetParamParser = map[uint8]map[uint16]HciCmdRetParamParser{
+	HCI_CMD_OGF_LINK_CONTROL_CMD: {
+		HCI_REMOTE_NAME_REQUEST_CANCEL:               HciRemoteNameRequestCancelRetParamParser,
+		HCI_LINK_KEY_REQUEST_REPLY:                   HciBdAddrRetParamParser,
+		HCI_LINK_KEY_REQUEST_NEGATIVE_REPLY:          HciBdAddrRetParamParser,
+		HCI_PIN_CODE_REQUEST_REPLY:                   HciBdAddrRetParamParser,
+		HCI_PIN_CODE_REQUEST_NEGATIVE_REPLY:          HciBdAddrRetParamParser,
+		HCI_IO_CAPABILITY_REQUEST_REPLY:              HciBdAddrRetParamParser,
+		HCI_USER_CONFIRMATION_REQUEST_REPLY:          HciBdAddrRetParamParser,
+		HCI_USER_CONFIRMATION_REQUEST_NEGATIVE_REPLY: HciBdAddrRetParamParser,
+		HCI_USER_PASSKEY_REQUEST_REPLY:               HciBdAddrRetParamParser,
+		HCI_USER_PASSKEY_REQUEST_NEGATIVE_REPLY:      HciBdAddrRetParamParser,
+		HCI_REMOTE_OOB_DATA_REQUEST_REPLY:            HciBdAddrRetParamParser,
+		HCI_REMOTE_OOB_DATA_REQUEST_NEGATIVE_REPLY:   HciBdAddrRetParamParser,
+		HCI_IO_CAPABILITY_REQUEST_NEGATIVE_REPLY:     HciBdAddrRetParamParser,
+	},
+	HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD: {
+		HCI_READ_LOCAL_NAME:      HciReadLocalNameRetParamParser,
+		HCI_READ_CLASS_OF_DEVICE: HciReadClassOfDeviceRetParamParser,
+	},
+	HCI_CMD_OGF_INFORMATIONAL_PARAM_CMD: {
+		HCI_READ_LOCAL_VERSION_INFORMATION: HciReadLocalVersionInformationRetParamParser,
+		HCI_READ_LOCAL_SUPPORTED_COMMANDS:  HciReadLocalSupportedCommandsRetParamParser,
+		HCI_READ_LOCAL_SUPPORTED_FEATURES:  HciReadLocalSupportedFeaturesRetParamParser,
+		HCI_READ_LOCAL_EXTENDED_FEATURES:   HciReadLocalExtendedFeaturesRetParamParser,
+		HCI_READ_BUFFER_SIZE:               HciReadBufferSizeRetParamParser,
+		HCI_READ_BD_ADDR:                   HciReadBdAddrRetParamParser,
+	},
+	HCI_CMD_OGF_STATUS_PARAM_CMD: {
+		HCI_READ_RSSI: HciReadRssiRetParamParser,
+	},
+	HCI_CMD_OGF_LE_CONTROLLER_CMD: {
+		HCI_LE_READ_BUFFER_SIZE:                                   HciLeReadBufferSizeRetParamParser,
+		HCI_LE_READ_LOCAL_SUPPORTED_FEATURES:                      HciLeReadLocalSupportedFeaturesRetParamParser,
+		HCI_LE_READ_ADVERTISING_PHYSICAL_CHANNEL_TX_POWER:         HciLeReadAdvertisingPhysicalChannelTxPowerRetParamParser,
+		HCI_LE_READ_FILTER_ACCEPT_LIST_SIZE:                       HciLeReadListSizeRetParamParser,
+		HCI_LE_RAND:                                               HciLeRandRetParamParser,
+		HCI_LE_READ_SUPPORTED_STATES:                              HciLeReadSupportedStatesRetParamParser,
+		HCI_LE_REMOTE_CONNECTION_PARAMETER_REQUEST_REPLY:          HciConnectionHandleRetParamParser,
+		HCI_LE_REMOTE_CONNECTION_PARAMETER_REQUEST_NEGATIVE_REPLY: HciConnectionHandleRetParamParser,
+		HCI_LE_SET_DATA_LENGTH:                                    HciConnectionHandleRetParamParser,
+		HCI_LE_LONG_TERM_KEY_REQUEST_REPLY:                        HciConnectionHandleRetParamParser,
+		HCI_LE_LONG_TERM_KEY_REQUEST_NEGATIVE_REPLY:               HciConnectionHandleRetParamParser,
+		HCI_LE_READ_SUGGESTED_DEFAULT_DATA_LENGTH:                 HciLeReadSuggestedDefaultDataLengthRetParamParser,
+		HCI_LE_READ_RESOLVING_LIST_SIZE:                           HciLeReadListSizeRetParamParser,
+		HCI_LE_READ_MAXIMUM_DATA_LENGTH:                           HciLeReadMaximumDataLengthRetParamParser,
+		HCI_LE_SET_EXTENDED_ADVERTISING_PARAMETERS:                HciLeSetExtendedAdvertisingParametersRetParamParser,
+		HCI_LE_READ_MAXIMUM_ADVERTISING_DATA_LENGTH:               HciLeReadMaximumAdvertisingDataLengthRetParamParser,
+		HCI_LE_READ_NUMBER_OF_SUPPORTED_ADVERTISING_SETS:          HciLeReadNumberOfSupportedAdvertisingSetsRetParamParser,
+		HCI_LE_READ_TRANSMIT_POWER:                                HciLeReadTransmitPowerRetParamParser,
+		HCI_LE_READ_BUFFER_SIZE_V2:                                HciLeReadBufferSizeRetParamParser,
+		HCI_LE_SET_CIG_PARAMETERS:                                 HciLeSetCigParametersRetParamParser,
+	},
+}
+
+func HciCmdRetParamParse(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	parser, ok := HciCmdRetParamParserMap[OpCodeOgf][OpCodeOcf]
+	if !ok {
+		parser = HciCmdRetParamDefaultParser
+	}
+	parsed := parser(OpCodeOgf, OpCodeOcf, retParamBuf)
+	parsed.OpCodeOgf = OpCodeOgf
+	parsed.OpCodeOcf = OpCodeOcf
+	return parsed
+}
+
+func HciCmdRetParamDefaultParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
+}
+
+// 只返回Status和Connection_Handle的命令共用
+type HciConnectionHandleRetParam struct {
+	Status           HciStatus
+	ConnectionHandle uint16
+}
+
+func HciConnectionHandleRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciConnectionHandleRetParam{}
+	if len(retParamBuf) < 3 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// 只返回Status和BD_ADDR的命令共用，主要是配对过程中的Reply/Negative Reply
+type HciBdAddrRetParam struct {
+	Status HciStatus
+	BdAddr [6]byte
+}
+
+func HciBdAddrRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	if len(retParamBuf) < 7 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciBdAddrRetParam{Status: HciStatus(retParamBuf[0]), BdAddr: BdAddrParse(retParamBuf[1:])}
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.3.12 Read Local Name Command
+type HciReadLocalNameRetParam struct {
+	Status    HciStatus
+	LocalName string
+}
+
+func HciReadLocalNameRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadLocalNameRetParam{}
+	if len(retParamBuf) < 1 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.LocalName = nullTerminatedStringParse(retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.3.25 Read Class of Device Command
+type HciReadClassOfDeviceRetParam struct {
+	Status        HciStatus
+	ClassOfDevice uint32
+	DeviceClass   DeviceClass
+}
+
+func HciReadClassOfDeviceRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadClassOfDeviceRetParam{}
+	if len(retParamBuf) < 4 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.ClassOfDevice = Uint24Parse(retParamBuf[1:])
+	pkt.DeviceClass = DeviceClassParse(pkt.ClassOfDevice)
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.4.1 Read Local Version Information Command
+type HciReadLocalVersionInformationRetParam struct {
+	Status           HciStatus
+	HciVersion       uint8
+	HciRevision      uint16
+	LmpPalVersion    uint8
+	ManufacturerName uint16 // Company Identifier
+	LmpPalSubversion uint16
+}
+
+func HciReadLocalVersionInformationRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadLocalVersionInformationRetParam{}
+	if len(retParamBuf) < 9 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(retParamBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.HciVersion = retParamBuf[pktIndex]
+	pktIndex += binary.Size(pkt.HciVersion)
+	pkt.HciRevision = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.HciRevision)
+	pkt.LmpPalVersion = retParamBuf[pktIndex]
+	pktIndex += binary.Size(pkt.LmpPalVersion)
+	pkt.ManufacturerName = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ManufacturerName)
+	pkt.LmpPalSubversion = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.4.2 Read Local Supported Commands Command
+type HciReadLocalSupportedCommandsRetParam struct {
+	Status            HciStatus
+	SupportedCommands [64]byte // 按octet/bit标记支持的命令
+}
+
+func HciReadLocalSupportedCommandsRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadLocalSupportedCommandsRetParam{}
+	if len(retParamBuf) < 1+len(pkt.SupportedCommands) {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	copy(pkt.SupportedCommands[:], retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.4.3 Read Local Supported Features Command
+type HciReadLocalSupportedFeaturesRetParam struct {
+	Status      HciStatus
+	LmpFeatures uint64
+}
+
+func HciReadLocalSupportedFeaturesRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadLocalSupportedFeaturesRetParam{}
+	if len(retParamBuf) < 9 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.LmpFeatures = binary.LittleEndian.Uint64(retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.4.4 Read Local Extended Features Command
+type HciReadLocalExtendedFeaturesRetParam struct {
+	Status              HciStatus
+	PageNumber          uint8
+	MaximumPageNumber   uint8
+	ExtendedLmpFeatures uint64
+}
+
+func HciReadLocalExtendedFeaturesRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadLocalExtendedFeaturesRetParam{}
+	if len(retParamBuf) < 11 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(retParamBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.PageNumber = retParamBuf[pktIndex]
+	pktIndex += binary.Size(pkt.PageNumber)
+	pkt.MaximumPageNumber = retParamBuf[pktIndex]
+	pktIndex += binary.Size(pkt.MaximumPageNumber)
+	pkt.ExtendedLmpFeatures = binary.LittleEndian.Uint64(retParamBuf[pktIndex:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.4.5 Read Buffer Size Command
+type HciReadBufferSizeRetParam struct {
+	Status                         HciStatus
+	AclDataPacketLength            uint16
+	SynchronousDataPacketLength    uint8
+	TotalNumAclDataPackets         uint16
+	TotalNumSynchronousDataPackets uint16
+}
+
+func HciReadBufferSizeRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadBufferSizeRetParam{}
+	if len(retParamBuf) < 8 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(retParamBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.AclDataPacketLength = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.AclDataPacketLength)
+	pkt.SynchronousDataPacketLength = retParamBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SynchronousDataPacketLength)
+	pkt.TotalNumAclDataPackets = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.TotalNumAclDataPackets)
+	pkt.TotalNumSynchronousDataPackets = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.4.6 Read BD_ADDR Command
+type HciReadBdAddrRetParam struct {
+	Status HciStatus
+	BdAddr [6]byte
+}
+
+func HciReadBdAddrRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadBdAddrRetParam{}
+	if len(retParamBuf) < 7 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.BdAddr = BdAddrParse(retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.5.4 Read RSSI Command
+type HciReadRssiRetParam struct {
+	Status           HciStatus
+	ConnectionHandle uint16
+	Rssi             int8 // 单位dB
+}
+
+func HciReadRssiRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciReadRssiRetParam{}
+	if len(retParamBuf) < 4 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(retParamBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.Rssi = int8(retParamBuf[pktIndex])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.2 LE Read Buffer Size Command
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.8.2 LE Read Buffer Size Command [v2]
+type HciLeReadBufferSizeRetParam struct {
+	Status                   HciStatus
+	LeAclDataPacketLength    uint16 // 为0时与BR/EDR共用Read Buffer Size的缓冲区
+	TotalNumLeAclDataPackets uint8
+	IsoDataPacketLength      uint16 // v2
+	TotalNumIsoDataPackets   uint8  // v2
+}
+
+func HciLeReadBufferSizeRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadBufferSizeRetParam{}
+	if len(retParamBuf) < 4 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(retParamBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.LeAclDataPacketLength = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.LeAclDataPacketLength)
+	pkt.TotalNumLeAclDataPackets = retParamBuf[pktIndex]
+	pktIndex += binary.Size(pkt.TotalNumLeAclDataPackets)
+	if OpCodeOcf == HCI_LE_READ_BUFFER_SIZE_V2 && len(retParamBuf[pktIndex:]) >= 3 {
+		pkt.IsoDataPacketLength = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+		pktIndex += binary.Size(pkt.IsoDataPacketLength)
+		pkt.TotalNumIsoDataPackets = retParamBuf[pktIndex]
+	}
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.3 LE Read Local Supported Features Command
+type HciLeReadLocalSupportedFeaturesRetParam struct {
+	Status     HciStatus
+	LeFeatures uint64
+}
+
+func HciLeReadLocalSupportedFeaturesRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadLocalSupportedFeaturesRetParam{}
+	if len(retParamBuf) < 9 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.LeFeatures = binary.LittleEndian.Uint64(retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.6 LE Read Advertising Channel Tx Power Command
+type HciLeReadAdvertisingPhysicalChannelTxPowerRetParam struct {
+	Status       HciStatus
+	TxPowerLevel int8 // 单位dBm
+}
+
+func HciLeReadAdvertisingPhysicalChannelTxPowerRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadAdvertisingPhysicalChannelTxPowerRetParam{}
+	if len(retParamBuf) < 2 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.TxPowerLevel = int8(retParamBuf[1])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// LE Read Filter Accept List Size/LE Read Resolving List Size
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.14 LE Read White List Size Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.41 LE Read Resolving List Size Command
+type HciLeReadListSizeRetParam struct {
+	Status   HciStatus
+	ListSize uint8
+}
+
+func HciLeReadListSizeRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadListSizeRetParam{}
+	if len(retParamBuf) < 2 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.ListSize = retParamBuf[1]
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.23 LE Rand Command
+type HciLeRandRetParam struct {
+	Status       HciStatus
+	RandomNumber [8]byte
+}
+
+func HciLeRandRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeRandRetParam{}
+	if len(retParamBuf) < 1+len(pkt.RandomNumber) {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	copy(pkt.RandomNumber[:], retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.27 LE Read Supported States Command
+type HciLeReadSupportedStatesRetParam struct {
+	Status   HciStatus
+	LeStates uint64
+}
+
+func HciLeReadSupportedStatesRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadSupportedStatesRetParam{}
+	if len(retParamBuf) < 9 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.LeStates = binary.LittleEndian.Uint64(retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.34 LE Read Suggested Default Data Length Command
+type HciLeReadSuggestedDefaultDataLengthRetParam struct {
+	Status               HciStatus
+	SuggestedMaxTxOctets uint16
+	SuggestedMaxTxTime   uint16 // 单位微秒
+}
+
+func HciLeReadSuggestedDefaultDataLengthRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadSuggestedDefaultDataLengthRetParam{}
+	if len(retParamBuf) < 5 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(retParamBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.SuggestedMaxTxOctets = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SuggestedMaxTxOctets)
+	pkt.SuggestedMaxTxTime = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.46 LE Read Maximum Data Length Command
+type HciLeReadMaximumDataLengthRetParam struct {
+	Status               HciStatus
+	SupportedMaxTxOctets uint16
+	SupportedMaxTxTime   uint16 // 单位微秒
+	SupportedMaxRxOctets uint16
+	SupportedMaxRxTime   uint16
+}
+
+func HciLeReadMaximumDataLengthRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadMaximumDataLengthRetParam{}
+	if len(retParamBuf) < 9 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(retParamBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.SupportedMaxTxOctets = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SupportedMaxTxOctets)
+	pkt.SupportedMaxTxTime = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SupportedMaxTxTime)
+	pkt.SupportedMaxRxOctets = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SupportedMaxRxOctets)
+	pkt.SupportedMaxRxTime = binary.LittleEndian.Uint16(retParamBuf[pktIndex:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.53 LE Set Extended Advertising Parameters Command
+type HciLeSetExtendedAdvertisingParametersRetParam struct {
+	Status          HciStatus
+	SelectedTxPower int8 // controller实际使用的发射功率，单位dBm
+}
+
+func HciLeSetExtendedAdvertisingParametersRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeSetExtendedAdvertisingParametersRetParam{}
+	if len(retParamBuf) < 2 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.SelectedTxPower = int8(retParamBuf[1])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.57 LE Read Maximum Advertising Data Length Command
+type HciLeReadMaximumAdvertisingDataLengthRetParam struct {
+	Status                   HciStatus
+	MaxAdvertisingDataLength uint16
+}
+
+func HciLeReadMaximumAdvertisingDataLengthRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadMaximumAdvertisingDataLengthRetParam{}
+	if len(retParamBuf) < 3 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.MaxAdvertisingDataLength = binary.LittleEndian.Uint16(retParamBuf[1:])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.58 LE Read Number of Supported Advertising Sets Command
+type HciLeReadNumberOfSupportedAdvertisingSetsRetParam struct {
+	Status                      HciStatus
+	NumSupportedAdvertisingSets uint8
+}
+
+func HciLeReadNumberOfSupportedAdvertisingSetsRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadNumberOfSupportedAdvertisingSetsRetParam{}
+	if len(retParamBuf) < 2 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.NumSupportedAdvertisingSets = retParamBuf[1]
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.74 LE Read Transmit Power Command
+type HciLeReadTransmitPowerRetParam struct {
+	Status     HciStatus
+	MinTxPower int8 // 单位dBm
+	MaxTxPower int8
+}
+
+func HciLeReadTransmitPowerRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeReadTransmitPowerRetParam{}
+	if len(retParamBuf) < 3 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Status = HciStatus(retParamBuf[0])
+	pkt.MinTxPower = int8(retParamBuf[1])
+	pkt.MaxTxPower = int8(retParamBuf[2])
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.8.97 LE Set CIG Parameters Command
+type HciLeSetCigParametersRetParam struct {
+	Status               HciStatus
+	CigId                uint8
+	CisCount             uint8
+	ConnectionHandleList []uint16 // 与命令中CIS_ID顺序一致
+}
+
+func HciLeSetCigParametersRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	pkt := HciLeSetCigParametersRetParam{}
+	if len(retParamBuf) < 3 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(retParamBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.CigId = retParamBuf[pktIndex]
+	pktIndex += binary.Size(pkt.CigId)
+	pkt.CisCount = retParamBuf[pktIndex]
+	pktIndex += binary.Size(pkt.CisCount)
+	if len(retParamBuf[pktIndex:]) < int(pkt.CisCount)*2 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for index := 0; index < int(pkt.CisCount); index++ {
+		pkt.ConnectionHandleList = append(pkt.ConnectionHandleList, binary.LittleEndian.Uint16(retParamBuf[pktIndex:]))
+		pktIndex += 2
+	}
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
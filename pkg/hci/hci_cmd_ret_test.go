@@ -0,0 +1,63 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommandCompleteReturnParameters(t *testing.T) {
+	testList := []struct {
+		name     string
+		opCode   []byte // 小端序
+		retParam []byte
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"read bd_addr", []byte{0x09, 0x10},
+			[]byte{0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11},
+			HCI_PKT_RET_CODE_OK, HciReadBdAddrRetParam{Status: 0x00, BdAddr: [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}},
+		},
+		{
+			"read local name", []byte{0x14, 0x0C},
+			[]byte{0x00, 'p', 'h', 'o', 'n', 'e', 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK, HciReadLocalNameRetParam{Status: 0x00, LocalName: "phone"},
+		},
+		{
+			"read buffer size", []byte{0x05, 0x10},
+			[]byte{0x00, 0xFD, 0x03, 0x40, 0x08, 0x00, 0x0A, 0x00},
+			HCI_PKT_RET_CODE_OK, HciReadBufferSizeRetParam{Status: 0x00, AclDataPacketLength: 1021, SynchronousDataPacketLength: 64, TotalNumAclDataPackets: 8, TotalNumSynchronousDataPackets: 10},
+		},
+		{
+			"read rssi", []byte{0x05, 0x14},
+			[]byte{0x00, 0x40, 0x00, 0xC4},
+			HCI_PKT_RET_CODE_OK, HciReadRssiRetParam{Status: 0x00, ConnectionHandle: 0x0040, Rssi: -60},
+		},
+		{
+			"read local version too short", []byte{0x01, 0x10},
+			[]byte{0x00, 0x09, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"opcode without return parameter parser", []byte{0x03, 0x0C},
+			[]byte{0x00},
+			HCI_PKT_RET_CODE_NOT_SUPPORT, nil,
+		},
+	}
+	for _, test := range testList {
+		buf := append([]byte{0x01}, test.opCode...)
+		buf = append(buf, test.retParam...)
+		parsed := HciEvtPktParse(HCI_EVT_COMMAND_COMPLETE, buf)
+		evt, ok := parsed.Ret.(CommandCompleteEvent)
+		if parsed.Code != HCI_PKT_RET_CODE_OK || !ok {
+			t.Fatalf("%s: Command Complete parse code %d", test.name, parsed.Code)
+		}
+		if evt.ReturnParsedResult.Code != test.wantCode {
+			t.Errorf("%s: return parameters code %d, want %d", test.name, evt.ReturnParsedResult.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(evt.ReturnParsedResult.Ret, test.want) {
+			t.Errorf("%s: return parameters %+v, want %+v", test.name, evt.ReturnParsedResult.Ret, test.want)
+		}
+	}
+}
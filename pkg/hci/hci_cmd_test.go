@@ -0,0 +1,183 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHciCmdPktParse(t *testing.T) {
+	testList := []struct {
+		name     string
+		ogf      uint8
+		ocf      uint16
+		buf      []byte
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"le create connection", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_CREATE_CONNECTION,
+			[]byte{
+				0x60, 0x00, 0x30, 0x00, 0x00, 0x01, 0x66, 0x55, 0x44, 0x33, 0x22, 0xC1, 0x01,
+				0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0xF4, 0x01, 0x00, 0x00, 0x00, 0x00,
+			},
+			HCI_PKT_RET_CODE_OK,
+			HciLeCreateConnection{
+				InitiatorFilterPolicy: 0x00,
+				PeerAddressType:       0x01,
+				PeerAddress:           [6]byte{0xC1, 0x22, 0x33, 0x44, 0x55, 0x66},
+				OwnAddressType:        0x01,
+				ConnectionInitialting: ConnectionInitialting{
+					ScanInterval: 0x0060, ScanWindow: 0x0030,
+					ConnectionParameters: ConnectionParameters{ConnIntervalMin: 0x0018, ConnIntervalMax: 0x0028, SupervisionTimout: 0x01F4},
+				},
+			},
+		},
+		{
+			"le create connection too short", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_CREATE_CONNECTION,
+			[]byte{0x60, 0x00, 0x30, 0x00, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le extended create connection, 1M phy", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_EXTENDED_CREATE_CONNECTION,
+			[]byte{
+				0x00, 0x00, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01,
+				0x60, 0x00, 0x30, 0x00, 0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0xF4, 0x01, 0x00, 0x00, 0x00, 0x00,
+			},
+			HCI_PKT_RET_CODE_OK,
+			HciLeExtendedCreateConnection{
+				PeerAddress:     [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66},
+				InitialtingPhys: 0x01,
+				ConnectionInitialtingList: [3]ConnectionInitialting{{
+					ScanInterval: 0x0060, ScanWindow: 0x0030,
+					ConnectionParameters: ConnectionParameters{ConnIntervalMin: 0x0018, ConnIntervalMax: 0x0028, SupervisionTimout: 0x01F4},
+				}},
+			},
+		},
+		{
+			"le extended create connection, fixed fields truncated", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_EXTENDED_CREATE_CONNECTION,
+			[]byte{0x00, 0x00, 0x00, 0x66, 0x55},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le extended create connection, phy parameters truncated", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_EXTENDED_CREATE_CONNECTION,
+			[]byte{0x00, 0x00, 0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x05, 0x60, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set advertising parameters", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_ADVERTISING_PARAMETERS,
+			[]byte{0xA0, 0x00, 0xB0, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetAdvertisingParameters{AdvertisingIntervalMin: 0x00A0, AdvertisingIntervalMax: 0x00B0, OwnAddressType: 0x01, AdvertisingChannelMap: 0x07},
+		},
+		{
+			"le set advertising parameters too short", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_ADVERTISING_PARAMETERS,
+			[]byte{0xA0, 0x00, 0xB0, 0x00, 0x00, 0x01, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set scan response data", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_SCAN_RESPONSE_DATA,
+			[]byte{0x04, 0x03, 0x09, 'h', 'i', 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetAdvertisingData{
+				ScanResponse: true, DataLength: 4, Data: []byte{0x03, 0x09, 'h', 'i'},
+				AdStructList: []AdStruct{{
+					Length: 3, AdType: AD_TYPE_COMPLETE_LOCAL_NAME, Data: []byte{'h', 'i'},
+					PayloadParsedResult: AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, AdType: AD_TYPE_COMPLETE_LOCAL_NAME, Ret: AdLocalName{Complete: true, Name: "hi"}},
+				}},
+			},
+		},
+		{
+			"le set advertising data length beyond payload", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_ADVERTISING_DATA,
+			[]byte{0x05, 0x02, 0x01},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set scan parameters", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_SCAN_PARAMETERS,
+			[]byte{0x01, 0x10, 0x00, 0x08, 0x00, 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetScanParameters{LeScanType: 0x01, LeScanInterval: 0x0010, LeScanWindow: 0x0008},
+		},
+		{
+			"le set scan enable too short", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_SCAN_ENABLE,
+			[]byte{0x01},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set extended advertising parameters", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_ADVERTISING_PARAMETERS,
+			[]byte{
+				0x01, 0x13, 0x00, 0xA0, 0x00, 0x00, 0xB0, 0x00, 0x00, 0x07, 0x01, 0x00,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x7F, 0x01, 0x00, 0x02, 0x03, 0x00,
+			},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetExtendedAdvertisingParameters{
+				AdvertisingHandle: 0x01, AdvertisingEventProperties: 0x0013,
+				PrimaryAdvertisingIntervalMin: 0x0000A0, PrimaryAdvertisingIntervalMax: 0x0000B0, PrimaryAdvertisingChannelMap: 0x07,
+				OwnAddressType: 0x01, AdvertisingTxPower: 127, PrimaryAdvertisingPhy: 0x01, SecondaryAdvertisingPhy: 0x02, AdvertisingSid: 0x03,
+			},
+		},
+		{
+			"le set extended advertising parameters too short", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_ADVERTISING_PARAMETERS,
+			[]byte{0x01, 0x13, 0x00, 0xA0, 0x00, 0x00, 0xB0, 0x00, 0x00, 0x07},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set extended advertising data, first fragment", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_ADVERTISING_DATA,
+			[]byte{0x01, EXT_ADV_DATA_OPERATION_FIRST_FRAGMENT, 0x01, 0x02, 0x05, 0x09},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetExtendedAdvertisingData{AdvertisingHandle: 0x01, Operation: EXT_ADV_DATA_OPERATION_FIRST_FRAGMENT, FragmentPreference: 0x01, DataLength: 2, Data: []byte{0x05, 0x09}},
+		},
+		{
+			"le set extended advertising data length beyond payload", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_ADVERTISING_DATA,
+			[]byte{0x01, EXT_ADV_DATA_OPERATION_COMPLETE, 0x01, 0x03, 0x02},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set extended advertising enable, two sets", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_ADVERTISING_ENABLE,
+			[]byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x01, 0xF4, 0x01, 0x05},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetExtendedAdvertisingEnable{Enable: 0x01, NumSets: 2, SetList: []ExtendedAdvertisingSet{{}, {AdvertisingHandle: 0x01, Duration: 0x01F4, MaxExtendedAdvertisingEvents: 0x05}}},
+		},
+		{
+			"le set extended advertising enable, sets truncated", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_ADVERTISING_ENABLE,
+			[]byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set extended scan parameters, 1M and coded", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_SCAN_PARAMETERS,
+			[]byte{0x00, 0x00, 0x05, 0x01, 0x10, 0x00, 0x08, 0x00, 0x00, 0x30, 0x00, 0x18, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetExtendedScanParameters{
+				ScanningPhys: 0x05,
+				PhyParametersList: [3]ExtendedScanPhyParameters{
+					{ScanType: 0x01, ScanInterval: 0x0010, ScanWindow: 0x0008}, {}, {ScanType: 0x00, ScanInterval: 0x0030, ScanWindow: 0x0018},
+				},
+			},
+		},
+		{
+			"le set extended scan parameters, phy parameters truncated", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_SCAN_PARAMETERS,
+			[]byte{0x00, 0x00, 0x05, 0x01, 0x10, 0x00, 0x08, 0x00, 0x00, 0x30},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set extended scan enable", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_SCAN_ENABLE,
+			[]byte{0x01, 0x01, 0xE8, 0x03, 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetExtendedScanEnable{Enable: 0x01, FilterDuplicates: 0x01, Duration: 0x03E8},
+		},
+		{
+			"le set extended scan enable too short", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EXTENDED_SCAN_ENABLE,
+			[]byte{0x01, 0x01, 0xE8, 0x03},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+	}
+	for _, test := range testList {
+		parsed := HciCmdPktParse(test.ogf, test.ocf, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
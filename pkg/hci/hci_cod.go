@@ -0,0 +1,245 @@
+// Class of Device解析
+// Assigned Numbers | 2.8 Class of Device
+// https://www.bluetooth.com/specifications/assigned-numbers/baseband/
+
+package hci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Major Service Class各bit，Class of Device bit13-23右移13位后的值
+const (
+	COD_SERVICE_LIMITED_DISCOVERABLE_MODE = 0x0001
+	COD_SERVICE_LE_AUDIO                  = 0x0002
+	COD_SERVICE_POSITIONING               = 0x0008
+	COD_SERVICE_NETWORKING                = 0x0010
+	COD_SERVICE_RENDERING                 = 0x0020
+	COD_SERVICE_CAPTURING                 = 0x0040
+	COD_SERVICE_OBJECT_TRANSFER           = 0x0080
+	COD_SERVICE_AUDIO                     = 0x0100
+	COD_SERVICE_TELEPHONY                 = 0x0200
+	COD_SERVICE_INFORMATION               = 0x0400
+)
+
+// Major Device Class，Class of Device bit8-12
+const (
+	COD_MAJOR_MISCELLANEOUS = 0x00
+	COD_MAJOR_COMPUTER      = 0x01
+	COD_MAJOR_PHONE         = 0x02
+	COD_MAJOR_LAN           = 0x03
+	COD_MAJOR_AUDIO_VIDEO   = 0x04
+	COD_MAJOR_PERIPHERAL    = 0x05
+	COD_MAJOR_IMAGING       = 0x06
+	COD_MAJOR_WEARABLE      = 0x07
+	COD_MAJOR_TOY           = 0x08
+	COD_MAJOR_HEALTH        = 0x09
+	COD_MAJOR_UNCATEGORIZED = 0x1F
+)
+
+var codServiceStrList = []struct {
+	Bit  uint16
+	Name string
+}{
+	{COD_SERVICE_LIMITED_DISCOVERABLE_MODE, "Limited Discoverable Mode"},
+	{COD_SERVICE_LE_AUDIO, "LE Audio"},
+	{COD_SERVICE_POSITIONING, "Positioning"},
+	{COD_SERVICE_NETWORKING, "Networking"},
+	{COD_SERVICE_RENDERING, "Rendering"},
+	{COD_SERVICE_CAPTURING, "Capturing"},
+	{COD_SERVICE_OBJECT_TRANSFER, "Object Transfer"},
+	{COD_SERVICE_AUDIO, "Audio"},
+	{COD_SERVICE_TELEPHONY, "Telephony"},
+	{COD_SERVICE_INFORMATION, "Information"},
+}
+
+var CodMajorStrMap = map[uint8]string{
+	COD_MAJOR_MISCELLANEOUS: "Miscellaneous",
+	COD_MAJOR_COMPUTER:      "Computer",
+	COD_MAJOR_PHONE:         "Phone",
+	COD_MAJOR_LAN:           "LAN/Network Access Point",
+	COD_MAJOR_AUDIO_VIDEO:   "Audio/Video",
+	COD_MAJOR_PERIPHERAL:    "Peripheral",
+	COD_MAJOR_IMAGING:       "Imaging",
+	COD_MAJOR_WEARABLE:      "Wearable",
+	COD_MAJOR_TOY:           "Toy",
+	COD_MAJOR_HEALTH:        "Health",
+	COD_MAJOR_UNCATEGORIZED: "Uncategorized",
+}
+
+// Minor Device Class(bit2-7)，Peripheral/Imaging/LAN按bit组合，不在表中
+var CodMinorStrMap = map[uint8]map[uint8]string{
+	COD_MAJOR_COMPUTER: {
+		0x00: "Uncategorized",
+		0x01: "Desktop Workstation",
+		0x02: "Server-class Computer",
+		0x03: "Laptop",
+		0x04: "Handheld PC/PDA",
+		0x05: "Palm-size PC/PDA",
+		0x06: "Wearable Computer",
+		0x07: "Tablet",
+	},
+	COD_MAJOR_PHONE: {
+		0x00: "Uncategorized",
+		0x01: "Cellular",
+		0x02: "Cordless",
+		0x03: "Smartphone",
+		0x04: "Wired Modem or Voice Gateway",
+		0x05: "Common ISDN Access",
+	},
+	COD_MAJOR_AUDIO_VIDEO: {
+		0x00: "Uncategorized",
+		0x01: "Wearable Headset Device",
+		0x02: "Hands-free Device",
+		0x04: "Microphone",
+		0x05: "Loudspeaker",
+		0x06: "Headphones",
+		0x07: "Portable Audio",
+		0x08: "Car Audio",
+		0x09: "Set-top Box",
+		0x0A: "HiFi Audio Device",
+		0x0B: "VCR",
+		0x0C: "Video Camera",
+		0x0D: "Camcorder",
+		0x0E: "Video Monitor",
+		0x0F: "Video Display and Loudspeaker",
+		0x10: "Video Conferencing",
+		0x12: "Gaming/Toy",
+	},
+	COD_MAJOR_WEARABLE: {
+		0x01: "Wristwatch",
+		0x02: "Pager",
+		0x03: "Jacket",
+		0x04: "Helmet",
+		0x05: "Glasses",
+	},
+	COD_MAJOR_TOY: {
+		0x01: "Robot",
+		0x02: "Vehicle",
+		0x03: "Doll/Action Figure",
+		0x04: "Controller",
+		0x05: "Game",
+	},
+	COD_MAJOR_HEALTH: {
+		0x00: "Undefined",
+		0x01: "Blood Pressure Monitor",
+		0x02: "Thermometer",
+		0x03: "Weighing Scale",
+		0x04: "Glucose Meter",
+		0x05: "Pulse Oximeter",
+		0x06: "Heart/Pulse Rate Monitor",
+		0x07: "Health Data Display",
+		0x08: "Step Counter",
+		0x09: "Body Composition Analyzer",
+		0x0A: "Peak Flow Monitor",
+		0x0B: "Medication Monitor",
+		0x0C: "Knee Prosthesis",
+		0x0D: "Ankle Prosthesis",
+		0x0E: "Generic Health Manager",
+		0x0F: "Personal Mobility Device",
+	},
+}
+
+// Peripheral Minor Device Class bit6-7
+var codPeripheralStrList = []string{"", "Keyboard", "Pointing Device", "Combo Keyboard/Pointing Device"}
+
+// Peripheral Minor Device Class bit2-5
+var codPeripheralTypeStrMap = map[uint8]string{
+	0x01: "Joystick",
+	0x02: "Gamepad",
+	0x03: "Remote Control",
+	0x04: "Sensing Device",
+	0x05: "Digitizer Tablet",
+	0x06: "Card Reader",
+	0x07: "Digital Pen",
+	0x08: "Handheld Scanner",
+	0x09: "Handheld Gestural Input Device",
+}
+
+// Imaging Minor Device Class bit4-7，可以同时置位
+var codImagingStrList = []string{"Display", "Camera", "Scanner", "Printer"}
+
+// LAN/Network Access Point Minor Device Class bit5-7
+var codLanStrList = []string{
+	"Fully Available",
+	"1% to 17% Utilized",
+	"17% to 33% Utilized",
+	"33% to 50% Utilized",
+	"50% to 67% Utilized",
+	"67% to 83% Utilized",
+	"83% to 99% Utilized",
+	"No Service Available",
+}
+
+// Class of Device各字段
+type DeviceClass struct {
+	MajorServiceClass uint16 // COD_SERVICE_XXX
+	MajorDeviceClass  uint8  // COD_MAJOR_XXX
+	MinorDeviceClass  uint8  // 与MajorDeviceClass相关
+}
+
+// 24bit Class of Device拆分，Format Type(bit0-1)只定义了0
+func DeviceClassParse(classOfDevice uint32) DeviceClass {
+	return DeviceClass{
+		MajorServiceClass: uint16(classOfDevice>>13) & 0x07ff,
+		MajorDeviceClass:  uint8(classOfDevice>>8) & 0x1f,
+		MinorDeviceClass:  uint8(classOfDevice>>2) & 0x3f,
+	}
+}
+
+func (class DeviceClass) ServiceClassList() []string {
+	var nameList []string
+	for _, service := range codServiceStrList {
+		if class.MajorServiceClass&service.Bit != 0 {
+			nameList = append(nameList, service.Name)
+		}
+	}
+	return nameList
+}
+
+func (class DeviceClass) MajorString() string {
+	if name, ok := CodMajorStrMap[class.MajorDeviceClass]; ok {
+		return name
+	}
+	return fmt.Sprintf("Reserved(0x%02X)", class.MajorDeviceClass)
+}
+
+func (class DeviceClass) MinorString() string {
+	switch class.MajorDeviceClass {
+	case COD_MAJOR_PERIPHERAL:
+		nameList := []string{}
+		if name := codPeripheralStrList[class.MinorDeviceClass>>4]; name != "" {
+			nameList = append(nameList, name)
+		}
+		if name, ok := codPeripheralTypeStrMap[class.MinorDeviceClass&0x0f]; ok {
+			nameList = append(nameList, name)
+		}
+		if len(nameList) == 0 {
+			return "Uncategorized"
+		}
+		return strings.Join(nameList, "/")
+	case COD_MAJOR_IMAGING:
+		nameList := []string{}
+		for index, name := range codImagingStrList {
+			if class.MinorDeviceClass&(0x04<<uint8(index)) != 0 {
+				nameList = append(nameList, name)
+			}
+		}
+		if len(nameList) == 0 {
+			return "Uncategorized"
+		}
+		return strings.Join(nameList, "/")
+	case COD_MAJOR_LAN:
+		return codLanStrList[class.MinorDeviceClass>>3]
+	}
+	if name, ok := CodMinorStrMap[class.MajorDeviceClass][class.MinorDeviceClass]; ok {
+		return name
+	}
+	return fmt.Sprintf("Reserved(0x%02X)", class.MinorDeviceClass)
+}
+
+// 格式: Major/Minor [Service, ...]
+func (class DeviceClass) String() string {
+	return fmt.Sprintf("%s/%s [%s]", class.MajorString(), class.MinorString(), strings.Join(class.ServiceClassList(), ", "))
+}
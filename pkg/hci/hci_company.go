@@ -0,0 +1,100 @@
+// Company Identifier，Read Local Version Information的Manufacturer_Name和Manufacturer Specific Data使用
+// Assigned Numbers | 7.1 Company Identifiers
+// https://www.bluetooth.com/specifications/assigned-numbers/company-identifiers/
+
+package hci
+
+import (
+	"fmt"
+)
+
+// 只收录常见的芯片和终端厂商
+var CompanyIdStrMap = map[uint16]string{
+	0x0000: "Ericsson Technology Licensing",
+	0x0001: "Nokia Mobile Phones",
+	0x0002: "Intel Corp.",
+	0x0003: "IBM Corp.",
+	0x0004: "Toshiba Corp.",
+	0x0005: "3Com",
+	0x0006: "Microsoft",
+	0x0007: "Lucent",
+	0x0008: "Motorola",
+	0x0009: "Infineon Technologies AG",
+	0x000A: "Qualcomm Technologies International, Ltd. (QTIL)",
+	0x000B: "Silicon Wave",
+	0x000C: "Digianswer A/S",
+	0x000D: "Texas Instruments Inc.",
+	0x000E: "Parthus Technologies Inc.",
+	0x000F: "Broadcom Corporation",
+	0x0010: "Mitel Semiconductor",
+	0x0011: "Widcomm, Inc.",
+	0x0012: "Zeevo, Inc.",
+	0x0013: "Atmel Corporation",
+	0x0014: "Mitsubishi Electric Corporation",
+	0x0015: "RTX Telecom A/S",
+	0x0016: "KC Technology Inc.",
+	0x0017: "Newlogic",
+	0x0018: "Transilica, Inc.",
+	0x0019: "Rohde & Schwarz GmbH & Co. KG",
+	0x001A: "TTPCom Limited",
+	0x001B: "Signia Technologies, Inc.",
+	0x001C: "Conexant Systems Inc.",
+	0x001D: "Qualcomm",
+	0x001E: "Inventel",
+	0x001F: "AVM Berlin",
+	0x0020: "BandSpeed, Inc.",
+	0x0021: "Mansella Ltd",
+	0x0022: "NEC Corporation",
+	0x0023: "WavePlus Technology Co., Ltd.",
+	0x0024: "Alcatel",
+	0x0025: "NXP Semiconductors",
+	0x0026: "C Technologies",
+	0x0027: "Open Interface",
+	0x0028: "RF Micro Devices",
+	0x0029: "Hitachi Ltd",
+	0x002A: "Symbol Technologies, Inc.",
+	0x002B: "Tenovis",
+	0x002C: "Macronix International Co. Ltd.",
+	0x002D: "GCT Semiconductor",
+	0x002E: "Norwood Systems",
+	0x002F: "MewTel Technology Inc.",
+	0x0030: "ST Microelectronics",
+	0x0031: "Synopsys, Inc.",
+	0x0032: "Red-M (Communications) Ltd",
+	0x0033: "Commil Ltd",
+	0x0034: "Computer Access Technology Corporation (CATC)",
+	0x0035: "Eclipse (HQ Espana) S.L.",
+	0x0036: "Renesas Electronics Corporation",
+	0x0037: "Mobilian Corporation",
+	0x003F: "Bluetooth SIG, Inc",
+	0x0046: "MediaTek, Inc.",
+	0x0048: "Marvell Technology Group Ltd.",
+	0x004C: "Apple, Inc.",
+	0x0057: "Harman International Industries, Inc.",
+	0x0059: "Nordic Semiconductor ASA",
+	0x005D: "Realtek Semiconductor Corporation",
+	0x0065: "HP, Inc.",
+	0x0067: "GN Audio A/S",
+	0x0075: "Samsung Electronics Co. Ltd.",
+	0x0087: "Garmin International, Inc.",
+	0x009E: "Bose Corporation",
+	0x00C4: "LG Electronics",
+	0x00D2: "Dialog Semiconductor B.V.",
+	0x00E0: "Google",
+	0x012D: "Sony Corporation",
+	0x0131: "Cypress Semiconductor",
+	0x0157: "Anhui Huami Information Technology Co., Ltd.",
+	0x0171: "Amazon.com Services, LLC",
+	0x027D: "HUAWEI Technologies Co., Ltd.",
+	0x02E5: "Espressif Systems (Shanghai) Co., Ltd.",
+	0x038F: "Xiaomi Inc.",
+	0x05A7: "Sonos Inc",
+	0xFFFF: "Test/Internal Use",
+}
+
+func CompanyIdString(companyId uint16) string {
+	if name, ok := CompanyIdStrMap[companyId]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(0x%04X)", companyId)
+}
@@ -0,0 +1,227 @@
+// BR/EDR ACL连接建立、角色切换、模式切换和断开相关命令和事件处理
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// BR/EDR连接中的角色
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.18 Role Change Event
+const (
+	BREDR_ROLE_MASTER = 0x00
+	BREDR_ROLE_SLAVE  = 0x01
+)
+
+// Accept Connection Request Role
+const (
+	ACCEPT_ROLE_BECOME_MASTER = 0x00
+	ACCEPT_ROLE_REMAIN_SLAVE  = 0x01
+)
+
+// Mode Change Current_Mode
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.20 Mode Change Event
+const (
+	CONN_MODE_ACTIVE = 0x00
+	CONN_MODE_HOLD   = 0x01
+	CONN_MODE_SNIFF  = 0x02
+)
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.5 Create Connection Command
+type HciCreateConnection struct {
+	BdAddr                 [6]byte
+	PacketType             uint16
+	PageScanRepetitionMode uint8
+	ClockOffset            uint16 // bit15为1时bit0-14有效
+	AllowRoleSwitch        uint8
+}
+
+func HciCreateConnectionParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciCreateConnection{}
+	if len(hciCmdPktPayloadBuf) < 13 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.BdAddr = BdAddrParse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.BdAddr)
+	pkt.PacketType = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.PacketType)
+	pkt.PageScanRepetitionMode = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.PageScanRepetitionMode)
+	bufIndex += 1 // Reserved
+	pkt.ClockOffset = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ClockOffset)
+	pkt.AllowRoleSwitch = hciCmdPktPayloadBuf[bufIndex]
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.6 Disconnect Command
+// BR/EDR和LE共用
+type HciDisconnect struct {
+	ConnectionHandle uint16
+	Reason           HciStatus
+}
+
+func HciDisconnectParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 3 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciDisconnect{ConnectionHandle: binary.LittleEndian.Uint16(hciCmdPktPayloadBuf) & 0x0fff, Reason: HciStatus(hciCmdPktPayloadBuf[2])}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.8 Accept Connection Request Command
+type HciAcceptConnectionRequest struct {
+	BdAddr [6]byte
+	Role   uint8 // ACCEPT_ROLE_XXX
+}
+
+func HciAcceptConnectionRequestParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 7 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciAcceptConnectionRequest{BdAddr: BdAddrParse(hciCmdPktPayloadBuf), Role: hciCmdPktPayloadBuf[6]}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.9 Reject Connection Request Command
+type HciRejectConnectionRequest struct {
+	BdAddr [6]byte
+	Reason HciStatus
+}
+
+func HciRejectConnectionRequestParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 7 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciRejectConnectionRequest{BdAddr: BdAddrParse(hciCmdPktPayloadBuf), Reason: HciStatus(hciCmdPktPayloadBuf[6])}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.21 Read Remote Supported Features Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.23 Read Remote Version Information Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.21 LE Read Remote Used Features Command
+// 只有Connection_Handle参数的命令共用
+type HciConnectionHandleCmd struct {
+	ConnectionHandle uint16
+}
+
+func HciConnectionHandleCmdParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 2 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: HciConnectionHandleCmd{ConnectionHandle: binary.LittleEndian.Uint16(hciCmdPktPayloadBuf) & 0x0fff}}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.4 Connection Request Event
+type ConnectionRequestEvent struct {
+	BdAddr        [6]byte
+	ClassOfDevice uint32
+	DeviceClass   DeviceClass
+	LinkType      uint8 // LINK_TYPE_XXX
+}
+
+// 对端发起连接，host需要回复Accept/Reject Connection Request
+func ConnectionRequestEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := ConnectionRequestEvent{}
+	if len(hciEvtPktPayloadBuf) < 10 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.BdAddr = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.BdAddr)
+	pkt.ClassOfDevice = Uint24Parse(hciEvtPktPayloadBuf[pktIndex:])
+	pkt.DeviceClass = DeviceClassParse(pkt.ClassOfDevice)
+	pktIndex += 3
+	pkt.LinkType = hciEvtPktPayloadBuf[pktIndex]
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.18 Role Change Event
+type RoleChangeEvent struct {
+	Status  HciStatus
+	BdAddr  [6]byte
+	NewRole uint8 // BREDR_ROLE_XXX
+}
+
+// 按对端地址上报，可能在Connection Complete之前上报
+func RoleChangeEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	if len(hciEvtPktPayloadBuf) < 8 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := RoleChangeEvent{Status: HciStatus(hciEvtPktPayloadBuf[0]), BdAddr: BdAddrParse(hciEvtPktPayloadBuf[1:]), NewRole: hciEvtPktPayloadBuf[7]}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.20 Mode Change Event
+type ModeChangeEvent struct {
+	Status           HciStatus
+	ConnectionHandle uint16
+	CurrentMode      uint8  // CONN_MODE_XXX
+	Interval         uint16 // hold/sniff间隔，单位0.625ms
+}
+
+func ModeChangeEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := ModeChangeEvent{}
+	if len(hciEvtPktPayloadBuf) < 6 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.CurrentMode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.CurrentMode)
+	pkt.Interval = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.11 Read Remote Supported Features Complete Event
+type ReadRemoteSupportedFeaturesCompleteEvent struct {
+	Status           HciStatus
+	ConnectionHandle uint16
+	LmpFeatures      uint64 // LMP features page 0
+}
+
+func ReadRemoteSupportedFeaturesCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := ReadRemoteSupportedFeaturesCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 11 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.LmpFeatures = binary.LittleEndian.Uint64(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.12 Read Remote Version Information Complete Event
+type ReadRemoteVersionInformationCompleteEvent struct {
+	Status           HciStatus
+	ConnectionHandle uint16
+	Version          uint8 // LMP/LL版本
+	ManufacturerName uint16
+	Subversion       uint16
+}
+
+// BR/EDR和LE共用
+func ReadRemoteVersionInformationCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := ReadRemoteVersionInformationCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 8 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.Version = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.Version)
+	pkt.ManufacturerName = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ManufacturerName)
+	pkt.Subversion = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
@@ -0,0 +1,99 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBredrConnCmdParse(t *testing.T) {
+	testList := []struct {
+		name     string
+		ocf      uint16
+		buf      []byte
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"create connection", HCI_CREATE_CONNECTION,
+			[]byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x18, 0xCC, 0x01, 0x00, 0x34, 0x92, 0x01},
+			HCI_PKT_RET_CODE_OK,
+			HciCreateConnection{BdAddr: [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, PacketType: 0xCC18, PageScanRepetitionMode: 0x01, ClockOffset: 0x9234, AllowRoleSwitch: 0x01},
+		},
+		{"create connection too short", HCI_CREATE_CONNECTION, []byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x18, 0xCC}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{"disconnect", HCI_DISCONNECT, []byte{0x41, 0x00, 0x13}, HCI_PKT_RET_CODE_OK, HciDisconnect{ConnectionHandle: 0x0041, Reason: HCI_STATUS_REMOTE_USER_TERMINATED_CONNECTION}},
+		{"disconnect too short", HCI_DISCONNECT, []byte{0x41, 0x00}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"accept connection request", HCI_ACCEPT_CONNECTION_REQUEST,
+			[]byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11, ACCEPT_ROLE_REMAIN_SLAVE},
+			HCI_PKT_RET_CODE_OK,
+			HciAcceptConnectionRequest{BdAddr: [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, Role: ACCEPT_ROLE_REMAIN_SLAVE},
+		},
+		{"reject connection request too short", HCI_REJECT_CONNECTION_REQUEST, []byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{"read remote version information", HCI_READ_REMOTE_VERSION_INFORMATION, []byte{0x41, 0x00}, HCI_PKT_RET_CODE_OK, HciConnectionHandleCmd{ConnectionHandle: 0x0041}},
+	}
+	for _, test := range testList {
+		parsed := HciCmdPktParse(HCI_CMD_OGF_LINK_CONTROL_CMD, test.ocf, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
+
+func TestBredrConnEvtParse(t *testing.T) {
+	testList := []struct {
+		name      string
+		eventCode uint8
+		buf       []byte
+		wantCode  int
+		want      interface{}
+	}{
+		{
+			"connection request", HCI_EVT_CONNECTION_REQUEST,
+			[]byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x04, 0x04, 0x24, LINK_TYPE_ACL},
+			HCI_PKT_RET_CODE_OK,
+			ConnectionRequestEvent{BdAddr: [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, ClassOfDevice: 0x240404, DeviceClass: DeviceClassParse(0x240404), LinkType: LINK_TYPE_ACL},
+		},
+		{"connection request too short", HCI_EVT_CONNECTION_REQUEST, []byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x04, 0x04, 0x24}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"role change", HCI_EVT_ROLE_CHANGE,
+			[]byte{0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, BREDR_ROLE_SLAVE},
+			HCI_PKT_RET_CODE_OK,
+			RoleChangeEvent{BdAddr: [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, NewRole: BREDR_ROLE_SLAVE},
+		},
+		{"role change too short", HCI_EVT_ROLE_CHANGE, []byte{0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"mode change", HCI_EVT_MODE_CHANGE,
+			[]byte{0x00, 0x41, 0x00, CONN_MODE_SNIFF, 0x20, 0x03},
+			HCI_PKT_RET_CODE_OK,
+			ModeChangeEvent{ConnectionHandle: 0x0041, CurrentMode: CONN_MODE_SNIFF, Interval: 800},
+		},
+		{"mode change too short", HCI_EVT_MODE_CHANGE, []byte{0x00, 0x41, 0x00, CONN_MODE_SNIFF, 0x20}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"read remote supported features complete", HCI_EVT_READ_REMOTE_SUPPORTED_FEATURES_COMPLETE,
+			[]byte{0x00, 0x41, 0x00, 0xBF, 0xFE, 0xCF, 0xFE, 0xDB, 0xFF, 0x7B, 0x87},
+			HCI_PKT_RET_CODE_OK,
+			ReadRemoteSupportedFeaturesCompleteEvent{ConnectionHandle: 0x0041, LmpFeatures: 0x877BFFDBFECFFEBF},
+		},
+		{
+			"read remote version information complete", HCI_EVT_READ_REMOTE_VERSION_INFORMATION_COMPLETE,
+			[]byte{0x00, 0x41, 0x00, 0x0B, 0x0F, 0x00, 0x0E, 0x61},
+			HCI_PKT_RET_CODE_OK,
+			ReadRemoteVersionInformationCompleteEvent{ConnectionHandle: 0x0041, Version: 0x0B, ManufacturerName: 0x000F, Subversion: 0x610E},
+		},
+		{"read remote version information complete too short", HCI_EVT_READ_REMOTE_VERSION_INFORMATION_COMPLETE, []byte{0x00, 0x41, 0x00, 0x0B}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		parsed := HciEvtPktParse(test.eventCode, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
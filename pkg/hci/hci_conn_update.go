@@ -0,0 +1,240 @@
+// LE连接参数/数据长度/PHY更新相关命令和事件处理
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.18 LE Connection Update Command
+type HciLeConnectionUpdate struct {
+	ConnectionHandle uint16
+	ConnectionParameters
+}
+
+func HciLeConnectionUpdateParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeConnectionUpdate{}
+	if len(hciCmdPktPayloadBuf) < 14 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ConnectionHandle)
+	connectionParametersParse(hciCmdPktPayloadBuf[bufIndex:], &pkt.ConnectionParameters)
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.31 LE Remote Connection Parameter Request Reply Command
+// host接受对端的连接参数请求，参数可以和请求的不同
+type HciLeRemoteConnectionParameterRequestReply struct {
+	ConnectionHandle uint16
+	ConnectionParameters
+}
+
+func HciLeRemoteConnectionParameterRequestReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeRemoteConnectionParameterRequestReply{}
+	if len(hciCmdPktPayloadBuf) < 14 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ConnectionHandle)
+	connectionParametersParse(hciCmdPktPayloadBuf[bufIndex:], &pkt.ConnectionParameters)
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.32 LE Remote Connection Parameter Request Negative Reply Command
+type HciLeRemoteConnectionParameterRequestNegativeReply struct {
+	ConnectionHandle uint16
+	Reason           HciStatus
+}
+
+func HciLeRemoteConnectionParameterRequestNegativeReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 3 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciLeRemoteConnectionParameterRequestNegativeReply{
+		ConnectionHandle: binary.LittleEndian.Uint16(hciCmdPktPayloadBuf),
+		Reason:           HciStatus(hciCmdPktPayloadBuf[2]),
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.33 LE Set Data Length Command
+type HciLeSetDataLength struct {
+	ConnectionHandle uint16
+	TxOctets         uint16
+	TxTime           uint16 // 单位us
+}
+
+func HciLeSetDataLengthParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetDataLength{}
+	if len(hciCmdPktPayloadBuf) < 6 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.TxOctets = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.TxOctets)
+	pkt.TxTime = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// LE Set PHY All_PHYs/TX_PHYs/RX_PHYs各bit
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.49 LE Set PHY Command
+const (
+	LE_SET_PHY_ALL_PHYS_NO_TX_PREFERENCE = 0x01
+	LE_SET_PHY_ALL_PHYS_NO_RX_PREFERENCE = 0x02
+
+	LE_SET_PHY_PREFER_1M    = 0x01
+	LE_SET_PHY_PREFER_2M    = 0x02
+	LE_SET_PHY_PREFER_CODED = 0x04
+)
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.8.49 LE Set PHY Command
+type HciLeSetPhy struct {
+	ConnectionHandle uint16
+	AllPhys          uint8 // LE_SET_PHY_ALL_PHYS_XXX
+	TxPhys           uint8 // LE_SET_PHY_PREFER_XXX
+	RxPhys           uint8
+	PhyOptions       uint16
+}
+
+func HciLeSetPhyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetPhy{}
+	if len(hciCmdPktPayloadBuf) < 7 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.AllPhys = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.AllPhys)
+	pkt.TxPhys = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.TxPhys)
+	pkt.RxPhys = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.RxPhys)
+	pkt.PhyOptions = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.3 LE Connection Update Complete Event
+type LeConnectionUpdateCompleteEvent struct {
+	SubEventCode       uint8
+	Status             HciStatus
+	ConnectionHandle   uint16
+	ConnInterval       uint16 // 单位1.25ms
+	ConnLatency        uint16
+	SupervisionTimeout uint16 // 单位10ms
+}
+
+// 本端或对端发起的连接参数更新完成
+func LeConnectionUpdateCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeConnectionUpdateCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 10 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.ConnInterval = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnInterval)
+	pkt.ConnLatency = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnLatency)
+	pkt.SupervisionTimeout = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.6 LE Remote Connection Parameter Request Event
+type LeRemoteConnectionParameterRequestEvent struct {
+	SubEventCode     uint8
+	ConnectionHandle uint16
+	IntervalMin      uint16
+	IntervalMax      uint16
+	Latency          uint16
+	Timeout          uint16
+}
+
+// 对端通过LL_CONNECTION_PARAM_REQ请求更新连接参数，host需要回复Reply/Negative Reply
+func LeRemoteConnectionParameterRequestEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeRemoteConnectionParameterRequestEvent{}
+	if len(hciEvtPktPayloadBuf) < 11 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.IntervalMin = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.IntervalMin)
+	pkt.IntervalMax = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.IntervalMax)
+	pkt.Latency = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Latency)
+	pkt.Timeout = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.7 LE Data Length Change Event
+type LeDataLengthChangeEvent struct {
+	SubEventCode     uint8
+	ConnectionHandle uint16
+	MaxTxOctets      uint16
+	MaxTxTime        uint16 // 单位us
+	MaxRxOctets      uint16
+	MaxRxTime        uint16
+}
+
+func LeDataLengthChangeEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeDataLengthChangeEvent{}
+	if len(hciEvtPktPayloadBuf) < 11 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.MaxTxOctets = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.MaxTxOctets)
+	pkt.MaxTxTime = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.MaxTxTime)
+	pkt.MaxRxOctets = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.MaxRxOctets)
+	pkt.MaxRxTime = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.7.65.12 LE PHY Update Complete Event
+type LePhyUpdateCompleteEvent struct {
+	SubEventCode     uint8
+	Status           HciStatus
+	ConnectionHandle uint16
+	TxPhy            uint8 // LE_PHY_XXX
+	RxPhy            uint8
+}
+
+// LE Set PHY触发或对端发起的PHY更新完成，PHY没有变化时也可能上报
+func LePhyUpdateCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LePhyUpdateCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 6 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.TxPhy = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.TxPhy)
+	pkt.RxPhy = hciEvtPktPayloadBuf[pktIndex]
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
@@ -0,0 +1,139 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLeConnectionUpdateCmdParse(t *testing.T) {
+	testList := []struct {
+		name     string
+		ocf      uint16
+		buf      []byte
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"le connection update", HCI_LE_CONNECTION_UPDATE,
+			[]byte{0x40, 0x00, 0x06, 0x00, 0x0C, 0x00, 0x04, 0x00, 0x64, 0x00, 0x00, 0x00, 0x10, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeConnectionUpdate{
+				ConnectionHandle: 0x0040,
+				ConnectionParameters: ConnectionParameters{
+					ConnIntervalMin: 0x0006, ConnIntervalMax: 0x000C, ConnLatency: 0x0004, SupervisionTimout: 0x0064, MaximumCeLength: 0x0010,
+				},
+			},
+		},
+		{
+			"le connection update too short", HCI_LE_CONNECTION_UPDATE,
+			[]byte{0x40, 0x00, 0x06, 0x00, 0x0C, 0x00, 0x04, 0x00, 0x64, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"remote connection parameter request reply", HCI_LE_REMOTE_CONNECTION_PARAMETER_REQUEST_REPLY,
+			[]byte{0x40, 0x00, 0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0x48, 0x00, 0x00, 0x00, 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeRemoteConnectionParameterRequestReply{
+				ConnectionHandle:     0x0040,
+				ConnectionParameters: ConnectionParameters{ConnIntervalMin: 0x0018, ConnIntervalMax: 0x0028, SupervisionTimout: 0x0048},
+			},
+		},
+		{
+			"remote connection parameter request negative reply", HCI_LE_REMOTE_CONNECTION_PARAMETER_REQUEST_NEGATIVE_REPLY,
+			[]byte{0x40, 0x00, 0x3B},
+			HCI_PKT_RET_CODE_OK,
+			HciLeRemoteConnectionParameterRequestNegativeReply{ConnectionHandle: 0x0040, Reason: HCI_STATUS_UNACCEPTABLE_CONNECTION_PARAMETERS},
+		},
+		{
+			"le set data length", HCI_LE_SET_DATA_LENGTH,
+			[]byte{0x40, 0x00, 0xFB, 0x00, 0x48, 0x08},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetDataLength{ConnectionHandle: 0x0040, TxOctets: 251, TxTime: 2120},
+		},
+		{
+			"le set data length too short", HCI_LE_SET_DATA_LENGTH,
+			[]byte{0x40, 0x00, 0xFB, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"le set phy", HCI_LE_SET_PHY,
+			[]byte{0x40, 0x00, LE_SET_PHY_ALL_PHYS_NO_RX_PREFERENCE, LE_SET_PHY_PREFER_2M, 0x00, 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeSetPhy{ConnectionHandle: 0x0040, AllPhys: LE_SET_PHY_ALL_PHYS_NO_RX_PREFERENCE, TxPhys: LE_SET_PHY_PREFER_2M},
+		},
+		{
+			"le set phy too short", HCI_LE_SET_PHY,
+			[]byte{0x40, 0x00, 0x00, 0x02, 0x02},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+	}
+	for _, test := range testList {
+		parsed := HciCmdPktParse(HCI_CMD_OGF_LE_CONTROLLER_CMD, test.ocf, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
+
+func TestLeConnectionUpdateEvtParse(t *testing.T) {
+	testList := []struct {
+		name     string
+		buf      []byte // LE Meta Event参数，从Subevent_Code开始
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"connection update complete",
+			[]byte{LE_CONNECTION_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x0C, 0x00, 0x04, 0x00, 0x64, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			LeConnectionUpdateCompleteEvent{SubEventCode: LE_CONNECTION_UPDATE_COMPLETE_EVENT, ConnectionHandle: 0x0040, ConnInterval: 0x000C, ConnLatency: 0x0004, SupervisionTimeout: 0x0064},
+		},
+		{
+			"connection update complete too short",
+			[]byte{LE_CONNECTION_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, 0x0C, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"remote connection parameter request",
+			[]byte{LE_REMOTE_CONNECTION_PARAMETER_REQUEST_EVENT, 0x40, 0x00, 0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0x48, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			LeRemoteConnectionParameterRequestEvent{SubEventCode: LE_REMOTE_CONNECTION_PARAMETER_REQUEST_EVENT, ConnectionHandle: 0x0040, IntervalMin: 0x0018, IntervalMax: 0x0028, Timeout: 0x0048},
+		},
+		{
+			"data length change",
+			[]byte{LE_DATA_LENGTH_CHANGE_EVENT, 0x40, 0x00, 0xFB, 0x00, 0x48, 0x08, 0x1B, 0x00, 0x48, 0x01},
+			HCI_PKT_RET_CODE_OK,
+			LeDataLengthChangeEvent{SubEventCode: LE_DATA_LENGTH_CHANGE_EVENT, ConnectionHandle: 0x0040, MaxTxOctets: 251, MaxTxTime: 2120, MaxRxOctets: 27, MaxRxTime: 328},
+		},
+		{
+			"data length change too short",
+			[]byte{LE_DATA_LENGTH_CHANGE_EVENT, 0x40, 0x00, 0xFB, 0x00, 0x48, 0x08, 0x1B, 0x00},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"phy update complete",
+			[]byte{LE_PHY_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, LE_PHY_2M, LE_PHY_1M},
+			HCI_PKT_RET_CODE_OK,
+			LePhyUpdateCompleteEvent{SubEventCode: LE_PHY_UPDATE_COMPLETE_EVENT, ConnectionHandle: 0x0040, TxPhy: LE_PHY_2M, RxPhy: LE_PHY_1M},
+		},
+		{
+			"phy update complete too short",
+			[]byte{LE_PHY_UPDATE_COMPLETE_EVENT, 0x00, 0x40, 0x00, LE_PHY_2M},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+	}
+	for _, test := range testList {
+		parsed := HciEvtPktParse(HCI_EVT_LE_META_EVENT, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// controller能力相关命令和字段解析，初始化阶段host读取controller信息和配置事件掩码时使用
+
+package hci
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// HCI_Version/LMP_Version
+// Assigned Numbers | 2.1 Bluetooth Core Specification versions
+var CoreVersionStrMap = map[int]string{
+	0:  "1.0b",
+	1:  "1.1",
+	2:  "1.2",
+	3:  "2.0+EDR",
+	4:  "2.1+EDR",
+	5:  "3.0+HS",
+	6:  "4.0",
+	7:  "4.1",
+	8:  "4.2",
+	9:  "5.0",
+	10: "5.1",
+	11: "5.2",
+	12: "5.3",
+	13: "5.4",
+}
+
+func CoreVersionString(version uint8) string {
+	if name, ok := CoreVersionStrMap[int(version)]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown(0x%02X)", version)
+}
+
+// 按bit序号输出为1的bit对应的名称，没有名称的bit输出为Reserved(bit)
+func bitNameList(bits uint64, nameList []string) []string {
+	list := []string{}
+	for bit := 0; bit < 64; bit++ {
+		if bits&(1<<uint(bit)) == 0 {
+			continue
+		}
+		if bit < len(nameList) && nameList[bit] != "" {
+			list = append(list, nameList[bit])
+		} else {
+			list = append(list, fmt.Sprintf("Reserved(%d)", bit))
+		}
+	}
+	return list
+}
+
+// LE Features，按bit序号
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 6, Part B 4.6 Feature support
+var LeFeatureStrList = []string{
+	"LE Encryption",
+	"Connection Parameters Request Procedure",
+	"Extended Reject Indication",
+	"Slave-initiated Features Exchange",
+	"LE Ping",
+	"LE Data Packet Length Extension",
+	"LL Privacy",
+	"Extended Scanner Filter Policies",
+	"LE 2M PHY",
+	"Stable Modulation Index - Transmitter",
+	"Stable Modulation Index - Receiver",
+	"LE Coded PHY",
+	"LE Extended Advertising",
+	"LE Periodic Advertising",
+	"Channel Selection Algorithm #2",
+	"LE Power Class 1",
+	"Minimum Number of Used Channels Procedure",
+	"Connection CTE Request",
+	"Connection CTE Response",
+	"Connectionless CTE Transmitter",
+	"Connectionless CTE Receiver",
+	"Antenna Switching During CTE Transmission (AoD)",
+	"Antenna Switching During CTE Reception (AoA)",
+	"Receiving Constant Tone Extensions",
+	"Periodic Advertising Sync Transfer - Sender",
+	"Periodic Advertising Sync Transfer - Recipient",
+	"Sleep Clock Accuracy Updates",
+	"Remote Public Key Validation",
+	"Connected Isochronous Stream - Central",
+	"Connected Isochronous Stream - Peripheral",
+	"Isochronous Broadcaster",
+	"Synchronized Receiver",
+	"Connected Isochronous Stream (Host Support)",
+	"LE Power Control Request",
+	"LE Power Control Request",
+	"LE Path Loss Monitoring",
+	"Periodic Advertising ADI support",
+	"Connection Subrating",
+	"Connection Subrating (Host Support)",
+	"Channel Classification",
+}
+
+func LeFeatureList(leFeatures uint64) []string {
+	return bitNameList(leFeatures, LeFeatureStrList)
+}
+
+// Supported_Commands，按octet/bit，空字符串为reserved
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 4, Part E 6.27 Supported Commands
+var SupportedCommandStrTable = [][8]string{
+	{"Inquiry", "Inquiry Cancel", "Periodic Inquiry Mode", "Exit Periodic Inquiry Mode", "Create Connection", "Disconnect", "Add SCO Connection", "Create Connection Cancel"},
+	{"Accept Connection Request", "Reject Connection Request", "Link Key Request Reply", "Link Key Request Negative Reply", "PIN Code Request Reply", "PIN Code Request Negative Reply", "Change Connection Packet Type", "Authentication Requested"},
+	{"Set Connection Encryption", "Change Connection Link Key", "Master Link Key", "Remote Name Request", "Remote Name Request Cancel", "Read Remote Supported Features", "Read Remote Extended Features", "Read Remote Version Information"},
+	{"Read Clock Offset", "Read LMP Handle", "", "", "", "", "", ""},
+	{"", "Hold Mode", "Sniff Mode", "Exit Sniff Mode", "Park State", "Exit Park State", "QoS Setup", "Role Discovery"},
+	{"Switch Role", "Read Link Policy Settings", "Write Link Policy Settings", "Read Default Link Policy Settings", "Write Default Link Policy Settings", "Flow Specification", "Set Event Mask", "Reset"},
+	{"Set Event Filter", "Flush", "Read PIN Type", "Write PIN Type", "Create New Unit Key", "Read Stored Link Key", "Write Stored Link Key", "Delete Stored Link Key"},
+	{"Write Local Name", "Read Local Name", "Read Connection Accept Timeout", "Write Connection Accept Timeout", "Read Page Timeout", "Write Page Timeout", "Read Scan Enable", "Write Scan Enable"},
+	{"Read Page Scan Activity", "Write Page Scan Activity", "Read Inquiry Scan Activity", "Write Inquiry Scan Activity", "Read Authentication Enable", "Write Authentication Enable", "Read Encryption Mode", "Write Encryption Mode"},
+	{"Read Class Of Device", "Write Class Of Device", "Read Voice Setting", "Write Voice Setting", "Read Automatic Flush Timeout", "Write Automatic Flush Timeout", "Read Num Broadcast Retransmissions", "Write Num Broadcast Retransmissions"},
+	{"Read Hold Mode Activity", "Write Hold Mode Activity", "Read Transmit Power Level", "Read Synchronous Flow Control Enable", "Write Synchronous Flow Control Enable", "Set Controller To Host Flow Control", "Host Buffer Size", "Host Number Of Completed Packets"},
+	{"Read Link Supervision Timeout", "Write Link Supervision Timeout", "Read Number of Supported IAC", "Read Current IAC LAP", "Write Current IAC LAP", "Read Page Scan Mode Period", "Write Page Scan Mode Period", "Read Page Scan Mode"},
+	{"Write Page Scan Mode", "Set AFH Host Channel Classification", "", "", "Read Inquiry Scan Type", "Write Inquiry Scan Type", "Read Inquiry Mode", "Write Inquiry Mode"},
+	{"Read Page Scan Type", "Write Page Scan Type", "Read AFH Channel Assessment Mode", "Write AFH Channel Assessment Mode", "", "", "", ""},
+	{"", "", "", "Read Local Version Information", "", "Read Local Supported Features", "Read Local Extended Features", "Read Buffer Size"},
+	{"Read Country Code", "Read BD_ADDR", "Read Failed Contact Counter", "Reset Failed Contact Counter", "Read Link Quality", "Read RSSI", "Read AFH Channel Map", "Read Clock"},
+	{"Read Loopback Mode", "Write Loopback Mode", "Enable Device Under Test Mode", "Setup Synchronous Connection Request", "Accept Synchronous Connection Request", "Reject Synchronous Connection Request", "", ""},
+	{"Read Extended Inquiry Response", "Write Extended Inquiry Response", "Refresh Encryption Key", "", "Sniff Subrating", "Read Simple Pairing Mode", "Write Simple Pairing Mode", "Read Local OOB Data"},
+	{"Read Inquiry Response Transmit Power Level", "Write Inquiry Transmit Power Level", "Read Default Erroneous Data Reporting", "Write Default Erroneous Data Reporting", "", "", "", "IO Capability Request Reply"},
+	{"User Confirmation Request Reply", "User Confirmation Request Negative Reply", "User Passkey Request Reply", "User Passkey Request Negative Reply", "Remote OOB Data Request Reply", "Write Simple Pairing Debug Mode", "Enhanced Flush", "Remote OOB Data Request Negative Reply"},
+	{"", "", "Send Keypress Notification", "IO Capability Request Negative Reply", "Read Encryption Key Size", "", "", ""},
+	{"Create Physical Link", "Accept Physical Link", "Disconnect Physical Link", "Create Logical Link", "Accept Logical Link", "Disconnect Logical Link", "Logical Link Cancel", "Flow Spec Modify"},
+	{"Read Logical Link Accept Timeout", "Write Logical Link Accept Timeout", "Set Event Mask Page 2", "Read Location Data", "Write Location Data", "Read Local AMP Info", "Read Local AMP_ASSOC", "Write Remote AMP_ASSOC"},
+	{"Read Flow Control Mode", "Write Flow Control Mode", "Read Data Block Size", "", "", "Enable AMP Receiver Reports", "AMP Test End", "AMP Test"},
+	{"Read Enhanced Transmit Power Level", "", "Read Best Effort Flush Timeout", "Write Best Effort Flush Timeout", "Short Range Mode", "Read LE Host Support", "Write LE Host Support", ""},
+	{"LE Set Event Mask", "LE Read Buffer Size [v1]", "LE Read Local Supported Features", "", "LE Set Random Address", "LE Set Advertising Parameters", "LE Read Advertising Physical Channel Tx Power", "LE Set Advertising Data"},
+	{"LE Set Scan Response Data", "LE Set Advertising Enable", "LE Set Scan Parameters", "LE Set Scan Enable", "LE Create Connection", "LE Create Connection Cancel", "LE Read Filter Accept List Size", "LE Clear Filter Accept List"},
+	{"LE Add Device To Filter Accept List", "LE Remove Device From Filter Accept List", "LE Connection Update", "LE Set Host Channel Classification", "LE Read Channel Map", "LE Read Remote Features", "LE Encrypt", "LE Rand"},
+	{"LE Enable Encryption", "LE Long Term Key Request Reply", "LE Long Term Key Request Negative Reply", "LE Read Supported States", "LE Receiver Test [v1]", "LE Transmitter Test [v1]", "LE Test End", ""},
+	{"", "", "", "Enhanced Setup Synchronous Connection", "Enhanced Accept Synchronous Connection", "Read Local Supported Codecs [v1]", "Set MWS Channel Parameters", "Set External Frame Configuration"},
+	{"Set MWS Signaling", "Set MWS Transport Layer", "Set MWS Scan Frequency Table", "Get MWS Transport Layer Configuration", "Set MWS PATTERN Configuration", "Set Triggered Clock Capture", "Truncated Page", "Truncated Page Cancel"},
+	{"Set Connectionless Slave Broadcast", "Set Connectionless Slave Broadcast Receive", "Start Synchronization Train", "Receive Synchronization Train", "Set Reserved LT_ADDR", "Delete Reserved LT_ADDR", "Set Connectionless Slave Broadcast Data", "Read Synchronization Train Parameters"},
+	{"Write Synchronization Train Parameters", "Remote OOB Extended Data Request Reply", "Read Secure Connections Host Support", "Write Secure Connections Host Support", "Read Authenticated Payload Timeout", "Write Authenticated Payload Timeout", "Read Local OOB Extended Data", "Write Secure Connections Test Mode"},
+	{"Read Extended Page Timeout", "Write Extended Page Timeout", "Read Extended Inquiry Length", "Write Extended Inquiry Length", "LE Remote Connection Parameter Request Reply", "LE Remote Connection Parameter Request Negative Reply", "LE Set Data Length", "LE Read Suggested Default Data Length"},
+	{"LE Write Suggested Default Data Length", "LE Read Local P-256 Public Key", "LE Generate DHKey [v1]", "LE Add Device To Resolving List", "LE Remove Device From Resolving List", "LE Clear Resolving List", "LE Read Resolving List Size", "LE Read Peer Resolvable Address"},
+	{"LE Read Local Resolvable Address", "LE Set Address Resolution Enable", "LE Set Resolvable Private Address Timeout", "LE Read Maximum Data Length", "LE Read PHY", "LE Set Default PHY", "LE Set PHY", "LE Receiver Test [v2]"},
+	{"LE Transmitter Test [v2]", "LE Set Advertising Set Random Address", "LE Set Extended Advertising Parameters", "LE Set Extended Advertising Data", "LE Set Extended Scan Response Data", "LE Set Extended Advertising Enable", "LE Read Maximum Advertising Data Length", "LE Read Number of Supported Advertising Sets"},
+	{"LE Remove Advertising Set", "LE Clear Advertising Sets", "LE Set Periodic Advertising Parameters", "LE Set Periodic Advertising Data", "LE Set Periodic Advertising Enable", "LE Set Extended Scan Parameters", "LE Set Extended Scan Enable", "LE Extended Create Connection"},
+	{"LE Periodic Advertising Create Sync", "LE Periodic Advertising Create Sync Cancel", "LE Periodic Advertising Terminate Sync", "LE Add Device To Periodic Advertiser List", "LE Remove Device From Periodic Advertiser List", "LE Clear Periodic Advertiser List", "LE Read Periodic Advertiser List Size", "LE Read Transmit Power"},
+	{"LE Read RF Path Compensation", "LE Write RF Path Compensation", "LE Set Privacy Mode", "LE Receiver Test [v3]", "LE Transmitter Test [v3]", "LE Set Connectionless CTE Transmit Parameters", "LE Set Connectionless CTE Transmit Enable", "LE Set Connectionless IQ Sampling Enable"},
+	{"LE Set Connection CTE Receive Parameters", "LE Set Connection CTE Transmit Parameters", "LE Connection CTE Request Enable", "LE Connection CTE Response Enable", "LE Read Antenna Information", "LE Set Periodic Advertising Receive Enable", "LE Periodic Advertising Sync Transfer", "LE Periodic Advertising Set Info Transfer"},
+	{"LE Set Periodic Advertising Sync Transfer Parameters", "LE Set Default Periodic Advertising Sync Transfer Parameters", "LE Generate DHKey [v2]", "Read Local Simple Pairing Options", "LE Modify Sleep Clock Accuracy", "LE Read Buffer Size [v2]", "LE Read ISO TX Sync", "LE Set CIG Parameters"},
+	{"LE Set CIG Parameters Test", "LE Create CIS", "LE Remove CIG", "LE Accept CIS Request", "LE Reject CIS Request", "LE Create BIG", "LE Create BIG Test", "LE Terminate BIG"},
+	{"LE BIG Create Sync", "LE BIG Terminate Sync", "LE Request Peer SCA", "LE Setup ISO Data Path", "LE Remove ISO Data Path", "LE ISO Transmit Test", "LE ISO Receive Test", "LE ISO Read Test Counters"},
+	{"LE ISO Test End", "LE Set Host Feature", "LE Read ISO Link Quality", "LE Enhanced Read Transmit Power Level", "LE Read Remote Transmit Power Level", "LE Set Path Loss Reporting Parameters", "LE Set Path Loss Reporting Enable", "LE Set Transmit Power Reporting Enable"},
+	{"LE Transmitter Test [v4]", "Set Ecosystem Base Interval", "Read Local Supported Codecs [v2]", "Read Local Supported Codec Capabilities", "Read Local Supported Controller Delay", "Configure Data Path", "LE Set Data Related Address Changes", "Set Min Encryption Key Size"},
+	{"LE Set Default Subrate", "LE Subrate Request", "", "", "", "", "", ""},
+}
+
+// 支持的命令名称列表，未收录的octet/bit输出为Unknown(octet.bit)
+func SupportedCommandList(supportedCommands [64]byte) []string {
+	list := []string{}
+	for octet, bits := range supportedCommands {
+		for bit := 0; bit < 8; bit++ {
+			if bits&(1<<uint(bit)) == 0 {
+				continue
+			}
+			if octet < len(SupportedCommandStrTable) && SupportedCommandStrTable[octet][bit] != "" {
+				list = append(list, SupportedCommandStrTable[octet][bit])
+			} else {
+				list = append(list, fmt.Sprintf("Unknown(%d.%d)", octet, bit))
+			}
+		}
+	}
+	return list
+}
+
+// 某个命令是否支持
+func SupportedCommandHas(supportedCommands [64]byte, octet int, bit int) bool {
+	return supportedCommands[octet]&(1<<uint(bit)) != 0
+}
+
+// Set Event Mask未配置时controller使用的默认值
+const (
+	EVENT_MASK_DEFAULT    = 0x00001FFFFFFFFFFF
+	LE_EVENT_MASK_DEFAULT = 0x000000000000001F
+)
+
+// Event_Mask，按bit序号，Command Complete/Command Status不可屏蔽
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.3.1 Set Event Mask Command
+var EventMaskStrList = []string{
+	"Inquiry Complete",
+	"Inquiry Result",
+	"Connection Complete",
+	"Connection Request",
+	"Disconnection Complete",
+	"Authentication Complete",
+	"Remote Name Request Complete",
+	"Encryption Change",
+	"Change Connection Link Key Complete",
+	"Master Link Key Complete",
+	"Read Remote Supported Features Complete",
+	"Read Remote Version Information Complete",
+	"QoS Setup Complete",
+	"",
+	"",
+	"Hardware Error",
+	"Flush Occurred",
+	"Role Change",
+	"",
+	"Mode Change",
+	"Return Link Keys",
+	"PIN Code Request",
+	"Link Key Request",
+	"Link Key Notification",
+	"Loopback Command",
+	"Data Buffer Overflow",
+	"Max Slots Change",
+	"Read Clock Offset Complete",
+	"Connection Packet Type Changed",
+	"QoS Violation",
+	"Page Scan Mode Change",
+	"Page Scan Repetition Mode Change",
+	"Flow Specification Complete",
+	"Inquiry Result with RSSI",
+	"Read Remote Extended Features Complete",
+	"", "", "", "", "", "", "", "",
+	"Synchronous Connection Complete",
+	"Synchronous Connection Changed",
+	"Sniff Subrating",
+	"Extended Inquiry Result",
+	"Encryption Key Refresh Complete",
+	"IO Capability Request",
+	"IO Capability Response",
+	"User Confirmation Request",
+	"User Passkey Request",
+	"Remote OOB Data Request",
+	"Simple Pairing Complete",
+	"",
+	"Link Supervision Timeout Changed",
+	"Enhanced Flush Complete",
+	"",
+	"User Passkey Notification",
+	"Keypress Notification",
+	"Remote Host Supported Features Notification",
+	"LE Meta",
+}
+
+// LE_Event_Mask，bit n对应subevent n+1
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 4, Part E 7.8.1 LE Set Event Mask command
+var LeEventMaskStrList = []string{
+	"LE Connection Complete",
+	"LE Advertising Report",
+	"LE Connection Update Complete",
+	"LE Read Remote Features Complete",
+	"LE Long Term Key Request",
+	"LE Remote Connection Parameter Request",
+	"LE Data Length Change",
+	"LE Read Local P-256 Public Key Complete",
+	"LE Generate DHKey Complete",
+	"LE Enhanced Connection Complete",
+	"LE Directed Advertising Report",
+	"LE PHY Update Complete",
+	"LE Extended Advertising Report",
+	"LE Periodic Advertising Sync Established",
+	"LE Periodic Advertising Report",
+	"LE Periodic Advertising Sync Lost",
+	"LE Scan Timeout",
+	"LE Advertising Set Terminated",
+	"LE Scan Request Received",
+	"LE Channel Selection Algorithm",
+	"LE Connectionless IQ Report",
+	"LE Connection IQ Report",
+	"LE CTE Request Failed",
+	"LE Periodic Advertising Sync Transfer Received",
+	"LE CIS Established",
+	"LE CIS Request",
+	"LE Create BIG Complete",
+	"LE Terminate BIG Complete",
+	"LE BIG Sync Established",
+	"LE BIG Sync Lost",
+	"LE Request Peer SCA Complete",
+	"LE Path Loss Threshold",
+	"LE Transmit Power Reporting",
+	"LE BIGInfo Advertising Report",
+	"LE Subrate Change",
+}
+
+func EventMaskList(eventMask uint64) []string {
+	return bitNameList(eventMask, EventMaskStrList)
+}
+
+func LeEventMaskList(leEventMask uint64) []string {
+	return bitNameList(leEventMask, LeEventMaskStrList)
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.3.1 Set Event Mask Command
+type HciSetEventMask struct {
+	EventMask uint64
+}
+
+func HciSetEventMaskParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 8 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciSetEventMask{EventMask: binary.LittleEndian.Uint64(hciCmdPktPayloadBuf)}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.1 LE Set Event Mask Command
+type HciLeSetEventMask struct {
+	LeEventMask uint64
+}
+
+func HciLeSetEventMaskParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 8 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciLeSetEventMask{LeEventMask: binary.LittleEndian.Uint64(hciCmdPktPayloadBuf)}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
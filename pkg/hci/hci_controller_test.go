@@ -0,0 +1,134 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestControllerInfoReturnParameters(t *testing.T) {
+	testList := []struct {
+		name     string
+		opCode   []byte // 小端序
+		retParam []byte
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"read local version", []byte{0x01, 0x10},
+			[]byte{0x00, 0x09, 0x34, 0x12, 0x09, 0x0F, 0x00, 0x78, 0x56},
+			HCI_PKT_RET_CODE_OK,
+			HciReadLocalVersionInformationRetParam{HciVersion: 9, HciRevision: 0x1234, LmpPalVersion: 9, ManufacturerName: 0x000F, LmpPalSubversion: 0x5678},
+		},
+		{"read local version truncated", []byte{0x01, 0x10}, []byte{0x00, 0x09, 0x34, 0x12, 0x09, 0x0F, 0x00, 0x78}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"read local supported commands", []byte{0x02, 0x10},
+			append([]byte{0x00, 0x21}, make([]byte, 63)...),
+			HCI_PKT_RET_CODE_OK,
+			HciReadLocalSupportedCommandsRetParam{SupportedCommands: [64]byte{0x21}},
+		},
+		{"read local supported commands truncated", []byte{0x02, 0x10}, append([]byte{0x00}, make([]byte, 63)...), HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"read local supported features", []byte{0x03, 0x10},
+			[]byte{0x00, 0xFF, 0xFE, 0x8F, 0xFE, 0xD8, 0x3F, 0x5B, 0x87},
+			HCI_PKT_RET_CODE_OK,
+			HciReadLocalSupportedFeaturesRetParam{LmpFeatures: 0x875B3FD8FE8FFEFF},
+		},
+		{"read local supported features truncated", []byte{0x03, 0x10}, []byte{0x00, 0xFF, 0xFE}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"read local extended features", []byte{0x04, 0x10},
+			[]byte{0x00, 0x01, 0x02, 0x0F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciReadLocalExtendedFeaturesRetParam{PageNumber: 1, MaximumPageNumber: 2, ExtendedLmpFeatures: 0x0F},
+		},
+		{"read local extended features truncated", []byte{0x04, 0x10}, []byte{0x00, 0x01, 0x02, 0x0F}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"le read local supported features", []byte{0x03, 0x20},
+			[]byte{0x00, 0x21, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			HciLeReadLocalSupportedFeaturesRetParam{LeFeatures: 0x0121},
+		},
+		{"le read local supported features truncated", []byte{0x03, 0x20}, []byte{0x00, 0x21, 0x01}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"le read buffer size v1", []byte{0x02, 0x20},
+			[]byte{0x00, 0xFB, 0x00, 0x0F},
+			HCI_PKT_RET_CODE_OK,
+			HciLeReadBufferSizeRetParam{LeAclDataPacketLength: 251, TotalNumLeAclDataPackets: 15},
+		},
+		{"le read buffer size v1 truncated", []byte{0x02, 0x20}, []byte{0x00, 0xFB, 0x00}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		buf := append([]byte{0x01}, test.opCode...)
+		buf = append(buf, test.retParam...)
+		parsed := HciEvtPktParse(HCI_EVT_COMMAND_COMPLETE, buf)
+		evt, ok := parsed.Ret.(CommandCompleteEvent)
+		if parsed.Code != HCI_PKT_RET_CODE_OK || !ok {
+			t.Fatalf("%s: Command Complete parse code %d", test.name, parsed.Code)
+		}
+		if evt.ReturnParsedResult.Code != test.wantCode {
+			t.Errorf("%s: return parameters code %d, want %d", test.name, evt.ReturnParsedResult.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(evt.ReturnParsedResult.Ret, test.want) {
+			t.Errorf("%s: return parameters %+v, want %+v", test.name, evt.ReturnParsedResult.Ret, test.want)
+		}
+	}
+}
+
+func TestHciEventMaskCmdParse(t *testing.T) {
+	testList := []struct {
+		name     string
+		ogf      uint8
+		ocf      uint16
+		buf      []byte
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"set event mask", HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD, HCI_SET_EVENT_MASK,
+			[]byte{0xFF, 0xFF, 0xFB, 0xFF, 0x07, 0xF8, 0xBF, 0x3D},
+			HCI_PKT_RET_CODE_OK, HciSetEventMask{EventMask: 0x3DBFF807FFFBFFFF},
+		},
+		{"set event mask truncated", HCI_CMD_OGF_CONTROLLER_BASEBAND_CMD, HCI_SET_EVENT_MASK, []byte{0xFF, 0xFF, 0xFB, 0xFF}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"le set event mask", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EVENT_MASK,
+			[]byte{0x7F, 0x0A, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK, HciLeSetEventMask{LeEventMask: 0x0A7F},
+		},
+		{"le set event mask truncated", HCI_CMD_OGF_LE_CONTROLLER_CMD, HCI_LE_SET_EVENT_MASK, []byte{0x7F}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		parsed := HciCmdPktParse(test.ogf, test.ocf, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
+
+func TestControllerNameList(t *testing.T) {
+	testList := []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"core version", CoreVersionString(9), "5.0"},
+		{"unknown core version", CoreVersionString(0x20), "Unknown(0x20)"},
+		{"company id", CompanyIdString(0x000F), "Broadcom Corporation"},
+		{"unknown company id", CompanyIdString(0x7FFE), "Unknown(0x7FFE)"},
+		{"le features", LeFeatureList(0x21), []string{"LE Encryption", "LE Data Packet Length Extension"}},
+		{"event mask reserved bit", EventMaskList(1<<2 | 1<<13), []string{"Connection Complete", "Reserved(13)"}},
+		{"le event mask default", LeEventMaskList(LE_EVENT_MASK_DEFAULT), []string{
+			"LE Connection Complete", "LE Advertising Report", "LE Connection Update Complete",
+			"LE Read Remote Features Complete", "LE Long Term Key Request",
+		}},
+		{"supported commands", SupportedCommandList([64]byte{0x21, 63: 0x80}), []string{"Inquiry", "Disconnect", "Unknown(63.7)"}},
+	}
+	for _, test := range testList {
+		if !reflect.DeepEqual(test.got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, test.got, test.want)
+		}
+	}
+}
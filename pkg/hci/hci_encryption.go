@@ -0,0 +1,141 @@
+// 链路加密相关命令和事件处理，Encryption Change和Key Refresh BR/EDR和LE共用
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// Encryption_Enabled
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.8 Encryption Change Event
+const (
+	ENCRYPTION_OFF        = 0x00
+	ENCRYPTION_ON         = 0x01 // BR/EDR为E0，LE为AES-CCM
+	ENCRYPTION_ON_AES_CCM = 0x02 // BR/EDR AES-CCM
+)
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.24 LE Start Encryption Command
+type HciLeEnableEncryption struct {
+	ConnectionHandle     uint16
+	RandomNumber         [8]byte
+	EncryptedDiversifier uint16
+	LongTermKey          [16]byte
+}
+
+// master使用LTK开始加密或者重新加密
+func HciLeEnableEncryptionParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeEnableEncryption{}
+	if len(hciCmdPktPayloadBuf) < 28 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ConnectionHandle)
+	copy(pkt.RandomNumber[:], hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.RandomNumber)
+	pkt.EncryptedDiversifier = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.EncryptedDiversifier)
+	copy(pkt.LongTermKey[:], hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.25 LE Long Term Key Request Reply Command
+type HciLeLongTermKeyRequestReply struct {
+	ConnectionHandle uint16
+	LongTermKey      [16]byte
+}
+
+func HciLeLongTermKeyRequestReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeLongTermKeyRequestReply{}
+	if len(hciCmdPktPayloadBuf) < 18 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf)
+	copy(pkt.LongTermKey[:], hciCmdPktPayloadBuf[2:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.8.26 LE Long Term Key Request Negative Reply Command
+type HciLeLongTermKeyRequestNegativeReply struct {
+	ConnectionHandle uint16
+}
+
+// 本端没有对应的LTK，controller回复对端PIN or Key Missing
+func HciLeLongTermKeyRequestNegativeReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 2 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciLeLongTermKeyRequestNegativeReply{ConnectionHandle: binary.LittleEndian.Uint16(hciCmdPktPayloadBuf)}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.8 Encryption Change Event
+// BLUETOOTH CORE SPECIFICATION Version 5.3 | Vol 4, Part E 7.7.8 Encryption Change [v2] event
+type EncryptionChangeEvent struct {
+	Status              HciStatus
+	ConnectionHandle    uint16
+	EncryptionEnabled   uint8 // ENCRYPTION_XXX
+	EncryptionKeySize   uint8 // 仅v2有效
+	EncryptionKeySizeV2 bool
+}
+
+func EncryptionChangeEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := EncryptionChangeEvent{}
+	if len(hciEvtPktPayloadBuf) < 4 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.EncryptionEnabled = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.EncryptionEnabled)
+	if EventCode == HCI_EVT_ENCRYPTION_CHANGE_V2 {
+		if len(hciEvtPktPayloadBuf) < 5 {
+			return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.EncryptionKeySize, pkt.EncryptionKeySizeV2 = hciEvtPktPayloadBuf[pktIndex], true
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.39 Encryption Key Refresh Complete Event
+type EncryptionKeyRefreshCompleteEvent struct {
+	Status           HciStatus
+	ConnectionHandle uint16
+}
+
+// LE已加密的连接重新加密时不上报Encryption Change，上报该事件
+func EncryptionKeyRefreshCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	if len(hciEvtPktPayloadBuf) < 3 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := EncryptionKeyRefreshCompleteEvent{Status: HciStatus(hciEvtPktPayloadBuf[0]), ConnectionHandle: binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[1:])}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.5 LE Long Term Key Request Event
+type LeLongTermKeyRequestEvent struct {
+	SubEventCode         uint8
+	ConnectionHandle     uint16
+	RandomNumber         [8]byte
+	EncryptedDiversifier uint16
+}
+
+// 本端为slave时对端开始加密，host需要回复LTK，LE Secure Connections时Rand和EDIV为0
+func LeLongTermKeyRequestEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeLongTermKeyRequestEvent{}
+	if len(hciEvtPktPayloadBuf) < 13 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	copy(pkt.RandomNumber[:], hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.RandomNumber)
+	pkt.EncryptedDiversifier = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
@@ -0,0 +1,105 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHciLeEncryptionCmdParse(t *testing.T) {
+	ltk := []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F}
+	testList := []struct {
+		name     string
+		ocf      uint16
+		buf      []byte
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"enable encryption", HCI_LE_ENABLE_ENCRYPTION,
+			append([]byte{0x40, 0x00, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x34, 0x12}, ltk...),
+			HCI_PKT_RET_CODE_OK,
+			HciLeEnableEncryption{
+				ConnectionHandle:     0x0040,
+				RandomNumber:         [8]byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18},
+				EncryptedDiversifier: 0x1234,
+				LongTermKey:          [16]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F},
+			},
+		},
+		{"enable encryption truncated", HCI_LE_ENABLE_ENCRYPTION, append([]byte{0x40, 0x00}, ltk...), HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"ltk reply", HCI_LE_LONG_TERM_KEY_REQUEST_REPLY, append([]byte{0x41, 0x00}, ltk...),
+			HCI_PKT_RET_CODE_OK,
+			HciLeLongTermKeyRequestReply{
+				ConnectionHandle: 0x0041,
+				LongTermKey:      [16]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0A, 0x0B, 0x0C, 0x0D, 0x0E, 0x0F},
+			},
+		},
+		{"ltk reply truncated", HCI_LE_LONG_TERM_KEY_REQUEST_REPLY, ltk, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{"ltk negative reply", HCI_LE_LONG_TERM_KEY_REQUEST_NEGATIVE_REPLY, []byte{0x42, 0x00}, HCI_PKT_RET_CODE_OK, HciLeLongTermKeyRequestNegativeReply{ConnectionHandle: 0x0042}},
+		{"ltk negative reply truncated", HCI_LE_LONG_TERM_KEY_REQUEST_NEGATIVE_REPLY, []byte{0x42}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		parsed := HciCmdPktParse(HCI_CMD_OGF_LE_CONTROLLER_CMD, test.ocf, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
+
+func TestEncryptionEventParse(t *testing.T) {
+	testList := []struct {
+		name      string
+		eventCode uint8
+		buf       []byte
+		wantCode  int
+		want      interface{}
+	}{
+		{
+			"encryption change v1", HCI_EVT_ENCRYPTION_CHANGE, []byte{0x00, 0x40, 0x00, ENCRYPTION_ON},
+			HCI_PKT_RET_CODE_OK,
+			EncryptionChangeEvent{Status: HCI_STATUS_SUCCESS, ConnectionHandle: 0x0040, EncryptionEnabled: ENCRYPTION_ON},
+		},
+		{"encryption change v1 truncated", HCI_EVT_ENCRYPTION_CHANGE, []byte{0x00, 0x40, 0x00}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"encryption change v2", HCI_EVT_ENCRYPTION_CHANGE_V2, []byte{0x00, 0x41, 0x00, ENCRYPTION_ON_AES_CCM, 0x10},
+			HCI_PKT_RET_CODE_OK,
+			EncryptionChangeEvent{
+				Status: HCI_STATUS_SUCCESS, ConnectionHandle: 0x0041, EncryptionEnabled: ENCRYPTION_ON_AES_CCM,
+				EncryptionKeySize: 0x10, EncryptionKeySizeV2: true,
+			},
+		},
+		{"encryption change v2 without key size", HCI_EVT_ENCRYPTION_CHANGE_V2, []byte{0x00, 0x41, 0x00, ENCRYPTION_ON}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"key refresh complete", HCI_EVT_ENCRYPTION_KEY_REFRESH_COMPLETE, []byte{0x3D, 0x42, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			EncryptionKeyRefreshCompleteEvent{Status: HCI_STATUS_CONNECTION_TERMINATED_DUE_TO_MIC_FAILURE, ConnectionHandle: 0x0042},
+		},
+		{"key refresh complete truncated", HCI_EVT_ENCRYPTION_KEY_REFRESH_COMPLETE, []byte{0x00, 0x42}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"le ltk request", HCI_EVT_LE_META_EVENT,
+			[]byte{LE_LONG_TERM_KEY_REQUEST_EVENT, 0x40, 0x00, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x34, 0x12},
+			HCI_PKT_RET_CODE_OK,
+			LeLongTermKeyRequestEvent{
+				SubEventCode:         LE_LONG_TERM_KEY_REQUEST_EVENT,
+				ConnectionHandle:     0x0040,
+				RandomNumber:         [8]byte{0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18},
+				EncryptedDiversifier: 0x1234,
+			},
+		},
+		{"le ltk request truncated", HCI_EVT_LE_META_EVENT, []byte{LE_LONG_TERM_KEY_REQUEST_EVENT, 0x40, 0x00, 0x11}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		parsed := HciEvtPktParse(test.eventCode, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
@@ -6,7 +6,39 @@ import "encoding/binary"
 
 // Evt列表
 const (
-	HCI_EVT_LE_META_EVENT = 0x3E
+	HCI_EVT_INQUIRY_COMPLETE                         = 0x01
+	HCI_EVT_INQUIRY_RESULT                           = 0x02
+	HCI_EVT_CONNECTION_COMPLETE                      = 0x03
+	HCI_EVT_CONNECTION_REQUEST                       = 0x04
+	HCI_EVT_DISCONNECTION_COMPLETE                   = 0x05
+	HCI_EVT_AUTHENTICATION_COMPLETE                  = 0x06
+	HCI_EVT_REMOTE_NAME_REQUEST_COMPLETE             = 0x07
+	HCI_EVT_ENCRYPTION_CHANGE                        = 0x08
+	HCI_EVT_READ_REMOTE_SUPPORTED_FEATURES_COMPLETE  = 0x0B
+	HCI_EVT_READ_REMOTE_VERSION_INFORMATION_COMPLETE = 0x0C
+	HCI_EVT_COMMAND_COMPLETE                         = 0x0E
+	HCI_EVT_COMMAND_STATUS                           = 0x0F
+	HCI_EVT_ROLE_CHANGE                              = 0x12
+	HCI_EVT_NUMBER_OF_COMPLETED_PACKETS              = 0x13
+	HCI_EVT_MODE_CHANGE                              = 0x14
+	HCI_EVT_PIN_CODE_REQUEST                         = 0x16
+	HCI_EVT_LINK_KEY_REQUEST                         = 0x17
+	HCI_EVT_LINK_KEY_NOTIFICATION                    = 0x18
+	HCI_EVT_INQUIRY_RESULT_WITH_RSSI                 = 0x22
+	HCI_EVT_READ_REMOTE_EXTENDED_FEATURES_COMPLETE   = 0x23
+	HCI_EVT_SYNCHRONOUS_CONNECTION_COMPLETE          = 0x2C
+	HCI_EVT_SYNCHRONOUS_CONNECTION_CHANGED           = 0x2D
+	HCI_EVT_EXTENDED_INQUIRY_RESULT                  = 0x2F
+	HCI_EVT_ENCRYPTION_KEY_REFRESH_COMPLETE          = 0x30
+	HCI_EVT_IO_CAPABILITY_REQUEST                    = 0x31
+	HCI_EVT_IO_CAPABILITY_RESPONSE                   = 0x32
+	HCI_EVT_USER_CONFIRMATION_REQUEST                = 0x33
+	HCI_EVT_USER_PASSKEY_REQUEST                     = 0x34
+	HCI_EVT_REMOTE_OOB_DATA_REQUEST                  = 0x35
+	HCI_EVT_SIMPLE_PAIRING_COMPLETE                  = 0x36
+	HCI_EVT_USER_PASSKEY_NOTIFICATION                = 0x3B
+	HCI_EVT_LE_META_EVENT                            = 0x3E
+	HCI_EVT_ENCRYPTION_CHANGE_V2                     = 0x59
 )
 
 const (
@@ -15,7 +47,24 @@ const (
 
 // HCI_EVT_LE_META_EVENT子类型
 const (
-	LE_ENHANCED_CONNECTION_COMPLETE_EVENT = 0x0A
+	LE_CONNECTION_COMPLETE_EVENT                 = 0x01
+	LE_ADVERTISING_REPORT_EVENT                  = 0x02
+	LE_CONNECTION_UPDATE_COMPLETE_EVENT          = 0x03
+	LE_READ_REMOTE_FEATURES_COMPLETE_EVENT       = 0x04
+	LE_LONG_TERM_KEY_REQUEST_EVENT               = 0x05
+	LE_REMOTE_CONNECTION_PARAMETER_REQUEST_EVENT = 0x06
+	LE_DATA_LENGTH_CHANGE_EVENT                  = 0x07
+	LE_ENHANCED_CONNECTION_COMPLETE_EVENT        = 0x0A
+	LE_DIRECTED_ADVERTISING_REPORT_EVENT         = 0x0B
+	LE_PHY_UPDATE_COMPLETE_EVENT                 = 0x0C
+	LE_EXTENDED_ADVERTISING_REPORT_EVENT         = 0x0D
+	LE_SCAN_TIMEOUT_EVENT                        = 0x11
+	LE_ADVERTISING_SET_TERMINATED_EVENT          = 0x12
+	LE_CIS_ESTABLISHED_EVENT                     = 0x19
+	LE_CREATE_BIG_COMPLETE_EVENT                 = 0x1B
+	LE_TERMINATE_BIG_COMPLETE_EVENT              = 0x1C
+	LE_BIG_SYNC_ESTABLISHED_EVENT                = 0x1D
+	LE_BIG_SYNC_LOST_EVENT                       = 0x1E
 )
 
 type HciEvtPktParseResult struct {
@@ -29,8 +78,121 @@ type HciEvtPktParser func(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf
 // 二维parser map
 // 没有二级的则使用-1做索引
 var HciEvtPktParserMap map[uint8]map[int]HciEvtPktParser = map[uint8]map[int]HciEvtPktParser{
+	HCI_EVT_INQUIRY_COMPLETE: {
+		NO_SUB_EVENT: InquiryCompleteEventParser,
+	},
+	HCI_EVT_INQUIRY_RESULT: {
+		NO_SUB_EVENT: InquiryResultEventParser,
+	},
+	HCI_EVT_DISCONNECTION_COMPLETE: {
+		NO_SUB_EVENT: DisconnectionCompleteEventParser,
+	},
+	HCI_EVT_REMOTE_NAME_REQUEST_COMPLETE: {
+		NO_SUB_EVENT: RemoteNameRequestCompleteEventParser,
+	},
+	HCI_EVT_INQUIRY_RESULT_WITH_RSSI: {
+		NO_SUB_EVENT: InquiryResultWithRssiEventParser,
+	},
+	HCI_EVT_EXTENDED_INQUIRY_RESULT: {
+		NO_SUB_EVENT: ExtendedInquiryResultEventParser,
+	},
+	HCI_EVT_CONNECTION_COMPLETE: {
+		NO_SUB_EVENT: ConnectionCompleteEventParser,
+	},
+	HCI_EVT_CONNECTION_REQUEST: {
+		NO_SUB_EVENT: ConnectionRequestEventParser,
+	},
+	HCI_EVT_READ_REMOTE_SUPPORTED_FEATURES_COMPLETE: {
+		NO_SUB_EVENT: ReadRemoteSupportedFeaturesCompleteEventParser,
+	},
+	HCI_EVT_READ_REMOTE_VERSION_INFORMATION_COMPLETE: {
+		NO_SUB_EVENT: ReadRemoteVersionInformationCompleteEventParser,
+	},
+	HCI_EVT_READ_REMOTE_EXTENDED_FEATURES_COMPLETE: {
+		NO_SUB_EVENT: ReadRemoteExtendedFeaturesCompleteEventParser,
+	},
+	HCI_EVT_ROLE_CHANGE: {
+		NO_SUB_EVENT: RoleChangeEventParser,
+	},
+	HCI_EVT_MODE_CHANGE: {
+		NO_SUB_EVENT: ModeChangeEventParser,
+	},
+	HCI_EVT_AUTHENTICATION_COMPLETE: {
+		NO_SUB_EVENT: AuthenticationCompleteEventParser,
+	},
+	HCI_EVT_PIN_CODE_REQUEST: {
+		NO_SUB_EVENT: BdAddrEventParser,
+	},
+	HCI_EVT_LINK_KEY_REQUEST: {
+		NO_SUB_EVENT: BdAddrEventParser,
+	},
+	HCI_EVT_LINK_KEY_NOTIFICATION: {
+		NO_SUB_EVENT: LinkKeyNotificationEventParser,
+	},
+	HCI_EVT_IO_CAPABILITY_REQUEST: {
+		NO_SUB_EVENT: BdAddrEventParser,
+	},
+	HCI_EVT_IO_CAPABILITY_RESPONSE: {
+		NO_SUB_EVENT: IoCapabilityResponseEventParser,
+	},
+	HCI_EVT_USER_CONFIRMATION_REQUEST: {
+		NO_SUB_EVENT: BdAddrNumericEventParser,
+	},
+	HCI_EVT_USER_PASSKEY_REQUEST: {
+		NO_SUB_EVENT: BdAddrEventParser,
+	},
+	HCI_EVT_REMOTE_OOB_DATA_REQUEST: {
+		NO_SUB_EVENT: BdAddrEventParser,
+	},
+	HCI_EVT_SIMPLE_PAIRING_COMPLETE: {
+		NO_SUB_EVENT: SimplePairingCompleteEventParser,
+	},
+	HCI_EVT_USER_PASSKEY_NOTIFICATION: {
+		NO_SUB_EVENT: BdAddrNumericEventParser,
+	},
+	HCI_EVT_ENCRYPTION_CHANGE: {
+		NO_SUB_EVENT: EncryptionChangeEventParser,
+	},
+	HCI_EVT_ENCRYPTION_CHANGE_V2: {
+		NO_SUB_EVENT: EncryptionChangeEventParser,
+	},
+	HCI_EVT_ENCRYPTION_KEY_REFRESH_COMPLETE: {
+		NO_SUB_EVENT: EncryptionKeyRefreshCompleteEventParser,
+	},
+	HCI_EVT_COMMAND_COMPLETE: {
+		NO_SUB_EVENT: CommandCompleteEventParser,
+	},
+	HCI_EVT_COMMAND_STATUS: {
+		NO_SUB_EVENT: CommandStatusEventParser,
+	},
+	HCI_EVT_NUMBER_OF_COMPLETED_PACKETS: {
+		NO_SUB_EVENT: NumberOfCompletedPacketsEventParser,
+	},
+	HCI_EVT_SYNCHRONOUS_CONNECTION_COMPLETE: {
+		NO_SUB_EVENT: SynchronousConnectionCompleteEventParser,
+	},
+	HCI_EVT_SYNCHRONOUS_CONNECTION_CHANGED: {
+		NO_SUB_EVENT: SynchronousConnectionChangedEventParser,
+	},
 	HCI_EVT_LE_META_EVENT: {
-		LE_ENHANCED_CONNECTION_COMPLETE_EVENT: LeEnhancedConnectionCompleteEventParser,
+		LE_CONNECTION_COMPLETE_EVENT:                 LeConnectionCompleteEventParser,
+		LE_ADVERTISING_REPORT_EVENT:                  LeAdvertisingReportEventParser,
+		LE_CONNECTION_UPDATE_COMPLETE_EVENT:          LeConnectionUpdateCompleteEventParser,
+		LE_READ_REMOTE_FEATURES_COMPLETE_EVENT:       LeReadRemoteFeaturesCompleteEventParser,
+		LE_LONG_TERM_KEY_REQUEST_EVENT:               LeLongTermKeyRequestEventParser,
+		LE_REMOTE_CONNECTION_PARAMETER_REQUEST_EVENT: LeRemoteConnectionParameterRequestEventParser,
+		LE_DATA_LENGTH_CHANGE_EVENT:                  LeDataLengthChangeEventParser,
+		LE_ENHANCED_CONNECTION_COMPLETE_EVENT:        LeEnhancedConnectionCompleteEventParser,
+		LE_DIRECTED_ADVERTISING_REPORT_EVENT:         LeDirectedAdvertisingReportEventParser,
+		LE_PHY_UPDATE_COMPLETE_EVENT:                 LePhyUpdateCompleteEventParser,
+		LE_EXTENDED_ADVERTISING_REPORT_EVENT:         LeExtendedAdvertisingReportEventParser,
+		LE_SCAN_TIMEOUT_EVENT:                        LeScanTimeoutEventParser,
+		LE_ADVERTISING_SET_TERMINATED_EVENT:          LeAdvertisingSetTerminatedEventParser,
+		LE_CIS_ESTABLISHED_EVENT:                     LeCisEstablishedEventParser,
+		LE_CREATE_BIG_COMPLETE_EVENT:                 LeCreateBigCompleteEventParser,
+		LE_TERMINATE_BIG_COMPLETE_EVENT:              LeBigTerminatedEventParser,
+		LE_BIG_SYNC_ESTABLISHED_EVENT:                LeBigSyncEstablishedEventParser,
+		LE_BIG_SYNC_LOST_EVENT:                       LeBigTerminatedEventParser,
 	},
 }
 
@@ -53,9 +215,59 @@ func HciEvtPktDefaultParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadB
 	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
 }
 
+// LE连接中本端的角色
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.1 LE Connection Complete Event
+const (
+	LE_ROLE_MASTER = 0x00
+	LE_ROLE_SLAVE  = 0x01
+)
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.1 LE Connection Complete Event
+type LeConnectionCompleteEvent struct {
+	SubEventCode        uint8
+	Status              HciStatus
+	ConnectionHandle    uint16
+	Role                uint8 // LE_ROLE_XXX
+	PeerAddressType     uint8
+	PeerAddress         [6]uint8
+	ConnInterval        uint16
+	ConnLatency         uint16
+	SupervisionTimeout  uint16
+	MasterClockAccuracy uint8
+}
+
+// 不支持LL Privacy的controller使用该事件上报连接结果
+func LeConnectionCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeConnectionCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 19 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.Role = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.Role)
+	pkt.PeerAddressType = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.PeerAddressType)
+	pkt.PeerAddress = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.PeerAddress)
+	pkt.ConnInterval = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnInterval)
+	pkt.ConnLatency = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnLatency)
+	pkt.SupervisionTimeout = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SupervisionTimeout)
+	pkt.MasterClockAccuracy = hciEvtPktPayloadBuf[pktIndex]
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
 type LeEnhancedConnectionCompleteEvent struct {
 	SubEventCode                  uint8
-	Status                        uint8
+	Status                        HciStatus
 	ConnectionHandle              uint16
 	Role                          uint8
 	PeerAddressType               uint8
@@ -74,7 +286,7 @@ func LeEnhancedConnectionCompleteEventParser(EventCode uint8, SubEventCode int,
 	pkt := LeEnhancedConnectionCompleteEvent{}
 	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
 	pktIndex += binary.Size(pkt.SubEventCode)
-	pkt.Status = hciEvtPktPayloadBuf[pktIndex]
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
 	pktIndex += binary.Size(pkt.Status)
 	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
 	pktIndex += binary.Size(pkt.ConnectionHandle)
@@ -106,3 +318,147 @@ func LeEnhancedConnectionCompleteEventParser(EventCode uint8, SubEventCode int,
 	pkt.MasterClockAccuracy = hciEvtPktPayloadBuf[pktIndex]
 	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
 }
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.3 Connection Complete Event
+type ConnectionCompleteEvent struct {
+	Status            HciStatus
+	ConnectionHandle  uint16
+	BdAddr            [6]byte
+	LinkType          uint8 // LINK_TYPE_SCO/LINK_TYPE_ACL
+	EncryptionEnabled uint8
+}
+
+// BR/EDR ACL或SCO连接建立
+func ConnectionCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := ConnectionCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 11 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.BdAddr = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.BdAddr)
+	pkt.LinkType = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.LinkType)
+	pkt.EncryptionEnabled = hciEvtPktPayloadBuf[pktIndex]
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.5 Disconnection Complete Event
+type DisconnectionCompleteEvent struct {
+	Status           HciStatus
+	ConnectionHandle uint16
+	Reason           HciStatus
+}
+
+// ACL/SCO/eSCO连接断开，handle断开后可能被复用
+func DisconnectionCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := DisconnectionCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 4 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.Reason = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.14 Command Complete Event
+type CommandCompleteEvent struct {
+	NumHciCommandPackets uint8 // host还可以发送的命令数
+	CommandOpCode        uint16
+	OpCodeOgf            uint8
+	OpCodeOcf            uint16
+	Status               HciStatus // Return_Parameters第一个字节
+	ReturnParameters     []byte
+	ReturnParsedResult   HciCmdRetParamParseResult // 按CommandOpCode解析后的Return_Parameters
+}
+
+// 命令执行完成，CommandOpCode为0时只用于更新NumHciCommandPackets
+func CommandCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := CommandCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 3 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.NumHciCommandPackets = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumHciCommandPackets)
+	pkt.CommandOpCode = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pkt.OpCodeOgf, pkt.OpCodeOcf = HciOpCodeSplit(pkt.CommandOpCode)
+	pktIndex += binary.Size(pkt.CommandOpCode)
+	pkt.ReturnParameters = make([]byte, len(hciEvtPktPayloadBuf[pktIndex:]))
+	copy(pkt.ReturnParameters, hciEvtPktPayloadBuf[pktIndex:])
+	if len(pkt.ReturnParameters) > 0 {
+		pkt.Status = HciStatus(pkt.ReturnParameters[0])
+	}
+	if pkt.CommandOpCode != 0 {
+		pkt.ReturnParsedResult = HciCmdRetParamParse(pkt.OpCodeOgf, pkt.OpCodeOcf, pkt.ReturnParameters)
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.15 Command Status Event
+type CommandStatusEvent struct {
+	Status               HciStatus
+	NumHciCommandPackets uint8
+	CommandOpCode        uint16
+	OpCodeOgf            uint8
+	OpCodeOcf            uint16
+}
+
+// 命令已被controller接收，执行结果通过后续事件上报
+func CommandStatusEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := CommandStatusEvent{}
+	if len(hciEvtPktPayloadBuf) < 4 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.NumHciCommandPackets = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumHciCommandPackets)
+	pkt.CommandOpCode = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pkt.OpCodeOgf, pkt.OpCodeOcf = HciOpCodeSplit(pkt.CommandOpCode)
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+type CompletedPackets struct {
+	ConnectionHandle    uint16
+	NumCompletedPackets uint16 // 上次上报后该handle发送完成(或被flush)的包数
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.19 Number Of Completed Packets Event
+type NumberOfCompletedPacketsEvent struct {
+	NumHandles           uint8
+	CompletedPacketsList []CompletedPackets
+}
+
+// controller释放ACL/SCO/ISO数据缓冲区，host据此恢复发送credit
+// 各handle的Connection_Handle和HC_Num_Of_Completed_Packets交替排列
+func NumberOfCompletedPacketsEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := NumberOfCompletedPacketsEvent{}
+	if len(hciEvtPktPayloadBuf) < 1 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.NumHandles = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumHandles)
+	if len(hciEvtPktPayloadBuf[pktIndex:]) < int(pkt.NumHandles)*4 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for index := 0; index < int(pkt.NumHandles); index++ {
+		completed := CompletedPackets{}
+		completed.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:]) & 0x0fff
+		pktIndex += binary.Size(completed.ConnectionHandle)
+		completed.NumCompletedPackets = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(completed.NumCompletedPackets)
+		pkt.CompletedPacketsList = append(pkt.CompletedPacketsList, completed)
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
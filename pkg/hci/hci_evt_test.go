@@ -0,0 +1,37 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNumberOfCompletedPacketsEventParse(t *testing.T) {
+	testList := []struct {
+		name     string
+		buf      []byte
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"two handles, packet boundary flags masked",
+			[]byte{0x02, 0x40, 0x20, 0x03, 0x00, 0x41, 0x00, 0x01, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			NumberOfCompletedPacketsEvent{NumHandles: 2, CompletedPacketsList: []CompletedPackets{
+				{ConnectionHandle: 0x0040, NumCompletedPackets: 3},
+				{ConnectionHandle: 0x0041, NumCompletedPackets: 1},
+			}},
+		},
+		{"empty", []byte{}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{"second handle truncated", []byte{0x02, 0x40, 0x00, 0x03, 0x00, 0x41}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		parsed := HciEvtPktParse(HCI_EVT_NUMBER_OF_COMPLETED_PACKETS, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// 对端特性交换相关命令和事件处理，LMP/LE features按bit解析为特性名称
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// 常用的LE Features bit
+// BLUETOOTH CORE SPECIFICATION Version 5.0 | Vol 6, Part B 4.6 Feature support
+const (
+	LE_FEATURE_ENCRYPTION                  = 0
+	LE_FEATURE_CONN_PARAMS_REQUEST         = 1
+	LE_FEATURE_LE_PING                     = 4
+	LE_FEATURE_DATA_PACKET_LENGTH_EXT      = 5
+	LE_FEATURE_LL_PRIVACY                  = 6
+	LE_FEATURE_2M_PHY                      = 8
+	LE_FEATURE_CODED_PHY                   = 11
+	LE_FEATURE_EXTENDED_ADVERTISING        = 12
+	LE_FEATURE_CHANNEL_SELECTION_ALGO_2    = 14
+	LE_FEATURE_CONNECTED_ISO_STREAM_MASTER = 28
+	LE_FEATURE_CONNECTED_ISO_STREAM_SLAVE  = 29
+)
+
+// 常用的LMP Features bit，按page区分
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part C] 3.3 Feature Mask Definition
+const (
+	LMP_FEATURE_ENCRYPTION                    = 2  // page 0
+	LMP_FEATURE_ROLE_SWITCH                   = 5  // page 0
+	LMP_FEATURE_SNIFF_MODE                    = 7  // page 0
+	LMP_FEATURE_EDR_ACL_2M                    = 25 // page 0
+	LMP_FEATURE_EDR_ACL_3M                    = 26 // page 0
+	LMP_FEATURE_LE_SUPPORTED_CONTROLLER       = 38 // page 0
+	LMP_FEATURE_SSP_CONTROLLER                = 51 // page 0
+	LMP_FEATURE_EXTENDED_FEATURES             = 63 // page 0
+	LMP_FEATURE_SSP_HOST                      = 0  // page 1
+	LMP_FEATURE_LE_SUPPORTED_HOST             = 1  // page 1
+	LMP_FEATURE_SECURE_CONNECTIONS_HOST       = 3  // page 1
+	LMP_FEATURE_SECURE_CONNECTIONS_CONTROLLER = 8  // page 2
+	LMP_FEATURE_PING                          = 9  // page 2
+)
+
+// LMP Features page 0，按bit序号
+var LmpFeaturePage0StrList = []string{
+	"3 slot packets",
+	"5 slot packets",
+	"Encryption",
+	"Slot offset",
+	"Timing accuracy",
+	"Role switch",
+	"Hold mode",
+	"Sniff mode",
+	"Park state",
+	"Power control requests",
+	"Channel quality driven data rate (CQDDR)",
+	"SCO link",
+	"HV2 packets",
+	"HV3 packets",
+	"u-law log synchronous data",
+	"A-law log synchronous data",
+	"CVSD synchronous data",
+	"Paging parameter negotiation",
+	"Power control",
+	"Transparent synchronous data",
+	"Flow control lag (least significant bit)",
+	"Flow control lag (middle bit)",
+	"Flow control lag (most significant bit)",
+	"Broadcast Encryption",
+	"",
+	"Enhanced Data Rate ACL 2 Mb/s mode",
+	"Enhanced Data Rate ACL 3 Mb/s mode",
+	"Enhanced inquiry scan",
+	"Interlaced inquiry scan",
+	"Interlaced page scan",
+	"RSSI with inquiry results",
+	"Extended SCO link (EV3 packets)",
+	"EV4 packets",
+	"EV5 packets",
+	"",
+	"AFH capable slave",
+	"AFH classification slave",
+	"BR/EDR Not Supported",
+	"LE Supported (Controller)",
+	"3-slot Enhanced Data Rate ACL packets",
+	"5-slot Enhanced Data Rate ACL packets",
+	"Sniff subrating",
+	"Pause encryption",
+	"AFH capable master",
+	"AFH classification master",
+	"Enhanced Data Rate eSCO 2 Mb/s mode",
+	"Enhanced Data Rate eSCO 3 Mb/s mode",
+	"3-slot Enhanced Data Rate eSCO packets",
+	"Extended Inquiry Response",
+	"Simultaneous LE and BR/EDR to Same Device Capable (Controller)",
+	"",
+	"Secure Simple Pairing (Controller Support)",
+	"Encapsulated PDU",
+	"Erroneous Data Reporting",
+	"Non-flushable Packet Boundary Flag",
+	"",
+	"Link Supervision Timeout Changed Event",
+	"Variable Inquiry TX Power Level",
+	"Enhanced Power Control",
+	"", "", "", "",
+	"Extended features",
+}
+
+// LMP Features page 1，host支持的特性
+var LmpFeaturePage1StrList = []string{
+	"Secure Simple Pairing (Host Support)",
+	"LE Supported (Host)",
+	"Simultaneous LE and BR/EDR to Same Device Capable (Host)",
+	"Secure Connections (Host Support)",
+}
+
+// LMP Features page 2
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part C] 3.3 Feature Mask Definition
+var LmpFeaturePage2StrList = []string{
+	"Connectionless Slave Broadcast - Master Operation",
+	"Connectionless Slave Broadcast - Slave Operation",
+	"Synchronization Train",
+	"Synchronization Scan",
+	"Inquiry Response Notification Event",
+	"Generalized interlaced scan",
+	"Coarse Clock Adjustment",
+	"",
+	"Secure Connections (Controller Support)",
+	"Ping",
+	"Slot Availability Mask",
+	"Train nudging",
+}
+
+var LmpFeaturePageStrListMap = map[int][]string{
+	0: LmpFeaturePage0StrList,
+	1: LmpFeaturePage1StrList,
+	2: LmpFeaturePage2StrList,
+}
+
+func LmpFeatureList(pageNumber uint8, lmpFeatures uint64) []string {
+	return bitNameList(lmpFeatures, LmpFeaturePageStrListMap[int(pageNumber)])
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.22 Read Remote Extended Features Command
+type HciReadRemoteExtendedFeatures struct {
+	ConnectionHandle uint16
+	PageNumber       uint8
+}
+
+func HciReadRemoteExtendedFeaturesParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 3 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciReadRemoteExtendedFeatures{ConnectionHandle: binary.LittleEndian.Uint16(hciCmdPktPayloadBuf) & 0x0fff, PageNumber: hciCmdPktPayloadBuf[2]}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.34 Read Remote Extended Features Complete Event
+type ReadRemoteExtendedFeaturesCompleteEvent struct {
+	Status              HciStatus
+	ConnectionHandle    uint16
+	PageNumber          uint8
+	MaximumPageNumber   uint8
+	ExtendedLmpFeatures uint64
+}
+
+func ReadRemoteExtendedFeaturesCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := ReadRemoteExtendedFeaturesCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 13 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.PageNumber = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.PageNumber)
+	pkt.MaximumPageNumber = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.MaximumPageNumber)
+	pkt.ExtendedLmpFeatures = binary.LittleEndian.Uint64(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.65.4 LE Read Remote Used Features Complete Event
+type LeReadRemoteFeaturesCompleteEvent struct {
+	SubEventCode     uint8
+	Status           HciStatus
+	ConnectionHandle uint16
+	LeFeatures       uint64
+}
+
+// 本端和对端都支持的LL特性，LE Read Remote Features命令触发，部分controller连接建立后自动上报
+func LeReadRemoteFeaturesCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeReadRemoteFeaturesCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 12 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.LeFeatures = binary.LittleEndian.Uint64(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
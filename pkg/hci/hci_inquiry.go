@@ -0,0 +1,229 @@
+// BR/EDR设备发现: Inquiry、Inquiry Result、Extended Inquiry Result和Remote Name Request处理
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// Inquiry LAP
+// Assigned Numbers | 2.2 Inquiry Access Codes
+const (
+	INQUIRY_LAP_GIAC = 0x9E8B33 // General Inquiry Access Code
+	INQUIRY_LAP_LIAC = 0x9E8B00 // Limited Inquiry Access Code
+)
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.1 Inquiry Command
+type HciInquiry struct {
+	Lap           uint32
+	InquiryLength uint8 // 单位1.28s
+	NumResponses  uint8 // 0表示不限制
+}
+
+func HciInquiryParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 5 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciInquiry{Lap: Uint24Parse(hciCmdPktPayloadBuf), InquiryLength: hciCmdPktPayloadBuf[3], NumResponses: hciCmdPktPayloadBuf[4]}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.2 Inquiry Cancel Command
+// 没有参数
+type HciInquiryCancel struct {
+}
+
+func HciInquiryCancelParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: HciInquiryCancel{}}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.19 Remote Name Request Command
+type HciRemoteNameRequest struct {
+	BdAddr                 [6]byte
+	PageScanRepetitionMode uint8
+	ClockOffset            uint16 // bit15为1时bit0-14有效
+}
+
+func HciRemoteNameRequestParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciRemoteNameRequest{}
+	if len(hciCmdPktPayloadBuf) < 10 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.BdAddr = BdAddrParse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.BdAddr)
+	pkt.PageScanRepetitionMode = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.PageScanRepetitionMode)
+	bufIndex += 1 // Reserved
+	pkt.ClockOffset = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.20 Remote Name Request Cancel Command
+type HciRemoteNameRequestCancel struct {
+	BdAddr [6]byte
+}
+
+func HciRemoteNameRequestCancelParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 6 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: HciRemoteNameRequestCancel{BdAddr: BdAddrParse(hciCmdPktPayloadBuf)}}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.20 Remote Name Request Cancel Command
+type HciRemoteNameRequestCancelRetParam struct {
+	Status HciStatus
+	BdAddr [6]byte
+}
+
+func HciRemoteNameRequestCancelRetParamParser(OpCodeOgf uint8, OpCodeOcf uint16, retParamBuf []byte) HciCmdRetParamParseResult {
+	if len(retParamBuf) < 7 {
+		return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciRemoteNameRequestCancelRetParam{Status: HciStatus(retParamBuf[0]), BdAddr: BdAddrParse(retParamBuf[1:])}
+	return HciCmdRetParamParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.1 Inquiry Complete Event
+type InquiryCompleteEvent struct {
+	Status HciStatus
+}
+
+func InquiryCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	if len(hciEvtPktPayloadBuf) < 1 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: InquiryCompleteEvent{Status: HciStatus(hciEvtPktPayloadBuf[0])}}
+}
+
+// 一个Inquiry响应
+type InquiryResponse struct {
+	BdAddr                 [6]byte
+	PageScanRepetitionMode uint8
+	ClassOfDevice          uint32
+	DeviceClass            DeviceClass
+	ClockOffset            uint16
+	Rssi                   int8 // 单位dBm，Inquiry Result没有RSSI
+}
+
+// BD_ADDR(6) + Page_Scan_Repetition_Mode(1) + Reserved + Class_of_Device(3) + Clock_Offset(2) [+ RSSI(1)]
+func inquiryResponseParse(buf []byte, reservedLen int, hasRssi bool) (InquiryResponse, int) {
+	response := InquiryResponse{}
+	bufIndex := 0
+	response.BdAddr = BdAddrParse(buf[bufIndex:])
+	bufIndex += binary.Size(response.BdAddr)
+	response.PageScanRepetitionMode = buf[bufIndex]
+	bufIndex += binary.Size(response.PageScanRepetitionMode)
+	bufIndex += reservedLen
+	response.ClassOfDevice = Uint24Parse(buf[bufIndex:])
+	response.DeviceClass = DeviceClassParse(response.ClassOfDevice)
+	bufIndex += 3
+	response.ClockOffset = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(response.ClockOffset)
+	if hasRssi {
+		response.Rssi = int8(buf[bufIndex])
+		bufIndex += binary.Size(response.Rssi)
+	}
+	return response, bufIndex
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.2 Inquiry Result Event
+type InquiryResultEvent struct {
+	NumResponses uint8
+	ResponseList []InquiryResponse
+}
+
+// 每个响应包含两个字节Reserved，多个响应按响应依次排列
+func InquiryResultEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := InquiryResultEvent{}
+	if len(hciEvtPktPayloadBuf) < 1 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.NumResponses = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumResponses)
+	if len(hciEvtPktPayloadBuf[pktIndex:]) < int(pkt.NumResponses)*14 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for index := 0; index < int(pkt.NumResponses); index++ {
+		response, responseLen := inquiryResponseParse(hciEvtPktPayloadBuf[pktIndex:], 2, false)
+		pktIndex += responseLen
+		pkt.ResponseList = append(pkt.ResponseList, response)
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.33 Inquiry Result with RSSI Event
+type InquiryResultWithRssiEvent struct {
+	NumResponses uint8
+	ResponseList []InquiryResponse
+}
+
+// 每个响应包含一个字节Reserved
+func InquiryResultWithRssiEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := InquiryResultWithRssiEvent{}
+	if len(hciEvtPktPayloadBuf) < 1 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.NumResponses = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumResponses)
+	if len(hciEvtPktPayloadBuf[pktIndex:]) < int(pkt.NumResponses)*14 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	for index := 0; index < int(pkt.NumResponses); index++ {
+		response, responseLen := inquiryResponseParse(hciEvtPktPayloadBuf[pktIndex:], 1, true)
+		pktIndex += responseLen
+		pkt.ResponseList = append(pkt.ResponseList, response)
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.38 Extended Inquiry Result Event
+type ExtendedInquiryResultEvent struct {
+	NumResponses uint8 // 固定为1
+	InquiryResponse
+	ExtendedInquiryResponse []byte
+	AdStructList            []AdStruct // EIR与广播数据格式相同，解析失败时为已经解析的部分
+}
+
+// EIR固定240字节，有效数据后面补0
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part C] 8 EXTENDED INQUIRY RESPONSE DATA FORMAT
+func ExtendedInquiryResultEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := ExtendedInquiryResultEvent{}
+	if len(hciEvtPktPayloadBuf) < 15 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.NumResponses = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.NumResponses)
+	response, responseLen := inquiryResponseParse(hciEvtPktPayloadBuf[pktIndex:], 1, true)
+	pkt.InquiryResponse = response
+	pktIndex += responseLen
+	pkt.ExtendedInquiryResponse = make([]byte, len(hciEvtPktPayloadBuf[pktIndex:]))
+	copy(pkt.ExtendedInquiryResponse, hciEvtPktPayloadBuf[pktIndex:])
+	pkt.AdStructList, _ = AdStructListParse(pkt.ExtendedInquiryResponse)
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.7 Remote Name Request Complete Event
+type RemoteNameRequestCompleteEvent struct {
+	Status     HciStatus
+	BdAddr     [6]byte
+	RemoteName string // UTF-8，不足248字节时以0结尾
+}
+
+func RemoteNameRequestCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := RemoteNameRequestCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 7 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.BdAddr = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.BdAddr)
+	pkt.RemoteName = nullTerminatedStringParse(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
@@ -0,0 +1,133 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInquiryCmdParse(t *testing.T) {
+	testList := []struct {
+		name     string
+		ocf      uint16
+		buf      []byte
+		wantCode int
+		want     interface{}
+	}{
+		{"inquiry", HCI_INQUIRY, []byte{0x33, 0x8B, 0x9E, 0x08, 0x00}, HCI_PKT_RET_CODE_OK, HciInquiry{Lap: INQUIRY_LAP_GIAC, InquiryLength: 8}},
+		{"inquiry too short", HCI_INQUIRY, []byte{0x33, 0x8B, 0x9E, 0x08}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"remote name request", HCI_REMOTE_NAME_REQUEST,
+			[]byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x34, 0x92},
+			HCI_PKT_RET_CODE_OK,
+			HciRemoteNameRequest{BdAddr: [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, PageScanRepetitionMode: 0x01, ClockOffset: 0x9234},
+		},
+		{"remote name request too short", HCI_REMOTE_NAME_REQUEST, []byte{0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{"remote name request cancel too short", HCI_REMOTE_NAME_REQUEST_CANCEL, []byte{0x66, 0x55, 0x44}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		parsed := HciCmdPktParse(HCI_CMD_OGF_LINK_CONTROL_CMD, test.ocf, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
+
+// Class of Device 0x5A020C: Phone/Smartphone，Networking、Capturing、Object Transfer、Telephony
+var inquiryTestResponse = InquiryResponse{
+	BdAddr:                 [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66},
+	PageScanRepetitionMode: 0x01,
+	ClassOfDevice:          0x5A020C,
+	DeviceClass:            DeviceClass{MajorServiceClass: 0x02D0, MajorDeviceClass: COD_MAJOR_PHONE, MinorDeviceClass: 0x03},
+	ClockOffset:            0x1234,
+}
+
+func TestInquiryEvtParse(t *testing.T) {
+	withRssi := inquiryTestResponse
+	withRssi.Rssi = -60
+	testList := []struct {
+		name      string
+		eventCode uint8
+		buf       []byte
+		wantCode  int
+		want      interface{}
+	}{
+		{
+			"inquiry result, two responses", HCI_EVT_INQUIRY_RESULT,
+			[]byte{
+				0x02,
+				0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x00, 0x0C, 0x02, 0x5A, 0x34, 0x12,
+				0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x00, 0x0C, 0x02, 0x5A, 0x34, 0x12,
+			},
+			HCI_PKT_RET_CODE_OK,
+			InquiryResultEvent{NumResponses: 2, ResponseList: []InquiryResponse{inquiryTestResponse, inquiryTestResponse}},
+		},
+		{
+			"inquiry result, second response missing", HCI_EVT_INQUIRY_RESULT,
+			[]byte{0x02, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x00, 0x0C, 0x02, 0x5A, 0x34, 0x12},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"inquiry result with rssi", HCI_EVT_INQUIRY_RESULT_WITH_RSSI,
+			[]byte{0x01, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x0C, 0x02, 0x5A, 0x34, 0x12, 0xC4},
+			HCI_PKT_RET_CODE_OK,
+			InquiryResultWithRssiEvent{NumResponses: 1, ResponseList: []InquiryResponse{withRssi}},
+		},
+		{
+			"extended inquiry result, name and uuid with padding", HCI_EVT_EXTENDED_INQUIRY_RESULT,
+			[]byte{
+				0x01, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x0C, 0x02, 0x5A, 0x34, 0x12, 0xC4,
+				0x03, 0x09, 'p', 'h', 0x03, 0x03, 0x1E, 0x11, 0x00, 0x00,
+			},
+			HCI_PKT_RET_CODE_OK,
+			ExtendedInquiryResultEvent{
+				NumResponses: 1, InquiryResponse: withRssi,
+				ExtendedInquiryResponse: []byte{0x03, 0x09, 'p', 'h', 0x03, 0x03, 0x1E, 0x11, 0x00, 0x00},
+				AdStructList: []AdStruct{
+					{Length: 3, AdType: AD_TYPE_COMPLETE_LOCAL_NAME, Data: []byte{'p', 'h'}, PayloadParsedResult: AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, AdType: AD_TYPE_COMPLETE_LOCAL_NAME, Ret: AdLocalName{Complete: true, Name: "ph"}}},
+					{Length: 3, AdType: AD_TYPE_COMPLETE_LIST_16BIT_SERVICE_UUID, Data: []byte{0x1E, 0x11}, PayloadParsedResult: AdStructParseResult{Code: HCI_PKT_RET_CODE_OK, AdType: AD_TYPE_COMPLETE_LIST_16BIT_SERVICE_UUID, Ret: AdServiceUuidList{Complete: true, UuidList: []string{"111E"}}}},
+				},
+			},
+		},
+		{
+			"extended inquiry result too short", HCI_EVT_EXTENDED_INQUIRY_RESULT,
+			[]byte{0x01, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 0x01, 0x00, 0x0C, 0x02, 0x5A, 0x34, 0x12},
+			HCI_PKT_RET_CODE_INVALID_LEN, nil,
+		},
+		{
+			"remote name request complete", HCI_EVT_REMOTE_NAME_REQUEST_COMPLETE,
+			[]byte{0x00, 0x66, 0x55, 0x44, 0x33, 0x22, 0x11, 'p', 'h', 'o', 'n', 'e', 0x00, 0x00},
+			HCI_PKT_RET_CODE_OK,
+			RemoteNameRequestCompleteEvent{BdAddr: [6]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, RemoteName: "phone"},
+		},
+		{"remote name request complete too short", HCI_EVT_REMOTE_NAME_REQUEST_COMPLETE, []byte{0x04, 0x66, 0x55}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+	}
+	for _, test := range testList {
+		parsed := HciEvtPktParse(test.eventCode, test.buf)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.want != nil && !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
+
+func TestDeviceClassString(t *testing.T) {
+	testList := []struct {
+		classOfDevice uint32
+		want          string
+	}{
+		{0x5A020C, "Phone/Smartphone [Networking, Capturing, Object Transfer, Telephony]"},
+		{0x240404, "Audio/Video/Wearable Headset Device [Rendering, Audio]"},
+	}
+	for _, test := range testList {
+		if got := DeviceClassParse(test.classOfDevice).String(); got != test.want {
+			t.Errorf("0x%06X: %q, want %q", test.classOfDevice, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,476 @@
+// LE Audio ISO(CIG/CIS/BIG)相关cmd/evt处理
+
+package hci
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.8.97 LE Set CIG Parameters Command
+type HciLeSetCigParameters struct {
+	CigId                   uint8
+	SduIntervalCToP         uint32 // 单位微秒
+	SduIntervalPToC         uint32
+	WorstCaseSca            uint8
+	Packing                 uint8
+	Framing                 uint8
+	MaxTransportLatencyCToP uint16 // 单位毫秒
+	MaxTransportLatencyPToC uint16
+	CisCount                uint8
+	CisParametersList       []CisParameters
+}
+
+type CisParameters struct {
+	CisId      uint8
+	MaxSduCToP uint16
+	MaxSduPToC uint16
+	PhyCToP    uint8
+	PhyPToC    uint8
+	RtnCToP    uint8
+	RtnPToC    uint8
+}
+
+func HciLeSetCigParametersParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeSetCigParameters{}
+	if len(hciCmdPktPayloadBuf) < 15 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.CigId = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.CigId)
+	pkt.SduIntervalCToP = Uint24Parse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += 3
+	pkt.SduIntervalPToC = Uint24Parse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += 3
+	pkt.WorstCaseSca = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.WorstCaseSca)
+	pkt.Packing = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Packing)
+	pkt.Framing = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Framing)
+	pkt.MaxTransportLatencyCToP = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.MaxTransportLatencyCToP)
+	pkt.MaxTransportLatencyPToC = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.MaxTransportLatencyPToC)
+	pkt.CisCount = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.CisCount)
+	for index := 0; index < int(pkt.CisCount); index++ {
+		cis := CisParameters{}
+		if len(hciCmdPktPayloadBuf[bufIndex:]) < binary.Size(cis) {
+			return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		cis.CisId = hciCmdPktPayloadBuf[bufIndex]
+		bufIndex += binary.Size(cis.CisId)
+		cis.MaxSduCToP = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+		bufIndex += binary.Size(cis.MaxSduCToP)
+		cis.MaxSduPToC = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+		bufIndex += binary.Size(cis.MaxSduPToC)
+		cis.PhyCToP = hciCmdPktPayloadBuf[bufIndex]
+		bufIndex += binary.Size(cis.PhyCToP)
+		cis.PhyPToC = hciCmdPktPayloadBuf[bufIndex]
+		bufIndex += binary.Size(cis.PhyPToC)
+		cis.RtnCToP = hciCmdPktPayloadBuf[bufIndex]
+		bufIndex += binary.Size(cis.RtnCToP)
+		cis.RtnPToC = hciCmdPktPayloadBuf[bufIndex]
+		bufIndex += binary.Size(cis.RtnPToC)
+		pkt.CisParametersList = append(pkt.CisParametersList, cis)
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.8.99 LE Create CIS Command
+type HciLeCreateCis struct {
+	CisCount          uint8
+	CisConnectionList []CisConnection
+}
+
+type CisConnection struct {
+	CisConnectionHandle uint16
+	AclConnectionHandle uint16
+}
+
+func HciLeCreateCisParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeCreateCis{}
+	if len(hciCmdPktPayloadBuf) < 1 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.CisCount = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.CisCount)
+	for index := 0; index < int(pkt.CisCount); index++ {
+		cis := CisConnection{}
+		if len(hciCmdPktPayloadBuf[bufIndex:]) < binary.Size(cis) {
+			return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		cis.CisConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+		bufIndex += binary.Size(cis.CisConnectionHandle)
+		cis.AclConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+		bufIndex += binary.Size(cis.AclConnectionHandle)
+		pkt.CisConnectionList = append(pkt.CisConnectionList, cis)
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.8.103 LE Create BIG Command
+type HciLeCreateBig struct {
+	BigHandle           uint8
+	AdvertisingHandle   uint8
+	NumBis              uint8
+	SduInterval         uint32 // 单位微秒
+	MaxSdu              uint16
+	MaxTransportLatency uint16 // 单位毫秒
+	Rtn                 uint8
+	Phy                 uint8
+	Packing             uint8
+	Framing             uint8
+	Encryption          uint8
+	BroadcastCode       [16]byte
+}
+
+func HciLeCreateBigParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeCreateBig{}
+	if len(hciCmdPktPayloadBuf) < 31 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.BigHandle = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.BigHandle)
+	pkt.AdvertisingHandle = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.AdvertisingHandle)
+	pkt.NumBis = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.NumBis)
+	pkt.SduInterval = Uint24Parse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += 3
+	pkt.MaxSdu = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.MaxSdu)
+	pkt.MaxTransportLatency = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.MaxTransportLatency)
+	pkt.Rtn = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Rtn)
+	pkt.Phy = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Phy)
+	pkt.Packing = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Packing)
+	pkt.Framing = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Framing)
+	pkt.Encryption = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Encryption)
+	copy(pkt.BroadcastCode[:], hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.8.106 LE BIG Create Sync Command
+type HciLeBigCreateSync struct {
+	BigHandle      uint8
+	SyncHandle     uint16
+	Encryption     uint8
+	BroadcastCode  [16]byte
+	Mse            uint8
+	BigSyncTimeout uint16 // 单位10ms
+	NumBis         uint8
+	BisList        []uint8 // BIS index
+}
+
+func HciLeBigCreateSyncParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLeBigCreateSync{}
+	if len(hciCmdPktPayloadBuf) < 24 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.BigHandle = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.BigHandle)
+	pkt.SyncHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.SyncHandle)
+	pkt.Encryption = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Encryption)
+	copy(pkt.BroadcastCode[:], hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += len(pkt.BroadcastCode)
+	pkt.Mse = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.Mse)
+	pkt.BigSyncTimeout = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.BigSyncTimeout)
+	pkt.NumBis = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.NumBis)
+	if len(hciCmdPktPayloadBuf[bufIndex:]) < int(pkt.NumBis) {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.BisList = make([]uint8, pkt.NumBis)
+	copy(pkt.BisList, hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.7.65.25 LE CIS Established Event
+type LeCisEstablishedEvent struct {
+	SubEventCode         uint8
+	Status               HciStatus
+	ConnectionHandle     uint16
+	CigSyncDelay         uint32 // 单位微秒
+	CisSyncDelay         uint32
+	TransportLatencyCToP uint32
+	TransportLatencyPToC uint32
+	PhyCToP              uint8
+	PhyPToC              uint8
+	Nse                  uint8
+	BnCToP               uint8
+	BnPToC               uint8
+	FtCToP               uint8
+	FtPToC               uint8
+	MaxPduCToP           uint16
+	MaxPduPToC           uint16
+	IsoInterval          uint16 // 单位1.25ms
+}
+
+func LeCisEstablishedEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeCisEstablishedEvent{}
+	if len(hciEvtPktPayloadBuf) < 29 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.CigSyncDelay = Uint24Parse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += 3
+	pkt.CisSyncDelay = Uint24Parse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += 3
+	pkt.TransportLatencyCToP = Uint24Parse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += 3
+	pkt.TransportLatencyPToC = Uint24Parse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += 3
+	pkt.PhyCToP = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.PhyCToP)
+	pkt.PhyPToC = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.PhyPToC)
+	pkt.Nse = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.Nse)
+	pkt.BnCToP = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.BnCToP)
+	pkt.BnPToC = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.BnPToC)
+	pkt.FtCToP = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.FtCToP)
+	pkt.FtPToC = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.FtPToC)
+	pkt.MaxPduCToP = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.MaxPduCToP)
+	pkt.MaxPduPToC = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.MaxPduPToC)
+	pkt.IsoInterval = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BIG建立后的公共参数(NSE ~ Connection_Handle[i])
+type BigParameters struct {
+	Nse                  uint8
+	Bn                   uint8
+	Pto                  uint8
+	Irc                  uint8
+	MaxPdu               uint16
+	IsoInterval          uint16 // 单位1.25ms
+	NumBis               uint8
+	ConnectionHandleList []uint16 // BIS连接handle
+}
+
+const bigParametersMinLen = 9
+
+func bigParametersParse(buf []byte) (BigParameters, bool) {
+	param := BigParameters{}
+	if len(buf) < bigParametersMinLen {
+		return param, false
+	}
+	bufIndex := 0
+	param.Nse = buf[bufIndex]
+	bufIndex += binary.Size(param.Nse)
+	param.Bn = buf[bufIndex]
+	bufIndex += binary.Size(param.Bn)
+	param.Pto = buf[bufIndex]
+	bufIndex += binary.Size(param.Pto)
+	param.Irc = buf[bufIndex]
+	bufIndex += binary.Size(param.Irc)
+	param.MaxPdu = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(param.MaxPdu)
+	param.IsoInterval = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(param.IsoInterval)
+	param.NumBis = buf[bufIndex]
+	bufIndex += binary.Size(param.NumBis)
+	if len(buf[bufIndex:]) < int(param.NumBis)*2 {
+		return param, false
+	}
+	for index := 0; index < int(param.NumBis); index++ {
+		param.ConnectionHandleList = append(param.ConnectionHandleList, binary.LittleEndian.Uint16(buf[bufIndex:]))
+		bufIndex += 2
+	}
+	return param, true
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.7.65.27 LE Create BIG Complete Event
+type LeCreateBigCompleteEvent struct {
+	SubEventCode        uint8
+	Status              HciStatus
+	BigHandle           uint8
+	BigSyncDelay        uint32 // 单位微秒
+	TransportLatencyBig uint32 // 单位微秒
+	Phy                 uint8
+	BigParameters
+}
+
+func LeCreateBigCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeCreateBigCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 3 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.BigHandle = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.BigHandle)
+	if len(hciEvtPktPayloadBuf[pktIndex:]) < 7+bigParametersMinLen {
+		// 失败时只有Status和BIG_Handle有效
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+	}
+	pkt.BigSyncDelay = Uint24Parse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += 3
+	pkt.TransportLatencyBig = Uint24Parse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += 3
+	pkt.Phy = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.Phy)
+	param, ok := bigParametersParse(hciEvtPktPayloadBuf[pktIndex:])
+	if !ok {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.BigParameters = param
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.7.65.29 LE BIG Sync Established Event
+type LeBigSyncEstablishedEvent struct {
+	SubEventCode        uint8
+	Status              HciStatus
+	BigHandle           uint8
+	TransportLatencyBig uint32 // 单位微秒
+	BigParameters
+}
+
+func LeBigSyncEstablishedEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeBigSyncEstablishedEvent{}
+	if len(hciEvtPktPayloadBuf) < 3 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.BigHandle = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.BigHandle)
+	if len(hciEvtPktPayloadBuf[pktIndex:]) < 3+bigParametersMinLen {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+	}
+	pkt.TransportLatencyBig = Uint24Parse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += 3
+	param, ok := bigParametersParse(hciEvtPktPayloadBuf[pktIndex:])
+	if !ok {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.BigParameters = param
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// LE Terminate BIG Complete/LE BIG Sync Lost，BIG结束，Reason为结束原因
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 4, Part E 7.7.65.28 LE Terminate BIG Complete Event / 7.7.65.30 LE BIG Sync Lost Event
+type LeBigTerminatedEvent struct {
+	SubEventCode uint8
+	BigHandle    uint8
+	Reason       HciStatus
+}
+
+func LeBigTerminatedEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LeBigTerminatedEvent{}
+	if len(hciEvtPktPayloadBuf) < 3 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SubEventCode = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.SubEventCode)
+	pkt.BigHandle = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.BigHandle)
+	pkt.Reason = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// 重组后的ISO SDU
+type IsoSdu struct {
+	Handle               uint16
+	TsFlag               uint8
+	TimeStamp            uint32
+	PacketSequenceNumber uint16
+	IsoSduLength         uint16
+	PacketStatusFlag     uint8 // HCI_ISO_PKT_STATUS_XXX
+	Data                 []byte
+}
+
+type isoSduReassemblyKey struct {
+	handle   uint16
+	received bool
+}
+
+// ISO SDU重组，按handle和方向(CIS双向共用handle)分别重组
+type IsoSduReassembler struct {
+	pendingMap map[isoSduReassemblyKey]*IsoSdu
+}
+
+func NewIsoSduReassembler() *IsoSduReassembler {
+	return &IsoSduReassembler{pendingMap: map[isoSduReassemblyKey]*IsoSdu{}}
+}
+
+// 输入一个ISO数据包，SDU重组完成时返回true
+// 异常的分片(无首包的后续分片、首包未结束又收到首包、长度不符)返回error，已缓存的未完成SDU丢弃
+func (reassembler *IsoSduReassembler) Push(received bool, pkt HciIso) (IsoSdu, bool, error) {
+	key := isoSduReassemblyKey{handle: pkt.Handle, received: received}
+	pending, hasPending := reassembler.pendingMap[key]
+
+	var err error
+	switch pkt.PbFlag {
+	case HCI_ISO_PB_FLAG_FIRST_FRAGMENT, HCI_ISO_PB_FLAG_COMPLETE_SDU:
+		if hasPending {
+			err = fmt.Errorf("iso handle %#x: sdu %d incomplete, %d/%d bytes received", pkt.Handle, pending.PacketSequenceNumber, len(pending.Data), pending.IsoSduLength)
+			delete(reassembler.pendingMap, key)
+		}
+		sdu := IsoSdu{
+			Handle:               pkt.Handle,
+			TsFlag:               pkt.TsFlag,
+			TimeStamp:            pkt.TimeStamp,
+			PacketSequenceNumber: pkt.PacketSequenceNumber,
+			IsoSduLength:         pkt.IsoSduLength,
+			PacketStatusFlag:     pkt.PacketStatusFlag,
+			Data:                 append([]byte{}, pkt.Data...),
+		}
+		if pkt.PbFlag == HCI_ISO_PB_FLAG_COMPLETE_SDU {
+			if err == nil && len(sdu.Data) != int(sdu.IsoSduLength) {
+				err = fmt.Errorf("iso handle %#x: sdu %d length %d, expect %d", pkt.Handle, sdu.PacketSequenceNumber, len(sdu.Data), sdu.IsoSduLength)
+			}
+			return sdu, true, err
+		}
+		reassembler.pendingMap[key] = &sdu
+		return IsoSdu{}, false, err
+	}
+
+	if !hasPending {
+		return IsoSdu{}, false, fmt.Errorf("iso handle %#x: fragment without first fragment", pkt.Handle)
+	}
+	pending.Data = append(pending.Data, pkt.Data...)
+	if pkt.PbFlag == HCI_ISO_PB_FLAG_CONTINUATION_FRAGMENT {
+		return IsoSdu{}, false, nil
+	}
+	delete(reassembler.pendingMap, key)
+	if len(pending.Data) != int(pending.IsoSduLength) {
+		err = fmt.Errorf("iso handle %#x: sdu %d length %d, expect %d", pkt.Handle, pending.PacketSequenceNumber, len(pending.Data), pending.IsoSduLength)
+	}
+	return *pending, true, err
+}
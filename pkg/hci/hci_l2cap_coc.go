@@ -0,0 +1,48 @@
+// LE Credit Based Flow Control Mode/Enhanced Credit Based Flow Control Mode的K-frame解析
+// 一个SDU的第一个K-frame包含SDU Length，需要按信道跟踪，由analyzer调用L2capKFrameParse
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// 按SPSM解析credit based信道上重组后的SDU，可以注册自定义SPSM的解析器
+var L2capSpsmParserMap map[int]L2capPsmParser = map[int]L2capPsmParser{
+	L2CAP_PSM_EATT: AttPsmParser,
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 3.4 CONNECTION-ORIENTED CHANNELS IN LE CREDIT BASED FLOW CONTROL MODE
+type L2capKFrame struct {
+	ChannelId        uint16
+	SduLengthPresent bool // SDU的第一个K-frame
+	SduLength        uint16
+	Payload          []byte
+
+	// SDU最后一个K-frame上重组后的SDU按SPSM解析的结果，HciAclPktParseResult，由analyzer设置
+	SduParsedResult interface{}
+}
+
+func L2capKFrameParse(frame L2capBasicFrame, first bool) HciAclPktParseResult {
+	pkt := L2capKFrame{ChannelId: frame.ChannelId, Payload: frame.Payload}
+	if first {
+		if len(frame.Payload) < 2 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN, ChannelId: frame.ChannelId}
+		}
+		pkt.SduLengthPresent = true
+		pkt.SduLength = binary.LittleEndian.Uint16(frame.Payload)
+		pkt.Payload = frame.Payload[2:]
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, ChannelId: frame.ChannelId, Ret: pkt}
+}
+
+// credit based信道上的SDU按SPSM分发，未注册的SPSM返回L2capChannelFrame
+func L2capSduParse(channel L2capChannelInfo, sduBuf []byte) HciAclPktParseResult {
+	parser, ok := L2capSpsmParserMap[int(channel.Psm)]
+	if !ok {
+		parser = L2capPsmDefaultParser
+	}
+	parsed := parser(channel, sduBuf)
+	parsed.ChannelId = channel.ChannelId
+	return parsed
+}
@@ -0,0 +1,144 @@
+// L2CAP Enhanced Retransmission Mode和Streaming Mode帧解析
+// 控制字段格式(16bit增强控制字段/32bit扩展控制字段)和是否有FCS由信道配置决定，由analyzer调用L2capErtmFrameParse
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// I-frame/S-frame
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 3.3.2 Control field
+const (
+	L2CAP_FRAME_TYPE_I = 0
+	L2CAP_FRAME_TYPE_S = 1
+)
+
+// SAR，I-frame的分段标记
+const (
+	L2CAP_SAR_UNSEGMENTED  = 0x00
+	L2CAP_SAR_START        = 0x01 // 包含SDU Length
+	L2CAP_SAR_END          = 0x02
+	L2CAP_SAR_CONTINUATION = 0x03
+)
+
+// S-frame的Supervisory function
+const (
+	L2CAP_SUPERVISORY_RR   = 0x00 // Receiver Ready
+	L2CAP_SUPERVISORY_REJ  = 0x01 // Reject，请求从ReqSeq开始重传
+	L2CAP_SUPERVISORY_RNR  = 0x02 // Receiver Not Ready
+	L2CAP_SUPERVISORY_SREJ = 0x03 // Selective Reject，请求重传ReqSeq
+)
+
+var L2capSupervisoryStrMap = map[int]string{
+	L2CAP_SUPERVISORY_RR:   "RR",
+	L2CAP_SUPERVISORY_REJ:  "REJ",
+	L2CAP_SUPERVISORY_RNR:  "RNR",
+	L2CAP_SUPERVISORY_SREJ: "SREJ",
+}
+
+// TxSeq/ReqSeq取值范围
+const (
+	L2CAP_ENHANCED_SEQ_MODULO = 64
+	L2CAP_EXTENDED_SEQ_MODULO = 16384
+)
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 3.3 CONNECTION-ORIENTED CHANNELS IN RETRANSMISSION/FLOW CONTROL/STREAMING MODES
+type L2capErtmFrame struct {
+	ChannelId           uint16
+	ExtendedControl     bool
+	FrameType           uint8 // L2CAP_FRAME_TYPE_XXX
+	TxSeq               uint16
+	ReqSeq              uint16
+	Final               bool
+	Poll                bool   // 仅S-frame
+	Sar                 uint8  // L2CAP_SAR_XXX，仅I-frame
+	SupervisoryFunction uint8  // L2CAP_SUPERVISORY_XXX，仅S-frame
+	SduLength           uint16 // 仅L2CAP_SAR_START
+	Payload             []byte
+	FcsPresent          bool
+	Fcs                 uint16
+	FcsValid            bool
+
+	// ERTM下TxSeq已经发送过的I-frame，由analyzer设置
+	Retransmission bool
+	// 完整SDU(未分段或最后一个分段)按PSM解析的结果，HciAclPktParseResult
+	SduParsedResult interface{}
+}
+
+// frame为信道上的B-frame，FCS覆盖basic L2CAP header
+func L2capErtmFrameParse(frame L2capBasicFrame, extendedControl bool, fcsPresent bool) HciAclPktParseResult {
+	pkt := L2capErtmFrame{ChannelId: frame.ChannelId, ExtendedControl: extendedControl, FcsPresent: fcsPresent}
+	buf := frame.Payload
+	if fcsPresent {
+		if len(buf) < 2 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.Fcs = binary.LittleEndian.Uint16(buf[len(buf)-2:])
+		header := make([]byte, 4, 4+len(buf)-2)
+		binary.LittleEndian.PutUint16(header, frame.Length)
+		binary.LittleEndian.PutUint16(header[2:], frame.ChannelId)
+		pkt.FcsValid = L2capFcs(append(header, buf[:len(buf)-2]...)) == pkt.Fcs
+		buf = buf[:len(buf)-2]
+	}
+	pktIndex := 0
+	if extendedControl {
+		if len(buf) < 4 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		control := binary.LittleEndian.Uint32(buf)
+		pktIndex += binary.Size(control)
+		pkt.FrameType = uint8(control & 0x01)
+		pkt.Final = control&0x02 != 0
+		pkt.ReqSeq = uint16(control>>2) & 0x3FFF
+		if pkt.FrameType == L2CAP_FRAME_TYPE_I {
+			pkt.Sar = uint8(control>>16) & 0x03
+			pkt.TxSeq = uint16(control>>18) & 0x3FFF
+		} else {
+			pkt.SupervisoryFunction = uint8(control>>16) & 0x03
+			pkt.Poll = control&(1<<18) != 0
+		}
+	} else {
+		if len(buf) < 2 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		control := binary.LittleEndian.Uint16(buf)
+		pktIndex += binary.Size(control)
+		pkt.FrameType = uint8(control & 0x01)
+		pkt.Final = control&0x80 != 0
+		pkt.ReqSeq = (control >> 8) & 0x3F
+		if pkt.FrameType == L2CAP_FRAME_TYPE_I {
+			pkt.TxSeq = (control >> 1) & 0x3F
+			pkt.Sar = uint8(control>>14) & 0x03
+		} else {
+			pkt.SupervisoryFunction = uint8(control>>2) & 0x03
+			pkt.Poll = control&0x10 != 0
+		}
+	}
+	if pkt.FrameType == L2CAP_FRAME_TYPE_I && pkt.Sar == L2CAP_SAR_START {
+		if len(buf[pktIndex:]) < 2 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.SduLength = binary.LittleEndian.Uint16(buf[pktIndex:])
+		pktIndex += binary.Size(pkt.SduLength)
+	}
+	pkt.Payload = buf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, ChannelId: frame.ChannelId, Ret: pkt}
+}
+
+// FCS为CRC-16，生成多项式D^16 + D^15 + D^2 + 1，初值0，低位在前
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 3.3.5 Frame Check Sequence (FCS)
+func L2capFcs(buf []byte) uint16 {
+	var crc uint16
+	for _, octet := range buf {
+		crc ^= uint16(octet)
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x0001 != 0 {
+				crc = (crc >> 1) ^ 0xA001
+			} else {
+				crc >>= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,139 @@
+package hci
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestL2capFcs(t *testing.T) {
+	testList := []struct {
+		name string
+		buf  []byte
+		want uint16
+	}{
+		{"check value", []byte("123456789"), 0xBB3D},
+		// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 3.3.5 Frame Check Sequence (FCS)
+		{"spec i-frame example", []byte{0x0E, 0x00, 0x40, 0x00, 0x02, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09}, 0x6138},
+		{"spec s-frame example", []byte{0x04, 0x00, 0x40, 0x00, 0x01, 0x01}, 0x14D4},
+		{"empty", nil, 0x0000},
+	}
+	for _, test := range testList {
+		if got := L2capFcs(test.buf); got != test.want {
+			t.Errorf("%s: L2capFcs() = %#04x, want %#04x", test.name, got, test.want)
+		}
+	}
+}
+
+func TestL2capErtmFrameParse(t *testing.T) {
+	testList := []struct {
+		name            string
+		payload         []byte // B-frame payload，Length和CID(0x0040)由payload生成
+		extendedControl bool
+		fcsPresent      bool
+		wantCode        int
+		want            L2capErtmFrame // 不比较ChannelId/ExtendedControl/FcsPresent/Fcs
+	}{
+		{
+			name:       "enhanced i-frame unsegmented with fcs",
+			payload:    []byte{0x02, 0x00, 0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x38, 0x61},
+			fcsPresent: true,
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			want:       L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_I, TxSeq: 1, Sar: L2CAP_SAR_UNSEGMENTED, Payload: []byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09}, FcsValid: true},
+		},
+		{
+			name:       "enhanced s-frame rr with fcs",
+			payload:    []byte{0x01, 0x01, 0xD4, 0x14},
+			fcsPresent: true,
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			want:       L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_S, ReqSeq: 1, SupervisoryFunction: L2CAP_SUPERVISORY_RR, Payload: []byte{}, FcsValid: true},
+		},
+		{
+			name:       "enhanced s-frame with bad fcs",
+			payload:    []byte{0x01, 0x01, 0xD5, 0x14},
+			fcsPresent: true,
+			wantCode:   HCI_PKT_RET_CODE_OK,
+			want:       L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_S, ReqSeq: 1, SupervisoryFunction: L2CAP_SUPERVISORY_RR, Payload: []byte{}},
+		},
+		{
+			name:     "enhanced i-frame sar start",
+			payload:  []byte{0x04, 0x45, 0x10, 0x00, 0xAA, 0xBB},
+			wantCode: HCI_PKT_RET_CODE_OK,
+			want:     L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_I, TxSeq: 2, ReqSeq: 5, Sar: L2CAP_SAR_START, SduLength: 0x0010, Payload: []byte{0xAA, 0xBB}},
+		},
+		{
+			name:     "enhanced i-frame sar end",
+			payload:  []byte{0x86, 0x80, 0xCC},
+			wantCode: HCI_PKT_RET_CODE_OK,
+			want:     L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_I, TxSeq: 3, Final: true, Sar: L2CAP_SAR_END, Payload: []byte{0xCC}},
+		},
+		{
+			name:     "enhanced s-frame srej with poll",
+			payload:  []byte{0x1D, 0x07},
+			wantCode: HCI_PKT_RET_CODE_OK,
+			want:     L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_S, ReqSeq: 7, Poll: true, SupervisoryFunction: L2CAP_SUPERVISORY_SREJ, Payload: []byte{}},
+		},
+		{
+			name:            "extended i-frame sar start",
+			payload:         []byte{0x00, 0x04, 0xD1, 0x48, 0x03, 0x00, 0x01, 0x02, 0x03},
+			extendedControl: true,
+			wantCode:        HCI_PKT_RET_CODE_OK,
+			want:            L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_I, TxSeq: 0x1234, ReqSeq: 0x0100, Sar: L2CAP_SAR_START, SduLength: 3, Payload: []byte{0x01, 0x02, 0x03}},
+		},
+		{
+			name:            "extended i-frame sar end",
+			payload:         []byte{0x00, 0x00, 0x0A, 0x00, 0x04},
+			extendedControl: true,
+			wantCode:        HCI_PKT_RET_CODE_OK,
+			want:            L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_I, TxSeq: 2, Sar: L2CAP_SAR_END, Payload: []byte{0x04}},
+		},
+		{
+			name:            "extended s-frame rej final",
+			payload:         []byte{0xFF, 0xFF, 0x01, 0x00},
+			extendedControl: true,
+			wantCode:        HCI_PKT_RET_CODE_OK,
+			want:            L2capErtmFrame{FrameType: L2CAP_FRAME_TYPE_S, ReqSeq: 0x3FFF, Final: true, SupervisoryFunction: L2CAP_SUPERVISORY_REJ, Payload: []byte{}},
+		},
+		{
+			name:            "extended control truncated",
+			payload:         []byte{0x00, 0x04, 0xD1},
+			extendedControl: true,
+			wantCode:        HCI_PKT_RET_CODE_INVALID_LEN,
+		},
+		{
+			name:     "sar start without sdu length",
+			payload:  []byte{0x04, 0x45, 0x10},
+			wantCode: HCI_PKT_RET_CODE_INVALID_LEN,
+		},
+		{
+			name:       "fcs truncated",
+			payload:    []byte{0x01},
+			fcsPresent: true,
+			wantCode:   HCI_PKT_RET_CODE_INVALID_LEN,
+		},
+	}
+	for _, test := range testList {
+		frame := L2capBasicFrame{Length: uint16(len(test.payload)), ChannelId: 0x0040, Payload: test.payload}
+		parsed := L2capErtmFrameParse(frame, test.extendedControl, test.fcsPresent)
+		if parsed.Code != test.wantCode {
+			t.Errorf("%s: code %d, want %d", test.name, parsed.Code, test.wantCode)
+			continue
+		}
+		if test.wantCode != HCI_PKT_RET_CODE_OK {
+			continue
+		}
+		got, ok := parsed.Ret.(L2capErtmFrame)
+		if !ok {
+			t.Errorf("%s: Ret is %T", test.name, parsed.Ret)
+			continue
+		}
+		want := test.want
+		if got.ChannelId != 0x0040 || got.ExtendedControl != test.extendedControl || got.FcsPresent != test.fcsPresent {
+			t.Errorf("%s: ChannelId %#x ExtendedControl %v FcsPresent %v", test.name, got.ChannelId, got.ExtendedControl, got.FcsPresent)
+		}
+		if got.FrameType != want.FrameType || got.TxSeq != want.TxSeq || got.ReqSeq != want.ReqSeq || got.Final != want.Final ||
+			got.Poll != want.Poll || got.Sar != want.Sar || got.SupervisoryFunction != want.SupervisoryFunction ||
+			got.SduLength != want.SduLength || got.FcsValid != want.FcsValid || !bytes.Equal(got.Payload, want.Payload) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+// L2CAP动态信道按PSM分发到上层协议，只解析协议头
+// 动态信道的PSM需要跟踪信令信道上的连接过程才能确定，由analyzer调用L2capDynamicChannelParse
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// PSM/SPSM
+// Assigned Numbers | 5.2 Protocol and Service Multiplexer (PSM)
+const (
+	L2CAP_PSM_SDP              = 0x0001
+	L2CAP_PSM_RFCOMM           = 0x0003
+	L2CAP_PSM_TCS_BIN          = 0x0005
+	L2CAP_PSM_TCS_BIN_CORDLESS = 0x0007
+	L2CAP_PSM_BNEP             = 0x000F
+	L2CAP_PSM_HID_CONTROL      = 0x0011
+	L2CAP_PSM_HID_INTERRUPT    = 0x0013
+	L2CAP_PSM_UPNP             = 0x0015
+	L2CAP_PSM_AVCTP            = 0x0017
+	L2CAP_PSM_AVDTP            = 0x0019
+	L2CAP_PSM_AVCTP_BROWSING   = 0x001B
+	L2CAP_PSM_UDI_C_PLANE      = 0x001D
+	L2CAP_PSM_ATT              = 0x001F
+	L2CAP_PSM_3DSP             = 0x0021
+	L2CAP_PSM_LE_IPSP          = 0x0023
+	L2CAP_PSM_OTS              = 0x0025
+	L2CAP_PSM_EATT             = 0x0027
+)
+
+var L2capPsmStrMap = map[int]string{
+	L2CAP_PSM_SDP:              "SDP",
+	L2CAP_PSM_RFCOMM:           "RFCOMM",
+	L2CAP_PSM_TCS_BIN:          "TCS-BIN",
+	L2CAP_PSM_TCS_BIN_CORDLESS: "TCS-BIN-CORDLESS",
+	L2CAP_PSM_BNEP:             "BNEP",
+	L2CAP_PSM_HID_CONTROL:      "HID Control",
+	L2CAP_PSM_HID_INTERRUPT:    "HID Interrupt",
+	L2CAP_PSM_UPNP:             "UPnP",
+	L2CAP_PSM_AVCTP:            "AVCTP",
+	L2CAP_PSM_AVDTP:            "AVDTP",
+	L2CAP_PSM_AVCTP_BROWSING:   "AVCTP Browsing",
+	L2CAP_PSM_UDI_C_PLANE:      "UDI_C-Plane",
+	L2CAP_PSM_ATT:              "ATT",
+	L2CAP_PSM_3DSP:             "3DSP",
+	L2CAP_PSM_LE_IPSP:          "LE_PSM_IPSP",
+	L2CAP_PSM_OTS:              "OTS",
+	L2CAP_PSM_EATT:             "EATT",
+}
+
+// 动态信道信息，由analyzer根据信令信道跟踪得到
+type L2capChannelInfo struct {
+	Handle    uint16
+	Psm       uint16
+	ChannelId uint16 // L2CAP头中的CID
+	PsmIndex  int    // 信道建立时该handle上已打开的相同PSM信道数，AVDTP为0的是信令信道
+}
+
+// 按PSM解析动态信道上的数据，未注册的PSM返回L2capChannelFrame
+type L2capPsmParser func(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult
+
+var L2capPsmParserMap map[int]L2capPsmParser = map[int]L2capPsmParser{
+	L2CAP_PSM_SDP:            SdpPduParser,
+	L2CAP_PSM_RFCOMM:         RfcommFrameParser,
+	L2CAP_PSM_BNEP:           BnepPacketParser,
+	L2CAP_PSM_HID_CONTROL:    HidTransactionParser,
+	L2CAP_PSM_HID_INTERRUPT:  HidTransactionParser,
+	L2CAP_PSM_AVCTP:          AvctpPacketParser,
+	L2CAP_PSM_AVDTP:          AvdtpPacketParser,
+	L2CAP_PSM_AVCTP_BROWSING: AvctpPacketParser,
+	L2CAP_PSM_ATT:            AttPsmParser,
+}
+
+// 未知PSM或信道的原始数据
+type L2capChannelFrame struct {
+	Psm       uint16
+	ChannelId uint16
+	Payload   []byte
+}
+
+func L2capPsmDefaultParser(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capChannelFrame{Psm: channel.Psm, ChannelId: channel.ChannelId, Payload: l2capPayloadBuf}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// 动态信道上basic mode的B-frame payload按PSM分发
+func L2capDynamicChannelParse(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	parser, ok := L2capPsmParserMap[int(channel.Psm)]
+	if !ok {
+		parser = L2capPsmDefaultParser
+	}
+	parsed := parser(channel, l2capPayloadBuf)
+	parsed.ChannelId = channel.ChannelId
+	return parsed
+}
+
+// BR/EDR上的ATT信道(GATT over BR/EDR)
+func AttPsmParser(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	return AttPktParse(channel.ChannelId, l2capPayloadBuf)
+}
+
+// SDP PDU ID
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part B 4.2 PROTOCOL DATA UNIT FORMAT
+const (
+	SDP_ERROR_RESPONSE                    = 0x01
+	SDP_SERVICE_SEARCH_REQUEST            = 0x02
+	SDP_SERVICE_SEARCH_RESPONSE           = 0x03
+	SDP_SERVICE_ATTRIBUTE_REQUEST         = 0x04
+	SDP_SERVICE_ATTRIBUTE_RESPONSE        = 0x05
+	SDP_SERVICE_SEARCH_ATTRIBUTE_REQUEST  = 0x06
+	SDP_SERVICE_SEARCH_ATTRIBUTE_RESPONSE = 0x07
+)
+
+// SDP头为大端序
+type SdpPdu struct {
+	PduId           uint8 // SDP_XXX
+	TransactionId   uint16
+	ParameterLength uint16
+	Parameters      []byte
+}
+
+func SdpPduParser(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	pkt := SdpPdu{}
+	if len(l2capPayloadBuf) < 5 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.PduId = l2capPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.PduId)
+	pkt.TransactionId = binary.BigEndian.Uint16(l2capPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.TransactionId)
+	pkt.ParameterLength = binary.BigEndian.Uint16(l2capPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ParameterLength)
+	if len(l2capPayloadBuf[pktIndex:]) < int(pkt.ParameterLength) {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Parameters = l2capPayloadBuf[pktIndex : pktIndex+int(pkt.ParameterLength)]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// RFCOMM帧类型，Control字段去掉P/F bit
+// RFCOMM with TS 07.10 | 5.2.1 Frame Structure
+const (
+	RFCOMM_FRAME_SABM   = 0x2F
+	RFCOMM_FRAME_UA     = 0x63
+	RFCOMM_FRAME_DM     = 0x0F
+	RFCOMM_FRAME_DISC   = 0x43
+	RFCOMM_FRAME_UIH    = 0xEF
+	RFCOMM_CONTROL_PF   = 0x10
+	RFCOMM_DLCI_CONTROL = 0x00 // 多路复用控制信道
+)
+
+type RfcommFrame struct {
+	Dlci            uint8 // Server Channel << 1 | Direction
+	CommandResponse bool
+	FrameType       uint8 // RFCOMM_FRAME_XXX
+	PollFinal       bool
+	Length          uint16
+	CreditsPresent  bool // credit based flow control时UIH帧P/F为1携带credit
+	Credits         uint8
+	Information     []byte
+	Fcs             uint8
+}
+
+func RfcommFrameParser(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	pkt := RfcommFrame{}
+	if len(l2capPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	address := l2capPayloadBuf[pktIndex]
+	pktIndex += binary.Size(address)
+	pkt.Dlci = address >> 2
+	pkt.CommandResponse = address&0x02 != 0
+	control := l2capPayloadBuf[pktIndex]
+	pktIndex += binary.Size(control)
+	pkt.FrameType = control &^ RFCOMM_CONTROL_PF
+	pkt.PollFinal = control&RFCOMM_CONTROL_PF != 0
+	length := l2capPayloadBuf[pktIndex]
+	pktIndex += binary.Size(length)
+	pkt.Length = uint16(length >> 1)
+	// EA bit为0时长度为2字节
+	if length&0x01 == 0 {
+		pkt.Length |= uint16(l2capPayloadBuf[pktIndex]) << 7
+		pktIndex++
+	}
+	if pkt.FrameType == RFCOMM_FRAME_UIH && pkt.PollFinal && pkt.Dlci != RFCOMM_DLCI_CONTROL {
+		if pktIndex >= len(l2capPayloadBuf) {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.CreditsPresent, pkt.Credits = true, l2capPayloadBuf[pktIndex]
+		pktIndex++
+	}
+	// 最后一个字节为FCS
+	if len(l2capPayloadBuf[pktIndex:]) < int(pkt.Length)+1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Information = l2capPayloadBuf[pktIndex : pktIndex+int(pkt.Length)]
+	pkt.Fcs = l2capPayloadBuf[pktIndex+int(pkt.Length)]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BNEP Type
+// Bluetooth Network Encapsulation Protocol (BNEP) Specification | 2.4 BNEP Header Formats
+const (
+	BNEP_GENERAL_ETHERNET                = 0x00
+	BNEP_CONTROL                         = 0x01
+	BNEP_COMPRESSED_ETHERNET             = 0x02
+	BNEP_COMPRESSED_ETHERNET_SOURCE_ONLY = 0x03
+	BNEP_COMPRESSED_ETHERNET_DEST_ONLY   = 0x04
+	BNEP_EXTENSION_FLAG                  = 0x80
+)
+
+type BnepPacket struct {
+	Type        uint8 // BNEP_XXX
+	Extension   bool
+	ControlType uint8 // 仅BNEP_CONTROL
+	Payload     []byte
+}
+
+func BnepPacketParser(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	if len(l2capPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := BnepPacket{Type: l2capPayloadBuf[0] &^ BNEP_EXTENSION_FLAG, Extension: l2capPayloadBuf[0]&BNEP_EXTENSION_FLAG != 0, Payload: l2capPayloadBuf[1:]}
+	if pkt.Type == BNEP_CONTROL {
+		if len(pkt.Payload) < 1 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.ControlType = pkt.Payload[0]
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// HID Transaction Type
+// Human Interface Device Profile | 3.1.1 HID Transaction Header
+const (
+	HID_TRANSACTION_HANDSHAKE    = 0x0
+	HID_TRANSACTION_HID_CONTROL  = 0x1
+	HID_TRANSACTION_GET_REPORT   = 0x4
+	HID_TRANSACTION_SET_REPORT   = 0x5
+	HID_TRANSACTION_GET_PROTOCOL = 0x6
+	HID_TRANSACTION_SET_PROTOCOL = 0x7
+	HID_TRANSACTION_DATA         = 0xA
+)
+
+type HidTransaction struct {
+	Type      uint8 // HID_TRANSACTION_XXX
+	Parameter uint8
+	Payload   []byte
+}
+
+func HidTransactionParser(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	if len(l2capPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HidTransaction{Type: l2capPayloadBuf[0] >> 4, Parameter: l2capPayloadBuf[0] & 0x0F, Payload: l2capPayloadBuf[1:]}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// AVCTP/AVDTP Packet Type
+const (
+	AV_PACKET_TYPE_SINGLE   = 0x00
+	AV_PACKET_TYPE_START    = 0x01
+	AV_PACKET_TYPE_CONTINUE = 0x02
+	AV_PACKET_TYPE_END      = 0x03
+)
+
+// Audio/Video Control Transport Protocol Specification | 6.1 AVCTP Packet Structure
+type AvctpPacket struct {
+	TransactionLabel uint8
+	PacketType       uint8 // AV_PACKET_TYPE_XXX
+	Response         bool
+	InvalidPid       bool   // 对端不支持该Profile Identifier
+	NumberOfPackets  uint8  // 仅AV_PACKET_TYPE_START
+	Pid              uint16 // AV_PACKET_TYPE_CONTINUE/AV_PACKET_TYPE_END没有PID
+	Message          []byte
+}
+
+func AvctpPacketParser(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AvctpPacket{}
+	if len(l2capPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	header := l2capPayloadBuf[pktIndex]
+	pktIndex += binary.Size(header)
+	pkt.TransactionLabel = header >> 4
+	pkt.PacketType = (header >> 2) & 0x03
+	pkt.Response = header&0x02 != 0
+	pkt.InvalidPid = header&0x01 != 0
+	if pkt.PacketType == AV_PACKET_TYPE_START {
+		if len(l2capPayloadBuf[pktIndex:]) < 1 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.NumberOfPackets = l2capPayloadBuf[pktIndex]
+		pktIndex += binary.Size(pkt.NumberOfPackets)
+	}
+	if pkt.PacketType == AV_PACKET_TYPE_SINGLE || pkt.PacketType == AV_PACKET_TYPE_START {
+		if len(l2capPayloadBuf[pktIndex:]) < 2 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.Pid = binary.BigEndian.Uint16(l2capPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(pkt.Pid)
+	}
+	pkt.Message = l2capPayloadBuf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// AVDTP Message Type
+// Audio/Video Distribution Transport Protocol Specification | 8.4.2 Signaling message format
+const (
+	AVDTP_MESSAGE_TYPE_COMMAND         = 0x00
+	AVDTP_MESSAGE_TYPE_GENERAL_REJECT  = 0x01
+	AVDTP_MESSAGE_TYPE_RESPONSE_ACCEPT = 0x02
+	AVDTP_MESSAGE_TYPE_RESPONSE_REJECT = 0x03
+)
+
+// AVDTP Signal Identifier
+// Audio/Video Distribution Transport Protocol Specification | 8.5 Signaling message identifiers
+const (
+	AVDTP_DISCOVER             = 0x01
+	AVDTP_GET_CAPABILITIES     = 0x02
+	AVDTP_SET_CONFIGURATION    = 0x03
+	AVDTP_GET_CONFIGURATION    = 0x04
+	AVDTP_RECONFIGURE          = 0x05
+	AVDTP_OPEN                 = 0x06
+	AVDTP_START                = 0x07
+	AVDTP_CLOSE                = 0x08
+	AVDTP_SUSPEND              = 0x09
+	AVDTP_ABORT                = 0x0A
+	AVDTP_SECURITY_CONTROL     = 0x0B
+	AVDTP_GET_ALL_CAPABILITIES = 0x0C
+	AVDTP_DELAYREPORT          = 0x0D
+)
+
+var AvdtpSignalStrMap = map[int]string{
+	AVDTP_DISCOVER:             "DISCOVER",
+	AVDTP_GET_CAPABILITIES:     "GET_CAPABILITIES",
+	AVDTP_SET_CONFIGURATION:    "SET_CONFIGURATION",
+	AVDTP_GET_CONFIGURATION:    "GET_CONFIGURATION",
+	AVDTP_RECONFIGURE:          "RECONFIGURE",
+	AVDTP_OPEN:                 "OPEN",
+	AVDTP_START:                "START",
+	AVDTP_CLOSE:                "CLOSE",
+	AVDTP_SUSPEND:              "SUSPEND",
+	AVDTP_ABORT:                "ABORT",
+	AVDTP_SECURITY_CONTROL:     "SECURITY_CONTROL",
+	AVDTP_GET_ALL_CAPABILITIES: "GET_ALL_CAPABILITIES",
+	AVDTP_DELAYREPORT:          "DELAYREPORT",
+}
+
+// AVDTP信令信道
+type AvdtpSignal struct {
+	TransactionLabel      uint8
+	PacketType            uint8 // AV_PACKET_TYPE_XXX
+	MessageType           uint8 // AVDTP_MESSAGE_TYPE_XXX
+	NumberOfSignalPackets uint8 // 仅AV_PACKET_TYPE_START
+	SignalIdentifier      uint8 // AVDTP_XXX，AV_PACKET_TYPE_CONTINUE/AV_PACKET_TYPE_END没有
+	Parameters            []byte
+}
+
+// AVDTP媒体信道，RTP头为大端序
+// RFC 3550 5.1 RTP Fixed Header Fields
+type AvdtpMediaPacket struct {
+	Version        uint8
+	Marker         bool
+	PayloadType    uint8
+	SequenceNumber uint16
+	Timestamp      uint32
+	Ssrc           uint32
+	Payload        []byte
+}
+
+// 同一ACL链路上第一个AVDTP信道为信令信道，之后的为媒体信道
+func AvdtpPacketParser(channel L2capChannelInfo, l2capPayloadBuf []byte) HciAclPktParseResult {
+	if channel.PsmIndex == 0 {
+		return AvdtpSignalParser(l2capPayloadBuf)
+	}
+	return AvdtpMediaPacketParser(l2capPayloadBuf)
+}
+
+func AvdtpSignalParser(l2capPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AvdtpSignal{}
+	if len(l2capPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	header := l2capPayloadBuf[pktIndex]
+	pktIndex += binary.Size(header)
+	pkt.TransactionLabel = header >> 4
+	pkt.PacketType = (header >> 2) & 0x03
+	pkt.MessageType = header & 0x03
+	if pkt.PacketType == AV_PACKET_TYPE_START {
+		if len(l2capPayloadBuf[pktIndex:]) < 1 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.NumberOfSignalPackets = l2capPayloadBuf[pktIndex]
+		pktIndex += binary.Size(pkt.NumberOfSignalPackets)
+	}
+	if pkt.PacketType == AV_PACKET_TYPE_SINGLE || pkt.PacketType == AV_PACKET_TYPE_START {
+		if len(l2capPayloadBuf[pktIndex:]) < 1 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.SignalIdentifier = l2capPayloadBuf[pktIndex] & 0x3F
+		pktIndex += binary.Size(pkt.SignalIdentifier)
+	}
+	pkt.Parameters = l2capPayloadBuf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+func AvdtpMediaPacketParser(l2capPayloadBuf []byte) HciAclPktParseResult {
+	pkt := AvdtpMediaPacket{}
+	if len(l2capPayloadBuf) < 12 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Version = l2capPayloadBuf[pktIndex] >> 6
+	csrcCount := int(l2capPayloadBuf[pktIndex] & 0x0F)
+	pktIndex++
+	pkt.Marker = l2capPayloadBuf[pktIndex]&0x80 != 0
+	pkt.PayloadType = l2capPayloadBuf[pktIndex] & 0x7F
+	pktIndex++
+	pkt.SequenceNumber = binary.BigEndian.Uint16(l2capPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SequenceNumber)
+	pkt.Timestamp = binary.BigEndian.Uint32(l2capPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Timestamp)
+	pkt.Ssrc = binary.BigEndian.Uint32(l2capPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Ssrc)
+	// 跳过CSRC列表
+	pktIndex += csrcCount * 4
+	if pktIndex > len(l2capPayloadBuf) {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Payload = l2capPayloadBuf[pktIndex:]
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
@@ -0,0 +1,64 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestL2capDynamicChannelParse(t *testing.T) {
+	avdtpSignaling := L2capChannelInfo{Handle: 0x0040, Psm: L2CAP_PSM_AVDTP, ChannelId: 0x0041, PsmIndex: 0}
+	avdtpMedia := L2capChannelInfo{Handle: 0x0040, Psm: L2CAP_PSM_AVDTP, ChannelId: 0x0042, PsmIndex: 1}
+	testList := []struct {
+		name     string
+		channel  L2capChannelInfo
+		buf      []byte // B-frame payload
+		wantCode int
+		want     interface{}
+	}{
+		{
+			"sdp service search request", L2capChannelInfo{Psm: L2CAP_PSM_SDP, ChannelId: 0x0043},
+			[]byte{0x02, 0x00, 0x01, 0x00, 0x02, 0xAB, 0xCD},
+			HCI_PKT_RET_CODE_OK, SdpPdu{PduId: SDP_SERVICE_SEARCH_REQUEST, TransactionId: 0x0001, ParameterLength: 2, Parameters: []byte{0xAB, 0xCD}},
+		},
+		{"sdp parameters truncated", L2capChannelInfo{Psm: L2CAP_PSM_SDP, ChannelId: 0x0043}, []byte{0x02, 0x00, 0x01, 0x00, 0x03, 0xAB}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		// 第一个AVDTP信道为信令信道
+		{
+			"avdtp signaling channel", avdtpSignaling, []byte{0x30, 0x01},
+			HCI_PKT_RET_CODE_OK, AvdtpSignal{TransactionLabel: 3, PacketType: AV_PACKET_TYPE_SINGLE, MessageType: AVDTP_MESSAGE_TYPE_COMMAND, SignalIdentifier: AVDTP_DISCOVER, Parameters: []byte{}},
+		},
+		{
+			"avdtp start packet", avdtpSignaling, []byte{0x36, 0x02, 0x02, 0x04},
+			HCI_PKT_RET_CODE_OK, AvdtpSignal{TransactionLabel: 3, PacketType: AV_PACKET_TYPE_START, MessageType: AVDTP_MESSAGE_TYPE_RESPONSE_ACCEPT, NumberOfSignalPackets: 2, SignalIdentifier: AVDTP_GET_CAPABILITIES, Parameters: []byte{0x04}},
+		},
+		// 之后的AVDTP信道为媒体信道，同样的数据按RTP解析
+		{
+			"avdtp media channel", avdtpMedia,
+			[]byte{0x80, 0x60, 0x00, 0x01, 0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x01, 0xAA},
+			HCI_PKT_RET_CODE_OK, AvdtpMediaPacket{Version: 2, PayloadType: 0x60, SequenceNumber: 1, Timestamp: 0x10, Ssrc: 1, Payload: []byte{0xAA}},
+		},
+		{"avdtp media header truncated", avdtpMedia, []byte{0x30, 0x01}, HCI_PKT_RET_CODE_INVALID_LEN, nil},
+		{
+			"att over br/edr", L2capChannelInfo{Psm: L2CAP_PSM_ATT, ChannelId: 0x0044},
+			[]byte{0x12, 0x03, 0x00, 0xAA},
+			HCI_PKT_RET_CODE_OK, AttWriteRequest{OpCode: ATT_WRITE_REQUEST, Handle: 0x0003, Value: []byte{0xAA}},
+		},
+		{
+			"unknown psm", L2capChannelInfo{Psm: 0x1001, ChannelId: 0x0045},
+			[]byte{0x01, 0x02},
+			HCI_PKT_RET_CODE_OK, L2capChannelFrame{Psm: 0x1001, ChannelId: 0x0045, Payload: []byte{0x01, 0x02}},
+		},
+	}
+	for _, test := range testList {
+		parsed := L2capDynamicChannelParse(test.channel, test.buf)
+		if parsed.Code != test.wantCode || parsed.ChannelId != test.channel.ChannelId {
+			t.Errorf("%s: code %d channel %#04x, want %d %#04x", test.name, parsed.Code, parsed.ChannelId, test.wantCode, test.channel.ChannelId)
+			continue
+		}
+		if test.want == nil {
+			continue
+		}
+		if !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
@@ -0,0 +1,754 @@
+// L2CAP信令信道处理，BR/EDR信令信道(0x0001)和LE信令信道(0x0005)
+// BR/EDR信令信道一个C-frame可以包含多条命令，LE信令信道每个C-frame只包含一条命令
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// 信令命令码
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4 SIGNALING PACKET FORMATS
+const (
+	L2CAP_COMMAND_REJECT                       = 0x01
+	L2CAP_CONNECTION_REQUEST                   = 0x02
+	L2CAP_CONNECTION_RESPONSE                  = 0x03
+	L2CAP_CONFIGURATION_REQUEST                = 0x04
+	L2CAP_CONFIGURATION_RESPONSE               = 0x05
+	L2CAP_DISCONNECTION_REQUEST                = 0x06
+	L2CAP_DISCONNECTION_RESPONSE               = 0x07
+	L2CAP_ECHO_REQUEST                         = 0x08
+	L2CAP_ECHO_RESPONSE                        = 0x09
+	L2CAP_INFORMATION_REQUEST                  = 0x0A
+	L2CAP_INFORMATION_RESPONSE                 = 0x0B
+	L2CAP_CONNECTION_PARAMETER_UPDATE_REQUEST  = 0x12
+	L2CAP_CONNECTION_PARAMETER_UPDATE_RESPONSE = 0x13
+	L2CAP_LE_CREDIT_BASED_CONNECTION_REQUEST   = 0x14
+	L2CAP_LE_CREDIT_BASED_CONNECTION_RESPONSE  = 0x15
+	L2CAP_FLOW_CONTROL_CREDIT_IND              = 0x16
+	L2CAP_CREDIT_BASED_CONNECTION_REQUEST      = 0x17 // Enhanced Credit Based
+	L2CAP_CREDIT_BASED_CONNECTION_RESPONSE     = 0x18
+	L2CAP_CREDIT_BASED_RECONFIGURE_REQUEST     = 0x19
+	L2CAP_CREDIT_BASED_RECONFIGURE_RESPONSE    = 0x1A
+)
+
+var L2capSignalingCodeStrMap = map[int]string{
+	L2CAP_COMMAND_REJECT:                       "Command Reject",
+	L2CAP_CONNECTION_REQUEST:                   "Connection Request",
+	L2CAP_CONNECTION_RESPONSE:                  "Connection Response",
+	L2CAP_CONFIGURATION_REQUEST:                "Configuration Request",
+	L2CAP_CONFIGURATION_RESPONSE:               "Configuration Response",
+	L2CAP_DISCONNECTION_REQUEST:                "Disconnection Request",
+	L2CAP_DISCONNECTION_RESPONSE:               "Disconnection Response",
+	L2CAP_ECHO_REQUEST:                         "Echo Request",
+	L2CAP_ECHO_RESPONSE:                        "Echo Response",
+	L2CAP_INFORMATION_REQUEST:                  "Information Request",
+	L2CAP_INFORMATION_RESPONSE:                 "Information Response",
+	L2CAP_CONNECTION_PARAMETER_UPDATE_REQUEST:  "Connection Parameter Update Request",
+	L2CAP_CONNECTION_PARAMETER_UPDATE_RESPONSE: "Connection Parameter Update Response",
+	L2CAP_LE_CREDIT_BASED_CONNECTION_REQUEST:   "LE Credit Based Connection Request",
+	L2CAP_LE_CREDIT_BASED_CONNECTION_RESPONSE:  "LE Credit Based Connection Response",
+	L2CAP_FLOW_CONTROL_CREDIT_IND:              "Flow Control Credit Ind",
+	L2CAP_CREDIT_BASED_CONNECTION_REQUEST:      "Credit Based Connection Request",
+	L2CAP_CREDIT_BASED_CONNECTION_RESPONSE:     "Credit Based Connection Response",
+	L2CAP_CREDIT_BASED_RECONFIGURE_REQUEST:     "Credit Based Reconfigure Request",
+	L2CAP_CREDIT_BASED_RECONFIGURE_RESPONSE:    "Credit Based Reconfigure Response",
+}
+
+// Command Reject Reason
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.1 L2CAP_COMMAND_REJECT_RSP
+const (
+	L2CAP_REJECT_COMMAND_NOT_UNDERSTOOD = 0x0000
+	L2CAP_REJECT_SIGNALING_MTU_EXCEEDED = 0x0001
+	L2CAP_REJECT_INVALID_CID_IN_REQUEST = 0x0002
+)
+
+// Connection Response Result
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.3 L2CAP_CONNECTION_RSP
+const (
+	L2CAP_CONNECTION_SUCCESSFUL                = 0x0000
+	L2CAP_CONNECTION_PENDING                   = 0x0001
+	L2CAP_CONNECTION_REFUSED_PSM_NOT_SUPPORTED = 0x0002
+	L2CAP_CONNECTION_REFUSED_SECURITY_BLOCK    = 0x0003
+	L2CAP_CONNECTION_REFUSED_NO_RESOURCES      = 0x0004
+	L2CAP_CONNECTION_REFUSED_INVALID_SCID      = 0x0006
+	L2CAP_CONNECTION_REFUSED_SCID_ALLOCATED    = 0x0007
+)
+
+// Configuration Response Result
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.5 L2CAP_CONFIGURATION_RSP
+const (
+	L2CAP_CONFIGURATION_SUCCESS                 = 0x0000
+	L2CAP_CONFIGURATION_UNACCEPTABLE_PARAMETERS = 0x0001
+	L2CAP_CONFIGURATION_REJECTED                = 0x0002
+	L2CAP_CONFIGURATION_UNKNOWN_OPTIONS         = 0x0003
+	L2CAP_CONFIGURATION_PENDING                 = 0x0004
+	L2CAP_CONFIGURATION_FLOW_SPEC_REJECTED      = 0x0005
+)
+
+// Configuration Flags和Option Type
+const (
+	L2CAP_CONFIGURATION_FLAG_CONTINUATION = 0x0001 // Flags bit 0，后续还有Configuration Request/Response
+	L2CAP_CONFIGURATION_OPTION_HINT       = 0x80   // Type bit 7，对端不认识该选项时可以忽略
+	L2CAP_CONFIGURATION_OPTION_TYPE_MASK  = 0x7F
+)
+
+// Configuration Option Type
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 5 CONFIGURATION PARAMETER OPTIONS
+const (
+	L2CAP_OPTION_MTU                         = 0x01
+	L2CAP_OPTION_FLUSH_TIMEOUT               = 0x02
+	L2CAP_OPTION_QOS                         = 0x03
+	L2CAP_OPTION_RETRANSMISSION_FLOW_CONTROL = 0x04
+	L2CAP_OPTION_FCS                         = 0x05
+	L2CAP_OPTION_EXTENDED_FLOW_SPECIFICATION = 0x06
+	L2CAP_OPTION_EXTENDED_WINDOW_SIZE        = 0x07
+)
+
+// Retransmission and Flow Control option Mode
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 5.4 Retransmission and Flow Control option
+const (
+	L2CAP_MODE_BASIC                   = 0x00
+	L2CAP_MODE_RETRANSMISSION          = 0x01
+	L2CAP_MODE_FLOW_CONTROL            = 0x02
+	L2CAP_MODE_ENHANCED_RETRANSMISSION = 0x03
+	L2CAP_MODE_STREAMING               = 0x04
+)
+
+var L2capModeStrMap = map[int]string{
+	L2CAP_MODE_BASIC:                   "Basic",
+	L2CAP_MODE_RETRANSMISSION:          "Retransmission",
+	L2CAP_MODE_FLOW_CONTROL:            "Flow Control",
+	L2CAP_MODE_ENHANCED_RETRANSMISSION: "Enhanced Retransmission",
+	L2CAP_MODE_STREAMING:               "Streaming",
+}
+
+// FCS option
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 5.5 Frame Check Sequence (FCS) option
+const (
+	L2CAP_FCS_NONE   = 0x00
+	L2CAP_FCS_16_BIT = 0x01
+)
+
+// Information Request InfoType
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.10 L2CAP_INFORMATION_REQ
+const (
+	L2CAP_INFO_TYPE_CONNECTIONLESS_MTU = 0x0001
+	L2CAP_INFO_TYPE_EXTENDED_FEATURES  = 0x0002
+	L2CAP_INFO_TYPE_FIXED_CHANNELS     = 0x0003
+)
+
+// Information Response Result
+const (
+	L2CAP_INFO_SUCCESS       = 0x0000
+	L2CAP_INFO_NOT_SUPPORTED = 0x0001
+)
+
+// Extended Features Mask，按bit序号
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.12 Extended feature mask
+var L2capExtendedFeatureStrList = []string{
+	0:  "Flow control mode",
+	1:  "Retransmission mode",
+	2:  "Bi-directional QoS",
+	3:  "Enhanced Retransmission Mode",
+	4:  "Streaming Mode",
+	5:  "FCS Option",
+	6:  "Extended Flow Specification for BR/EDR",
+	7:  "Fixed Channels",
+	8:  "Extended Window Size",
+	9:  "Unicast Connectionless Data Reception",
+	10: "Enhanced Credit Based Flow Control Mode",
+}
+
+func L2capExtendedFeatureList(featureMask uint32) []string {
+	return bitNameList(uint64(featureMask), L2capExtendedFeatureStrList)
+}
+
+// LE Credit Based Connection Response/Credit Based Connection Response Result
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.23 L2CAP_LE_CREDIT_BASED_CONNECTION_RSP
+const (
+	L2CAP_LE_CONNECTION_SUCCESSFUL                          = 0x0000
+	L2CAP_LE_CONNECTION_REFUSED_SPSM_NOT_SUPPORTED          = 0x0002
+	L2CAP_LE_CONNECTION_REFUSED_NO_RESOURCES                = 0x0004
+	L2CAP_LE_CONNECTION_REFUSED_INSUFFICIENT_AUTHENTICATION = 0x0005
+	L2CAP_LE_CONNECTION_REFUSED_INSUFFICIENT_AUTHORIZATION  = 0x0006
+	L2CAP_LE_CONNECTION_REFUSED_INSUFFICIENT_KEY_SIZE       = 0x0007
+	L2CAP_LE_CONNECTION_REFUSED_INSUFFICIENT_ENCRYPTION     = 0x0008
+	L2CAP_LE_CONNECTION_REFUSED_INVALID_SCID                = 0x0009
+	L2CAP_LE_CONNECTION_REFUSED_SCID_ALLOCATED              = 0x000A
+	L2CAP_LE_CONNECTION_REFUSED_UNACCEPTABLE_PARAMETERS     = 0x000B
+	L2CAP_LE_CONNECTION_REFUSED_INVALID_PARAMETERS          = 0x000C // 仅Credit Based Connection Response
+	L2CAP_LE_CONNECTION_PENDING_NO_FURTHER_INFORMATION      = 0x000D
+	L2CAP_LE_CONNECTION_PENDING_AUTHENTICATION              = 0x000E
+	L2CAP_LE_CONNECTION_PENDING_AUTHORIZATION               = 0x000F
+)
+
+// Connection Parameter Update Response Result
+const (
+	L2CAP_CONNECTION_PARAMETERS_ACCEPTED = 0x0000
+	L2CAP_CONNECTION_PARAMETERS_REJECTED = 0x0001
+)
+
+type L2capSignalingParser func(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult
+
+// LE信令信道允许的命令
+var L2capLeSignalingParserMap map[int]L2capSignalingParser = map[int]L2capSignalingParser{
+	L2CAP_COMMAND_REJECT:                       L2capCommandRejectParser,
+	L2CAP_DISCONNECTION_REQUEST:                L2capDisconnectionParser,
+	L2CAP_DISCONNECTION_RESPONSE:               L2capDisconnectionParser,
+	L2CAP_CONNECTION_PARAMETER_UPDATE_REQUEST:  L2capConnectionParameterUpdateRequestParser,
+	L2CAP_CONNECTION_PARAMETER_UPDATE_RESPONSE: L2capConnectionParameterUpdateResponseParser,
+	L2CAP_LE_CREDIT_BASED_CONNECTION_REQUEST:   L2capLeCreditBasedConnectionRequestParser,
+	L2CAP_LE_CREDIT_BASED_CONNECTION_RESPONSE:  L2capLeCreditBasedConnectionResponseParser,
+	L2CAP_FLOW_CONTROL_CREDIT_IND:              L2capFlowControlCreditIndParser,
+	L2CAP_CREDIT_BASED_CONNECTION_REQUEST:      L2capCreditBasedConnectionRequestParser,
+	L2CAP_CREDIT_BASED_CONNECTION_RESPONSE:     L2capCreditBasedConnectionResponseParser,
+}
+
+// BR/EDR信令信道允许的命令
+var L2capBrEdrSignalingParserMap map[int]L2capSignalingParser = map[int]L2capSignalingParser{
+	L2CAP_COMMAND_REJECT:                   L2capCommandRejectParser,
+	L2CAP_CONNECTION_REQUEST:               L2capConnectionRequestParser,
+	L2CAP_CONNECTION_RESPONSE:              L2capConnectionResponseParser,
+	L2CAP_CONFIGURATION_REQUEST:            L2capConfigurationRequestParser,
+	L2CAP_CONFIGURATION_RESPONSE:           L2capConfigurationResponseParser,
+	L2CAP_DISCONNECTION_REQUEST:            L2capDisconnectionParser,
+	L2CAP_DISCONNECTION_RESPONSE:           L2capDisconnectionParser,
+	L2CAP_ECHO_REQUEST:                     L2capEchoParser,
+	L2CAP_ECHO_RESPONSE:                    L2capEchoParser,
+	L2CAP_INFORMATION_REQUEST:              L2capInformationRequestParser,
+	L2CAP_INFORMATION_RESPONSE:             L2capInformationResponseParser,
+	L2CAP_FLOW_CONTROL_CREDIT_IND:          L2capFlowControlCreditIndParser,
+	L2CAP_CREDIT_BASED_CONNECTION_REQUEST:  L2capCreditBasedConnectionRequestParser,
+	L2CAP_CREDIT_BASED_CONNECTION_RESPONSE: L2capCreditBasedConnectionResponseParser,
+}
+
+func L2capSignalingDefaultParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
+}
+
+// 解析一条信令命令，返回命令占用的长度
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4 SIGNALING PACKET FORMATS
+func l2capSignalingCommandParse(parserMap map[int]L2capSignalingParser, payloadBuf []byte) (HciAclPktParseResult, int) {
+	if len(payloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}, len(payloadBuf)
+	}
+	pktIndex := 0
+	Code := payloadBuf[pktIndex]
+	pktIndex += binary.Size(Code)
+	Identifier := payloadBuf[pktIndex]
+	pktIndex += binary.Size(Identifier)
+	Length := binary.LittleEndian.Uint16(payloadBuf[pktIndex:])
+	pktIndex += binary.Size(Length)
+	if len(payloadBuf[pktIndex:]) < int(Length) {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN, OpCode: Code}, len(payloadBuf)
+	}
+	parser, ok := parserMap[int(Code)]
+	if !ok {
+		parser = L2capSignalingDefaultParser
+	}
+	parsed := parser(Code, Identifier, payloadBuf[pktIndex:pktIndex+int(Length)])
+	parsed.OpCode = Code
+	return parsed, pktIndex + int(Length)
+}
+
+// LE信令信道每个C-frame只包含一条命令
+func L2capLeSignalingParse(ChannelId uint16, payloadBuf []byte) HciAclPktParseResult {
+	parsed, _ := l2capSignalingCommandParse(L2capLeSignalingParserMap, payloadBuf)
+	return parsed
+}
+
+// BR/EDR信令信道的一个C-frame中的所有命令，每条命令的OpCode为信令命令码
+type L2capSignalingCommandList []HciAclPktParseResult
+
+// BR/EDR信令信道Ret为L2capSignalingCommandList，OpCode为第一条命令的命令码
+func L2capBrEdrSignalingParse(ChannelId uint16, payloadBuf []byte) HciAclPktParseResult {
+	commandList := L2capSignalingCommandList{}
+	for pktIndex := 0; pktIndex < len(payloadBuf); {
+		parsed, length := l2capSignalingCommandParse(L2capBrEdrSignalingParserMap, payloadBuf[pktIndex:])
+		parsed.ChannelId = ChannelId
+		commandList = append(commandList, parsed)
+		pktIndex += length
+	}
+	if len(commandList) == 0 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, OpCode: commandList[0].OpCode, Ret: commandList}
+}
+
+// 信令信道上的所有命令，LE信令信道只有一条
+func L2capSignalingCommands(parsed HciAclPktParseResult) (L2capSignalingCommandList, bool) {
+	switch parsed.ChannelId {
+	case L2CAP_CID_SIGNALING:
+		commandList, ok := parsed.Ret.(L2capSignalingCommandList)
+		return commandList, ok
+	case L2CAP_CID_LE_SIGNALING:
+		return L2capSignalingCommandList{parsed}, true
+	}
+	return nil, false
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.1 L2CAP_COMMAND_REJECT_RSP
+type L2capCommandReject struct {
+	Identifier     uint8
+	Reason         uint16 // L2CAP_REJECT_XXX
+	ActualSigMtu   uint16 // L2CAP_REJECT_SIGNALING_MTU_EXCEEDED
+	LocalEndpoint  uint16 // L2CAP_REJECT_INVALID_CID_IN_REQUEST，发送拒绝一端的CID
+	RemoteEndpoint uint16
+}
+
+func L2capCommandRejectParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capCommandReject{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 2 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Reason = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Reason)
+	switch pkt.Reason {
+	case L2CAP_REJECT_SIGNALING_MTU_EXCEEDED:
+		if len(signalingPayloadBuf[pktIndex:]) < 2 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.ActualSigMtu = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	case L2CAP_REJECT_INVALID_CID_IN_REQUEST:
+		if len(signalingPayloadBuf[pktIndex:]) < 4 {
+			return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+		}
+		pkt.LocalEndpoint = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+		pktIndex += binary.Size(pkt.LocalEndpoint)
+		pkt.RemoteEndpoint = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.2 L2CAP_CONNECTION_REQ
+type L2capConnectionRequest struct {
+	Identifier uint8
+	Psm        uint16
+	SourceCid  uint16 // 发送请求一端分配的CID
+}
+
+func L2capConnectionRequestParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	if len(signalingPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := L2capConnectionRequest{Identifier: Identifier}
+	pkt.Psm = binary.LittleEndian.Uint16(signalingPayloadBuf)
+	pkt.SourceCid = binary.LittleEndian.Uint16(signalingPayloadBuf[2:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.3 L2CAP_CONNECTION_RSP
+type L2capConnectionResponse struct {
+	Identifier     uint8
+	DestinationCid uint16 // 发送响应一端分配的CID
+	SourceCid      uint16 // 请求中的Source CID
+	Result         uint16 // L2CAP_CONNECTION_XXX
+	Status         uint16 // Result为pending时有效，0x0001 Authentication pending，0x0002 Authorization pending
+}
+
+func L2capConnectionResponseParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capConnectionResponse{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 8 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.DestinationCid = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.DestinationCid)
+	pkt.SourceCid = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SourceCid)
+	pkt.Result = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Result)
+	pkt.Status = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 5.4 Retransmission and Flow Control option
+type L2capRetransmissionFlowControlOption struct {
+	Mode                  uint8 // L2CAP_MODE_XXX
+	TxWindowSize          uint8
+	MaxTransmit           uint8
+	RetransmissionTimeout uint16 // 单位ms
+	MonitorTimeout        uint16 // 单位ms
+	MaximumPduSize        uint16 // MPS
+}
+
+// 配置选项，OptionList保留所有选项的原始数据
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 5 CONFIGURATION PARAMETER OPTIONS
+type L2capConfigurationOption struct {
+	Type uint8 // L2CAP_OPTION_XXX，不包含hint bit
+	Hint bool
+	Data []byte
+}
+
+type L2capConfigurationOptions struct {
+	OptionList                []L2capConfigurationOption
+	MtuPresent                bool
+	Mtu                       uint16
+	FlushTimeoutPresent       bool
+	FlushTimeout              uint16 // 单位ms，0xFFFF为不刷新
+	RetransmissionPresent     bool
+	Retransmission            L2capRetransmissionFlowControlOption
+	FcsPresent                bool
+	Fcs                       uint8 // L2CAP_FCS_XXX
+	ExtendedWindowSizePresent bool
+	ExtendedWindowSize        uint16
+}
+
+func l2capConfigurationOptionsParse(optionBuf []byte) (L2capConfigurationOptions, bool) {
+	options := L2capConfigurationOptions{}
+	for pktIndex := 0; pktIndex < len(optionBuf); {
+		if len(optionBuf[pktIndex:]) < 2 {
+			return options, false
+		}
+		option := L2capConfigurationOption{Type: optionBuf[pktIndex] & L2CAP_CONFIGURATION_OPTION_TYPE_MASK, Hint: optionBuf[pktIndex]&L2CAP_CONFIGURATION_OPTION_HINT != 0}
+		length := int(optionBuf[pktIndex+1])
+		pktIndex += 2
+		if len(optionBuf[pktIndex:]) < length {
+			return options, false
+		}
+		option.Data = optionBuf[pktIndex : pktIndex+length]
+		pktIndex += length
+		options.OptionList = append(options.OptionList, option)
+		switch {
+		case option.Type == L2CAP_OPTION_MTU && length >= 2:
+			options.MtuPresent, options.Mtu = true, binary.LittleEndian.Uint16(option.Data)
+		case option.Type == L2CAP_OPTION_FLUSH_TIMEOUT && length >= 2:
+			options.FlushTimeoutPresent, options.FlushTimeout = true, binary.LittleEndian.Uint16(option.Data)
+		case option.Type == L2CAP_OPTION_RETRANSMISSION_FLOW_CONTROL && length >= 9:
+			options.RetransmissionPresent = true
+			options.Retransmission = L2capRetransmissionFlowControlOption{
+				Mode:                  option.Data[0],
+				TxWindowSize:          option.Data[1],
+				MaxTransmit:           option.Data[2],
+				RetransmissionTimeout: binary.LittleEndian.Uint16(option.Data[3:]),
+				MonitorTimeout:        binary.LittleEndian.Uint16(option.Data[5:]),
+				MaximumPduSize:        binary.LittleEndian.Uint16(option.Data[7:]),
+			}
+		case option.Type == L2CAP_OPTION_FCS && length >= 1:
+			options.FcsPresent, options.Fcs = true, option.Data[0]
+		case option.Type == L2CAP_OPTION_EXTENDED_WINDOW_SIZE && length >= 2:
+			options.ExtendedWindowSizePresent, options.ExtendedWindowSize = true, binary.LittleEndian.Uint16(option.Data)
+		}
+	}
+	return options, true
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.4 L2CAP_CONFIGURATION_REQ
+type L2capConfigurationRequest struct {
+	Identifier     uint8
+	DestinationCid uint16 // 接收请求一端的CID
+	Flags          uint16 // L2CAP_CONFIGURATION_FLAG_CONTINUATION
+	Options        L2capConfigurationOptions
+}
+
+func L2capConfigurationRequestParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capConfigurationRequest{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.DestinationCid = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.DestinationCid)
+	pkt.Flags = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Flags)
+	options, ok := l2capConfigurationOptionsParse(signalingPayloadBuf[pktIndex:])
+	if !ok {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Options = options
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.5 L2CAP_CONFIGURATION_RSP
+type L2capConfigurationResponse struct {
+	Identifier uint8
+	SourceCid  uint16 // 接收响应一端的CID
+	Flags      uint16
+	Result     uint16 // L2CAP_CONFIGURATION_XXX
+	Options    L2capConfigurationOptions
+}
+
+func L2capConfigurationResponseParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capConfigurationResponse{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 6 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.SourceCid = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SourceCid)
+	pkt.Flags = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Flags)
+	pkt.Result = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Result)
+	options, ok := l2capConfigurationOptionsParse(signalingPayloadBuf[pktIndex:])
+	if !ok {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.Options = options
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.6 L2CAP_DISCONNECTION_REQ
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.7 L2CAP_DISCONNECTION_RSP
+// 请求和响应格式相同，CID均以发送请求一端为Source
+type L2capDisconnection struct {
+	Identifier     uint8
+	Response       bool
+	DestinationCid uint16
+	SourceCid      uint16
+}
+
+func L2capDisconnectionParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	if len(signalingPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := L2capDisconnection{Identifier: Identifier, Response: Code == L2CAP_DISCONNECTION_RESPONSE}
+	pkt.DestinationCid = binary.LittleEndian.Uint16(signalingPayloadBuf)
+	pkt.SourceCid = binary.LittleEndian.Uint16(signalingPayloadBuf[2:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.8 L2CAP_ECHO_REQ
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.9 L2CAP_ECHO_RSP
+type L2capEcho struct {
+	Identifier uint8
+	Response   bool
+	Data       []byte
+}
+
+func L2capEchoParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capEcho{Identifier: Identifier, Response: Code == L2CAP_ECHO_RESPONSE, Data: signalingPayloadBuf}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.10 L2CAP_INFORMATION_REQ
+type L2capInformationRequest struct {
+	Identifier uint8
+	InfoType   uint16 // L2CAP_INFO_TYPE_XXX
+}
+
+func L2capInformationRequestParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	if len(signalingPayloadBuf) < 2 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := L2capInformationRequest{Identifier: Identifier, InfoType: binary.LittleEndian.Uint16(signalingPayloadBuf)}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.11 L2CAP_INFORMATION_RSP
+type L2capInformationResponse struct {
+	Identifier          uint8
+	InfoType            uint16 // L2CAP_INFO_TYPE_XXX
+	Result              uint16 // L2CAP_INFO_XXX
+	Data                []byte
+	ConnectionlessMtu   uint16 // L2CAP_INFO_TYPE_CONNECTIONLESS_MTU
+	ExtendedFeatureMask uint32 // L2CAP_INFO_TYPE_EXTENDED_FEATURES
+	FixedChannels       uint64 // L2CAP_INFO_TYPE_FIXED_CHANNELS，bit n为CID n
+}
+
+func L2capInformationResponseParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capInformationResponse{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.InfoType = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.InfoType)
+	pkt.Result = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Result)
+	pkt.Data = signalingPayloadBuf[pktIndex:]
+	if pkt.Result != L2CAP_INFO_SUCCESS {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+	}
+	switch {
+	case pkt.InfoType == L2CAP_INFO_TYPE_CONNECTIONLESS_MTU && len(pkt.Data) >= 2:
+		pkt.ConnectionlessMtu = binary.LittleEndian.Uint16(pkt.Data)
+	case pkt.InfoType == L2CAP_INFO_TYPE_EXTENDED_FEATURES && len(pkt.Data) >= 4:
+		pkt.ExtendedFeatureMask = binary.LittleEndian.Uint32(pkt.Data)
+	case pkt.InfoType == L2CAP_INFO_TYPE_FIXED_CHANNELS && len(pkt.Data) >= 8:
+		pkt.FixedChannels = binary.LittleEndian.Uint64(pkt.Data)
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part A] 4.20 CONNECTION PARAMETER UPDATE REQUEST
+// slave请求master更新连接参数
+type L2capConnectionParameterUpdateRequest struct {
+	Identifier        uint8
+	IntervalMin       uint16 // 单位1.25ms
+	IntervalMax       uint16
+	SlaveLatency      uint16
+	TimeoutMultiplier uint16 // 单位10ms
+}
+
+func L2capConnectionParameterUpdateRequestParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capConnectionParameterUpdateRequest{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 8 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.IntervalMin = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.IntervalMin)
+	pkt.IntervalMax = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.IntervalMax)
+	pkt.SlaveLatency = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SlaveLatency)
+	pkt.TimeoutMultiplier = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part A] 4.21 CONNECTION PARAMETER UPDATE RESPONSE
+type L2capConnectionParameterUpdateResponse struct {
+	Identifier uint8
+	Result     uint16 // L2CAP_CONNECTION_PARAMETERS_XXX
+}
+
+func L2capConnectionParameterUpdateResponseParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	if len(signalingPayloadBuf) < 2 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := L2capConnectionParameterUpdateResponse{Identifier: Identifier, Result: binary.LittleEndian.Uint16(signalingPayloadBuf)}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.22 L2CAP_LE_CREDIT_BASED_CONNECTION_REQ
+type L2capLeCreditBasedConnectionRequest struct {
+	Identifier     uint8
+	Spsm           uint16
+	SourceCid      uint16
+	Mtu            uint16 // SDU最大长度
+	Mps            uint16 // K-frame payload最大长度
+	InitialCredits uint16 // 对端可以发送的K-frame个数
+}
+
+func L2capLeCreditBasedConnectionRequestParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capLeCreditBasedConnectionRequest{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 10 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Spsm = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Spsm)
+	pkt.SourceCid = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.SourceCid)
+	pkt.Mtu = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Mtu)
+	pkt.Mps = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Mps)
+	pkt.InitialCredits = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.23 L2CAP_LE_CREDIT_BASED_CONNECTION_RSP
+type L2capLeCreditBasedConnectionResponse struct {
+	Identifier     uint8
+	DestinationCid uint16
+	Mtu            uint16
+	Mps            uint16
+	InitialCredits uint16
+	Result         uint16 // L2CAP_LE_CONNECTION_XXX
+}
+
+func L2capLeCreditBasedConnectionResponseParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capLeCreditBasedConnectionResponse{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 10 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.DestinationCid = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.DestinationCid)
+	pkt.Mtu = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Mtu)
+	pkt.Mps = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Mps)
+	pkt.InitialCredits = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.InitialCredits)
+	pkt.Result = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.24 L2CAP_FLOW_CONTROL_CREDIT_IND
+// 发送端允许对端在Cid上再发送Credits个K-frame
+type L2capFlowControlCreditInd struct {
+	Identifier uint8
+	Cid        uint16 // 发送端的CID
+	Credits    uint16
+}
+
+func L2capFlowControlCreditIndParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	if len(signalingPayloadBuf) < 4 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := L2capFlowControlCreditInd{Identifier: Identifier}
+	pkt.Cid = binary.LittleEndian.Uint16(signalingPayloadBuf)
+	pkt.Credits = binary.LittleEndian.Uint16(signalingPayloadBuf[2:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.25 L2CAP_CREDIT_BASED_CONNECTION_REQ
+// 一次最多建立5个信道
+type L2capCreditBasedConnectionRequest struct {
+	Identifier     uint8
+	Spsm           uint16
+	Mtu            uint16
+	Mps            uint16
+	InitialCredits uint16
+	SourceCidList  []uint16
+}
+
+func L2capCreditBasedConnectionRequestParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capCreditBasedConnectionRequest{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 10 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Spsm = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Spsm)
+	pkt.Mtu = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Mtu)
+	pkt.Mps = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Mps)
+	pkt.InitialCredits = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.InitialCredits)
+	pkt.SourceCidList = l2capCidListParse(signalingPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH CORE SPECIFICATION Version 5.4 | Vol 3, Part A 4.26 L2CAP_CREDIT_BASED_CONNECTION_RSP
+// 拒绝的信道Destination CID为0x0000
+type L2capCreditBasedConnectionResponse struct {
+	Identifier         uint8
+	Mtu                uint16
+	Mps                uint16
+	InitialCredits     uint16
+	Result             uint16 // L2CAP_LE_CONNECTION_XXX
+	DestinationCidList []uint16
+}
+
+func L2capCreditBasedConnectionResponseParser(Code uint8, Identifier uint8, signalingPayloadBuf []byte) HciAclPktParseResult {
+	pkt := L2capCreditBasedConnectionResponse{Identifier: Identifier}
+	if len(signalingPayloadBuf) < 8 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Mtu = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Mtu)
+	pkt.Mps = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Mps)
+	pkt.InitialCredits = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.InitialCredits)
+	pkt.Result = binary.LittleEndian.Uint16(signalingPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.Result)
+	pkt.DestinationCidList = l2capCidListParse(signalingPayloadBuf[pktIndex:])
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+func l2capCidListParse(cidBuf []byte) []uint16 {
+	cidList := []uint16{}
+	for pktIndex := 0; pktIndex+2 <= len(cidBuf) && len(cidList) < 5; pktIndex += 2 {
+		cidList = append(cidList, binary.LittleEndian.Uint16(cidBuf[pktIndex:]))
+	}
+	return cidList
+}
@@ -0,0 +1,112 @@
+package hci
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 一个BR/EDR C-frame中包含Connection Request和带全部常用选项的Configuration Request
+func TestL2capBrEdrSignalingParse(t *testing.T) {
+	buf := []byte{
+		0x2D, 0x00, 0x01, 0x00,
+		// Connection Request, PSM 0x0019, Source CID 0x0040
+		0x02, 0x01, 0x04, 0x00, 0x19, 0x00, 0x40, 0x00,
+		// Configuration Request, Destination CID 0x0041
+		0x04, 0x02, 0x21, 0x00, 0x41, 0x00, 0x00, 0x00,
+		0x01, 0x02, 0xA0, 0x02, // MTU
+		0x02, 0x02, 0xFF, 0xFF, // Flush Timeout
+		0x04, 0x09, 0x03, 0x3F, 0x0A, 0xD0, 0x07, 0xE0, 0x2E, 0xF0, 0x03, // Retransmission and Flow Control
+		0x05, 0x01, 0x00, // FCS
+		0x07, 0x02, 0x00, 0x01, // Extended Window Size
+		0x89, 0x01, 0xAA, // 未知的hint选项
+	}
+	parsed := HciAclPktParse(buf)
+	if parsed.Code != HCI_PKT_RET_CODE_OK || parsed.ChannelId != L2CAP_CID_SIGNALING || parsed.OpCode != L2CAP_CONNECTION_REQUEST {
+		t.Fatalf("code %d channel %#04x opcode %#x", parsed.Code, parsed.ChannelId, parsed.OpCode)
+	}
+	commandList, ok := L2capSignalingCommands(parsed)
+	if !ok {
+		t.Fatalf("ret %T is not a command list", parsed.Ret)
+	}
+	want := L2capSignalingCommandList{
+		{
+			Code: HCI_PKT_RET_CODE_OK, ChannelId: L2CAP_CID_SIGNALING, OpCode: L2CAP_CONNECTION_REQUEST,
+			Ret: L2capConnectionRequest{Identifier: 0x01, Psm: 0x0019, SourceCid: 0x0040},
+		},
+		{
+			Code: HCI_PKT_RET_CODE_OK, ChannelId: L2CAP_CID_SIGNALING, OpCode: L2CAP_CONFIGURATION_REQUEST,
+			Ret: L2capConfigurationRequest{Identifier: 0x02, DestinationCid: 0x0041, Options: L2capConfigurationOptions{
+				OptionList: []L2capConfigurationOption{
+					{Type: L2CAP_OPTION_MTU, Data: []byte{0xA0, 0x02}},
+					{Type: L2CAP_OPTION_FLUSH_TIMEOUT, Data: []byte{0xFF, 0xFF}},
+					{Type: L2CAP_OPTION_RETRANSMISSION_FLOW_CONTROL, Data: []byte{0x03, 0x3F, 0x0A, 0xD0, 0x07, 0xE0, 0x2E, 0xF0, 0x03}},
+					{Type: L2CAP_OPTION_FCS, Data: []byte{0x00}},
+					{Type: L2CAP_OPTION_EXTENDED_WINDOW_SIZE, Data: []byte{0x00, 0x01}},
+					{Type: 0x09, Hint: true, Data: []byte{0xAA}},
+				},
+				MtuPresent: true, Mtu: 672,
+				FlushTimeoutPresent: true, FlushTimeout: 0xFFFF,
+				RetransmissionPresent: true,
+				Retransmission: L2capRetransmissionFlowControlOption{
+					Mode: L2CAP_MODE_ENHANCED_RETRANSMISSION, TxWindowSize: 63, MaxTransmit: 10,
+					RetransmissionTimeout: 2000, MonitorTimeout: 12000, MaximumPduSize: 1008,
+				},
+				FcsPresent: true, Fcs: L2CAP_FCS_NONE,
+				ExtendedWindowSizePresent: true, ExtendedWindowSize: 0x0100,
+			}},
+		},
+	}
+	if !reflect.DeepEqual(commandList, want) {
+		t.Errorf("commands %+v, want %+v", commandList, want)
+	}
+}
+
+func TestL2capSignalingParse(t *testing.T) {
+	testList := []struct {
+		name          string
+		buf           []byte // 含Basic L2CAP header
+		wantCode      int
+		wantChannelId uint16
+		wantOpCode    uint8
+		want          interface{}
+	}{
+		{
+			"le connection parameter update request", []byte{0x0C, 0x00, 0x05, 0x00, 0x12, 0x07, 0x08, 0x00, 0x18, 0x00, 0x28, 0x00, 0x00, 0x00, 0xF4, 0x01},
+			HCI_PKT_RET_CODE_OK, L2CAP_CID_LE_SIGNALING, L2CAP_CONNECTION_PARAMETER_UPDATE_REQUEST,
+			L2capConnectionParameterUpdateRequest{Identifier: 0x07, IntervalMin: 0x0018, IntervalMax: 0x0028, TimeoutMultiplier: 0x01F4},
+		},
+		{
+			"le connection parameter update response", []byte{0x06, 0x00, 0x05, 0x00, 0x13, 0x07, 0x02, 0x00, 0x01, 0x00},
+			HCI_PKT_RET_CODE_OK, L2CAP_CID_LE_SIGNALING, L2CAP_CONNECTION_PARAMETER_UPDATE_RESPONSE,
+			L2capConnectionParameterUpdateResponse{Identifier: 0x07, Result: L2CAP_CONNECTION_PARAMETERS_REJECTED},
+		},
+		// Connection Request只能出现在BR/EDR信令信道
+		{"le signaling rejects br/edr command", []byte{0x08, 0x00, 0x05, 0x00, 0x02, 0x01, 0x04, 0x00, 0x19, 0x00, 0x40, 0x00}, HCI_PKT_RET_CODE_NOT_SUPPORT, L2CAP_CID_LE_SIGNALING, L2CAP_CONNECTION_REQUEST, nil},
+		{"le command length exceeds payload", []byte{0x06, 0x00, 0x05, 0x00, 0x13, 0x07, 0x04, 0x00, 0x01, 0x00}, HCI_PKT_RET_CODE_INVALID_LEN, L2CAP_CID_LE_SIGNALING, L2CAP_CONNECTION_PARAMETER_UPDATE_RESPONSE, nil},
+		{"le request too short", []byte{0x08, 0x00, 0x05, 0x00, 0x12, 0x07, 0x04, 0x00, 0x18, 0x00, 0x28, 0x00}, HCI_PKT_RET_CODE_INVALID_LEN, L2CAP_CID_LE_SIGNALING, L2CAP_CONNECTION_PARAMETER_UPDATE_REQUEST, nil},
+		{"le command header truncated", []byte{0x03, 0x00, 0x05, 0x00, 0x12, 0x07, 0x08}, HCI_PKT_RET_CODE_INVALID_LEN, L2CAP_CID_LE_SIGNALING, 0, nil},
+		{"br/edr empty c-frame", []byte{0x00, 0x00, 0x01, 0x00}, HCI_PKT_RET_CODE_INVALID_LEN, L2CAP_CID_SIGNALING, 0, nil},
+		// 第二条命令的选项长度超出命令长度，第一条命令仍然可用
+		{
+			"br/edr truncated configuration option", []byte{0x12, 0x00, 0x01, 0x00, 0x08, 0x01, 0x02, 0x00, 0xAA, 0xBB, 0x04, 0x02, 0x08, 0x00, 0x41, 0x00, 0x00, 0x00, 0x01, 0x03, 0xA0, 0x02},
+			HCI_PKT_RET_CODE_OK, L2CAP_CID_SIGNALING, L2CAP_ECHO_REQUEST,
+			L2capSignalingCommandList{
+				{Code: HCI_PKT_RET_CODE_OK, ChannelId: L2CAP_CID_SIGNALING, OpCode: L2CAP_ECHO_REQUEST, Ret: L2capEcho{Identifier: 0x01, Data: []byte{0xAA, 0xBB}}},
+				{Code: HCI_PKT_RET_CODE_INVALID_LEN, ChannelId: L2CAP_CID_SIGNALING, OpCode: L2CAP_CONFIGURATION_REQUEST},
+			},
+		},
+	}
+	for _, test := range testList {
+		parsed := HciAclPktParse(test.buf)
+		if parsed.Code != test.wantCode || parsed.ChannelId != test.wantChannelId || parsed.OpCode != test.wantOpCode {
+			t.Errorf("%s: code %d channel %#04x opcode %#x, want %d %#04x %#x", test.name, parsed.Code, parsed.ChannelId, parsed.OpCode, test.wantCode, test.wantChannelId, test.wantOpCode)
+			continue
+		}
+		if test.want == nil {
+			continue
+		}
+		if !reflect.DeepEqual(parsed.Ret, test.want) {
+			t.Errorf("%s: %+v, want %+v", test.name, parsed.Ret, test.want)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// BR/EDR配对和鉴权相关命令和事件处理，包括Secure Simple Pairing和legacy PIN配对
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// IO_Capability
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.41 IO Capability Response Event
+const (
+	IO_CAPABILITY_DISPLAY_ONLY       = 0x00
+	IO_CAPABILITY_DISPLAY_YES_NO     = 0x01
+	IO_CAPABILITY_KEYBOARD_ONLY      = 0x02
+	IO_CAPABILITY_NO_INPUT_NO_OUTPUT = 0x03
+)
+
+var IoCapabilityStrMap = map[uint8]string{
+	IO_CAPABILITY_DISPLAY_ONLY:       "DisplayOnly",
+	IO_CAPABILITY_DISPLAY_YES_NO:     "DisplayYesNo",
+	IO_CAPABILITY_KEYBOARD_ONLY:      "KeyboardOnly",
+	IO_CAPABILITY_NO_INPUT_NO_OUTPUT: "NoInputNoOutput",
+}
+
+// Authentication_Requirements，bit0为MITM Protection Required
+const (
+	AUTH_REQ_MITM_NOT_REQUIRED_NO_BONDING        = 0x00
+	AUTH_REQ_MITM_REQUIRED_NO_BONDING            = 0x01
+	AUTH_REQ_MITM_NOT_REQUIRED_DEDICATED_BONDING = 0x02
+	AUTH_REQ_MITM_REQUIRED_DEDICATED_BONDING     = 0x03
+	AUTH_REQ_MITM_NOT_REQUIRED_GENERAL_BONDING   = 0x04
+	AUTH_REQ_MITM_REQUIRED_GENERAL_BONDING       = 0x05
+)
+
+// Key_Type
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.24 Link Key Notification Event
+const (
+	LINK_KEY_TYPE_COMBINATION          = 0x00
+	LINK_KEY_TYPE_DEBUG_COMBINATION    = 0x03
+	LINK_KEY_TYPE_UNAUTHENTICATED_P192 = 0x04
+	LINK_KEY_TYPE_AUTHENTICATED_P192   = 0x05
+	LINK_KEY_TYPE_CHANGED_COMBINATION  = 0x06
+	LINK_KEY_TYPE_UNAUTHENTICATED_P256 = 0x07
+	LINK_KEY_TYPE_AUTHENTICATED_P256   = 0x08
+)
+
+var LinkKeyTypeStrMap = map[uint8]string{
+	LINK_KEY_TYPE_COMBINATION:          "Combination Key",
+	LINK_KEY_TYPE_DEBUG_COMBINATION:    "Debug Combination Key",
+	LINK_KEY_TYPE_UNAUTHENTICATED_P192: "Unauthenticated Combination Key generated from P-192",
+	LINK_KEY_TYPE_AUTHENTICATED_P192:   "Authenticated Combination Key generated from P-192",
+	LINK_KEY_TYPE_CHANGED_COMBINATION:  "Changed Combination Key",
+	LINK_KEY_TYPE_UNAUTHENTICATED_P256: "Unauthenticated Combination Key generated from P-256",
+	LINK_KEY_TYPE_AUTHENTICATED_P256:   "Authenticated Combination Key generated from P-256",
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.10 Link Key Request Reply Command
+type HciLinkKeyRequestReply struct {
+	BdAddr  [6]byte
+	LinkKey [16]byte
+}
+
+func HciLinkKeyRequestReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciLinkKeyRequestReply{}
+	if len(hciCmdPktPayloadBuf) < 22 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.BdAddr = BdAddrParse(hciCmdPktPayloadBuf)
+	copy(pkt.LinkKey[:], hciCmdPktPayloadBuf[6:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.12 PIN Code Request Reply Command
+type HciPinCodeRequestReply struct {
+	BdAddr        [6]byte
+	PinCodeLength uint8
+	PinCode       []byte
+}
+
+func HciPinCodeRequestReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciPinCodeRequestReply{}
+	if len(hciCmdPktPayloadBuf) < 23 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.BdAddr = BdAddrParse(hciCmdPktPayloadBuf)
+	pkt.PinCodeLength = hciCmdPktPayloadBuf[6]
+	if pkt.PinCodeLength > 16 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.PinCode = hciCmdPktPayloadBuf[7 : 7+int(pkt.PinCodeLength)]
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.39 IO Capability Request Reply Command
+type HciIoCapabilityRequestReply struct {
+	BdAddr                     [6]byte
+	IoCapability               uint8 // IO_CAPABILITY_XXX
+	OobDataPresent             uint8
+	AuthenticationRequirements uint8 // AUTH_REQ_XXX
+}
+
+func HciIoCapabilityRequestReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 9 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciIoCapabilityRequestReply{
+		BdAddr:                     BdAddrParse(hciCmdPktPayloadBuf),
+		IoCapability:               hciCmdPktPayloadBuf[6],
+		OobDataPresent:             hciCmdPktPayloadBuf[7],
+		AuthenticationRequirements: hciCmdPktPayloadBuf[8],
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.42 User Passkey Request Reply Command
+type HciUserPasskeyRequestReply struct {
+	BdAddr       [6]byte
+	NumericValue uint32
+}
+
+func HciUserPasskeyRequestReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 10 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciUserPasskeyRequestReply{BdAddr: BdAddrParse(hciCmdPktPayloadBuf), NumericValue: binary.LittleEndian.Uint32(hciCmdPktPayloadBuf[6:])}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.44 Remote OOB Data Request Reply Command
+type HciRemoteOobDataRequestReply struct {
+	BdAddr [6]byte
+	C      [16]byte
+	R      [16]byte
+}
+
+func HciRemoteOobDataRequestReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciRemoteOobDataRequestReply{}
+	if len(hciCmdPktPayloadBuf) < 38 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.BdAddr = BdAddrParse(hciCmdPktPayloadBuf)
+	copy(pkt.C[:], hciCmdPktPayloadBuf[6:])
+	copy(pkt.R[:], hciCmdPktPayloadBuf[22:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.11 Link Key Request Negative Reply Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.13 PIN Code Request Negative Reply Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.40 User Confirmation Request Reply Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.41 User Confirmation Request Negative Reply Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.43 User Passkey Request Negative Reply Command
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.45 Remote OOB Data Request Negative Reply Command
+// 只有BD_ADDR参数的命令共用，按OpCode区分
+type HciBdAddrCmd struct {
+	BdAddr [6]byte
+}
+
+func HciBdAddrCmdParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 6 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: HciBdAddrCmd{BdAddr: BdAddrParse(hciCmdPktPayloadBuf)}}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.36 IO Capability Request Negative Reply Command
+type HciIoCapabilityRequestNegativeReply struct {
+	BdAddr [6]byte
+	Reason HciStatus
+}
+
+func HciIoCapabilityRequestNegativeReplyParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	if len(hciCmdPktPayloadBuf) < 7 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := HciIoCapabilityRequestNegativeReply{BdAddr: BdAddrParse(hciCmdPktPayloadBuf), Reason: HciStatus(hciCmdPktPayloadBuf[6])}
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.6 Authentication Complete Event
+type AuthenticationCompleteEvent struct {
+	Status           HciStatus
+	ConnectionHandle uint16
+}
+
+// 本端Authentication Requested的结果
+func AuthenticationCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	if len(hciEvtPktPayloadBuf) < 3 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := AuthenticationCompleteEvent{Status: HciStatus(hciEvtPktPayloadBuf[0]), ConnectionHandle: binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[1:])}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.22 PIN Code Request Event
+type PinCodeRequestEvent struct {
+	BdAddr [6]byte
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.23 Link Key Request Event
+type LinkKeyRequestEvent struct {
+	BdAddr [6]byte
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.40 IO Capability Request Event
+type IoCapabilityRequestEvent struct {
+	BdAddr [6]byte
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.43 User Passkey Request Event
+type UserPasskeyRequestEvent struct {
+	BdAddr [6]byte
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.44 Remote OOB Data Request Event
+type RemoteOobDataRequestEvent struct {
+	BdAddr [6]byte
+}
+
+// 只有BD_ADDR参数的事件共用
+func BdAddrEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	if len(hciEvtPktPayloadBuf) < 6 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bdAddr := BdAddrParse(hciEvtPktPayloadBuf)
+	switch EventCode {
+	case HCI_EVT_PIN_CODE_REQUEST:
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: PinCodeRequestEvent{BdAddr: bdAddr}}
+	case HCI_EVT_LINK_KEY_REQUEST:
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: LinkKeyRequestEvent{BdAddr: bdAddr}}
+	case HCI_EVT_IO_CAPABILITY_REQUEST:
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: IoCapabilityRequestEvent{BdAddr: bdAddr}}
+	case HCI_EVT_USER_PASSKEY_REQUEST:
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: UserPasskeyRequestEvent{BdAddr: bdAddr}}
+	case HCI_EVT_REMOTE_OOB_DATA_REQUEST:
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: RemoteOobDataRequestEvent{BdAddr: bdAddr}}
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.24 Link Key Notification Event
+type LinkKeyNotificationEvent struct {
+	BdAddr  [6]byte
+	LinkKey [16]byte
+	KeyType uint8 // LINK_KEY_TYPE_XXX
+}
+
+func LinkKeyNotificationEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := LinkKeyNotificationEvent{}
+	if len(hciEvtPktPayloadBuf) < 23 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.BdAddr = BdAddrParse(hciEvtPktPayloadBuf)
+	copy(pkt.LinkKey[:], hciEvtPktPayloadBuf[6:])
+	pkt.KeyType = hciEvtPktPayloadBuf[22]
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.41 IO Capability Response Event
+type IoCapabilityResponseEvent struct {
+	BdAddr                     [6]byte
+	IoCapability               uint8 // IO_CAPABILITY_XXX
+	OobDataPresent             uint8
+	AuthenticationRequirements uint8 // AUTH_REQ_XXX
+}
+
+// 对端的IO能力
+func IoCapabilityResponseEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	if len(hciEvtPktPayloadBuf) < 9 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := IoCapabilityResponseEvent{
+		BdAddr:                     BdAddrParse(hciEvtPktPayloadBuf),
+		IoCapability:               hciEvtPktPayloadBuf[6],
+		OobDataPresent:             hciEvtPktPayloadBuf[7],
+		AuthenticationRequirements: hciEvtPktPayloadBuf[8],
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.42 User Confirmation Request Event
+type UserConfirmationRequestEvent struct {
+	BdAddr       [6]byte
+	NumericValue uint32
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.48 User Passkey Notification Event
+type UserPasskeyNotificationEvent struct {
+	BdAddr  [6]byte
+	Passkey uint32
+}
+
+// 带6位数字的事件共用
+func BdAddrNumericEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	if len(hciEvtPktPayloadBuf) < 10 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bdAddr := BdAddrParse(hciEvtPktPayloadBuf)
+	value := binary.LittleEndian.Uint32(hciEvtPktPayloadBuf[6:])
+	if EventCode == HCI_EVT_USER_PASSKEY_NOTIFICATION {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: UserPasskeyNotificationEvent{BdAddr: bdAddr, Passkey: value}}
+	}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: UserConfirmationRequestEvent{BdAddr: bdAddr, NumericValue: value}}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.45 Simple Pairing Complete Event
+type SimplePairingCompleteEvent struct {
+	Status HciStatus
+	BdAddr [6]byte
+}
+
+func SimplePairingCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	if len(hciEvtPktPayloadBuf) < 7 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := SimplePairingCompleteEvent{Status: HciStatus(hciEvtPktPayloadBuf[0]), BdAddr: BdAddrParse(hciEvtPktPayloadBuf[1:])}
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
@@ -0,0 +1,103 @@
+// SMP PDU解析
+// 只解析Pairing Request/Pairing Response，其他命令返回HCI_PKT_RET_CODE_NOT_SUPPORT
+
+package hci
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part H] 3.3 Command Format
+const (
+	SMP_PAIRING_REQUEST               = 0x01
+	SMP_PAIRING_RESPONSE              = 0x02
+	SMP_PAIRING_CONFIRM               = 0x03
+	SMP_PAIRING_RANDOM                = 0x04
+	SMP_PAIRING_FAILED                = 0x05
+	SMP_ENCRYPTION_INFORMATION        = 0x06
+	SMP_MASTER_IDENTIFICATION         = 0x07
+	SMP_IDENTITY_INFORMATION          = 0x08
+	SMP_IDENTITY_ADDRESS_INFORMATION  = 0x09
+	SMP_SIGNING_INFORMATION           = 0x0A
+	SMP_SECURITY_REQUEST              = 0x0B
+	SMP_PAIRING_PUBLIC_KEY            = 0x0C
+	SMP_PAIRING_DHKEY_CHECK           = 0x0D
+	SMP_PAIRING_KEYPRESS_NOTIFICATION = 0x0E
+)
+
+var SmpCodeStrMap = map[int]string{
+	SMP_PAIRING_REQUEST:               "Pairing Request",
+	SMP_PAIRING_RESPONSE:              "Pairing Response",
+	SMP_PAIRING_CONFIRM:               "Pairing Confirm",
+	SMP_PAIRING_RANDOM:                "Pairing Random",
+	SMP_PAIRING_FAILED:                "Pairing Failed",
+	SMP_ENCRYPTION_INFORMATION:        "Encryption Information",
+	SMP_MASTER_IDENTIFICATION:         "Master Identification",
+	SMP_IDENTITY_INFORMATION:          "Identity Information",
+	SMP_IDENTITY_ADDRESS_INFORMATION:  "Identity Address Information",
+	SMP_SIGNING_INFORMATION:           "Signing Information",
+	SMP_SECURITY_REQUEST:              "Security Request",
+	SMP_PAIRING_PUBLIC_KEY:            "Pairing Public Key",
+	SMP_PAIRING_DHKEY_CHECK:           "Pairing DHKey Check",
+	SMP_PAIRING_KEYPRESS_NOTIFICATION: "Pairing Keypress Notification",
+}
+
+// AuthReq
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part H] 3.5.1 Pairing Request
+const (
+	SMP_AUTH_REQ_BONDING  = 0x01 // Bonding_Flags为0b01
+	SMP_AUTH_REQ_MITM     = 0x04
+	SMP_AUTH_REQ_SC       = 0x08 // 支持LE Secure Connections
+	SMP_AUTH_REQ_KEYPRESS = 0x10
+	SMP_AUTH_REQ_CT2      = 0x20 // Version 5.0新增
+)
+
+type SmpPktParser func(Code uint8, smpPayloadBuf []byte) HciAclPktParseResult
+
+var SmpPktParserMap map[int]SmpPktParser = map[int]SmpPktParser{
+	SMP_PAIRING_REQUEST:  SmpPairingFeatureParser,
+	SMP_PAIRING_RESPONSE: SmpPairingFeatureParser,
+}
+
+func SmpPktDefaultParser(Code uint8, smpPayloadBuf []byte) HciAclPktParseResult {
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_NOT_SUPPORT}
+}
+
+// SMP信道(L2CAP_CID_SMP，L2CAP_CID_BREDR_SMP)
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part H] 3.3 Command Format
+func SmpPktParse(ChannelId uint16, l2capPayloadBuf []byte) HciAclPktParseResult {
+	if len(l2capPayloadBuf) < 1 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	Code := l2capPayloadBuf[0]
+	parser, ok := SmpPktParserMap[int(Code)]
+	if !ok {
+		parser = SmpPktDefaultParser
+	}
+	parsed := parser(Code, l2capPayloadBuf[1:])
+	parsed.OpCode = Code
+	return parsed
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part H] 3.5.1 Pairing Request/3.5.2 Pairing Response
+type SmpPairingFeature struct {
+	Response                 bool // true为Pairing Response
+	IoCapability             uint8
+	OobDataFlag              uint8
+	AuthReq                  uint8 // SMP_AUTH_REQ_XXX
+	MaximumEncryptionKeySize uint8
+	InitiatorKeyDistribution uint8
+	ResponderKeyDistribution uint8
+}
+
+func SmpPairingFeatureParser(Code uint8, smpPayloadBuf []byte) HciAclPktParseResult {
+	if len(smpPayloadBuf) < 6 {
+		return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt := SmpPairingFeature{
+		Response:                 Code == SMP_PAIRING_RESPONSE,
+		IoCapability:             smpPayloadBuf[0],
+		OobDataFlag:              smpPayloadBuf[1],
+		AuthReq:                  smpPayloadBuf[2],
+		MaximumEncryptionKeySize: smpPayloadBuf[3],
+		InitiatorKeyDistribution: smpPayloadBuf[4],
+		ResponderKeyDistribution: smpPayloadBuf[5],
+	}
+	return HciAclPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
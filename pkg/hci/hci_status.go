@@ -0,0 +1,161 @@
+// HCI错误码
+// BLUETOOTH CORE SPECIFICATION Version 5.2 | Vol 1, Part F 1.3 LIST OF ERROR CODES
+
+package hci
+
+import (
+	"fmt"
+)
+
+// 命令响应的Status、事件的Status以及断开原因Reason共用同一张错误码表
+type HciStatus uint8
+
+const (
+	HCI_STATUS_SUCCESS                                                  HciStatus = 0x00
+	HCI_STATUS_UNKNOWN_HCI_COMMAND                                      HciStatus = 0x01
+	HCI_STATUS_UNKNOWN_CONNECTION_IDENTIFIER                            HciStatus = 0x02
+	HCI_STATUS_HARDWARE_FAILURE                                         HciStatus = 0x03
+	HCI_STATUS_PAGE_TIMEOUT                                             HciStatus = 0x04
+	HCI_STATUS_AUTHENTICATION_FAILURE                                   HciStatus = 0x05
+	HCI_STATUS_PIN_OR_KEY_MISSING                                       HciStatus = 0x06
+	HCI_STATUS_MEMORY_CAPACITY_EXCEEDED                                 HciStatus = 0x07
+	HCI_STATUS_CONNECTION_TIMEOUT                                       HciStatus = 0x08
+	HCI_STATUS_CONNECTION_LIMIT_EXCEEDED                                HciStatus = 0x09
+	HCI_STATUS_SYNCHRONOUS_CONNECTION_LIMIT_TO_A_DEVICE_EXCEEDED        HciStatus = 0x0A
+	HCI_STATUS_CONNECTION_ALREADY_EXISTS                                HciStatus = 0x0B
+	HCI_STATUS_COMMAND_DISALLOWED                                       HciStatus = 0x0C
+	HCI_STATUS_CONNECTION_REJECTED_DUE_TO_LIMITED_RESOURCES             HciStatus = 0x0D
+	HCI_STATUS_CONNECTION_REJECTED_DUE_TO_SECURITY_REASONS              HciStatus = 0x0E
+	HCI_STATUS_CONNECTION_REJECTED_DUE_TO_UNACCEPTABLE_BD_ADDR          HciStatus = 0x0F
+	HCI_STATUS_CONNECTION_ACCEPT_TIMEOUT_EXCEEDED                       HciStatus = 0x10
+	HCI_STATUS_UNSUPPORTED_FEATURE_OR_PARAMETER_VALUE                   HciStatus = 0x11
+	HCI_STATUS_INVALID_HCI_COMMAND_PARAMETERS                           HciStatus = 0x12
+	HCI_STATUS_REMOTE_USER_TERMINATED_CONNECTION                        HciStatus = 0x13
+	HCI_STATUS_REMOTE_DEVICE_TERMINATED_CONNECTION_DUE_TO_LOW_RESOURCES HciStatus = 0x14
+	HCI_STATUS_REMOTE_DEVICE_TERMINATED_CONNECTION_DUE_TO_POWER_OFF     HciStatus = 0x15
+	HCI_STATUS_CONNECTION_TERMINATED_BY_LOCAL_HOST                      HciStatus = 0x16
+	HCI_STATUS_REPEATED_ATTEMPTS                                        HciStatus = 0x17
+	HCI_STATUS_PAIRING_NOT_ALLOWED                                      HciStatus = 0x18
+	HCI_STATUS_UNKNOWN_LMP_PDU                                          HciStatus = 0x19
+	HCI_STATUS_UNSUPPORTED_REMOTE_FEATURE                               HciStatus = 0x1A
+	HCI_STATUS_SCO_OFFSET_REJECTED                                      HciStatus = 0x1B
+	HCI_STATUS_SCO_INTERVAL_REJECTED                                    HciStatus = 0x1C
+	HCI_STATUS_SCO_AIR_MODE_REJECTED                                    HciStatus = 0x1D
+	HCI_STATUS_INVALID_LMP_LL_PARAMETERS                                HciStatus = 0x1E
+	HCI_STATUS_UNSPECIFIED_ERROR                                        HciStatus = 0x1F
+	HCI_STATUS_UNSUPPORTED_LMP_LL_PARAMETER_VALUE                       HciStatus = 0x20
+	HCI_STATUS_ROLE_CHANGE_NOT_ALLOWED                                  HciStatus = 0x21
+	HCI_STATUS_LMP_LL_RESPONSE_TIMEOUT                                  HciStatus = 0x22
+	HCI_STATUS_LMP_ERROR_TRANSACTION_COLLISION                          HciStatus = 0x23
+	HCI_STATUS_LMP_PDU_NOT_ALLOWED                                      HciStatus = 0x24
+	HCI_STATUS_ENCRYPTION_MODE_NOT_ACCEPTABLE                           HciStatus = 0x25
+	HCI_STATUS_LINK_KEY_CANNOT_BE_CHANGED                               HciStatus = 0x26
+	HCI_STATUS_REQUESTED_QOS_NOT_SUPPORTED                              HciStatus = 0x27
+	HCI_STATUS_INSTANT_PASSED                                           HciStatus = 0x28
+	HCI_STATUS_PAIRING_WITH_UNIT_KEY_NOT_SUPPORTED                      HciStatus = 0x29
+	HCI_STATUS_DIFFERENT_TRANSACTION_COLLISION                          HciStatus = 0x2A
+	HCI_STATUS_QOS_UNACCEPTABLE_PARAMETER                               HciStatus = 0x2C
+	HCI_STATUS_QOS_REJECTED                                             HciStatus = 0x2D
+	HCI_STATUS_CHANNEL_CLASSIFICATION_NOT_SUPPORTED                     HciStatus = 0x2E
+	HCI_STATUS_INSUFFICIENT_SECURITY                                    HciStatus = 0x2F
+	HCI_STATUS_PARAMETER_OUT_OF_MANDATORY_RANGE                         HciStatus = 0x30
+	HCI_STATUS_ROLE_SWITCH_PENDING                                      HciStatus = 0x32
+	HCI_STATUS_RESERVED_SLOT_VIOLATION                                  HciStatus = 0x34
+	HCI_STATUS_ROLE_SWITCH_FAILED                                       HciStatus = 0x35
+	HCI_STATUS_EXTENDED_INQUIRY_RESPONSE_TOO_LARGE                      HciStatus = 0x36
+	HCI_STATUS_SECURE_SIMPLE_PAIRING_NOT_SUPPORTED_BY_HOST              HciStatus = 0x37
+	HCI_STATUS_HOST_BUSY_PAIRING                                        HciStatus = 0x38
+	HCI_STATUS_CONNECTION_REJECTED_DUE_TO_NO_SUITABLE_CHANNEL_FOUND     HciStatus = 0x39
+	HCI_STATUS_CONTROLLER_BUSY                                          HciStatus = 0x3A
+	HCI_STATUS_UNACCEPTABLE_CONNECTION_PARAMETERS                       HciStatus = 0x3B
+	HCI_STATUS_ADVERTISING_TIMEOUT                                      HciStatus = 0x3C
+	HCI_STATUS_CONNECTION_TERMINATED_DUE_TO_MIC_FAILURE                 HciStatus = 0x3D
+	HCI_STATUS_CONNECTION_FAILED_TO_BE_ESTABLISHED                      HciStatus = 0x3E
+	HCI_STATUS_MAC_CONNECTION_FAILED                                    HciStatus = 0x3F
+	HCI_STATUS_COARSE_CLOCK_ADJUSTMENT_REJECTED                         HciStatus = 0x40
+	HCI_STATUS_TYPE0_SUBMAP_NOT_DEFINED                                 HciStatus = 0x41
+	HCI_STATUS_UNKNOWN_ADVERTISING_IDENTIFIER                           HciStatus = 0x42
+	HCI_STATUS_LIMIT_REACHED                                            HciStatus = 0x43
+	HCI_STATUS_OPERATION_CANCELLED_BY_HOST                              HciStatus = 0x44
+	HCI_STATUS_PACKET_TOO_LONG                                          HciStatus = 0x45
+)
+
+// HciStatus 对应字符串，0x2B/0x31/0x33为保留值
+var HciStatusStrMap = map[HciStatus]string{
+	HCI_STATUS_SUCCESS:                                                  "Success",
+	HCI_STATUS_UNKNOWN_HCI_COMMAND:                                      "Unknown HCI Command",
+	HCI_STATUS_UNKNOWN_CONNECTION_IDENTIFIER:                            "Unknown Connection Identifier",
+	HCI_STATUS_HARDWARE_FAILURE:                                         "Hardware Failure",
+	HCI_STATUS_PAGE_TIMEOUT:                                             "Page Timeout",
+	HCI_STATUS_AUTHENTICATION_FAILURE:                                   "Authentication Failure",
+	HCI_STATUS_PIN_OR_KEY_MISSING:                                       "PIN or Key Missing",
+	HCI_STATUS_MEMORY_CAPACITY_EXCEEDED:                                 "Memory Capacity Exceeded",
+	HCI_STATUS_CONNECTION_TIMEOUT:                                       "Connection Timeout",
+	HCI_STATUS_CONNECTION_LIMIT_EXCEEDED:                                "Connection Limit Exceeded",
+	HCI_STATUS_SYNCHRONOUS_CONNECTION_LIMIT_TO_A_DEVICE_EXCEEDED:        "Synchronous Connection Limit To A Device Exceeded",
+	HCI_STATUS_CONNECTION_ALREADY_EXISTS:                                "Connection Already Exists",
+	HCI_STATUS_COMMAND_DISALLOWED:                                       "Command Disallowed",
+	HCI_STATUS_CONNECTION_REJECTED_DUE_TO_LIMITED_RESOURCES:             "Connection Rejected due to Limited Resources",
+	HCI_STATUS_CONNECTION_REJECTED_DUE_TO_SECURITY_REASONS:              "Connection Rejected Due To Security Reasons",
+	HCI_STATUS_CONNECTION_REJECTED_DUE_TO_UNACCEPTABLE_BD_ADDR:          "Connection Rejected due to Unacceptable BD_ADDR",
+	HCI_STATUS_CONNECTION_ACCEPT_TIMEOUT_EXCEEDED:                       "Connection Accept Timeout Exceeded",
+	HCI_STATUS_UNSUPPORTED_FEATURE_OR_PARAMETER_VALUE:                   "Unsupported Feature or Parameter Value",
+	HCI_STATUS_INVALID_HCI_COMMAND_PARAMETERS:                           "Invalid HCI Command Parameters",
+	HCI_STATUS_REMOTE_USER_TERMINATED_CONNECTION:                        "Remote User Terminated Connection",
+	HCI_STATUS_REMOTE_DEVICE_TERMINATED_CONNECTION_DUE_TO_LOW_RESOURCES: "Remote Device Terminated Connection due to Low Resources",
+	HCI_STATUS_REMOTE_DEVICE_TERMINATED_CONNECTION_DUE_TO_POWER_OFF:     "Remote Device Terminated Connection due to Power Off",
+	HCI_STATUS_CONNECTION_TERMINATED_BY_LOCAL_HOST:                      "Connection Terminated By Local Host",
+	HCI_STATUS_REPEATED_ATTEMPTS:                                        "Repeated Attempts",
+	HCI_STATUS_PAIRING_NOT_ALLOWED:                                      "Pairing Not Allowed",
+	HCI_STATUS_UNKNOWN_LMP_PDU:                                          "Unknown LMP PDU",
+	HCI_STATUS_UNSUPPORTED_REMOTE_FEATURE:                               "Unsupported Remote Feature",
+	HCI_STATUS_SCO_OFFSET_REJECTED:                                      "SCO Offset Rejected",
+	HCI_STATUS_SCO_INTERVAL_REJECTED:                                    "SCO Interval Rejected",
+	HCI_STATUS_SCO_AIR_MODE_REJECTED:                                    "SCO Air Mode Rejected",
+	HCI_STATUS_INVALID_LMP_LL_PARAMETERS:                                "Invalid LMP Parameters / Invalid LL Parameters",
+	HCI_STATUS_UNSPECIFIED_ERROR:                                        "Unspecified Error",
+	HCI_STATUS_UNSUPPORTED_LMP_LL_PARAMETER_VALUE:                       "Unsupported LMP Parameter Value / Unsupported LL Parameter Value",
+	HCI_STATUS_ROLE_CHANGE_NOT_ALLOWED:                                  "Role Change Not Allowed",
+	HCI_STATUS_LMP_LL_RESPONSE_TIMEOUT:                                  "LMP Response Timeout / LL Response Timeout",
+	HCI_STATUS_LMP_ERROR_TRANSACTION_COLLISION:                          "LMP Error Transaction Collision / LL Procedure Collision",
+	HCI_STATUS_LMP_PDU_NOT_ALLOWED:                                      "LMP PDU Not Allowed",
+	HCI_STATUS_ENCRYPTION_MODE_NOT_ACCEPTABLE:                           "Encryption Mode Not Acceptable",
+	HCI_STATUS_LINK_KEY_CANNOT_BE_CHANGED:                               "Link Key cannot be Changed",
+	HCI_STATUS_REQUESTED_QOS_NOT_SUPPORTED:                              "Requested QoS Not Supported",
+	HCI_STATUS_INSTANT_PASSED:                                           "Instant Passed",
+	HCI_STATUS_PAIRING_WITH_UNIT_KEY_NOT_SUPPORTED:                      "Pairing With Unit Key Not Supported",
+	HCI_STATUS_DIFFERENT_TRANSACTION_COLLISION:                          "Different Transaction Collision",
+	HCI_STATUS_QOS_UNACCEPTABLE_PARAMETER:                               "QoS Unacceptable Parameter",
+	HCI_STATUS_QOS_REJECTED:                                             "QoS Rejected",
+	HCI_STATUS_CHANNEL_CLASSIFICATION_NOT_SUPPORTED:                     "Channel Classification Not Supported",
+	HCI_STATUS_INSUFFICIENT_SECURITY:                                    "Insufficient Security",
+	HCI_STATUS_PARAMETER_OUT_OF_MANDATORY_RANGE:                         "Parameter Out Of Mandatory Range",
+	HCI_STATUS_ROLE_SWITCH_PENDING:                                      "Role Switch Pending",
+	HCI_STATUS_RESERVED_SLOT_VIOLATION:                                  "Reserved Slot Violation",
+	HCI_STATUS_ROLE_SWITCH_FAILED:                                       "Role Switch Failed",
+	HCI_STATUS_EXTENDED_INQUIRY_RESPONSE_TOO_LARGE:                      "Extended Inquiry Response Too Large",
+	HCI_STATUS_SECURE_SIMPLE_PAIRING_NOT_SUPPORTED_BY_HOST:              "Secure Simple Pairing Not Supported By Host",
+	HCI_STATUS_HOST_BUSY_PAIRING:                                        "Host Busy - Pairing",
+	HCI_STATUS_CONNECTION_REJECTED_DUE_TO_NO_SUITABLE_CHANNEL_FOUND:     "Connection Rejected due to No Suitable Channel Found",
+	HCI_STATUS_CONTROLLER_BUSY:                                          "Controller Busy",
+	HCI_STATUS_UNACCEPTABLE_CONNECTION_PARAMETERS:                       "Unacceptable Connection Parameters",
+	HCI_STATUS_ADVERTISING_TIMEOUT:                                      "Advertising Timeout",
+	HCI_STATUS_CONNECTION_TERMINATED_DUE_TO_MIC_FAILURE:                 "Connection Terminated due to MIC Failure",
+	HCI_STATUS_CONNECTION_FAILED_TO_BE_ESTABLISHED:                      "Connection Failed to be Established / Synchronization Timeout",
+	HCI_STATUS_MAC_CONNECTION_FAILED:                                    "MAC Connection Failed",
+	HCI_STATUS_COARSE_CLOCK_ADJUSTMENT_REJECTED:                         "Coarse Clock Adjustment Rejected but Will Try to Adjust Using Clock Dragging",
+	HCI_STATUS_TYPE0_SUBMAP_NOT_DEFINED:                                 "Type0 Submap Not Defined",
+	HCI_STATUS_UNKNOWN_ADVERTISING_IDENTIFIER:                           "Unknown Advertising Identifier",
+	HCI_STATUS_LIMIT_REACHED:                                            "Limit Reached",
+	HCI_STATUS_OPERATION_CANCELLED_BY_HOST:                              "Operation Cancelled by Host",
+	HCI_STATUS_PACKET_TOO_LONG:                                          "Packet Too Long",
+}
+
+// 格式: 名称(0xXX)，不在错误码表中的值输出Reserved
+func (status HciStatus) String() string {
+	str, ok := HciStatusStrMap[status]
+	if !ok {
+		str = "Reserved"
+	}
+	return fmt.Sprintf("%s(0x%02X)", str, uint8(status))
+}
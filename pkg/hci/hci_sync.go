@@ -0,0 +1,358 @@
+// SCO/eSCO同步连接相关cmd/evt处理
+
+package hci
+
+import (
+	"encoding/binary"
+)
+
+// Voice_Setting各字段
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 6.12 Voice Setting
+const (
+	VOICE_SETTING_DEFAULT = 0x0060 // Linear, 2's complement, 16-bit, CVSD
+
+	VOICE_SETTING_AIR_CODING_MASK        = 0x0003
+	VOICE_SETTING_AIR_CODING_CVSD        = 0x0000
+	VOICE_SETTING_AIR_CODING_U_LAW       = 0x0001
+	VOICE_SETTING_AIR_CODING_A_LAW       = 0x0002
+	VOICE_SETTING_AIR_CODING_TRANSPARENT = 0x0003
+
+	VOICE_SETTING_INPUT_SAMPLE_SIZE_16BIT = 0x0020
+
+	VOICE_SETTING_INPUT_DATA_FORMAT_MASK             = 0x00C0
+	VOICE_SETTING_INPUT_DATA_FORMAT_ONES_COMPLEMENT  = 0x0000
+	VOICE_SETTING_INPUT_DATA_FORMAT_TWOS_COMPLEMENT  = 0x0040
+	VOICE_SETTING_INPUT_DATA_FORMAT_SIGN_MAGNITUDE   = 0x0080
+	VOICE_SETTING_INPUT_DATA_FORMAT_UNSIGNED         = 0x00C0
+	VOICE_SETTING_INPUT_CODING_MASK                  = 0x0300
+	VOICE_SETTING_INPUT_CODING_LINEAR                = 0x0000
+	VOICE_SETTING_INPUT_CODING_U_LAW                 = 0x0100
+	VOICE_SETTING_INPUT_CODING_A_LAW                 = 0x0200
+	VOICE_SETTING_LINEAR_PCM_BIT_POSITION_MASK       = 0x001C
+	VOICE_SETTING_LINEAR_PCM_BIT_POSITION_BIT_OFFSET = 2
+)
+
+// Coding_Format
+// Assigned Numbers 2.11 Codec IDs
+const (
+	CODING_FORMAT_U_LAW       = 0x00
+	CODING_FORMAT_A_LAW       = 0x01
+	CODING_FORMAT_CVSD        = 0x02
+	CODING_FORMAT_TRANSPARENT = 0x03
+	CODING_FORMAT_LINEAR_PCM  = 0x04
+	CODING_FORMAT_MSBC        = 0x05
+	CODING_FORMAT_LC3         = 0x06
+	CODING_FORMAT_G729A       = 0x07
+	CODING_FORMAT_VENDOR      = 0xFF
+)
+
+// PCM_Data_Format
+const (
+	PCM_DATA_FORMAT_NA              = 0x00
+	PCM_DATA_FORMAT_ONES_COMPLEMENT = 0x01
+	PCM_DATA_FORMAT_TWOS_COMPLEMENT = 0x02
+	PCM_DATA_FORMAT_SIGN_MAGNITUDE  = 0x03
+	PCM_DATA_FORMAT_UNSIGNED        = 0x04
+)
+
+// Data_Path
+const (
+	DATA_PATH_HCI = 0x00
+)
+
+// Link_Type
+const (
+	LINK_TYPE_SCO  = 0x00
+	LINK_TYPE_ACL  = 0x01
+	LINK_TYPE_ESCO = 0x02
+)
+
+// Air_Mode
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.35 Synchronous Connection Complete Event
+const (
+	AIR_MODE_U_LAW       = 0x00
+	AIR_MODE_A_LAW       = 0x01
+	AIR_MODE_CVSD        = 0x02
+	AIR_MODE_TRANSPARENT = 0x03
+)
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.26 Setup Synchronous Connection Command
+type HciSetupSynchronousConnection struct {
+	ConnectionHandle     uint16 // ACL连接handle
+	TransmitBandwidth    uint32
+	ReceiveBandwidth     uint32
+	MaxLatency           uint16
+	VoiceSetting         uint16
+	RetransmissionEffort uint8
+	PacketType           uint16
+}
+
+func HciSetupSynchronousConnectionParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciSetupSynchronousConnection{}
+	if len(hciCmdPktPayloadBuf) < 17 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.TransmitBandwidth = binary.LittleEndian.Uint32(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.TransmitBandwidth)
+	pkt.ReceiveBandwidth = binary.LittleEndian.Uint32(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ReceiveBandwidth)
+	pkt.MaxLatency = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.MaxLatency)
+	pkt.VoiceSetting = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.VoiceSetting)
+	pkt.RetransmissionEffort = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.RetransmissionEffort)
+	pkt.PacketType = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.1.27 Accept Synchronous Connection Request Command
+type HciAcceptSynchronousConnectionRequest struct {
+	BdAddr               [6]byte
+	TransmitBandwidth    uint32
+	ReceiveBandwidth     uint32
+	MaxLatency           uint16
+	VoiceSetting         uint16 // Content_Format
+	RetransmissionEffort uint8
+	PacketType           uint16
+}
+
+func HciAcceptSynchronousConnectionRequestParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciAcceptSynchronousConnectionRequest{}
+	if len(hciCmdPktPayloadBuf) < 21 {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.BdAddr = BdAddrParse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += len(pkt.BdAddr)
+	pkt.TransmitBandwidth = binary.LittleEndian.Uint32(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.TransmitBandwidth)
+	pkt.ReceiveBandwidth = binary.LittleEndian.Uint32(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ReceiveBandwidth)
+	pkt.MaxLatency = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.MaxLatency)
+	pkt.VoiceSetting = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.VoiceSetting)
+	pkt.RetransmissionEffort = hciCmdPktPayloadBuf[bufIndex]
+	bufIndex += binary.Size(pkt.RetransmissionEffort)
+	pkt.PacketType = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// Coding_Format: 1字节Coding Format + 2字节Company ID + 2字节Vendor Codec ID
+type CodingFormat struct {
+	CodingFormat  uint8 // CODING_FORMAT_XXX
+	CompanyId     uint16
+	VendorCodecId uint16
+}
+
+func codingFormatParse(buf []byte) CodingFormat {
+	return CodingFormat{
+		CodingFormat:  buf[0],
+		CompanyId:     binary.LittleEndian.Uint16(buf[1:]),
+		VendorCodecId: binary.LittleEndian.Uint16(buf[3:]),
+	}
+}
+
+// Enhanced Setup/Accept共用的参数
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.1.45 Enhanced Setup Synchronous Connection Command
+type EnhancedSynchronousConnectionParameters struct {
+	TransmitBandwidth                 uint32
+	ReceiveBandwidth                  uint32
+	TransmitCodingFormat              CodingFormat // 空口编码
+	ReceiveCodingFormat               CodingFormat
+	TransmitCodecFrameSize            uint16
+	ReceiveCodecFrameSize             uint16
+	InputBandwidth                    uint32
+	OutputBandwidth                   uint32
+	InputCodingFormat                 CodingFormat // host -> controller数据编码
+	OutputCodingFormat                CodingFormat // controller -> host数据编码
+	InputCodedDataSize                uint16       // 单位bit
+	OutputCodedDataSize               uint16
+	InputPcmDataFormat                uint8 // PCM_DATA_FORMAT_XXX
+	OutputPcmDataFormat               uint8
+	InputPcmSamplePayloadMsbPosition  uint8
+	OutputPcmSamplePayloadMsbPosition uint8
+	InputDataPath                     uint8 // DATA_PATH_HCI为HCI传输
+	OutputDataPath                    uint8
+	InputTransportUnitSize            uint8
+	OutputTransportUnitSize           uint8
+	MaxLatency                        uint16
+	PacketType                        uint16
+	RetransmissionEffort              uint8
+}
+
+const enhancedSynchronousConnectionParametersLen = 57
+
+func enhancedSynchronousConnectionParametersParse(buf []byte) EnhancedSynchronousConnectionParameters {
+	param := EnhancedSynchronousConnectionParameters{}
+	bufIndex := 0
+	param.TransmitBandwidth = binary.LittleEndian.Uint32(buf[bufIndex:])
+	bufIndex += binary.Size(param.TransmitBandwidth)
+	param.ReceiveBandwidth = binary.LittleEndian.Uint32(buf[bufIndex:])
+	bufIndex += binary.Size(param.ReceiveBandwidth)
+	param.TransmitCodingFormat = codingFormatParse(buf[bufIndex:])
+	bufIndex += binary.Size(param.TransmitCodingFormat)
+	param.ReceiveCodingFormat = codingFormatParse(buf[bufIndex:])
+	bufIndex += binary.Size(param.ReceiveCodingFormat)
+	param.TransmitCodecFrameSize = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(param.TransmitCodecFrameSize)
+	param.ReceiveCodecFrameSize = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(param.ReceiveCodecFrameSize)
+	param.InputBandwidth = binary.LittleEndian.Uint32(buf[bufIndex:])
+	bufIndex += binary.Size(param.InputBandwidth)
+	param.OutputBandwidth = binary.LittleEndian.Uint32(buf[bufIndex:])
+	bufIndex += binary.Size(param.OutputBandwidth)
+	param.InputCodingFormat = codingFormatParse(buf[bufIndex:])
+	bufIndex += binary.Size(param.InputCodingFormat)
+	param.OutputCodingFormat = codingFormatParse(buf[bufIndex:])
+	bufIndex += binary.Size(param.OutputCodingFormat)
+	param.InputCodedDataSize = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(param.InputCodedDataSize)
+	param.OutputCodedDataSize = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(param.OutputCodedDataSize)
+	param.InputPcmDataFormat = buf[bufIndex]
+	bufIndex += binary.Size(param.InputPcmDataFormat)
+	param.OutputPcmDataFormat = buf[bufIndex]
+	bufIndex += binary.Size(param.OutputPcmDataFormat)
+	param.InputPcmSamplePayloadMsbPosition = buf[bufIndex]
+	bufIndex += binary.Size(param.InputPcmSamplePayloadMsbPosition)
+	param.OutputPcmSamplePayloadMsbPosition = buf[bufIndex]
+	bufIndex += binary.Size(param.OutputPcmSamplePayloadMsbPosition)
+	param.InputDataPath = buf[bufIndex]
+	bufIndex += binary.Size(param.InputDataPath)
+	param.OutputDataPath = buf[bufIndex]
+	bufIndex += binary.Size(param.OutputDataPath)
+	param.InputTransportUnitSize = buf[bufIndex]
+	bufIndex += binary.Size(param.InputTransportUnitSize)
+	param.OutputTransportUnitSize = buf[bufIndex]
+	bufIndex += binary.Size(param.OutputTransportUnitSize)
+	param.MaxLatency = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(param.MaxLatency)
+	param.PacketType = binary.LittleEndian.Uint16(buf[bufIndex:])
+	bufIndex += binary.Size(param.PacketType)
+	param.RetransmissionEffort = buf[bufIndex]
+	return param
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.1.45 Enhanced Setup Synchronous Connection Command
+type HciEnhancedSetupSynchronousConnection struct {
+	ConnectionHandle uint16 // ACL连接handle
+	EnhancedSynchronousConnectionParameters
+}
+
+func HciEnhancedSetupSynchronousConnectionParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciEnhancedSetupSynchronousConnection{}
+	if len(hciCmdPktPayloadBuf) < binary.Size(pkt.ConnectionHandle)+enhancedSynchronousConnectionParametersLen {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.EnhancedSynchronousConnectionParameters = enhancedSynchronousConnectionParametersParse(hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 5.0 | Vol 2, Part E 7.1.46 Enhanced Accept Synchronous Connection Request Command
+type HciEnhancedAcceptSynchronousConnectionRequest struct {
+	BdAddr [6]byte
+	EnhancedSynchronousConnectionParameters
+}
+
+func HciEnhancedAcceptSynchronousConnectionRequestParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciEnhancedAcceptSynchronousConnectionRequest{}
+	if len(hciCmdPktPayloadBuf) < len(pkt.BdAddr)+enhancedSynchronousConnectionParametersLen {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	bufIndex := 0
+	pkt.BdAddr = BdAddrParse(hciCmdPktPayloadBuf[bufIndex:])
+	bufIndex += len(pkt.BdAddr)
+	pkt.EnhancedSynchronousConnectionParameters = enhancedSynchronousConnectionParametersParse(hciCmdPktPayloadBuf[bufIndex:])
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.3.28 Write Voice Setting Command
+type HciWriteVoiceSetting struct {
+	VoiceSetting uint16
+}
+
+func HciWriteVoiceSettingParser(OpCodeOgf uint8, OpCodeOcf uint16, hciCmdPktPayloadBuf []byte) HciCmdPktParseResult {
+	pkt := HciWriteVoiceSetting{}
+	if len(hciCmdPktPayloadBuf) < binary.Size(pkt.VoiceSetting) {
+		return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pkt.VoiceSetting = binary.LittleEndian.Uint16(hciCmdPktPayloadBuf)
+	return HciCmdPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.35 Synchronous Connection Complete Event
+type SynchronousConnectionCompleteEvent struct {
+	Status               HciStatus
+	ConnectionHandle     uint16 // SCO/eSCO连接handle，HciSync.Handle与之对应
+	BdAddr               [6]byte
+	LinkType             uint8 // LINK_TYPE_XXX
+	TransmissionInterval uint8
+	RetransmissionWindow uint8
+	RxPacketLength       uint16
+	TxPacketLength       uint16
+	AirMode              uint8 // AIR_MODE_XXX
+}
+
+func SynchronousConnectionCompleteEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := SynchronousConnectionCompleteEvent{}
+	if len(hciEvtPktPayloadBuf) < 17 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.BdAddr = BdAddrParse(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += len(pkt.BdAddr)
+	pkt.LinkType = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.LinkType)
+	pkt.TransmissionInterval = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.TransmissionInterval)
+	pkt.RetransmissionWindow = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.RetransmissionWindow)
+	pkt.RxPacketLength = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.RxPacketLength)
+	pkt.TxPacketLength = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.TxPacketLength)
+	pkt.AirMode = hciEvtPktPayloadBuf[pktIndex]
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
+
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 7.7.36 Synchronous Connection Changed Event
+type SynchronousConnectionChangedEvent struct {
+	Status               HciStatus
+	ConnectionHandle     uint16
+	TransmissionInterval uint8
+	RetransmissionWindow uint8
+	RxPacketLength       uint16
+	TxPacketLength       uint16
+}
+
+func SynchronousConnectionChangedEventParser(EventCode uint8, SubEventCode int, hciEvtPktPayloadBuf []byte) HciEvtPktParseResult {
+	pkt := SynchronousConnectionChangedEvent{}
+	if len(hciEvtPktPayloadBuf) < 9 {
+		return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_INVALID_LEN}
+	}
+	pktIndex := 0
+	pkt.Status = HciStatus(hciEvtPktPayloadBuf[pktIndex])
+	pktIndex += binary.Size(pkt.Status)
+	pkt.ConnectionHandle = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.ConnectionHandle)
+	pkt.TransmissionInterval = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.TransmissionInterval)
+	pkt.RetransmissionWindow = hciEvtPktPayloadBuf[pktIndex]
+	pktIndex += binary.Size(pkt.RetransmissionWindow)
+	pkt.RxPacketLength = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	pktIndex += binary.Size(pkt.RxPacketLength)
+	pkt.TxPacketLength = binary.LittleEndian.Uint16(hciEvtPktPayloadBuf[pktIndex:])
+	return HciEvtPktParseResult{Code: HCI_PKT_RET_CODE_OK, Ret: pkt}
+}
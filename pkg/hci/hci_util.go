@@ -0,0 +1,67 @@
+// hci公共解析函数
+
+package hci
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// BD_ADDR在报文中为小端序，转换为大端序便于显示
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part B] 1.2 BLUETOOTH DEVICE ADDRESSING
+func BdAddrParse(buf []byte) [6]byte {
+	addr := [6]byte{}
+	for index := 0; index < len(addr) && index < len(buf); index++ {
+		addr[len(addr)-1-index] = buf[index]
+	}
+	return addr
+}
+
+// BD_ADDR格式化输出: XX:XX:XX:XX:XX:XX
+func BdAddrString(addr [6]byte) string {
+	return fmt.Sprintf("%02X:%02X:%02X:%02X:%02X:%02X", addr[0], addr[1], addr[2], addr[3], addr[4], addr[5])
+}
+
+// 3字节小端序整数
+func Uint24Parse(buf []byte) uint32 {
+	return uint32(buf[0]) | uint32(buf[1])<<8 | uint32(buf[2])<<16
+}
+
+// OGF和OCF合成OpCode
+func HciOpCode(opCodeOgf uint8, opCodeOcf uint16) uint16 {
+	return uint16(opCodeOgf)<<10 | opCodeOcf&0x03ff
+}
+
+// OpCode拆分为OGF(高6bit)和OCF(低10bit)
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 2, Part E] 5.4.1 HCI Command Packet
+func HciOpCodeSplit(opCode uint16) (uint8, uint16) {
+	return uint8(opCode >> 10 & 0x3f), opCode & 0x03ff
+}
+
+// 以0结尾的UTF-8字符串，没有结束符时取整个buf
+func nullTerminatedStringParse(buf []byte) string {
+	for index, c := range buf {
+		if c == 0 {
+			return string(buf[:index])
+		}
+	}
+	return string(buf)
+}
+
+// 报文中的UUID为小端序，16bit/32bit输出为4/8位十六进制，128bit输出为XXXXXXXX-XXXX-XXXX-XXXX-XXXXXXXXXXXX
+// BLUETOOTH SPECIFICATION Version 4.2 [Vol 3, Part B] 2.5.1 UUID
+func UuidString(buf []byte) string {
+	switch len(buf) {
+	case 2:
+		return fmt.Sprintf("%04X", binary.LittleEndian.Uint16(buf))
+	case 4:
+		return fmt.Sprintf("%08X", binary.LittleEndian.Uint32(buf))
+	case 16:
+		uuid := [16]byte{}
+		for index := range uuid {
+			uuid[index] = buf[len(buf)-1-index]
+		}
+		return fmt.Sprintf("%X-%X-%X-%X-%X", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16])
+	}
+	return fmt.Sprintf("%X", buf)
+}